@@ -0,0 +1,123 @@
+package bidder2
+
+import (
+	"context"
+	"errors"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgotkn "github.com/SolmateDev/solana-go/programs/token"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	bin "github.com/gagliardetto/binary"
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+)
+
+type balanceUpdate struct {
+	isVault bool
+	amount  uint64
+}
+
+// Track the lamports in the bidder wallet and the tokens in the pc vault.
+func loopBalance(
+	ctx context.Context,
+	wsClient *sgows.Client,
+	id sgo.PublicKey,
+	isVault bool,
+	errorC chan<- error,
+	balanceC chan<- balanceUpdate,
+) {
+	var err error
+	doneC := ctx.Done()
+	sub, err := wsClient.AccountSubscribe(id, sgorpc.CommitmentConfirmed)
+	if err != nil {
+		errorC <- err
+		return
+	}
+	defer sub.Unsubscribe()
+	streamC := sub.RecvStream()
+	subErrorC := sub.RecvErr()
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-subErrorC:
+			break out
+		case d := <-streamC:
+			x, ok := d.(*sgows.AccountResult)
+			if !ok {
+				err = errors.New("bad account result")
+				break out
+			}
+			update := balanceUpdate{isVault: isVault}
+			if isVault {
+				vault := new(sgotkn.Account)
+				err = bin.UnmarshalBorsh(vault, x.Value.Account.Data.GetBinary())
+				if err != nil {
+					break out
+				}
+				update.amount = vault.Amount
+			} else {
+				update.amount = x.Value.Lamports
+			}
+			select {
+			case <-doneC:
+				break out
+			case balanceC <- update:
+			}
+		}
+	}
+	if err != nil {
+		select {
+		case <-doneC:
+		case errorC <- err:
+		}
+	}
+}
+
+func (in *internal) on_balance(update balanceUpdate) {
+	if update.isVault {
+		in.pcVaultBalance = update.amount
+	} else {
+		in.solBalance = update.amount
+	}
+	if update.isVault {
+		// we may have been waiting on funds to top up a bid
+		in.place_bids()
+	}
+	for _, b := range in.balance_list() {
+		in.balanceHome.Broadcast(b)
+	}
+}
+
+// list the balance for every pipeline in which we have a deposit on the upcoming period
+func (in *internal) balance_list() []*pbb.Balance {
+	ans := make([]*pbb.Balance, 0, len(in.pipelineM)+1)
+	for id, pi := range in.pipelineM {
+		y := pi.upcoming(in.slot)
+		if y == nil {
+			continue
+		}
+		ours, others := y.deposits(in.bidder.PublicKey())
+		if ours == 0 {
+			continue
+		}
+		ans = append(ans, &pbb.Balance{
+			Pipeline: &pbb.PipelineDeposit{
+				PipelineId: id,
+				Deposit:    ours,
+				Share:      float32(float64(ours) / float64(ours+others)),
+			},
+			PcVault: in.pcVaultBalance,
+			Sol:     in.solBalance,
+		})
+	}
+	if len(ans) == 0 {
+		ans = append(ans, &pbb.Balance{
+			PcVault: in.pcVaultBalance,
+			Sol:     in.solBalance,
+		})
+	}
+	return ans
+}
@@ -0,0 +1,277 @@
+package bidder2
+
+import (
+	"context"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	cba "github.com/solpipe/cba"
+	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
+)
+
+const MAX_TRIES_BID = 3
+const DELAY_BID = 5 * time.Second
+const MAX_TRIES_CLAIM_REFUND = 5
+const DELAY_CLAIM_REFUND = 30 * time.Second
+
+type bidResult struct {
+	pipelineId sgo.PublicKey
+	payoutId   sgo.PublicKey
+	amount     uint64
+	err        error
+}
+
+type refundResult struct {
+	pipelineId sgo.PublicKey
+	err        error
+}
+
+// Top up our bid on the upcoming payout of each pipeline so that our deposit
+// matches the target deposit.  Deposits cannot be withdrawn from an open
+// payout, so a lower target only means we stop topping up.
+func (in *internal) place_bids() {
+	for id, target := range in.targetM {
+		pi, present := in.pipelineM[id]
+		if !present {
+			continue
+		}
+		y := pi.upcoming(in.slot)
+		if y == nil {
+			continue
+		}
+		if 0 < y.pending {
+			// wait for the last bid to settle before sending another
+			continue
+		}
+		ours, _ := y.deposits(in.bidder.PublicKey())
+		if target.Deposit <= ours {
+			continue
+		}
+		amount := target.Deposit - ours
+		if in.pcVaultBalance < in.pending_total()+amount {
+			log.Debugf("insufficient funds in pc vault to bid %d on pipeline=%s", amount, id)
+			continue
+		}
+		in.send_bid(pi, y, amount)
+	}
+}
+
+// the sum of deposits in bids that have been sent, but not confirmed
+func (in *internal) pending_total() uint64 {
+	ans := uint64(0)
+	for _, pi := range in.pipelineM {
+		for _, y := range pi.payoutM {
+			ans += y.pending
+		}
+	}
+	return ans
+}
+
+func (in *internal) send_bid(pi *pipelineInfo, y *payoutInfo, amount uint64) {
+	y.pending = amount
+	y.pendingBase = y.confirmed(in.bidder.PublicKey())
+	bidder := in.bidder
+	pcVaultId := in.pcVaultId
+	controller := in.router.Controller
+	pipeline := pi.p
+	payout := y.pwd.Payout
+	log.Debugf("bidding %d on pipeline=%s payout=%s", amount, pipeline.Id.String(), payout.Id.String())
+	errorC := make(chan error, 1)
+	in.wrapper.SendDetached(
+		y.ctx,
+		MAX_TRIES_BID,
+		DELAY_BID,
+		func(script *spt.Script) error {
+			return RunBid(
+				script,
+				bidder,
+				pcVaultId,
+				controller,
+				pipeline,
+				payout,
+				amount,
+			)
+		},
+		errorC,
+	)
+	go loopBidResult(in.ctx, pipeline.Id, payout.Id, amount, errorC, in.bidResultC)
+}
+
+func RunBid(
+	script *spt.Script,
+	bidder signer.Signer,
+	pcVaultId sgo.PublicKey,
+	controller ctr.Controller,
+	pipeline pipe.Pipeline,
+	payout pyt.Payout,
+	amount uint64,
+) error {
+	err := script.SetTx(bidder)
+	if err != nil {
+		return err
+	}
+	err = script.Bid(
+		controller,
+		cba.Bid{
+			User:    bidder.PublicKey(),
+			Deposit: amount,
+			IsBlank: false,
+		},
+		pipeline,
+		payout,
+		bidder,
+		pcVaultId,
+	)
+	if err != nil {
+		return err
+	}
+	err = script.FinishTx(true)
+	if err != nil {
+		log.Debugf("failed to bid on payout=%s", payout.Id.String())
+		return err
+	}
+	log.Debugf("bid %d on payout=%s", amount, payout.Id.String())
+	return nil
+}
+
+func loopBidResult(
+	ctx context.Context,
+	pipelineId sgo.PublicKey,
+	payoutId sgo.PublicKey,
+	amount uint64,
+	errorC <-chan error,
+	resultC chan<- bidResult,
+) {
+	doneC := ctx.Done()
+	var err error
+	select {
+	case <-doneC:
+		return
+	case err = <-errorC:
+	}
+	select {
+	case <-doneC:
+	case resultC <- bidResult{pipelineId: pipelineId, payoutId: payoutId, amount: amount, err: err}:
+	}
+}
+
+// A bid that landed stays pending until the bid status subscription shows
+// the deposit (see settle), or else place_bids would top up a second time.
+func (in *internal) on_bid_result(r bidResult) {
+	pi, present := in.pipelineM[r.pipelineId.String()]
+	if !present {
+		return
+	}
+	y, present := pi.payoutM[r.payoutId.String()]
+	if !present {
+		return
+	}
+	if r.err == nil {
+		y.settle(in.bidder.PublicKey())
+		return
+	}
+	log.Debugf("bid on payout=%s failed: %s", r.payoutId.String(), r.err.Error())
+	y.pending = 0
+	y.pendingBase = 0
+	in.rebalance()
+}
+
+func (in *internal) on_claim(update claimUpdate) {
+	pi, present := in.pipelineM[update.pipelineId.String()]
+	if !present {
+		return
+	}
+	pi.refund = update.claim.Balance
+	in.claim_refund(pi)
+}
+
+// Claim refunds left over once periods on which we bid have finished.
+func (in *internal) claim_refund(pi *pipelineInfo) {
+	if pi.refund == 0 || pi.refundPending {
+		return
+	}
+	pi.refundPending = true
+	bidder := in.bidder
+	controller := in.router.Controller
+	pipeline := pi.p
+	claim := cba.Claim{User: bidder.PublicKey(), Balance: pi.refund}
+	log.Debugf("claiming refund %d from pipeline=%s", pi.refund, pipeline.Id.String())
+	errorC := make(chan error, 1)
+	in.wrapper.SendDetached(
+		pi.ctx,
+		MAX_TRIES_CLAIM_REFUND,
+		DELAY_CLAIM_REFUND,
+		func(script *spt.Script) error {
+			return RunClaimRefund(
+				script,
+				bidder,
+				controller,
+				pipeline,
+				claim,
+			)
+		},
+		errorC,
+	)
+	go loopRefundResult(in.ctx, pipeline.Id, errorC, in.refundResultC)
+}
+
+func RunClaimRefund(
+	script *spt.Script,
+	bidder signer.Signer,
+	controller ctr.Controller,
+	pipeline pipe.Pipeline,
+	claim cba.Claim,
+) error {
+	err := script.SetTx(bidder)
+	if err != nil {
+		return err
+	}
+	err = script.ClaimRefund(controller, pipeline, claim, bidder)
+	if err != nil {
+		return err
+	}
+	err = script.FinishTx(true)
+	if err != nil {
+		log.Debugf("failed to claim refund from pipeline=%s", pipeline.Id.String())
+		return err
+	}
+	return nil
+}
+
+func loopRefundResult(
+	ctx context.Context,
+	pipelineId sgo.PublicKey,
+	errorC <-chan error,
+	resultC chan<- refundResult,
+) {
+	doneC := ctx.Done()
+	var err error
+	select {
+	case <-doneC:
+		return
+	case err = <-errorC:
+	}
+	select {
+	case <-doneC:
+	case resultC <- refundResult{pipelineId: pipelineId, err: err}:
+	}
+}
+
+func (in *internal) on_refund_result(r refundResult) {
+	pi, present := in.pipelineM[r.pipelineId.String()]
+	if !present {
+		return
+	}
+	pi.refundPending = false
+	if r.err != nil {
+		log.Debugf("failed to claim refund from pipeline=%s: %s", r.pipelineId.String(), r.err.Error())
+		return
+	}
+	// the claim subscription will tell us if there is still a balance
+	pi.refund = 0
+}
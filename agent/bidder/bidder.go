@@ -2,41 +2,205 @@ package bidder2
 
 import (
 	"context"
+	"errors"
 
 	sgo "github.com/SolmateDev/solana-go"
 	sgotkn "github.com/SolmateDev/solana-go/programs/token"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
+	"github.com/solpipe/solpipe-tool/util"
 )
 
 type Agent struct {
-	ctx       context.Context
-	cancel    context.CancelFunc
-	router    rtr.Router
-	bidder    sgo.PrivateKey
-	pcVault   sgo.PublicKey
-	internalC chan<- func(*internal)
+	ctx        context.Context
+	cancel     context.CancelFunc
+	router     rtr.Router
+	bidder     signer.Signer
+	pcVault    sgo.PublicKey
+	internalC  chan<- func(*internal)
+	budgetReqC chan<- dssub.ResponseChannel[*pbb.TpsBudget]
+	statsReqC  chan<- dssub.ResponseChannel[*pbb.Stats]
+	balReqC    chan<- dssub.ResponseChannel[*pbb.Balance]
 }
 
+// Create a bidding agent.  The agent adjusts deposits across pipelines so that
+// the bidder gets the TPS in budget.  The budget is read from and saved to the
+// configuration file.
 func Create(
 	ctx context.Context,
 	rpcClient *sgorpc.Client,
 	wsClient *sgows.Client,
-	bidder sgo.PrivateKey,
+	bidder signer.Signer,
 	pcVaultId sgo.PublicKey,
 	pcVault *sgotkn.Account,
 	router rtr.Router,
+	configFilePath string,
 ) (Agent, error) {
+	budget, err := ConfigLoad(configFilePath)
+	if err != nil {
+		return Agent{}, err
+	}
+	script, err := spt.Create(
+		ctx,
+		&spt.Configuration{Version: router.Controller.Version},
+		rpcClient,
+		wsClient,
+	)
+	if err != nil {
+		return Agent{}, err
+	}
 	ctxC, cancel := context.WithCancel(ctx)
-	internalC := make(chan func(*internal))
-	go loopInternal(ctxC, cancel, internalC, router)
+	internalC := make(chan func(*internal), 10)
+	budgetHome := dssub.CreateSubHome[*pbb.TpsBudget]()
+	statsHome := dssub.CreateSubHome[*pbb.Stats]()
+	balanceHome := dssub.CreateSubHome[*pbb.Balance]()
+	go loopInternal(
+		ctxC,
+		cancel,
+		internalC,
+		wsClient,
+		router,
+		spt.Wrap(ctxC, script),
+		bidder,
+		pcVaultId,
+		configFilePath,
+		budget,
+		budgetHome,
+		statsHome,
+		balanceHome,
+	)
 
 	return Agent{
 		ctx: ctxC, cancel: cancel,
-		router:    router,
-		bidder:    bidder,
-		pcVault:   pcVaultId,
-		internalC: internalC,
+		router:     router,
+		bidder:     bidder,
+		pcVault:    pcVaultId,
+		internalC:  internalC,
+		budgetReqC: budgetHome.ReqC,
+		statsReqC:  statsHome.ReqC,
+		balReqC:    balanceHome.ReqC,
 	}, nil
 }
+
+func CheckBudget(budget *pbb.TpsBudget) error {
+	if budget == nil {
+		return errors.New("no budget")
+	}
+	if budget.MinTps < 0 || budget.MaxTps < 0 || budget.MaxSpend < 0 {
+		return errors.New("budget values must be non-negative")
+	}
+	if budget.MaxTps < budget.MinTps {
+		return errors.New("max tps is less than min tps")
+	}
+	if budget.MaxBidDelta < 0 || 1 < budget.MaxBidDelta {
+		return errors.New("max bid delta must be between 0 and 1")
+	}
+	return nil
+}
+
+// get alerted when the budget changes
+func (e1 Agent) OnBudget() dssub.Subscription[*pbb.TpsBudget] {
+	return dssub.SubscriptionRequest(e1.budgetReqC, func(x *pbb.TpsBudget) bool { return true })
+}
+
+// get network, pipeline and deposit updates
+func (e1 Agent) OnStats() dssub.Subscription[*pbb.Stats] {
+	return dssub.SubscriptionRequest(e1.statsReqC, func(x *pbb.Stats) bool { return true })
+}
+
+// get alerted when the deposit we want in a pipeline changes
+func (e1 Agent) OnDeposit() dssub.Subscription[*pbb.Stats] {
+	return dssub.SubscriptionRequest(e1.statsReqC, func(x *pbb.Stats) bool { return x.GetDeposit() != nil })
+}
+
+func (e1 Agent) OnBalance() dssub.Subscription[*pbb.Balance] {
+	return dssub.SubscriptionRequest(e1.balReqC, func(x *pbb.Balance) bool { return true })
+}
+
+func (e1 Agent) Budget() (*pbb.TpsBudget, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan *pbb.TpsBudget, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ans := util.InitBudget()
+		util.CopyProtoMessage(in.budget, ans)
+		ansC <- ans
+	}:
+	}
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case ans := <-ansC:
+		return ans, nil
+	}
+}
+
+func (e1 Agent) SetBudget(budget *pbb.TpsBudget) error {
+	err := CheckBudget(budget)
+	if err != nil {
+		return err
+	}
+	newBudget := util.InitBudget()
+	util.CopyProtoMessage(budget, newBudget)
+	doneC := e1.ctx.Done()
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		in.set_budget(newBudget)
+	}:
+	}
+	return nil
+}
+
+// get the current network, pipeline and deposit stats
+func (e1 Agent) Stats() ([]*pbb.Stats, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan []*pbb.Stats, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ansC <- in.stats_list()
+	}:
+	}
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case ans := <-ansC:
+		return ans, nil
+	}
+}
+
+// get the current balance of the pc vault, wallet and per pipeline deposits
+func (e1 Agent) Balance() ([]*pbb.Balance, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan []*pbb.Balance, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ansC <- in.balance_list()
+	}:
+	}
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case ans := <-ansC:
+		return ans, nil
+	}
+}
+
+// make a blocking call to close
+func (e1 Agent) Close() error {
+	signalC := e1.CloseSignal()
+	e1.cancel()
+	return <-signalC
+}
@@ -0,0 +1,152 @@
+package bidder2
+
+import (
+	"context"
+	"io"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+	"google.golang.org/grpc"
+)
+
+// Serve the Brain grpc service so that the bidder can set a TPS budget and
+// watch stats and balances.
+type brainServer struct {
+	pbb.UnimplementedBrainServer
+	agent Agent
+}
+
+// attach the Brain grpc service to a server (typically listening on the admin url)
+func (e1 Agent) Attach(grpcServer *grpc.Server) {
+	log.Debug("creating brain grpc server")
+	pbb.RegisterBrainServer(grpcServer, brainServer{agent: e1})
+}
+
+func (e1 brainServer) GetTpsBudget(req *pbb.Empty, stream pbb.Brain_GetTpsBudgetServer) error {
+	ctx := stream.Context()
+	sub := e1.agent.OnBudget()
+	defer sub.Unsubscribe()
+	budget, err := e1.agent.Budget()
+	if err != nil {
+		return err
+	}
+	err = stream.Send(budget)
+	if err != nil {
+		return err
+	}
+	doneC := ctx.Done()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-sub.ErrorC:
+			break out
+		case budget = <-sub.StreamC:
+			err = stream.Send(budget)
+			if err == io.EOF {
+				err = nil
+				break out
+			} else if err != nil {
+				break out
+			}
+		}
+	}
+	return err
+}
+
+func (e1 brainServer) SetTpsBudget(ctx context.Context, req *pbb.TpsBudget) (*pbb.TpsBudget, error) {
+	err := e1.agent.SetBudget(req)
+	if err != nil {
+		return nil, err
+	}
+	return e1.agent.Budget()
+}
+
+func (e1 brainServer) GetStats(req *pbb.Empty, stream pbb.Brain_GetStatsServer) error {
+	ctx := stream.Context()
+	sub := e1.agent.OnStats()
+	defer sub.Unsubscribe()
+	list, err := e1.agent.Stats()
+	if err != nil {
+		return err
+	}
+	for _, s := range list {
+		err = stream.Send(s)
+		if err != nil {
+			return err
+		}
+	}
+	doneC := ctx.Done()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-sub.ErrorC:
+			break out
+		case s := <-sub.StreamC:
+			err = stream.Send(s)
+			if err == io.EOF {
+				err = nil
+				break out
+			} else if err != nil {
+				break out
+			}
+		}
+	}
+	return err
+}
+
+func (e1 brainServer) GetBalance(req *pbb.Empty, stream pbb.Brain_GetBalanceServer) error {
+	ctx := stream.Context()
+	sub := e1.agent.OnBalance()
+	defer sub.Unsubscribe()
+	list, err := e1.agent.Balance()
+	if err != nil {
+		return err
+	}
+	for _, b := range list {
+		err = stream.Send(b)
+		if err != nil {
+			return err
+		}
+	}
+	doneC := ctx.Done()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-sub.ErrorC:
+			break out
+		case b := <-sub.StreamC:
+			err = stream.Send(b)
+			if err == io.EOF {
+				err = nil
+				break out
+			} else if err != nil {
+				break out
+			}
+		}
+	}
+	return err
+}
+
+// connect to the Brain grpc service of a running bidding agent
+func Dial(ctx context.Context, url string) (pbb.BrainClient, error) {
+	ctx2, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx2, url, grpc.WithBlock(), grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	go loopClientShutdown(ctx, conn)
+	return pbb.NewBrainClient(conn), nil
+}
+
+func loopClientShutdown(ctx context.Context, conn *grpc.ClientConn) {
+	<-ctx.Done()
+	conn.Close()
+}
@@ -0,0 +1,44 @@
+package bidder2
+
+import (
+	"encoding/json"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+	"github.com/solpipe/solpipe-tool/util"
+)
+
+// The configuration file holds the TPS budget from which the per pipeline
+// deposits are calculated.  A missing file means we start with a budget that
+// buys nothing.
+func ConfigLoad(configFilePath string) (*pbb.TpsBudget, error) {
+	log.Debugf("loading configuration file from %s", configFilePath)
+	budget := util.InitBudget()
+	f, err := os.Open(configFilePath)
+	if os.IsNotExist(err) {
+		return budget, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(budget)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckBudget(budget)
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (in *internal) config_save() error {
+	log.Debugf("saving configuration file to %s", in.configFilePath)
+	f, err := os.Create(in.configFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(in.budget)
+}
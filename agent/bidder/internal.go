@@ -4,30 +4,115 @@ import (
 	"context"
 	"errors"
 
+	sgo "github.com/SolmateDev/solana-go"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
 	log "github.com/sirupsen/logrus"
+	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
+	"github.com/solpipe/solpipe-tool/util"
 )
 
 type internal struct {
 	ctx              context.Context
 	errorC           chan<- error
 	closeSignalCList []chan<- error
+	router           rtr.Router
+	wrapper          spt.Wrapper
+	bidder           signer.Signer
+	pcVaultId        sgo.PublicKey
+	configFilePath   string
+	slot             uint64
+	networkTps       float64
+	budget           *pbb.TpsBudget
+	pipelineM        map[string]*pipelineInfo        // pipeline id -> pipeline
+	targetM          map[string]*pbb.PipelineDeposit // pipeline id -> deposit we want
+	pcVaultBalance   uint64
+	solBalance       uint64
+	budgetHome       *dssub.SubHome[*pbb.TpsBudget]
+	statsHome        *dssub.SubHome[*pbb.Stats]
+	balanceHome      *dssub.SubHome[*pbb.Balance]
+	stakeC           chan<- stakeUpdate
+	payoutC          chan<- payoutUpdate
+	bidC             chan<- bidUpdate
+	claimC           chan<- claimUpdate
+	bidResultC       chan<- bidResult
+	refundResultC    chan<- refundResult
 }
 
 func loopInternal(
 	ctx context.Context,
 	cancel context.CancelFunc,
 	internalC <-chan func(*internal),
+	wsClient *sgows.Client,
 	router rtr.Router,
+	wrapper spt.Wrapper,
+	bidder signer.Signer,
+	pcVaultId sgo.PublicKey,
+	configFilePath string,
+	budget *pbb.TpsBudget,
+	budgetHome *dssub.SubHome[*pbb.TpsBudget],
+	statsHome *dssub.SubHome[*pbb.Stats],
+	balanceHome *dssub.SubHome[*pbb.Balance],
 ) {
+	defer cancel()
 	var err error
 	doneC := ctx.Done()
-	errorC := make(chan error, 1)
+	errorC := make(chan error, 5)
+	stakeC := make(chan stakeUpdate)
+	payoutC := make(chan payoutUpdate)
+	bidC := make(chan bidUpdate)
+	balanceC := make(chan balanceUpdate)
+	claimC := make(chan claimUpdate)
+	bidResultC := make(chan bidResult)
+	refundResultC := make(chan refundResult)
 
 	in := new(internal)
 	in.ctx = ctx
 	in.errorC = errorC
 	in.closeSignalCList = make([]chan<- error, 0)
+	in.router = router
+	in.wrapper = wrapper
+	in.bidder = bidder
+	in.pcVaultId = pcVaultId
+	in.configFilePath = configFilePath
+	in.slot = 0
+	in.networkTps = 0
+	in.budget = budget
+	in.pipelineM = make(map[string]*pipelineInfo)
+	in.targetM = make(map[string]*pbb.PipelineDeposit)
+	in.budgetHome = budgetHome
+	in.statsHome = statsHome
+	in.balanceHome = balanceHome
+	in.stakeC = stakeC
+	in.payoutC = payoutC
+	in.bidC = bidC
+	in.claimC = claimC
+	in.bidResultC = bidResultC
+	in.refundResultC = refundResultC
+
+	pipelineSub := router.ObjectOnPipeline()
+	defer pipelineSub.Unsubscribe()
+	networkSub := router.Network.OnNetworkStats()
+	defer networkSub.Unsubscribe()
+	slotSub := router.Controller.SlotHome().OnSlot()
+	defer slotSub.Unsubscribe()
+
+	go loopBalance(ctx, wsClient, bidder.PublicKey(), false, errorC, balanceC)
+	go loopBalance(ctx, wsClient, pcVaultId, true, errorC, balanceC)
+
+	{
+		list, err := router.AllPipeline()
+		if err != nil {
+			errorC <- err
+		} else {
+			for _, p := range list {
+				in.on_pipeline(p)
+			}
+		}
+	}
 
 out:
 	for {
@@ -36,6 +121,47 @@ out:
 			break out
 		case err = <-errorC:
 			break out
+		case req := <-internalC:
+			req(in)
+		case id := <-in.budgetHome.DeleteC:
+			in.budgetHome.Delete(id)
+		case r := <-in.budgetHome.ReqC:
+			in.budgetHome.Receive(r)
+		case id := <-in.statsHome.DeleteC:
+			in.statsHome.Delete(id)
+		case r := <-in.statsHome.ReqC:
+			in.statsHome.Receive(r)
+		case id := <-in.balanceHome.DeleteC:
+			in.balanceHome.Delete(id)
+		case r := <-in.balanceHome.ReqC:
+			in.balanceHome.Receive(r)
+		case err = <-slotSub.ErrorC:
+			break out
+		case in.slot = <-slotSub.StreamC:
+		case err = <-networkSub.ErrorC:
+			break out
+		case ns := <-networkSub.StreamC:
+			in.networkTps = ns.AverageTransactionsPerSecond
+			in.statsHome.Broadcast(in.stats_network())
+			in.rebalance()
+		case err = <-pipelineSub.ErrorC:
+			break out
+		case p := <-pipelineSub.StreamC:
+			in.on_pipeline(p)
+		case update := <-stakeC:
+			in.on_stake(update)
+		case update := <-payoutC:
+			in.on_payout(update)
+		case update := <-bidC:
+			in.on_bid(update)
+		case update := <-balanceC:
+			in.on_balance(update)
+		case update := <-claimC:
+			in.on_claim(update)
+		case r := <-bidResultC:
+			in.on_bid_result(r)
+		case r := <-refundResultC:
+			in.on_refund_result(r)
 		}
 	}
 
@@ -44,11 +170,124 @@ out:
 
 func (in *internal) finish(err error) {
 	log.Debug(err)
+	for _, pi := range in.pipelineM {
+		pi.cancel()
+	}
+	in.budgetHome.Close()
+	in.statsHome.Close()
+	in.balanceHome.Close()
 	for _, signalC := range in.closeSignalCList {
 		signalC <- err
 	}
 }
 
+func (in *internal) set_budget(budget *pbb.TpsBudget) {
+	in.budget = budget
+	err := in.config_save()
+	if err != nil {
+		log.Debugf("failed to save budget: %s", err.Error())
+	}
+	budgetCopy := util.InitBudget()
+	util.CopyProtoMessage(budget, budgetCopy)
+	in.budgetHome.Broadcast(budgetCopy)
+	in.rebalance()
+}
+
+// Collect quotes for the upcoming period of every pipeline.
+func (in *internal) quotes() []pipelineQuote {
+	ans := make([]pipelineQuote, 0, len(in.pipelineM))
+	for id, pi := range in.pipelineM {
+		y := pi.upcoming(in.slot)
+		if y == nil {
+			continue
+		}
+		ours, others := y.deposits(in.bidder.PublicKey())
+		ans = append(ans, pipelineQuote{
+			id:             id,
+			capacity:       in.networkTps * pi.stakeShare,
+			otherDeposit:   float64(others),
+			periodSeconds:  float64(y.pwd.Data.Period.Length) * SLOT_DURATION_SECONDS,
+			currentDeposit: float64(ours),
+		})
+	}
+	return ans
+}
+
+// Recalculate how much we want to deposit in each pipeline and let subscribers
+// know if anything has changed.
+func (in *internal) rebalance() {
+	if in.budget == nil {
+		return
+	}
+	newTargetM := make(map[string]*pbb.PipelineDeposit)
+	for _, d := range in.target_deposits(in.quotes()) {
+		newTargetM[d.PipelineId] = d
+		old, present := in.targetM[d.PipelineId]
+		if present && old.Deposit == d.Deposit {
+			continue
+		}
+		in.statsHome.Broadcast(&pbb.Stats{Data: &pbb.Stats_Deposit{Deposit: d}})
+	}
+	for id := range in.targetM {
+		_, present := newTargetM[id]
+		if !present {
+			// we no longer have an upcoming period on this pipeline
+			in.statsHome.Broadcast(&pbb.Stats{Data: &pbb.Stats_Deposit{Deposit: &pbb.PipelineDeposit{
+				PipelineId: id,
+				Deposit:    0,
+				Share:      0,
+			}}})
+		}
+	}
+	in.targetM = newTargetM
+	in.place_bids()
+}
+
+func (in *internal) stats_network() *pbb.Stats {
+	return &pbb.Stats{Data: &pbb.Stats_Network{Network: &pbb.NetworkStats{
+		NetworkTps: float32(in.networkTps),
+	}}}
+}
+
+func (in *internal) pipeline_stats(pi *pipelineInfo) *pbb.Stats {
+	ps := &pbb.PipelineStats{
+		PipelineId:     pi.p.Id.String(),
+		StakeRatio:     float32(pi.stakeShare),
+		Price:          0,
+		Discount:       0,
+		CurrentDeposit: 0,
+	}
+	y := pi.upcoming(in.slot)
+	if y != nil {
+		ours, others := y.deposits(in.bidder.PublicKey())
+		q := pipelineQuote{
+			capacity:      in.networkTps * pi.stakeShare,
+			otherDeposit:  float64(others),
+			periodSeconds: float64(y.pwd.Data.Period.Length) * SLOT_DURATION_SECONDS,
+		}
+		ps.Price = float32(q.price())
+		ps.CurrentDeposit = ours
+	}
+	return &pbb.Stats{Data: &pbb.Stats_Pipeline{Pipeline: ps}}
+}
+
+func (in *internal) stats_pipeline(pi *pipelineInfo) {
+	in.statsHome.Broadcast(in.pipeline_stats(pi))
+}
+
+// get a snapshot of all stats so new subscribers do not have to wait for changes
+func (in *internal) stats_list() []*pbb.Stats {
+	ans := make([]*pbb.Stats, 0, 1+2*len(in.pipelineM))
+	ans = append(ans, in.stats_network())
+	for _, pi := range in.pipelineM {
+		ans = append(ans, in.pipeline_stats(pi))
+	}
+	for _, d := range in.targetM {
+		ans = append(ans, &pbb.Stats{Data: &pbb.Stats_Deposit{Deposit: d}})
+	}
+	return ans
+}
+
 func (e1 Agent) CloseSignal() <-chan error {
 	signalC := make(chan error, 1)
 	select {
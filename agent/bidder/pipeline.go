@@ -0,0 +1,348 @@
+package bidder2
+
+import (
+	"context"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	cba "github.com/solpipe/cba"
+	sch "github.com/solpipe/solpipe-tool/scheduler"
+	schpyt "github.com/solpipe/solpipe-tool/scheduler/payout"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+)
+
+type pipelineInfo struct {
+	ctx           context.Context
+	p             pipe.Pipeline
+	cancel        context.CancelFunc
+	stakeShare    float64
+	payoutM       map[string]*payoutInfo // payout id -> payout
+	refund        uint64                 // refund waiting to be claimed
+	refundPending bool
+}
+
+type payoutInfo struct {
+	ctx     context.Context // canceled once bids can no longer be inserted
+	cancel  context.CancelFunc
+	pwd     pipe.PayoutWithData
+	bs      pyt.BidStatus
+	pending uint64 // deposit in a bid that has been sent, but not seen in bs
+	// our deposit in bs when the pending bid was sent
+	pendingBase uint64
+}
+
+type stakeUpdate struct {
+	pipelineId sgo.PublicKey
+	share      float64
+}
+
+type payoutUpdate struct {
+	pipelineId sgo.PublicKey
+	pwd        pipe.PayoutWithData
+}
+
+type claimUpdate struct {
+	pipelineId sgo.PublicKey
+	claim      cba.Claim
+}
+
+type bidUpdate struct {
+	pipelineId sgo.PublicKey
+	payoutId   sgo.PublicKey
+	bs         pyt.BidStatus
+}
+
+func (in *internal) on_pipeline(pipeline pipe.Pipeline) {
+	_, present := in.pipelineM[pipeline.Id.String()]
+	if present {
+		return
+	}
+	ctxC, cancel := context.WithCancel(in.ctx)
+	in.pipelineM[pipeline.Id.String()] = &pipelineInfo{
+		ctx:           ctxC,
+		p:             pipeline,
+		cancel:        cancel,
+		stakeShare:    0,
+		payoutM:       make(map[string]*payoutInfo),
+		refund:        0,
+		refundPending: false,
+	}
+	go loopPipeline(
+		ctxC,
+		pipeline,
+		in.bidder.PublicKey(),
+		in.errorC,
+		in.stakeC,
+		in.payoutC,
+		in.claimC,
+	)
+}
+
+func (in *internal) on_stake(update stakeUpdate) {
+	pi, present := in.pipelineM[update.pipelineId.String()]
+	if !present {
+		return
+	}
+	pi.stakeShare = update.share
+	in.stats_pipeline(pi)
+	in.rebalance()
+}
+
+func (in *internal) on_payout(update payoutUpdate) {
+	pi, present := in.pipelineM[update.pipelineId.String()]
+	if !present {
+		return
+	}
+	_, present = pi.payoutM[update.pwd.Id.String()]
+	if present {
+		return
+	}
+	bs, err := update.pwd.Payout.BidStatus()
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	ctxC, cancel := context.WithCancel(pi.ctx)
+	pi.payoutM[update.pwd.Id.String()] = &payoutInfo{
+		ctx:     ctxC,
+		cancel:  cancel,
+		pwd:     update.pwd,
+		bs:      bs,
+		pending: 0,
+	}
+	go loopPayoutBid(ctxC, in.router, update.pipelineId, update.pwd, in.errorC, in.bidC)
+	in.rebalance()
+}
+
+func (in *internal) on_bid(update bidUpdate) {
+	pi, present := in.pipelineM[update.pipelineId.String()]
+	if !present {
+		return
+	}
+	y, present := pi.payoutM[update.payoutId.String()]
+	if !present {
+		return
+	}
+	if !update.bs.IsOpen {
+		y.cancel()
+		delete(pi.payoutM, update.payoutId.String())
+	} else {
+		y.bs = update.bs
+		y.settle(in.bidder.PublicKey())
+	}
+	in.stats_pipeline(pi)
+	in.rebalance()
+}
+
+// Find the payout on which we can still bid that starts soonest.
+func (pi *pipelineInfo) upcoming(slot uint64) *payoutInfo {
+	var ans *payoutInfo
+	for _, y := range pi.payoutM {
+		if y.bs.IsFinal || !y.bs.IsOpen {
+			continue
+		}
+		if y.pwd.Data.Period.IsBlank || y.pwd.Data.Period.Start <= slot {
+			continue
+		}
+		if ans == nil || y.pwd.Data.Period.Start < ans.pwd.Data.Period.Start {
+			ans = y
+		}
+	}
+	return ans
+}
+
+// how much has the bidder deposited and how much has everyone else deposited;
+// the pending deposit is not in TotalDeposits yet, so it only counts as ours
+func (y *payoutInfo) deposits(bidder sgo.PublicKey) (ours uint64, others uint64) {
+	ours = y.confirmed(bidder)
+	if ours < y.bs.TotalDeposits {
+		others = y.bs.TotalDeposits - ours
+	}
+	if 0 < y.pending && ours < y.pendingBase+y.pending {
+		ours = y.pendingBase + y.pending
+	}
+	return
+}
+
+// our deposit as seen in the bid status
+func (y *payoutInfo) confirmed(bidder sgo.PublicKey) uint64 {
+	ans := uint64(0)
+	for _, bid := range y.bs.Bid {
+		if bid.User.Equals(bidder) {
+			ans += bid.Deposit
+		}
+	}
+	return ans
+}
+
+// Forget the pending bid once the bid status shows our deposit has grown by
+// the pending amount.  The bid list update often arrives after the bid
+// transaction has been confirmed.
+func (y *payoutInfo) settle(bidder sgo.PublicKey) {
+	if 0 < y.pending && y.pendingBase+y.pending <= y.confirmed(bidder) {
+		y.pending = 0
+		y.pendingBase = 0
+	}
+}
+
+func loopPipeline(
+	ctx context.Context,
+	pipeline pipe.Pipeline,
+	bidder sgo.PublicKey,
+	errorC chan<- error,
+	stakeC chan<- stakeUpdate,
+	payoutC chan<- payoutUpdate,
+	claimC chan<- claimUpdate,
+) {
+	var err error
+	doneC := ctx.Done()
+
+	stakeSub := pipeline.OnRelativeStake()
+	defer stakeSub.Unsubscribe()
+	payoutSub := pipeline.OnPayout()
+	defer payoutSub.Unsubscribe()
+	claimSub := pipeline.OnClaim(bidder)
+	defer claimSub.Unsubscribe()
+
+	{
+		list, err := pipeline.AllPayouts()
+		if err != nil {
+			select {
+			case <-doneC:
+			case errorC <- err:
+			}
+			return
+		}
+		for _, pwd := range list {
+			select {
+			case <-doneC:
+				return
+			case payoutC <- payoutUpdate{pipelineId: pipeline.Id, pwd: pwd}:
+			}
+		}
+	}
+	{
+		list, err := pipeline.AllClaim()
+		if err != nil {
+			select {
+			case <-doneC:
+			case errorC <- err:
+			}
+			return
+		}
+		for _, c := range list {
+			if !c.User.Equals(bidder) {
+				continue
+			}
+			select {
+			case <-doneC:
+				return
+			case claimC <- claimUpdate{pipelineId: pipeline.Id, claim: c}:
+			}
+		}
+	}
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-stakeSub.ErrorC:
+			break out
+		case s := <-stakeSub.StreamC:
+			select {
+			case <-doneC:
+				break out
+			case stakeC <- stakeUpdate{pipelineId: pipeline.Id, share: s.Share()}:
+			}
+		case err = <-payoutSub.ErrorC:
+			break out
+		case pwd := <-payoutSub.StreamC:
+			select {
+			case <-doneC:
+				break out
+			case payoutC <- payoutUpdate{pipelineId: pipeline.Id, pwd: pwd}:
+			}
+		case err = <-claimSub.ErrorC:
+			break out
+		case c := <-claimSub.StreamC:
+			select {
+			case <-doneC:
+				break out
+			case claimC <- claimUpdate{pipelineId: pipeline.Id, claim: c}:
+			}
+		}
+	}
+
+	if err != nil {
+		select {
+		case <-time.After(5 * time.Second):
+		case errorC <- err:
+		}
+	}
+}
+
+// Track the bid status of a payout.  Once EVENT_BID_CLOSED (or EVENT_BID_FINAL)
+// fires, bids can no longer be inserted and the payout is dropped.
+func loopPayoutBid(
+	ctx context.Context,
+	router rtr.Router,
+	pipelineId sgo.PublicKey,
+	pwd pipe.PayoutWithData,
+	errorC chan<- error,
+	bidC chan<- bidUpdate,
+) {
+	var err error
+	doneC := ctx.Done()
+	payoutDoneC := pwd.Payout.OnClose()
+
+	bidSub := pwd.Payout.OnBidStatus()
+	defer bidSub.Unsubscribe()
+	payoutSchedule := schpyt.Schedule(ctx, router, pwd)
+	eventSub := payoutSchedule.OnEvent()
+	defer eventSub.Unsubscribe()
+
+	closed := bidUpdate{pipelineId: pipelineId, payoutId: pwd.Id, bs: pyt.BidStatus{IsOpen: false}}
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case <-payoutDoneC:
+			select {
+			case <-doneC:
+			case bidC <- closed:
+			}
+			break out
+		case err = <-eventSub.ErrorC:
+			break out
+		case event := <-eventSub.StreamC:
+			if event.Type != sch.EVENT_BID_CLOSED && event.Type != sch.EVENT_BID_FINAL {
+				continue
+			}
+			select {
+			case <-doneC:
+			case bidC <- closed:
+			}
+			break out
+		case err = <-bidSub.ErrorC:
+			break out
+		case bs := <-bidSub.StreamC:
+			select {
+			case <-doneC:
+				break out
+			case bidC <- bidUpdate{pipelineId: pipelineId, payoutId: pwd.Id, bs: bs}:
+			}
+		}
+	}
+
+	if err != nil {
+		select {
+		case <-time.After(5 * time.Second):
+		case errorC <- err:
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package bidder2
+
+import (
+	"math"
+	"sort"
+
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+)
+
+// how many seconds does a slot last on average
+const SLOT_DURATION_SECONDS float64 = 0.4
+
+// a deposit of zero means we have the entire pipeline to ourselves for a tiny
+// deposit; use this floor so the math below does not divide by zero
+const MIN_OTHER_DEPOSIT float64 = 1
+
+// how many iterations to use when binary searching for the marginal price
+const ALLOCATE_ITERATIONS int = 100
+
+// The market conditions for the upcoming period of a single pipeline.
+type pipelineQuote struct {
+	id string
+	// TPS capacity of the pipeline (network tps * stake share)
+	capacity float64
+	// deposits from bidders other than us
+	otherDeposit float64
+	// how long the period lasts in seconds
+	periodSeconds float64
+	// what we have already deposited
+	currentDeposit float64
+}
+
+// Deposit required so that our share of the pipeline delivers tps.
+// share=d/(o+d) => tps=c*d/(o+d) => d=o*tps/(c-tps)
+func (q pipelineQuote) depositForTps(tps float64) float64 {
+	if tps <= 0 || q.capacity <= 0 {
+		return 0
+	}
+	if q.capacity <= tps {
+		return math.Inf(1)
+	}
+	return q.other() * tps / (q.capacity - tps)
+}
+
+// TPS delivered from a deposit of size d
+func (q pipelineQuote) tpsForDeposit(d float64) float64 {
+	if d <= 0 || q.capacity <= 0 {
+		return 0
+	}
+	return q.capacity * d / (q.other() + d)
+}
+
+func (q pipelineQuote) other() float64 {
+	if q.otherDeposit < MIN_OTHER_DEPOSIT {
+		return MIN_OTHER_DEPOSIT
+	}
+	return q.otherDeposit
+}
+
+// Price in pc_mint per transaction if we were to take a marginal slice of the pipeline.
+func (q pipelineQuote) price() float64 {
+	if q.capacity <= 0 || q.periodSeconds <= 0 {
+		return 0
+	}
+	return q.other() / (q.capacity * q.periodSeconds)
+}
+
+// The spend rate (pc_mint/second) of the deposit.
+func (q pipelineQuote) spendRate(deposit float64) float64 {
+	if q.periodSeconds <= 0 {
+		return math.Inf(1)
+	}
+	return deposit / q.periodSeconds
+}
+
+// With marginal price lambda (pc_mint/second per extra tps), how much tps do
+// we buy from this pipeline.  Marginal cost of t tps is o*c/((c-t)^2*T).
+func (q pipelineQuote) tpsAtMarginalPrice(lambda float64) float64 {
+	if lambda <= 0 || q.capacity <= 0 || q.periodSeconds <= 0 {
+		return 0
+	}
+	t := q.capacity - math.Sqrt(q.other()*q.capacity/(lambda*q.periodSeconds))
+	if t < 0 {
+		return 0
+	}
+	return t
+}
+
+type allocation struct {
+	deposit map[string]float64 // pipeline id -> deposit
+	tps     float64
+	spend   float64
+}
+
+func allocationAtMarginalPrice(quotes []pipelineQuote, lambda float64) allocation {
+	ans := allocation{deposit: make(map[string]float64)}
+	for _, q := range quotes {
+		t := q.tpsAtMarginalPrice(lambda)
+		d := q.depositForTps(t)
+		ans.deposit[q.id] = d
+		ans.tps += t
+		ans.spend += q.spendRate(d)
+	}
+	return ans
+}
+
+// Split deposits across pipelines so that we buy the TPS in the budget at the
+// lowest cost.  The marginal cost is equalized across pipelines.  We buy
+// MaxTps unless doing so exceeds MaxSpend, in which case we buy as much as
+// MaxSpend affords, but never less than MinTps.  A MaxSpend of zero means
+// spending is unlimited.
+func allocate(budget *pbb.TpsBudget, quotes []pipelineQuote) allocation {
+	usable := make([]pipelineQuote, 0, len(quotes))
+	for _, q := range quotes {
+		if 0 < q.capacity && 0 < q.periodSeconds {
+			usable = append(usable, q)
+		}
+	}
+	sort.Slice(usable, func(i, j int) bool {
+		return usable[i].price() < usable[j].price()
+	})
+	if budget == nil || budget.MaxTps <= 0 || len(usable) == 0 {
+		return allocation{deposit: make(map[string]float64)}
+	}
+	a := allocateTps(usable, float64(budget.MaxTps), float64(budget.MaxSpend))
+	if a.tps < float64(budget.MinTps) {
+		// MinTps takes precedence over MaxSpend
+		a = allocateTps(usable, float64(budget.MinTps), 0)
+	}
+	return a
+}
+
+// Binary search for the marginal price that buys maxTps without spending
+// more than maxSpend (unlimited if zero).
+func allocateTps(usable []pipelineQuote, maxTps float64, maxSpend float64) allocation {
+	// find an upper bound on the marginal price
+	lo := float64(0)
+	hi := float64(1)
+	for i := 0; i < ALLOCATE_ITERATIONS; i++ {
+		a := allocationAtMarginalPrice(usable, hi)
+		if maxTps <= a.tps || (0 < maxSpend && maxSpend <= a.spend) {
+			break
+		}
+		hi *= 2
+	}
+	for i := 0; i < ALLOCATE_ITERATIONS; i++ {
+		mid := (lo + hi) / 2
+		a := allocationAtMarginalPrice(usable, mid)
+		if maxTps < a.tps || (0 < maxSpend && maxSpend < a.spend) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return allocationAtMarginalPrice(usable, lo)
+}
+
+// Limit the change in deposit so that we do not swing the market (and our
+// spending) by more than maxDelta (as a fraction) in one adjustment.
+func limitDelta(current float64, target float64, maxDelta float64) float64 {
+	if current <= 0 || maxDelta <= 0 {
+		return target
+	}
+	upper := current * (1 + maxDelta)
+	lower := current * (1 - maxDelta)
+	if upper < target {
+		return upper
+	}
+	if target < lower {
+		return lower
+	}
+	return target
+}
+
+// Convert an allocation into deposits per pipeline, applying the maximum bid delta.
+func (in *internal) target_deposits(quotes []pipelineQuote) []*pbb.PipelineDeposit {
+	a := allocate(in.budget, quotes)
+	ans := make([]*pbb.PipelineDeposit, 0, len(quotes))
+	for _, q := range quotes {
+		target, present := a.deposit[q.id]
+		if !present {
+			target = 0
+		}
+		target = limitDelta(q.currentDeposit, target, float64(in.budget.MaxBidDelta))
+		target = math.Floor(target)
+		share := float64(0)
+		if 0 < target {
+			share = target / (q.other() + target)
+		}
+		ans = append(ans, &pbb.PipelineDeposit{
+			PipelineId: q.id,
+			Deposit:    uint64(target),
+			Share:      float32(share),
+		})
+	}
+	return ans
+}
@@ -0,0 +1,76 @@
+package bidder2
+
+import (
+	"math"
+	"testing"
+
+	pbb "github.com/solpipe/solpipe-tool/proto/bid"
+)
+
+func testQuotes() []pipelineQuote {
+	return []pipelineQuote{
+		{id: "cheap", capacity: 100, otherDeposit: 1000, periodSeconds: 60},
+		{id: "expensive", capacity: 100, otherDeposit: 100000, periodSeconds: 60},
+	}
+}
+
+func TestAllocateMaxTps(t *testing.T) {
+	budget := &pbb.TpsBudget{MinTps: 0, MaxTps: 50, MaxSpend: 0, MaxBidDelta: 0}
+	a := allocate(budget, testQuotes())
+	if math.Abs(a.tps-50) > 0.01 {
+		t.Fatalf("expected 50 tps, got %f", a.tps)
+	}
+	if a.deposit["expensive"] >= a.deposit["cheap"] {
+		t.Fatalf("expected more deposit in cheap pipeline; %+v", a.deposit)
+	}
+	// the deposits should deliver the tps the allocation claims
+	quotes := testQuotes()
+	got := float64(0)
+	for _, q := range quotes {
+		got += q.tpsForDeposit(a.deposit[q.id])
+	}
+	if math.Abs(got-a.tps) > 0.01 {
+		t.Fatalf("deposits deliver %f tps, allocation claims %f", got, a.tps)
+	}
+}
+
+func TestAllocateMaxSpend(t *testing.T) {
+	budget := &pbb.TpsBudget{MinTps: 0, MaxTps: 90, MaxSpend: 10, MaxBidDelta: 0}
+	a := allocate(budget, testQuotes())
+	if a.spend > 10.001 {
+		t.Fatalf("spend %f exceeds max spend", a.spend)
+	}
+	if a.tps >= 90 {
+		t.Fatalf("should not reach max tps with this spend; got %f", a.tps)
+	}
+}
+
+func TestAllocateMinTps(t *testing.T) {
+	budget := &pbb.TpsBudget{MinTps: 60, MaxTps: 90, MaxSpend: 10, MaxBidDelta: 0}
+	a := allocate(budget, testQuotes())
+	if math.Abs(a.tps-60) > 0.01 {
+		t.Fatalf("expected min tps of 60 despite max spend, got %f", a.tps)
+	}
+	if a.spend <= 10 {
+		t.Fatalf("spend %f should exceed max spend to reach min tps", a.spend)
+	}
+}
+
+func TestAllocateNoBudget(t *testing.T) {
+	a := allocate(&pbb.TpsBudget{}, testQuotes())
+	if a.tps != 0 || a.spend != 0 {
+		t.Fatalf("expected empty allocation, got %+v", a)
+	}
+}
+
+func TestLimitDelta(t *testing.T) {
+	if x := limitDelta(100, 200, 0.25); x != 125 {
+		t.Fatalf("expected 125, got %f", x)
+	}
+	if x := limitDelta(100, 10, 0.25); x != 75 {
+		t.Fatalf("expected 75, got %f", x)
+	}
+	if x := limitDelta(0, 10, 0.25); x != 10 {
+		t.Fatalf("expected 10, got %f", x)
+	}
+}
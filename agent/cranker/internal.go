@@ -16,6 +16,7 @@ import (
 	dssub "github.com/solpipe/solpipe-tool/ds/sub"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
 	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 	"github.com/solpipe/solpipe-tool/util"
@@ -123,6 +124,7 @@ out:
 			_, present := in.status[resp.request.pipeline.Id.String()]
 			if present {
 				if resp.err != nil {
+					metricCrank.With(CRANK_FAILURE).Inc()
 					log.Debug("error in crank")
 					os.Stderr.WriteString(resp.err.Error())
 					// try again
@@ -133,6 +135,8 @@ out:
 					//	60*time.Second,
 					//	resp.request,
 					//)
+				} else {
+					metricCrank.With(CRANK_SUCCESS).Inc()
 				}
 			}
 		case err = <-bidSub.ErrorC:
@@ -227,7 +231,7 @@ func (in *internal) init(
 		return err
 	}
 
-	in.script, err = script.Create(in.ctx, &script.Configuration{Version: in.router.Controller.Version}, in.rpc, in.ws)
+	in.script, err = script.Create(in.ctx, &script.Configuration{Version: in.router.Controller.Version, Fee: in.config.Fee}, in.rpc, in.ws)
 	if err != nil {
 		return err
 	}
@@ -250,6 +254,7 @@ func (in *internal) init(
 		return err
 	}
 	in.balance = r.Value
+	setBalanceMetric(in.balance)
 
 	list, err := in.router.AllPipeline()
 	if err != nil {
@@ -277,7 +282,7 @@ func (in *internal) finish(err error) {
 	}
 }
 
-func (in *internal) admin() sgo.PrivateKey {
+func (in *internal) admin() signer.Signer {
 	return in.config.Admin
 }
 
@@ -353,6 +358,7 @@ func (in *internal) crank(status *pipelineStatus) error {
 	status.lastAttempedCrank = in.slot
 	var err error
 	if in.balance <= in.balanceThreshold {
+		metricCrank.With(CRANK_DEPLETED).Inc()
 		err = fmt.Errorf("Funds have been depleted.  Current balance: %d", in.balance)
 		return err
 	}
@@ -435,6 +441,7 @@ out:
 
 func (in *internal) on_balance(b uint64) {
 	in.balance = b
+	setBalanceMetric(b)
 }
 
 func getNextPeriod(slot uint64, pr cba.PeriodRing) (period cba.Period, present bool) {
@@ -0,0 +1,21 @@
+package cranker
+
+import (
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/metrics"
+)
+
+const (
+	CRANK_SUCCESS  = "success"
+	CRANK_FAILURE  = "failure"
+	CRANK_DEPLETED = "depleted"
+)
+
+var (
+	metricBalance = metrics.NewGauge("solpipe_cranker_balance_sol", "SOL balance of the cranker account")
+	metricCrank   = metrics.NewCounterVec("solpipe_cranker_crank_total", "crank attempts by result", "result")
+)
+
+func setBalanceMetric(lamports uint64) {
+	metricBalance.Set(float64(lamports) / float64(sgo.LAMPORTS_PER_SOL))
+}
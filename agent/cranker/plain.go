@@ -4,9 +4,9 @@ import (
 	"context"
 	"time"
 
-	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
@@ -21,7 +21,7 @@ type PureCranker struct {
 // Run one per payout
 func CrankPayout(
 	ctx context.Context,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
 	wrapper spt.Wrapper,
@@ -137,7 +137,7 @@ out:
 
 func runCrank(
 	script *spt.Script,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
 	payout pyt.Payout,
@@ -156,10 +156,12 @@ func runCrank(
 	if err != nil {
 		return err
 	}
-	err = script.FinishTx(true)
+	o := script.Finish(true)
+	err = o.Error()
 	if err != nil {
 		return err
 	}
+	log.Debugf("crank payout=%s landed (slot=%d; broadcasts=%d; blockhashes=%d)", payout.Id.String(), o.Slot, o.Broadcasts, o.Blockhashes)
 	return nil
 }
 
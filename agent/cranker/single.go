@@ -6,6 +6,7 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 	cba "github.com/solpipe/cba"
 	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 )
@@ -36,7 +37,7 @@ type crankResponse struct {
 type crankInternal struct {
 	ctx       context.Context
 	responseC chan<- crankResponse
-	admin     sgo.PrivateKey
+	admin     signer.Signer
 	pcVault   sgo.PublicKey
 	mint      sgo.PublicKey
 	script    *script.Script
@@ -48,7 +49,7 @@ func loopCrank(
 	ctx context.Context,
 	requestC <-chan crankRequest,
 	responseC chan<- crankResponse,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	pcVault sgo.PublicKey,
 	mint sgo.PublicKey,
 	script *script.Script,
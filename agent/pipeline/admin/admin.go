@@ -9,6 +9,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/solpipe/solpipe-tool/ds/sub"
 	pba "github.com/solpipe/solpipe-tool/proto/admin"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
 
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	"github.com/solpipe/solpipe-tool/util"
@@ -21,6 +22,7 @@ type Server struct {
 	internalC chan<- func(*internal)
 	reqLogC   chan<- sub.ResponseChannel[*pba.LogLine]
 	pipeline  pipe.Pipeline
+	relay     relay.Relay
 }
 
 func Attach(
@@ -31,6 +33,7 @@ func Attach(
 	periodSettingsC chan<- *pba.PeriodSettings,
 	rateSettingsC chan<- *pba.RateSettings,
 	pipeline pipe.Pipeline,
+	pipelineRelay relay.Relay,
 ) (<-chan error, error) {
 	log.Debug("creating owner grpc server")
 	signalC := make(chan error, 1)
@@ -43,6 +46,7 @@ func Attach(
 		internalC: internalC,
 		reqLogC:   reqLogC,
 		pipeline:  pipeline,
+		relay:     pipelineRelay,
 	}
 
 	if initialSettings == nil {
@@ -57,6 +61,7 @@ func Attach(
 		configFilePath,
 		periodSettingsC,
 		rateSettingsC,
+		pipelineRelay,
 	)
 
 	pba.RegisterPipelineServer(grpcServer, e1)
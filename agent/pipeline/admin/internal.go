@@ -8,6 +8,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/solpipe/solpipe-tool/ds/sub"
 	pba "github.com/solpipe/solpipe-tool/proto/admin"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
 	"github.com/solpipe/solpipe-tool/script"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 )
@@ -23,11 +24,14 @@ type internal struct {
 	homeLog          *sub.SubHome[*pba.LogLine]
 	periodSettingsC  chan<- *pba.PeriodSettings
 	rateSettingsC    chan<- *pba.RateSettings
+	relay            relay.Relay
+	bidderRate       map[string]float64 // bidder -> tps override
 }
 
 type SettingsForFile struct {
 	RateSettings   *pba.RateSettings   `json:"rate"`
 	PeriodSettings *pba.PeriodSettings `json:"period"`
+	BidderRate     map[string]float64  `json:"bidder_rate,omitempty"`
 }
 
 func DefaultRateSettings() *pba.RateSettings {
@@ -61,6 +65,7 @@ func loopInternal(
 	configFilePath string,
 	periodSettingsC chan<- *pba.PeriodSettings,
 	rateSettingsC chan<- *pba.RateSettings,
+	pipelineRelay relay.Relay,
 ) {
 	var err error
 	doneC := ctx.Done()
@@ -77,6 +82,8 @@ func loopInternal(
 	}
 	in.periodSettingsC = periodSettingsC
 	in.rateSettingsC = rateSettingsC
+	in.relay = pipelineRelay
+	in.bidderRate = make(map[string]float64)
 	log.Debug("settings+++!+!+!+!+")
 	log.Debugf("initial settings=%+v", initialSettings)
 	in.rateSettings = initialSettings.ToProtoRateSettings()
@@ -92,6 +99,7 @@ func loopInternal(
 		in.errorC <- err
 	}
 	in.settings_change()
+	in.bidder_rate_restore()
 
 out:
 	for !in.closeServer {
@@ -168,7 +176,7 @@ func (in *internal) log(level pba.Severity, message string) {
 
 func (in *internal) finish(err error) {
 	if err != nil {
-		log.Debug("exiting admin agent loop with err:\n%s", err.Error())
+		log.Debugf("exiting admin agent loop with err:\n%s", err.Error())
 	} else {
 		log.Debug("exiting admin agent loop with no error")
 	}
@@ -201,22 +209,28 @@ func (in *internal) config_load() error {
 
 	in.periodSettings = c.PeriodSettings
 	in.rateSettings = c.RateSettings
+	if c.BidderRate != nil {
+		in.bidderRate = c.BidderRate
+	}
 
 	return nil
 }
 
 func (in *internal) config_save() error {
 	log.Debugf("saving configuration file to %s", in.configFilePath)
-	f, err := os.OpenFile(in.configFilePath, os.O_WRONLY, 0640)
+	// truncate so that removing a bidder override does not leave stale bytes behind
+	f, err := os.OpenFile(in.configFilePath, os.O_WRONLY|os.O_TRUNC, 0640)
 	if err != nil {
 		f, err = os.Create(in.configFilePath)
 		if err != nil {
 			return err
 		}
 	}
+	defer f.Close()
 	c := new(SettingsForFile)
 	c.PeriodSettings = in.periodSettings
 	c.RateSettings = in.rateSettings
+	c.BidderRate = in.bidderRate
 	err = json.NewEncoder(f).Encode(c)
 	if err != nil {
 		return err
@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	pba "github.com/solpipe/solpipe-tool/proto/admin"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+)
+
+func (e1 Server) GetBidderRates(ctx context.Context, req *pba.Empty) (*pba.BidderRateList, error) {
+	doneC := ctx.Done()
+	ansC := make(chan *pba.BidderRateList, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ansC <- in.bidder_rate_list()
+	}:
+	}
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case ans := <-ansC:
+		return ans, nil
+	}
+}
+
+// Cap, boost or suspend (tps=0) a bidder.  Set override=false to go back to
+// the TPS implied by the bid of the bidder.
+func (e1 Server) SetBidderRate(ctx context.Context, req *pba.BidderRate) (*pba.BidderRate, error) {
+	if req == nil {
+		return nil, errors.New("blank request")
+	}
+	bidder, err := sgo.PublicKeyFromBase58(req.Bidder)
+	if err != nil {
+		return nil, err
+	}
+	rate := relay.RATE_BID_SHARE
+	if req.Override {
+		if math.IsNaN(req.Tps) || math.IsInf(req.Tps, 0) {
+			return nil, errors.New("tps must be a finite number")
+		}
+		if req.Tps < 0 {
+			return nil, errors.New("tps cannot be negative")
+		}
+		rate = req.Tps
+	}
+
+	// persist first so the relay never runs a rate that a restart would lose
+	doneC := ctx.Done()
+	errorC := make(chan error, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		errorC <- in.bidder_rate_set(bidder, rate)
+	}:
+	}
+	select {
+	case <-doneC:
+		err = errors.New("canceled")
+	case err = <-errorC:
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = e1.relay.AdjustRate(ctx, bidder, rate)
+	if err != nil {
+		return nil, err
+	}
+	return &pba.BidderRate{Bidder: bidder.String(), Override: req.Override, Tps: req.Tps}, nil
+}
+
+func (in *internal) bidder_rate_set(bidder sgo.PublicKey, rate float64) error {
+	old, hadOld := in.bidderRate[bidder.String()]
+	if rate < 0 {
+		delete(in.bidderRate, bidder.String())
+	} else {
+		in.bidderRate[bidder.String()] = rate
+	}
+	err := in.config_save()
+	if err != nil {
+		if hadOld {
+			in.bidderRate[bidder.String()] = old
+		} else {
+			delete(in.bidderRate, bidder.String())
+		}
+		return err
+	}
+	switch {
+	case rate < 0:
+		in.log(pba.Severity_INFO, fmt.Sprintf("bidder=%s rate follows bid share", bidder.String()))
+	case rate == 0:
+		in.log(pba.Severity_INFO, fmt.Sprintf("bidder=%s suspended", bidder.String()))
+	default:
+		in.log(pba.Severity_INFO, fmt.Sprintf("bidder=%s rate set to %f tps", bidder.String(), rate))
+	}
+	return nil
+}
+
+func (in *internal) bidder_rate_list() *pba.BidderRateList {
+	ans := &pba.BidderRateList{Rate: make([]*pba.BidderRate, 0, len(in.bidderRate))}
+	for bidder, tps := range in.bidderRate {
+		ans.Rate = append(ans.Rate, &pba.BidderRate{Bidder: bidder, Override: true, Tps: tps})
+	}
+	sort.Slice(ans.Rate, func(i, j int) bool {
+		return ans.Rate[i].Bidder < ans.Rate[j].Bidder
+	})
+	return ans
+}
+
+// apply the overrides from the config file to the relay
+func (in *internal) bidder_rate_restore() {
+	if len(in.bidderRate) == 0 {
+		return
+	}
+	m := make(map[string]float64, len(in.bidderRate))
+	for k, v := range in.bidderRate {
+		m[k] = v
+	}
+	go loopBidderRateRestore(in.ctx, in.relay, m)
+}
+
+func loopBidderRateRestore(ctx context.Context, pipelineRelay relay.Relay, m map[string]float64) {
+	for k, rate := range m {
+		bidder, err := sgo.PublicKeyFromBase58(k)
+		if err != nil {
+			log.Debugf("bad bidder in config file: %s", err.Error())
+			continue
+		}
+		err = pipelineRelay.AdjustRate(ctx, bidder, rate)
+		if err != nil {
+			log.Debugf("failed to restore rate for bidder=%s: %s", k, err.Error())
+		}
+	}
+}
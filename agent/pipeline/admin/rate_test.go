@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	pba "github.com/solpipe/solpipe-tool/proto/admin"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/state"
+	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type adjustment struct {
+	sender sgo.PublicKey
+	rate   float64
+}
+
+type testRelay struct {
+	adjustC chan adjustment
+}
+
+func (r testRelay) Submit(ctx context.Context, sender sgo.PublicKey, tx *sgo.Transaction) (sgo.Signature, error) {
+	return sgo.Signature{}, nil
+}
+
+func (r testRelay) AdjustRate(ctx context.Context, sender sgo.PublicKey, newRate float64) error {
+	r.adjustC <- adjustment{sender: sender, rate: newRate}
+	return nil
+}
+
+func (r testRelay) Wait(ctx context.Context, signature sgo.Signature) (relay.WaitResult, error) {
+	return relay.WaitResult{}, nil
+}
+
+// Attach an admin server to a grpc server on a random port.
+func attachTest(t *testing.T, ctx context.Context, configFilePath string) (pba.PipelineClient, testRelay) {
+	r := testRelay{adjustC: make(chan adjustment, 10)}
+	s := grpc.NewServer()
+	_, err := Attach(
+		ctx,
+		s,
+		&pipe.PipelineSettings{
+			CrankFee:    &state.Rate{N: 1, D: 100},
+			BidSpace:    10,
+			PayoutShare: &state.Rate{N: 1, D: 1},
+		},
+		configFilePath,
+		make(chan *pba.PeriodSettings, 10),
+		make(chan *pba.RateSettings, 10),
+		pipe.Pipeline{},
+		r,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.DialContext(ctx, l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pba.NewPipelineClient(conn), r
+}
+
+func TestBidderRate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	configFilePath := filepath.Join(t.TempDir(), "admin.json")
+	client, r := attachTest(t, ctx, configFilePath)
+	bidder, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.SetBidderRate(ctx, &pba.BidderRate{Bidder: bidder.PublicKey().String(), Override: true, Tps: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := <-r.adjustC
+	if !a.sender.Equals(bidder.PublicKey()) || a.rate != 5 {
+		t.Fatalf("adjusted %+v", a)
+	}
+	list, err := client.GetBidderRates(ctx, &pba.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Rate) != 1 || list.Rate[0].Bidder != bidder.PublicKey().String() || list.Rate[0].Tps != 5 {
+		t.Fatalf("rates %+v", list.Rate)
+	}
+
+	for _, req := range []*pba.BidderRate{
+		{Bidder: "not a key", Override: true, Tps: 1},
+		{Bidder: bidder.PublicKey().String(), Override: true, Tps: -1},
+		{Bidder: bidder.PublicKey().String(), Override: true, Tps: math.NaN()},
+		{Bidder: bidder.PublicKey().String(), Override: true, Tps: math.Inf(1)},
+	} {
+		_, err = client.SetBidderRate(ctx, req)
+		if err == nil {
+			t.Fatalf("accepted %+v", req)
+		}
+	}
+
+	// a restarted server restores the override from the config file
+	ctx2, cancel2 := context.WithCancel(ctx)
+	_, r2 := attachTest(t, ctx2, configFilePath)
+	a = <-r2.adjustC
+	cancel2()
+	if !a.sender.Equals(bidder.PublicKey()) || a.rate != 5 {
+		t.Fatalf("restored %+v", a)
+	}
+
+	_, err = client.SetBidderRate(ctx, &pba.BidderRate{Bidder: bidder.PublicKey().String(), Override: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = <-r.adjustC
+	if a.rate != relay.RATE_BID_SHARE {
+		t.Fatalf("adjusted %+v", a)
+	}
+	list, err = client.GetBidderRates(ctx, &pba.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Rate) != 0 {
+		t.Fatalf("rates %+v", list.Rate)
+	}
+
+	// the relay is left alone when the config file cannot be saved
+	err = os.Remove(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(configFilePath, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SetBidderRate(ctx, &pba.BidderRate{Bidder: bidder.PublicKey().String(), Override: true, Tps: 7})
+	if err == nil {
+		t.Fatal("rate set without saving the config file")
+	}
+	select {
+	case a = <-r.adjustC:
+		t.Fatalf("adjusted %+v", a)
+	default:
+	}
+}
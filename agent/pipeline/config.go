@@ -5,13 +5,14 @@ import (
 
 	sgo "github.com/SolmateDev/solana-go"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/signer"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 )
 
 type Configuration struct {
 	ProgramIdCba *sgo.PublicKey
 	Pipeline     *sgo.PublicKey
-	Wallet       *sgo.PrivateKey
+	Wallet       signer.Signer
 	Settings     *pipe.PipelineSettings
 }
 
@@ -63,6 +64,6 @@ func (args *InitializationArg) Check() error {
 	return nil
 }
 
-func (args *InitializationArg) Admin() sgo.PrivateKey {
+func (args *InitializationArg) Admin() signer.Signer {
 	return args.Relay.Admin
 }
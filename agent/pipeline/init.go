@@ -79,25 +79,25 @@ func Initialize(
 
 	s1, err := script.Create(
 		ctx,
-		&script.Configuration{Version: args.Relay.Version},
+		args.Relay.ScriptConfiguration(),
 		rpcClient,
 		wsClient,
 	)
 	if err != nil {
 		return
 	}
-	err = s1.SetTx(*programConfig.Wallet)
+	err = s1.SetTx(programConfig.Wallet)
 	if err != nil {
 		return
 	}
 	if createAdminAccount {
-		_, err = s1.CreateAccount(0, args.Admin().PublicKey(), *programConfig.Wallet)
+		_, err = s1.CreateAccount(0, args.Admin().PublicKey(), programConfig.Wallet)
 		if err != nil {
 			return
 		}
 	}
 	if adminBalance < minRent {
-		err = s1.Transfer(*programConfig.Wallet, args.Admin().PublicKey(), minRent-adminBalance)
+		err = s1.Transfer(programConfig.Wallet, args.Admin().PublicKey(), minRent-adminBalance)
 		if err != nil {
 			return
 		}
@@ -106,7 +106,7 @@ func Initialize(
 	if pipelineIdKeypair == nil {
 		pid, err = s1.AddPipeline(
 			controller,
-			*programConfig.Wallet,
+			programConfig.Wallet,
 			args.Admin(),
 			*programConfig.Settings.CrankFee,
 			100,
@@ -118,7 +118,7 @@ func Initialize(
 		pid, err = s1.AddPipelineDirect(
 			*pipelineIdKeypair,
 			controller,
-			*programConfig.Wallet,
+			programConfig.Wallet,
 			args.Admin(),
 			*programConfig.Settings.CrankFee,
 			100,
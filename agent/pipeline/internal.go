@@ -9,6 +9,7 @@ import (
 	sch "github.com/solpipe/solpipe-tool/scheduler"
 	schpipe "github.com/solpipe/solpipe-tool/scheduler/pipeline"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 )
@@ -25,7 +26,7 @@ type internal struct {
 	slot             uint64
 	periodSettings   *pba.PeriodSettings
 	rateSettings     *pba.RateSettings
-	admin            sgo.PrivateKey
+	admin            signer.Signer
 	payoutM          map[string]*payoutInfo // map: payout id -> *
 	deletePayoutC    chan<- sgo.PublicKey
 }
@@ -37,7 +38,7 @@ func loopInternal(
 	router rtr.Router,
 	pipeline pipe.Pipeline,
 	wrapper spt.Wrapper,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	periodSettingsC <-chan *pba.PeriodSettings,
 	rateSettingsC <-chan *pba.RateSettings,
 ) {
@@ -11,6 +11,7 @@ import (
 	sch "github.com/solpipe/solpipe-tool/scheduler"
 	schpyt "github.com/solpipe/solpipe-tool/scheduler/payout"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
@@ -143,7 +144,7 @@ func (in *internal) run_payout_close_bids(event sch.Event) error {
 
 func RunCloseBids(
 	script *spt.Script,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
 	payout pyt.Payout,
@@ -208,7 +209,7 @@ const DELAY_PAYOUT_CLOSE_PAYOUT = 30 * time.Second
 
 func RunClosePayout(
 	script *spt.Script,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
 	payout pyt.Payout,
@@ -227,12 +228,13 @@ func RunClosePayout(
 	if err != nil {
 		return err
 	}
-	err = script.FinishTx(true)
+	o := script.Finish(true)
+	err = o.Error()
 	if err != nil {
 		log.Debugf("failed to close payout id=%s", payout.Id.String())
 		os.Stderr.WriteString(err.Error() + "\n")
 		return err
 	}
-	log.Debugf("payout id=%s has successfully been closed", payout.Id.String())
+	log.Debugf("payout id=%s has successfully been closed (slot=%d; broadcasts=%d)", payout.Id.String(), o.Slot, o.Broadcasts)
 	return nil
 }
@@ -4,11 +4,11 @@ import (
 	"errors"
 	"time"
 
-	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	sch "github.com/solpipe/solpipe-tool/scheduler"
 	schpipe "github.com/solpipe/solpipe-tool/scheduler/pipeline"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/state"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
@@ -79,7 +79,7 @@ func (in *internal) run_period_append(event sch.Event) error {
 
 func RunAppendPeriod(
 	script *spt.Script,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	sh slt.SlotHome,
 	start uint64,
 	length uint64,
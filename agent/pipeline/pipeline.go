@@ -15,6 +15,7 @@ import (
 	pxypipe "github.com/solpipe/solpipe-tool/proxy/relay/pipeline"
 	pxysvr "github.com/solpipe/solpipe-tool/proxy/server"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 	slt "github.com/solpipe/solpipe-tool/state/slot"
@@ -78,22 +79,30 @@ func Create(
 	var grpcServerClearNet *grpc.Server
 	var torLi *proxy.ListenerInfo
 	var clearLi *proxy.ListenerInfo
-	grpcServerTor, err = proxy.CreateListener(
-		ctx,
-		args.Admin(),
-	)
-	if err != nil {
+	// a remote admin signer cannot host an onion service
+	_, localAdmin := signer.Local(args.Admin())
+	if !localAdmin && args.Relay.ClearNet == nil {
 		cancel()
-		return Agent{}, err
+		return Agent{}, errors.New("a remote admin signer needs a clear net listener")
 	}
-	torLi, err = proxy.CreateListenerTor(
-		ctx,
-		args.Relay.Admin,
-		torMgr,
-	)
-	if err != nil {
-		cancel()
-		return Agent{}, err
+	if localAdmin {
+		grpcServerTor, err = proxy.CreateListener(
+			ctx,
+			args.Admin(),
+		)
+		if err != nil {
+			cancel()
+			return Agent{}, err
+		}
+		torLi, err = proxy.CreateListenerTor(
+			ctx,
+			args.Relay.Admin,
+			torMgr,
+		)
+		if err != nil {
+			cancel()
+			return Agent{}, err
+		}
 	}
 	if args.Relay.ClearNet != nil {
 		grpcServerClearNet, err = proxy.CreateListener(
@@ -135,27 +144,34 @@ func Create(
 	}
 	wrapper := spt.Wrap(ctx, script)
 
-	var signalC <-chan error
-	signalC, err = admin.Attach(
-		ctx,
-		grpcAdminServer,
-		args.Program.Settings,
-		args.ConfigFilePath,
-		periodSettingsC,
-		rateSettingsC,
-		pipeline,
-	)
+	meterStore, err := args.Relay.MeterStore(ctx)
 	if err != nil {
 		cancel()
 		return Agent{}, err
 	}
-
 	pipelineRelay, err := pxypipe.Create(
 		ctx,
 		*args.Relay,
 		router,
 		pipeline,
 		torMgr,
+		meterStore,
+	)
+	if err != nil {
+		cancel()
+		return Agent{}, err
+	}
+
+	var signalC <-chan error
+	signalC, err = admin.Attach(
+		ctx,
+		grpcAdminServer,
+		args.Program.Settings,
+		args.ConfigFilePath,
+		periodSettingsC,
+		rateSettingsC,
+		pipeline,
+		pipelineRelay,
 	)
 	if err != nil {
 		cancel()
@@ -164,7 +180,10 @@ func Create(
 
 	// register grpc for tor, then clear net (order does not matter)
 	// we can only have one instance of the proxy server
-	sList := []*grpc.Server{grpcServerTor}
+	sList := []*grpc.Server{}
+	if grpcServerTor != nil {
+		sList = append(sList, grpcServerTor)
+	}
 	if grpcServerClearNet != nil {
 		sList = append(sList, grpcServerClearNet)
 
@@ -177,6 +196,17 @@ func Create(
 		args.Admin(),
 		pipelineRelay,
 		args.Relay.ClearNet, // will be nil if there is no clear net
+		meterStore,
+	)
+	if err != nil {
+		cancel()
+		return Agent{}, err
+	}
+	err = pxysvr.AttachJsonRpc(
+		ctx,
+		sList,
+		*args.Relay,
+		pipelineRelay,
 	)
 	if err != nil {
 		cancel()
@@ -199,8 +229,10 @@ func Create(
 	)
 
 	// handle tor listener
-	go loopClose(ctx, torLi.Listener)
-	go loopListen(grpcServerTor, torLi.Listener, errorC)
+	if grpcServerTor != nil {
+		go loopClose(ctx, torLi.Listener)
+		go loopListen(grpcServerTor, torLi.Listener, errorC)
+	}
 	if grpcServerClearNet != nil {
 		go loopClose(ctx, clearLi.Listener)
 		go loopListen(grpcServerClearNet, clearLi.Listener, errorC)
@@ -120,7 +120,7 @@ func TestSetup(t *testing.T) {
 		args.Program = &ap.Configuration{
 			ProgramIdCba: cba.ProgramID.ToPointer(),
 			Pipeline:     nil,
-			Wallet:       &wallet,
+			Wallet:       wallet,
 			Settings: &pipe.PipelineSettings{
 				CrankFee:    &state.Rate{N: 1, D: 10},
 				PayoutShare: &state.Rate{N: 4, D: 10},
@@ -185,7 +185,7 @@ func TestSetup(t *testing.T) {
 				Program: &ap.Configuration{
 					ProgramIdCba: cba.ProgramID.ToPointer(),
 					Pipeline:     pipeline.Id.ToPointer(),
-					Wallet:       &pipelineConfig.Admin,
+					Wallet:       pipelineConfig.Admin,
 					Settings: &pipe.PipelineSettings{
 						CrankFee:    &state.Rate{N: 1, D: 100},
 						PayoutShare: &state.Rate{N: 95, D: 100},
@@ -35,32 +35,33 @@ func TestDelegateStake(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	solShell, err := sbox.ShellSolana()
+	s, err := sbox.Script(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	sig, err := solShell.CreateStakeDirect(
-		ctx,
+	err = s.SetTx(sbox.Faucet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateStake(
 		sbox.Faucet,
 		sbox.Stake,
-		relayConfig.Admin,
+		relayConfig.Admin.PublicKey(),
 		1000*sgo.LAMPORTS_PER_SOL,
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("sig=%s", sig.String())
-
-	sig, err = solShell.DelegateStake(
-		ctx,
-		sbox.Faucet,
+	err = s.DelegateStake(
 		sbox.Stake.PublicKey(),
-		sbox.StakerAdmin,
+		relayConfig.Admin,
 		sbox.Vote.PublicKey(),
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("sig=%s", sig.String())
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
 }
@@ -3,64 +3,148 @@ package staker
 import (
 	"context"
 
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
 	log "github.com/sirupsen/logrus"
+	sch "github.com/solpipe/solpipe-tool/scheduler"
+	spt "github.com/solpipe/solpipe-tool/script"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	rpt "github.com/solpipe/solpipe-tool/state/receipt"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+	val "github.com/solpipe/solpipe-tool/state/validator"
 )
 
 type internal struct {
 	ctx              context.Context
 	errorC           chan<- error
 	closeSignalCList []chan<- error
+	rpc              *sgorpc.Client
+	scriptWrapper    spt.Wrapper
 	controller       ctr.Controller
 	router           rtr.Router
+	validator        val.Validator
+	staker           skr.Staker
 	config           *Configuration
+	delegation       skr.Delegation
+	eventC           chan<- sch.Event
+	deletePayoutC    chan<- sgo.PublicKey
+	payoutM          map[string]*payoutInfo
+	earnings         []Earning
 }
 
 func loopInternal(
 	ctx context.Context,
 	internalC <-chan func(*internal),
 	cancel context.CancelFunc,
+	rpcClient *sgorpc.Client,
+	scriptWrapper spt.Wrapper,
 	router rtr.Router,
+	validator val.Validator,
+	staker skr.Staker,
 	config *Configuration,
+	delegation skr.Delegation,
 	sub *sgows.AccountSubscription,
 ) {
+	defer cancel()
+	defer sub.Unsubscribe()
 	var err error
 	errorC := make(chan error, 1)
 	doneC := ctx.Done()
+	eventC := make(chan sch.Event)
+	newReceiptC := make(chan rpt.ReceiptWithData)
+	deletePayoutC := make(chan sgo.PublicKey)
 
 	in := new(internal)
 	in.ctx = ctx
 	in.errorC = errorC
 	in.closeSignalCList = make([]chan<- error, 0)
+	in.rpc = rpcClient
+	in.scriptWrapper = scriptWrapper
 	in.controller = router.Controller
 	in.router = router
+	in.validator = validator
+	in.staker = staker
+	in.config = config
+	in.delegation = delegation
+	in.eventC = eventC
+	in.deletePayoutC = deletePayoutC
+	in.payoutM = make(map[string]*payoutInfo)
+	in.earnings = make([]Earning, 0)
+	setStakeMetric(delegation.Stake)
+
+	go loopValidatorReceipt(in.ctx, in.errorC, in.validator, newReceiptC)
 
-	//go loopStakeAccount(in.ctx, in.errorC, sub)
 	streamC := sub.RecvStream()
 	finishC := sub.RecvErr()
 
+	log.Debugf("entering loop for stake=%s on validator=%s", config.Stake.String(), validator.Id.String())
 out:
 	for {
 		select {
 		case err = <-finishC:
 			break out
 		case <-streamC:
-
+			err = in.on_stake_account()
+			if err != nil {
+				break out
+			}
 		case <-doneC:
 			break out
 		case err = <-errorC:
 			break out
 		case req := <-internalC:
 			req(in)
+		case rwd := <-newReceiptC:
+			in.on_receipt(rwd)
+		case id := <-deletePayoutC:
+			in.on_payout_delete(id)
+		case event := <-eventC:
+			err = in.on_event(event)
+			if err != nil {
+				break out
+			}
 		}
 	}
 	in.finish(err)
 }
 
+// The stake account changed.  Stop if the stake has been moved to another validator,
+// since receipts of the old validator no longer count it.
+func (in *internal) on_stake_account() error {
+	d, err := fetchDelegation(in.ctx, in.rpc, in.config.Stake)
+	if err != nil {
+		return err
+	}
+	if !d.Vote.Equals(in.delegation.Vote) {
+		return errStakeRedelegated
+	}
+	in.delegation = d
+	setStakeMetric(d.Stake)
+	return nil
+}
+
+func (in *internal) on_event(event sch.Event) error {
+	var err error
+	switch event.Type {
+	case sch.TRIGGER_STAKER_ADD:
+		err = in.run_staker_add(event)
+	case sch.TRIGGER_STAKER_WITHDRAW:
+		err = in.run_staker_withdraw(event)
+	case sch.EVENT_STAKER_HAVE_WITHDRAWN:
+		log.Debugf("stake=%s has been withdrawn from a receipt", in.config.Stake.String())
+	default:
+		log.Debugf("received unmatched event: %s", event.String())
+	}
+	return err
+}
+
 func (in *internal) finish(err error) {
 	log.Debug(err)
+	for _, pi := range in.payoutM {
+		pi.cancel()
+	}
 	for i := 0; i < len(in.closeSignalCList); i++ {
 		in.closeSignalCList[i] <- err
 	}
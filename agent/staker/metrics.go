@@ -0,0 +1,28 @@
+package staker
+
+import "github.com/solpipe/solpipe-tool/metrics"
+
+const (
+	STAKER_ADD_SUCCESS = "success"
+	STAKER_ADD_FAILURE = "failure"
+)
+
+var metricStake = metrics.NewGauge(
+	"solpipe_staker_delegated_stake",
+	"lamports delegated by the stake account",
+)
+
+var metricAdd = metrics.NewCounterVec(
+	"solpipe_staker_add_total",
+	"attempts to add the stake to a validator receipt by result",
+	"result",
+)
+
+var metricEarnings = metrics.NewCounter(
+	"solpipe_staker_earnings_total",
+	"estimated earnings of the stake in the smallest unit of the controller mint",
+)
+
+func setStakeMetric(lamports uint64) {
+	metricStake.Set(float64(lamports))
+}
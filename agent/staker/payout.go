@@ -0,0 +1,149 @@
+package staker
+
+import (
+	"context"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	sch "github.com/solpipe/solpipe-tool/scheduler"
+	schpyt "github.com/solpipe/solpipe-tool/scheduler/payout"
+	schskr "github.com/solpipe/solpipe-tool/scheduler/staker"
+	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
+	rpt "github.com/solpipe/solpipe-tool/state/receipt"
+	val "github.com/solpipe/solpipe-tool/state/validator"
+)
+
+type payoutInfo struct {
+	pwd            pipe.PayoutWithData
+	rwd            rpt.ReceiptWithData
+	stakerSchedule sch.Schedule
+	cancel         context.CancelFunc
+}
+
+// The validator has a receipt for a new payout, so schedule adding the stake to it.
+func (in *internal) on_receipt(rwd rpt.ReceiptWithData) {
+	payoutId := rwd.Data.Payout
+	_, present := in.payoutM[payoutId.String()]
+	if present {
+		return
+	}
+	pwd, err := in.router.PayoutById(payoutId)
+	if err != nil {
+		log.Debugf("no payout=%s for receipt=%s: %s", payoutId.String(), rwd.Receipt.Id.String(), err.Error())
+		return
+	}
+	slot, err := in.controller.SlotHome().Time()
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	if pwd.Data.Period.Start+pwd.Data.Period.Length <= slot {
+		log.Debugf("skipping payout=%s as the period has finished", payoutId.String())
+		return
+	}
+	log.Debugf("staker on_payout payout=%s receipt=%s", payoutId.String(), rwd.Receipt.Id.String())
+
+	pi := new(payoutInfo)
+	in.payoutM[payoutId.String()] = pi
+	pi.pwd = pwd
+	pi.rwd = rwd
+	var ctxC context.Context
+	ctxC, pi.cancel = context.WithCancel(in.ctx)
+	payoutSchedule := schpyt.Schedule(ctxC, in.router, pwd)
+	pi.stakerSchedule = schskr.Schedule(ctxC, pwd, payoutSchedule, in.staker, rwd)
+	go loopPayout(ctxC, in.eventC, in.errorC, in.deletePayoutC, pwd, pi.stakerSchedule)
+}
+
+func (in *internal) on_payout_delete(id sgo.PublicKey) {
+	pi, present := in.payoutM[id.String()]
+	if !present {
+		return
+	}
+	pi.cancel()
+	delete(in.payoutM, id.String())
+}
+
+// forward staker schedule events until the payout account closes
+func loopPayout(
+	ctx context.Context,
+	eventC chan<- sch.Event,
+	errorC chan<- error,
+	deleteC chan<- sgo.PublicKey,
+	pwd pipe.PayoutWithData,
+	stakerSchedule sch.Schedule,
+) {
+	var err error
+	doneC := ctx.Done()
+	closeC := pwd.Payout.OnClose()
+	sub := stakerSchedule.OnEvent()
+	defer sub.Unsubscribe()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case <-closeC:
+			select {
+			case <-doneC:
+			case deleteC <- pwd.Id:
+			}
+			break out
+		case err = <-sub.ErrorC:
+			break out
+		case event := <-sub.StreamC:
+			select {
+			case <-doneC:
+				break out
+			case eventC <- event:
+			}
+		}
+	}
+	if err != nil {
+		errorC <- err
+	}
+}
+
+// send every receipt the validator has, now and in the future
+func loopValidatorReceipt(
+	ctx context.Context,
+	errorC chan<- error,
+	validator val.Validator,
+	newReceiptC chan<- rpt.ReceiptWithData,
+) {
+	var err error
+	doneC := ctx.Done()
+	sub := validator.OnReceipt()
+	defer sub.Unsubscribe()
+
+	list, err := validator.AllReceipt()
+	if err != nil {
+		errorC <- err
+		return
+	}
+	for _, rwd := range list {
+		select {
+		case <-doneC:
+			return
+		case newReceiptC <- rwd:
+		}
+	}
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-sub.ErrorC:
+			break out
+		case rwd := <-sub.StreamC:
+			select {
+			case <-doneC:
+				break out
+			case newReceiptC <- rwd:
+			}
+		}
+	}
+	if err != nil {
+		errorC <- err
+	}
+}
@@ -0,0 +1,203 @@
+package staker
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	sch "github.com/solpipe/solpipe-tool/scheduler"
+	schskr "github.com/solpipe/solpipe-tool/scheduler/staker"
+	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+	"github.com/solpipe/solpipe-tool/util"
+)
+
+const MAX_TRIES_STAKER_ADD = 10
+
+var errStakeRedelegated = errors.New("stake has been delegated to another validator")
+
+func (in *internal) run_staker_add(event sch.Event) error {
+	trigger, err := schskr.ReadTrigger(event)
+	if err != nil {
+		return err
+	}
+
+	controllerId := in.controller.Id()
+	stake := in.config.Stake
+	admin := in.config.Admin
+	payoutId := trigger.Payout.Id
+	receiptId := trigger.Receipt.Id
+	validatorId := in.validator.Id
+	log.Debugf(
+		"staker add to receipt: (stake=%s;payout=%s;receipt=%s;validator=%s)",
+		stake.String(),
+		payoutId.String(),
+		receiptId.String(),
+		validatorId.String(),
+	)
+	// a receipt we failed to join only costs us that payout, so do not exit on error
+	go loopSend(
+		util.MergeCtx(in.ctx, trigger.Context),
+		in.scriptWrapper,
+		MAX_TRIES_STAKER_ADD,
+		10*time.Second,
+		func(script *spt.Script) error {
+			return runStakerAdd(
+				script,
+				controllerId,
+				stake,
+				admin,
+				payoutId,
+				receiptId,
+				validatorId,
+			)
+		},
+		payoutId,
+	)
+	return nil
+}
+
+func loopSend(
+	ctx context.Context,
+	scriptWrapper spt.Wrapper,
+	maxTries int,
+	delay time.Duration,
+	cb func(script *spt.Script) error,
+	payoutId sgo.PublicKey,
+) {
+	err := scriptWrapper.Send(ctx, maxTries, delay, cb)
+	if err != nil {
+		log.Debugf("failed to add stake to payout=%s: %s", payoutId.String(), err.Error())
+		metricAdd.With(STAKER_ADD_FAILURE).Inc()
+		return
+	}
+	metricAdd.With(STAKER_ADD_SUCCESS).Inc()
+}
+
+func runStakerAdd(
+	script *spt.Script,
+	controllerId sgo.PublicKey,
+	stake sgo.PublicKey,
+	admin signer.Signer,
+	payoutId sgo.PublicKey,
+	receiptId sgo.PublicKey,
+	validatorId sgo.PublicKey,
+) error {
+	err := script.SetTx(admin)
+	if err != nil {
+		return err
+	}
+	err = script.AddStakerToReceipt(
+		controllerId,
+		stake,
+		admin,
+		payoutId,
+		receiptId,
+		validatorId,
+	)
+	if err != nil {
+		return err
+	}
+	err = script.FinishTx(true)
+	if err != nil {
+		return err
+	}
+	log.Debugf("successfully added stake to payout=%s", payoutId.String())
+	return nil
+}
+
+// What the stake earned from a payout.
+type Earning struct {
+	Payout         sgo.PublicKey
+	Receipt        sgo.PublicKey
+	DelegatedStake uint64
+	ValidatorStake uint64
+	// estimated share of the validator payout, in the smallest unit of the controller mint
+	Amount uint64
+}
+
+// The CBA program has no staker withdraw instruction; the validator withdraws the receipt
+// for everyone once the period finishes.  All that is left to do is to tally the earnings.
+func (in *internal) run_staker_withdraw(event sch.Event) error {
+	trigger, err := schskr.ReadTriggerReceipt(event)
+	if err != nil {
+		return err
+	}
+	e, err := in.earning(trigger)
+	if err != nil {
+		log.Debugf("failed to calculate earnings for payout=%s: %s", trigger.Payout.Id.String(), err.Error())
+		return nil
+	}
+	in.earnings = append(in.earnings, e)
+	metricEarnings.Add(float64(e.Amount))
+	log.Infof(
+		"stake=%s earned %d from payout=%s (delegated stake=%d of validator stake=%d)",
+		in.config.Stake.String(),
+		e.Amount,
+		e.Payout.String(),
+		e.DelegatedStake,
+		e.ValidatorStake,
+	)
+	return nil
+}
+
+// the validator share of the bids is split evenly among validators and then pro rata by stake
+func (in *internal) earning(trigger *schskr.TriggerReceipt) (e Earning, err error) {
+	e.Payout = trigger.Payout.Id
+	e.Receipt = trigger.Receipt.Id
+	e.DelegatedStake = trigger.StakerReceipt.Data.DelegatedStake
+
+	data, err := trigger.Payout.Data()
+	if err != nil {
+		return
+	}
+	bs, err := trigger.Payout.BidStatus()
+	if err != nil {
+		return
+	}
+	pipeline, err := in.router.PipelineById(data.Pipeline)
+	if err != nil {
+		return
+	}
+	pd, err := pipeline.Data()
+	if err != nil {
+		return
+	}
+	e.ValidatorStake, _, err = in.validator.StakeRatio()
+	if err != nil {
+		return
+	}
+	e.Amount, err = earningAmount(bs.TotalDeposits, pd.ValidatorPayoutShare, data.ValidatorCount, e.DelegatedStake, e.ValidatorStake)
+	return
+}
+
+// totalDeposits*share/validatorCount*delegatedStake/validatorStake, rounded down
+func earningAmount(
+	totalDeposits uint64,
+	share [2]uint64,
+	validatorCount uint64,
+	delegatedStake uint64,
+	validatorStake uint64,
+) (uint64, error) {
+	if share[1] == 0 || validatorStake == 0 {
+		return 0, errors.New("no validator share or stake")
+	}
+	if validatorCount == 0 {
+		validatorCount = 1
+	}
+
+	x := new(big.Int).SetUint64(totalDeposits)
+	x.Mul(x, new(big.Int).SetUint64(share[0]))
+	x.Mul(x, new(big.Int).SetUint64(delegatedStake))
+	y := new(big.Int).SetUint64(share[1])
+	y.Mul(y, new(big.Int).SetUint64(validatorCount))
+	y.Mul(y, new(big.Int).SetUint64(validatorStake))
+	x.Quo(x, y)
+	if !x.IsUint64() {
+		return 0, errors.New("earnings overflow")
+	}
+	return x.Uint64(), nil
+}
@@ -0,0 +1,44 @@
+package staker
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEarningAmount(t *testing.T) {
+	for _, x := range []struct {
+		totalDeposits  uint64
+		share          [2]uint64
+		validatorCount uint64
+		delegatedStake uint64
+		validatorStake uint64
+		amount         uint64
+	}{
+		// half of 1000 to two validators, a quarter of the stake of ours
+		{1000, [2]uint64{1, 2}, 2, 25, 100, 62},
+		// a validator count of zero is treated as one
+		{1000, [2]uint64{1, 2}, 0, 25, 100, 125},
+		{1000, [2]uint64{1, 1}, 1, 100, 100, 1000},
+		{0, [2]uint64{1, 1}, 1, 100, 100, 0},
+		// the intermediate product does not overflow
+		{math.MaxUint64, [2]uint64{1, 2}, 1, math.MaxUint64, math.MaxUint64, math.MaxUint64 / 2},
+	} {
+		amount, err := earningAmount(x.totalDeposits, x.share, x.validatorCount, x.delegatedStake, x.validatorStake)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amount != x.amount {
+			t.Errorf("%+v: got %d", x, amount)
+		}
+	}
+
+	if _, err := earningAmount(1000, [2]uint64{1, 0}, 1, 1, 1); err == nil {
+		t.Error("accepted a share with a zero denominator")
+	}
+	if _, err := earningAmount(1000, [2]uint64{1, 1}, 1, 1, 0); err == nil {
+		t.Error("accepted a validator without stake")
+	}
+	if _, err := earningAmount(math.MaxUint64, [2]uint64{2, 1}, 1, 1, 1); err == nil {
+		t.Error("accepted earnings that overflow")
+	}
+}
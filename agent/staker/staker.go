@@ -6,12 +6,15 @@ import (
 	"errors"
 	"os"
 
-	ctr "github.com/solpipe/solpipe-tool/state/controller"
-	rtr "github.com/solpipe/solpipe-tool/state/router"
-	skr "github.com/solpipe/solpipe-tool/state/staker"
 	sgo "github.com/SolmateDev/solana-go"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+	val "github.com/solpipe/solpipe-tool/state/validator"
 )
 
 type Staker struct {
@@ -21,10 +24,11 @@ type Staker struct {
 	controller ctr.Controller
 	router     rtr.Router
 	staker     skr.Staker
+	Validator  val.Validator
 }
 
 type Configuration struct {
-	Admin sgo.PrivateKey
+	Admin signer.Signer
 	Stake sgo.PublicKey // public key
 }
 
@@ -45,6 +49,8 @@ func ConfigFromEnv() (*Configuration, error) {
 	return ans, nil
 }
 
+// Run the staker schedule for every payout of the validator to which the stake is delegated.
+// The stake must have been registered (see script.AddStaker) beforehand.
 func Create(
 	ctxOutside context.Context,
 	config *Configuration,
@@ -53,50 +59,78 @@ func Create(
 	router rtr.Router,
 ) (Staker, error) {
 	var err error
-	ctx, cancel := context.WithCancel(ctxOutside)
-	internalC := make(chan func(*internal), 10)
 	if config == nil {
 		config, err = ConfigFromEnv()
 		if err != nil {
-			cancel()
 			return Staker{}, err
 		}
 	}
+	ctx, cancel := context.WithCancel(ctxOutside)
+
+	delegation, err := fetchDelegation(ctx, rpcClient, config.Stake)
+	if err != nil {
+		cancel()
+		return Staker{}, err
+	}
+	validator, err := router.ValidatorByVote(delegation.Vote)
+	if err != nil {
+		cancel()
+		return Staker{}, err
+	}
+	staker, err := router.StakerByStake(config.Stake)
+	if err != nil {
+		cancel()
+		return Staker{}, err
+	}
+	script, err := spt.Create(ctx, &spt.Configuration{Version: router.Controller.Version}, rpcClient, wsClient)
+	if err != nil {
+		cancel()
+		return Staker{}, err
+	}
 	sub, err := wsClient.AccountSubscribe(config.Stake, sgorpc.CommitmentFinalized)
 	if err != nil {
 		cancel()
 		return Staker{}, err
 	}
 
-	go loopInternal(ctx, internalC, cancel, router, config, sub)
+	internalC := make(chan func(*internal), 10)
+	go loopInternal(
+		ctx,
+		internalC,
+		cancel,
+		rpcClient,
+		spt.Wrap(ctx, script),
+		router,
+		validator,
+		staker,
+		config,
+		delegation,
+		sub,
+	)
 	e1 := Staker{
-		ctx: ctx, cancel: cancel, internalC: internalC, router: router,
+		ctx:        ctx,
+		cancel:     cancel,
+		internalC:  internalC,
+		controller: router.Controller,
+		router:     router,
+		staker:     staker,
+		Validator:  validator,
 	}
 	return e1, nil
 }
 
-func loopStakeAccount(ctx context.Context, errorC chan<- error, sub *sgows.AccountSubscription) {
-	defer sub.Unsubscribe()
-	var err error
-
-	streamC := sub.RecvStream()
-	finishC := sub.RecvErr()
-	doneC := ctx.Done()
-
-out:
-	for {
-		select {
-		case <-doneC:
-			break out
-		case err = <-finishC:
-			break out
-		case <-streamC:
-			// we cannot deserialize staking accounts, so ...
-		}
-	}
+func fetchDelegation(ctx context.Context, rpcClient *sgorpc.Client, stake sgo.PublicKey) (skr.Delegation, error) {
+	r, err := rpcClient.GetAccountInfo(ctx, stake)
 	if err != nil {
-		errorC <- err
+		return skr.Delegation{}, err
+	}
+	if r.Value == nil {
+		return skr.Delegation{}, errors.New("no stake account")
+	}
+	if !r.Value.Owner.Equals(sgo.StakeProgramID) {
+		return skr.Delegation{}, errors.New("account is not owned by the stake program")
 	}
+	return skr.ParseDelegation(r.Value.Data.GetBinary())
 }
 
 func (e1 Staker) CloseSignal() <-chan error {
@@ -125,3 +159,22 @@ func (e1 Staker) Close() {
 	e1.cancel()
 	<-doneC
 }
+
+// Earnings recorded so far, one per payout in which the stake was counted.
+func (e1 Staker) Earnings() ([]Earning, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan []Earning, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ansC <- append([]Earning{}, in.earnings...)
+	}:
+	}
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case list := <-ansC:
+		return list, nil
+	}
+}
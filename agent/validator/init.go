@@ -10,11 +10,12 @@ import (
 	"github.com/cretz/bine/tor"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
 	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 )
 
 type InitializationArg struct {
-	Wallet         sgo.PrivateKey
+	Wallet         signer.Signer
 	ControllerId   sgo.PublicKey
 	Vote           sgo.PrivateKey
 	Stake          sgo.PublicKey
@@ -70,7 +71,7 @@ func Initialize(
 
 	s1, err := script.Create(
 		ctx,
-		&script.Configuration{Version: args.RelayConfig.Version},
+		args.RelayConfig.ScriptConfiguration(),
 		rpcClient,
 		wsClient,
 	)
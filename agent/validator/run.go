@@ -8,6 +8,7 @@ import (
 	sch "github.com/solpipe/solpipe-tool/scheduler"
 	schval "github.com/solpipe/solpipe-tool/scheduler/validator"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	val "github.com/solpipe/solpipe-tool/state/validator"
@@ -53,7 +54,7 @@ func runValidatorSetPayout(
 	payoutId sgo.PublicKey,
 	pipelineId sgo.PublicKey,
 	validatorId sgo.PublicKey,
-	validatorAdmin sgo.PrivateKey,
+	validatorAdmin signer.Signer,
 ) error {
 	err := script.SetTx(validatorAdmin)
 	if err != nil {
@@ -128,7 +129,7 @@ func (in *internal) run_validator_withdraw_receipt(event sch.Event) error {
 
 func runValidatorWithdrawReceipt(
 	script *spt.Script,
-	validatorAdmin sgo.PrivateKey,
+	validatorAdmin signer.Signer,
 	controller ctr.Controller,
 	payoutId sgo.PublicKey,
 	pipeline pipe.Pipeline,
@@ -19,6 +19,7 @@ import (
 	pxyval "github.com/solpipe/solpipe-tool/proxy/relay/validator"
 	pxysvr "github.com/solpipe/solpipe-tool/proxy/server"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 	val "github.com/solpipe/solpipe-tool/state/validator"
@@ -39,7 +40,7 @@ type Agent struct {
 
 type Configuration struct {
 	Version vrs.CbaVersion
-	Admin   sgo.PrivateKey
+	Admin   signer.Signer
 }
 
 type ListenResult struct {
@@ -164,23 +165,31 @@ func Create(
 	var grpcServerClearNet *grpc.Server
 	var torLi *proxy.ListenerInfo
 	var clearLi *proxy.ListenerInfo
-	grpcServerTor, err = proxy.CreateListener(
-		ctx,
-		config.Admin,
-	)
-	if err != nil {
+	// a remote admin signer cannot host an onion service
+	_, localAdmin := signer.Local(config.Admin)
+	if !localAdmin && config.ClearNet == nil {
 		cancel()
-		return Agent{}, err
+		return Agent{}, errors.New("a remote admin signer needs a clear net listener")
 	}
+	if localAdmin {
+		grpcServerTor, err = proxy.CreateListener(
+			ctx,
+			config.Admin,
+		)
+		if err != nil {
+			cancel()
+			return Agent{}, err
+		}
 
-	torLi, err = proxy.CreateListenerTor(
-		ctx,
-		config.Admin,
-		torMgr,
-	)
-	if err != nil {
-		cancel()
-		return Agent{}, err
+		torLi, err = proxy.CreateListenerTor(
+			ctx,
+			config.Admin,
+			torMgr,
+		)
+		if err != nil {
+			cancel()
+			return Agent{}, err
+		}
 	}
 	if config.ClearNet != nil {
 		grpcServerClearNet, err = proxy.CreateListener(
@@ -226,10 +235,18 @@ func Create(
 		cancel()
 		return
 	}
+	meterStore, err := config.MeterStore(ctxC)
+	if err != nil {
+		cancel()
+		return
+	}
 	internalC := make(chan func(*internal), 10)
 	serverErrorC := make(chan error, 4)
 
-	sList := []*grpc.Server{grpcServerTor}
+	sList := []*grpc.Server{}
+	if grpcServerTor != nil {
+		sList = append(sList, grpcServerTor)
+	}
 	if grpcServerClearNet != nil {
 		sList = append(sList, grpcServerClearNet)
 	}
@@ -241,14 +258,17 @@ func Create(
 		config.Admin,
 		relay,
 		config.ClearNet,
+		meterStore,
 	)
 	if err != nil {
 		cancel()
 		return
 	}
-	reflection.Register(grpcServerTor)
-	go loopGrpcListen(ctxC, torLi.Listener, grpcServerTor, serverErrorC)
-	go loopGrpcShutdown(ctxC, torMgr, torLi.Listener, grpcServerTor)
+	if grpcServerTor != nil {
+		reflection.Register(grpcServerTor)
+		go loopGrpcListen(ctxC, torLi.Listener, grpcServerTor, serverErrorC)
+		go loopGrpcShutdown(ctxC, torMgr, torLi.Listener, grpcServerTor)
+	}
 	if grpcServerClearNet != nil {
 		reflection.Register(grpcServerClearNet)
 		go loopGrpcListen(ctxC, clearLi.Listener, grpcServerClearNet, serverErrorC)
@@ -1,18 +1,28 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 
 	sgo "github.com/SolmateDev/solana-go"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	bin "github.com/gagliardetto/binary"
 	bdr "github.com/solpipe/solpipe-tool/agent/bidder"
+	"github.com/solpipe/solpipe-tool/proxy"
+	"github.com/solpipe/solpipe-tool/proxy/client"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/proxy/server"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	ntk "github.com/solpipe/solpipe-tool/state/network"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 
 	sgotkn2 "github.com/SolmateDev/solana-go/programs/token"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 type Bidder struct {
@@ -28,14 +38,18 @@ func (r *BidderSetup) Run(kongCtx *CLIContext) error {
 }
 
 type BidderAgent struct {
-	Key           string `arg name:"key" help:"the private key of the wallet that owns tokens used to bid on bandwidth and also authenticates over grpc with the staked validator"`
-	Configuration string `arg name:"config" help:"the file path to the configuration file"`
+	AdminUrl      string `option name:"admin_url" help:"url (tcp://HOST:PORT or unix:///my/file/path) on which to serve the Brain grpc service used to set the TPS budget"`
+	JsonRpcUrl    string `option name:"jsonrpc_url" help:"url (tcp://HOST:PORT or unix:///my/file/path) on which to serve a Solana JSON RPC endpoint tunneled through the pipeline set by jsonrpc_pipeline"`
+	JsonRpcPipe   string `option name:"jsonrpc_pipeline" help:"the id of the pipeline that serves the JSON RPC endpoint"`
+	Key           string `arg name:"key" help:"a key file, keystore or grpcs:// remote signer url for the wallet that owns tokens used to bid on bandwidth and also authenticates over grpc with the staked validator"`
+	Configuration string `arg name:"config" help:"the file path to the configuration file holding the TPS budget; it is written whenever the budget changes"`
 }
 
 func (r *BidderAgent) Run(kongCtx *CLIContext) error {
 
 	ctx := kongCtx.Ctx
-	admin, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Key)
+	// read once; a keystore prompts for its passphrase on every read
+	admin, err := readSigner(ctx, r.Key)
 	if err != nil {
 		return err
 	}
@@ -46,9 +60,10 @@ func (r *BidderAgent) Run(kongCtx *CLIContext) error {
 		kongCtx.Clients.RpcUrl,
 		kongCtx.Clients.WsUrl,
 		kongCtx.Clients.Headers.Clone(),
-		"",
+		r.AdminUrl,
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 
 	rpcClient := relayConfig.Rpc()
 	wsClient, err := relayConfig.Ws(ctx)
@@ -64,10 +79,6 @@ func (r *BidderAgent) Run(kongCtx *CLIContext) error {
 		return err
 	}
 
-	userKey, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Key)
-	if err != nil {
-		return err
-	}
 	router, err := rtr.CreateRouter(ctx, network, rpcClient, wsClient, nil, relayConfig.Version)
 	if err != nil {
 		return err
@@ -81,7 +92,7 @@ func (r *BidderAgent) Run(kongCtx *CLIContext) error {
 	pcMint := controllerData.PcMint
 	x, err := rpcClient.GetTokenAccountsByOwner(
 		ctx,
-		userKey.PublicKey(),
+		admin.PublicKey(),
 		&sgorpc.GetTokenAccountsConfig{Mint: &pcMint},
 		&sgorpc.GetTokenAccountsOpts{Commitment: sgorpc.CommitmentFinalized},
 	)
@@ -110,17 +121,83 @@ backout:
 		ctx,
 		rpcClient,
 		wsClient,
-		userKey,
+		admin,
 		userVaultId,
 		userVault,
 		router,
+		r.Configuration,
 	)
 	if err != nil {
 		return err
 	}
-	err = <-bidder.CloseSignal()
+	if 0 < len(r.AdminUrl) {
+		l, err := relayConfig.AdminListener(ctx)
+		if err != nil {
+			return err
+		}
+		grpcServer := grpc.NewServer()
+		bidder.Attach(grpcServer)
+		reflection.Register(grpcServer)
+		go loopBrainServe(grpcServer, l)
+	}
+	var jsonRpcC <-chan error
+	if 0 < len(r.JsonRpcUrl) {
+		jsonRpcC, err = r.serveJsonRpc(ctx, router, rpcClient, admin)
+		if err != nil {
+			return err
+		}
+	}
+	select {
+	case err = <-bidder.CloseSignal():
+	case err = <-jsonRpcC:
+	}
+	return err
+}
+
+// connect to the pipeline and serve its JSON RPC tunnel on JsonRpcUrl
+func (r *BidderAgent) serveJsonRpc(
+	ctx context.Context,
+	router rtr.Router,
+	rpcClient *sgorpc.Client,
+	admin signer.Signer,
+) (<-chan error, error) {
+	pipelineId, err := sgo.PublicKeyFromBase58(r.JsonRpcPipe)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("jsonrpc_pipeline: %w", err)
+	}
+	pipeline, err := router.PipelineById(pipelineId)
+	if err != nil {
+		return nil, err
+	}
+	data, err := pipeline.Data()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := server.ClusterFromRpc(ctx, rpcClient)
+	if err != nil {
+		return nil, err
+	}
+	l, err := relay.Listen(ctx, r.JsonRpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	torMgr, err := proxy.SetupTor(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	go loopCloseTor(ctx, torMgr)
+	conn, err := proxy.CreateConnectionTorClearIfAvailable(ctx, data.Admin, admin, torMgr)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("serving json rpc via pipeline=%s on %s", pipelineId.String(), r.JsonRpcUrl)
+	return client.ServeJsonRpc(ctx, conn, cluster, l), nil
+}
+
+// the admin listener closes itself once ctx is done, which stops the server
+func loopBrainServe(grpcServer *grpc.Server, l net.Listener) {
+	err := grpcServer.Serve(l)
+	if err != nil {
+		log.Debug(err)
 	}
-	return nil
 }
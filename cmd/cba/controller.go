@@ -24,7 +24,7 @@ type ControllerStatus struct {
 func (r *ControllerStatus) Run(kongCtx *CLIContext) error {
 	ctx := kongCtx.Ctx
 
-	admin, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Admin)
+	admin, err := readPrivateKey(r.Admin)
 	if err != nil {
 		return err
 	}
@@ -38,6 +38,7 @@ func (r *ControllerStatus) Run(kongCtx *CLIContext) error {
 		"",
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 
 	rpcClient := relayConfig.Rpc()
 	wsClient, err := relayConfig.Ws(ctx)
@@ -71,11 +72,11 @@ type ControllerCreate struct {
 func (r *ControllerCreate) Run(kongCtx *CLIContext) error {
 	ctx := kongCtx.Ctx
 
-	payer, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Payer)
+	payer, err := readPrivateKey(r.Payer)
 	if err != nil {
 		return err
 	}
-	admin, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Admin)
+	admin, err := readPrivateKey(r.Admin)
 	if err != nil {
 		return err
 	}
@@ -103,7 +104,7 @@ func (r *ControllerCreate) Run(kongCtx *CLIContext) error {
 	if len(r.Cranker) == 0 {
 		cranker = admin
 	} else {
-		cranker, err = sgo.PrivateKeyFromSolanaKeygenFile(r.Cranker)
+		cranker, err = readPrivateKey(r.Cranker)
 		if err != nil {
 			return err
 		}
@@ -118,6 +119,7 @@ func (r *ControllerCreate) Run(kongCtx *CLIContext) error {
 		"",
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 	s1, err := relayConfig.ScriptBuilder(ctx)
 	if err != nil {
 		return err
@@ -13,18 +13,21 @@ import (
 
 type Cranker struct {
 	BalanceThreshold uint64 `arg name:"minbal" help:"what is the balance threshold at which the program needs to exit with an error code"`
-	Key              string `arg name:"key" help:"the file path of the private key"`
+	Key              string `arg name:"key" help:"the file path of the private key, a keystore or a grpcs:// remote signer url"`
+	RouterSnapshot   string `option name:"router_snapshot" help:"file in which to keep a snapshot of program accounts so restarts do not fetch them all again"`
+	Metrics          string `option name:"metrics" help:"HOST:PORT on which to serve Prometheus metrics under /metrics"`
 }
 
 func (r *Cranker) Run(kongCtx *CLIContext) error {
 	ctx := kongCtx.Ctx
 
-	crankerKey, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Key)
+	crankerKey, err := readSigner(ctx, r.Key)
 	if err != nil {
 		return err
 	}
 
 	log.Infof("ws url=%s", kongCtx.Clients.WsUrl)
+	metricsC := runMetrics(ctx, r.Metrics)
 
 	relayConfig := relay.CreateConfiguration(
 		kongCtx.Clients.Version,
@@ -35,6 +38,8 @@ func (r *Cranker) Run(kongCtx *CLIContext) error {
 		"",
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+	relayConfig.RouterSnapshot = r.RouterSnapshot
 
 	rpcClient := relayConfig.Rpc()
 	wsClient, err := relayConfig.Ws(ctx)
@@ -53,7 +58,12 @@ func (r *Cranker) Run(kongCtx *CLIContext) error {
 		return err
 	}
 
-	router, err := rtr.CreateRouter(ctx, network, rpcClient, wsClient, nil, relayConfig.Version)
+	var router rtr.Router
+	if 0 < len(r.RouterSnapshot) {
+		router, err = rtr.CreateRouterWithSnapshot(ctx, network, rpcClient, wsClient, r.RouterSnapshot, relayConfig.Version)
+	} else {
+		router, err = rtr.CreateRouter(ctx, network, rpcClient, wsClient, nil, relayConfig.Version)
+	}
 	if err != nil {
 		return err
 	}
@@ -105,5 +115,9 @@ func (r *Cranker) Run(kongCtx *CLIContext) error {
 		return err
 	}
 
-	return <-cranker.CloseSignal()
+	select {
+	case err = <-cranker.CloseSignal():
+	case err = <-metricsC:
+	}
+	return err
 }
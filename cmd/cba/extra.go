@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
 
+	"github.com/solpipe/solpipe-tool/metrics"
 	"github.com/solpipe/solpipe-tool/state"
+	"github.com/solpipe/solpipe-tool/tracing"
 )
 
 type ConfigRate state.Rate
@@ -57,3 +60,20 @@ func convertRate(in string, defaultRate *state.Rate) (*state.Rate, error) {
 	}
 
 }
+
+// Serve Prometheus metrics if listenUrl is set; otherwise the returned
+// channel is nil and never fires.
+func runMetrics(ctx context.Context, listenUrl string) <-chan error {
+	if len(listenUrl) == 0 {
+		return nil
+	}
+	return metrics.Run(ctx, listenUrl)
+}
+
+// Export spans if target is set; otherwise the returned channel is nil and never fires.
+func runTracing(ctx context.Context, target string, service string) <-chan error {
+	if len(target) == 0 {
+		return nil
+	}
+	return tracing.Run(ctx, target, service)
+}
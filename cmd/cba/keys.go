@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/signer"
+	"github.com/solpipe/solpipe-tool/util"
+	"golang.org/x/term"
+)
+
+type Keys struct {
+	New          KeysNew          `cmd name:"new" help:"generate a private key and save it in an encrypted keystore"`
+	Import       KeysImport       `cmd name:"import" help:"encrypt a base58 private key or solana-keygen key into a keystore"`
+	ExportPubkey KeysExportPubkey `cmd name:"export-pubkey" help:"print the public key of a keystore or key file"`
+	VanityOnion  KeysVanityOnion  `cmd name:"vanity-onion" help:"generate a private key whose tor onion address starts with a prefix"`
+}
+
+type KeysNew struct {
+	Kdf  string `option name:"kdf" default:"scrypt" enum:"scrypt,argon2id" help:"key derivation function for the passphrase (scrypt or argon2id)"`
+	File string `arg name:"file" help:"file to write the keystore to; an existing file is not overwritten"`
+}
+
+type KeysImport struct {
+	Kdf   string `option name:"kdf" default:"scrypt" enum:"scrypt,argon2id" help:"key derivation function for the passphrase (scrypt or argon2id)"`
+	Input string `arg name:"input" help:"a file with the base58 private key or solana-keygen array to import, or - for stdin"`
+	File  string `arg name:"file" help:"file to write the keystore to; an existing file is not overwritten"`
+}
+
+type KeysExportPubkey struct {
+	File string `arg name:"file" help:"the keystore or key file"`
+}
+
+type KeysVanityOnion struct {
+	Kdf      string `option name:"kdf" default:"scrypt" enum:"scrypt,argon2id" help:"key derivation function for the passphrase (scrypt or argon2id)"`
+	MaxTries uint64 `option name:"max_tries" default:"100000000" help:"give up after this many keys"`
+	Prefix   string `arg name:"prefix" help:"the onion address prefix (base32, lower case)"`
+	File     string `arg name:"file" help:"file to write the keystore to; an existing file is not overwritten"`
+}
+
+func (r *KeysNew) Run(kongCtx *CLIContext) error {
+	key, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		return err
+	}
+	return writeKeystore(r.File, key, r.Kdf)
+}
+
+// The key is never taken as an argument so that it stays out of the shell
+// history.
+func (r *KeysImport) Run(kongCtx *CLIContext) error {
+	var data []byte
+	var err error
+	if r.Input == "-" {
+		data, err = readStdinSecret("base58 private key: ")
+	} else {
+		data, err = os.ReadFile(r.Input)
+	}
+	if err != nil {
+		return err
+	}
+	key, err := parseImportKey(data)
+	if err != nil {
+		return err
+	}
+	return writeKeystore(r.File, key, r.Kdf)
+}
+
+// Read without echo when stdin is a terminal.
+func readStdinSecret(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return io.ReadAll(os.Stdin)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	ans, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return ans, err
+}
+
+// data is either a base58 private key or a solana-keygen JSON array.
+func parseImportKey(data []byte) (sgo.PrivateKey, error) {
+	text := strings.TrimSpace(string(data))
+	var key sgo.PrivateKey
+	if strings.HasPrefix(text, "[") {
+		var values []byte
+		err := json.Unmarshal([]byte(text), &values)
+		if err != nil {
+			return nil, fmt.Errorf("decode keygen array: %w", err)
+		}
+		key = sgo.PrivateKey(values)
+	} else {
+		var err error
+		key, err = sgo.PrivateKeyFromBase58(text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("private key has %d bytes, expected %d", len(key), ed25519.PrivateKeySize)
+	}
+	return key, nil
+}
+
+// A keystore keeps the public key in the clear, so no passphrase is needed.
+func (r *KeysExportPubkey) Run(kongCtx *CLIContext) error {
+	data, err := os.ReadFile(r.File)
+	if err != nil {
+		return err
+	}
+	ks, err := signer.ParseKeystore(data)
+	if err == nil {
+		fmt.Println(ks.PublicKey)
+		return nil
+	}
+	key, err := sgo.PrivateKeyFromSolanaKeygenFile(r.File)
+	if err != nil {
+		return err
+	}
+	fmt.Println(key.PublicKey().String())
+	return nil
+}
+
+func (r *KeysVanityOnion) Run(kongCtx *CLIContext) error {
+	if len(r.Prefix) == 0 {
+		return errors.New("no prefix")
+	}
+	key, err := util.GenerateVanityOnionAddressSolanaPrivatekey(r.Prefix, r.MaxTries)
+	if err != nil {
+		return err
+	}
+	onionID, err := util.GetOnionID(key.PublicKey().Bytes())
+	if err != nil {
+		return err
+	}
+	err = writeKeystore(r.File, key, r.Kdf)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s.onion\n", onionID)
+	return nil
+}
+
+// Print the public key once the keystore is on disk.
+func writeKeystore(fp string, key sgo.PrivateKey, kdf string) error {
+	passphrase, err := readPassphrase(fmt.Sprintf("new passphrase for %s: ", fp), true)
+	if err != nil {
+		return err
+	}
+	if len(passphrase) == 0 {
+		return errors.New("blank passphrase")
+	}
+	ks, err := signer.EncryptKeystore(key, passphrase, kdf)
+	if err != nil {
+		return err
+	}
+	err = signer.WriteKeystore(fp, ks)
+	if err != nil {
+		return err
+	}
+	fmt.Println(ks.PublicKey)
+	return nil
+}
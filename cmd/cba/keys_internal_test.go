@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+)
+
+func TestParseImportKey(t *testing.T) {
+	key, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]int, len(key))
+	for i, b := range key {
+		values[i] = int(b)
+	}
+	array, err := json.Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{[]byte(key.String() + "\n"), array} {
+		x, err := parseImportKey(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !x.PublicKey().Equals(key.PublicKey()) {
+			t.Fatalf("wrong key from %s", string(data))
+		}
+	}
+	for _, data := range []string{"", "[1,2,3]", "not base58!"} {
+		_, err = parseImportKey([]byte(data))
+		if err == nil {
+			t.Fatalf("parsed %q", data)
+		}
+	}
+}
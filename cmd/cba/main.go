@@ -15,8 +15,12 @@ import (
 	"github.com/alecthomas/kong"
 	log "github.com/sirupsen/logrus"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/state"
 	vrs "github.com/solpipe/solpipe-tool/state/version"
+	"github.com/solpipe/solpipe-tool/util"
+	"golang.org/x/term"
 )
 
 type CLIContext struct {
@@ -29,6 +33,8 @@ type debugFlag bool
 type ProgramIdCba string
 
 type ApiKey string
+type PriorityFee string
+type ComputeLimit bool
 type Version string
 type RpcUrl string
 type WsUrl string
@@ -39,14 +45,21 @@ var cli struct {
 	Version      Version      `option name:"version" help:"What version is the controller"`
 	RpcUrl       RpcUrl       `option name:"rpc" help:"Connection information to a Solana validator Rpc endpoint with format protocol://host:port (ie http://localhost:8899)"`
 	WsUrl        WsUrl        `option name:"ws" help:"Connection information to a Solana validator Websocket endpoint with format protocol://host:port (ie ws://localhost:8900)" type:"string"`
-	ApiKey       ApiKey       `option name:"apikey" help:"An API Key used to connect to an RPC Provider"`
+	PriorityFee  PriorityFee  `option name:"priority-fee" help:"Compute unit price on transactions in micro-lamports; MICROLAMPORTS for a fixed price or pPERCENTILE[:MIN[:MAX]] (ie p75:1000:50000) for a percentile of recent fees paid on the same accounts"`
+	Compute      ComputeLimit `option name:"simulate-compute" help:"Set the compute unit limit of transactions from a simulation" default:"false"`
+	ApiKey       ApiKey       `option name:"apikey" help:"An API Key used to connect to an RPC Provider; KEY (sent as a bearer token), header:NAME:KEY, query:NAME:KEY, path:KEY or PROVIDER:KEY with PROVIDER one of helius, quicknode, alchemy, triton"`
 	Cranker      Cranker      `cmd name:"cranker" help:"Crank the CBA program"`
 	Bidder       Bidder       `cmd name:"bid" help:"Bid for transaction bandwidth."`
 	Controller   Controller   `cmd name:"controller" help:"Manage the controller"`
 	Pipeline     Pipeline     `cmd name:"pipeline" help:"Run a JSON RPC send_tx proxy"`
 	Payout       Payout       `cmd name:"payout" help:"Get Payout status"`
 	Validator    Validator    `cmd name:"validator" help:"Run a JSON RPC send_tx proxy"`
+	Staker       Staker       `cmd name:"staker" help:"Count stake in validator receipts"`
+	Replay       Replay       `cmd name:"replay" help:"Record program state to a file and play it back offline"`
 	Web          Web          `cmd name:"web" help:"Run a web server allowing state updates over HTTP and Websockets"`
+	Signer       Signer       `cmd name:"signer" help:"Run a remote signing service"`
+	Keys         Keys         `cmd name:"keys" help:"Create and inspect encrypted keystores"`
+	Tx           Tx           `cmd name:"tx" help:"Build, sign offline and submit admin transactions using durable nonces"`
 }
 
 // PROGRAM_ID_CBA=2nV2HN9eaaoyk4WmiiEtUShup9hVQ21rNawfor9qoqam
@@ -57,6 +70,8 @@ type Clients struct {
 	WsUrl   string
 	Headers http.Header
 	Version vrs.CbaVersion
+	apiKey  *util.ApiKey
+	Fee     *script.FeePolicy // nil sends no ComputeBudget instructions
 }
 
 func (v RpcUrl) AfterApply(clients *Clients) error {
@@ -81,13 +96,106 @@ func (v Version) AfterApply(clients *Clients) error {
 
 func (key ApiKey) AfterApply(clients *Clients) error {
 	if len(key) == 0 {
+		return nil
+	}
+	k, err := util.ParseApiKey(string(key))
+	if err != nil {
+		return err
+	}
+	clients.apiKey = &k
+	return nil
+}
+
+// Put the api key into the urls or headers.  This runs after all the flags have
+// been parsed so that it does not matter in which order kong applies them.
+func (clients *Clients) apply_api_key() error {
+	if clients.Headers == nil {
 		clients.Headers = http.Header{}
-	} else {
-		return errors.New("not implemented yet")
+	}
+	if clients.apiKey == nil {
+		return nil
+	}
+	log.AddHook(clients.apiKey.LogHook())
+	var err error
+	clients.RpcUrl, err = clients.apiKey.Apply(clients.RpcUrl, clients.Headers)
+	if err != nil {
+		return err
+	}
+	clients.WsUrl, err = clients.apiKey.Apply(clients.WsUrl, clients.Headers)
+	if err != nil {
+		return err
 	}
 	return nil
 }
 
+func (v PriorityFee) AfterApply(clients *Clients) error {
+	if len(v) == 0 {
+		return nil
+	}
+	fp, err := parsePriorityFee(string(v))
+	if err != nil {
+		return err
+	}
+	if clients.Fee != nil {
+		fp.SimulateLimit = clients.Fee.SimulateLimit
+	}
+	clients.Fee = fp
+	return nil
+}
+
+func (v ComputeLimit) AfterApply(clients *Clients) error {
+	if !v {
+		return nil
+	}
+	if clients.Fee == nil {
+		clients.Fee = new(script.FeePolicy)
+	}
+	clients.Fee.SimulateLimit = true
+	return nil
+}
+
+// MICROLAMPORTS or pPERCENTILE[:MIN[:MAX]]
+func parsePriorityFee(v string) (*script.FeePolicy, error) {
+	fp := new(script.FeePolicy)
+	var err error
+	if !strings.HasPrefix(v, "p") {
+		fp.MicroLamports, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return fp, nil
+	}
+	x := strings.Split(v[1:], ":")
+	if 3 < len(x) {
+		return nil, errors.New("priority fee is not of form pPERCENTILE[:MIN[:MAX]]")
+	}
+	percentile, err := strconv.ParseUint(x[0], 10, 8)
+	if err != nil {
+		return nil, err
+	}
+	if percentile == 0 {
+		return nil, errors.New("percentile must be at least 1")
+	}
+	fp.Percentile = uint8(percentile)
+	if 1 < len(x) {
+		fp.MicroLamports, err = strconv.ParseUint(x[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if 2 < len(x) {
+		fp.MaxMicroLamports, err = strconv.ParseUint(x[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = fp.Check()
+	if err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
 func (idstr ProgramIdCba) AfterApply(clients *Clients) error {
 	id, err := sgo.PublicKeyFromBase58(string(idstr))
 	if err != nil {
@@ -117,7 +225,9 @@ func main() {
 	if len(clients.Version) == 0 {
 		clients.Version = vrs.VERSION_1
 	}
-	err := kongCtx.Run(&CLIContext{Ctx: ctx, Clients: clients})
+	err := clients.apply_api_key()
+	kongCtx.FatalIfErrorf(err)
+	err = kongCtx.Run(&CLIContext{Ctx: ctx, Clients: clients})
 	kongCtx.FatalIfErrorf(err)
 }
 
@@ -149,15 +259,67 @@ func readRate(rate string) (*state.Rate, error) {
 	return ans, nil
 }
 
-// argument is either file name or private key in base58
+// input is a base58 private key, a solana-keygen file or a keystore.
 func readPrivateKey(input string) (ans sgo.PrivateKey, err error) {
 
 	ans, err = sgo.PrivateKeyFromBase58(input)
+	if err == nil {
+		return
+	}
+	data, err := os.ReadFile(input)
 	if err != nil {
-		ans, err = sgo.PrivateKeyFromSolanaKeygenFile(input)
-		if err != nil {
-			return
-		}
+		return
+	}
+	ks, err := signer.ParseKeystore(data)
+	if err != nil {
+		return sgo.PrivateKeyFromSolanaKeygenFile(input)
+	}
+	passphrase, err := readPassphrase(fmt.Sprintf("passphrase for %s (%s): ", input, ks.PublicKey), false)
+	if err != nil {
+		return
+	}
+	return ks.Decrypt(passphrase)
+}
+
+const ENV_KEYSTORE_PASSPHRASE = "SOLPIPE_KEYSTORE_PASSPHRASE"
+
+// Take the passphrase from SOLPIPE_KEYSTORE_PASSPHRASE so agents can start
+// unattended, otherwise prompt on the terminal.
+func readPassphrase(prompt string, confirm bool) ([]byte, error) {
+	passphrase, present := os.LookupEnv(ENV_KEYSTORE_PASSPHRASE)
+	if present {
+		return []byte(passphrase), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no terminal to ask for a passphrase; set %s", ENV_KEYSTORE_PASSPHRASE)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	ans, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if !confirm {
+		return ans, nil
+	}
+	fmt.Fprint(os.Stderr, "repeat passphrase: ")
+	again, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if string(ans) != string(again) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return ans, nil
+}
+
+// Read a signer for agents.  Besides what readPrivateKey accepts, input may be
+// a remote signer given as grpcs://[token@]host:port/<public key>.
+func readSigner(ctx context.Context, input string) (signer.Signer, error) {
+	if signer.IsRemoteUrl(input) {
+		return signer.DialUrl(ctx, input)
 	}
-	return
+	return readPrivateKey(input)
 }
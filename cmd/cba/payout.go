@@ -25,7 +25,7 @@ func (r *PayoutStatus) Run(kongCtx *CLIContext) error {
 	if kongCtx.Clients == nil {
 		return errors.New("no rpc or ws client")
 	}
-	rpcClient := sgorpc.New(kongCtx.Clients.RpcUrl)
+	rpcClient := sgorpc.NewWithHeaders(kongCtx.Clients.RpcUrl, kongCtx.Clients.Headers.Clone())
 	summary := new(ReceiptSummary)
 	var err error
 	{
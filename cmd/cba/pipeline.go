@@ -45,12 +45,9 @@ func (r *PipelineCreate) Run(kongCtx *CLIContext) error {
 	if kongCtx.Clients == nil {
 		return errors.New("no rpc or ws client")
 	}
-	payer, err := sgo.PrivateKeyFromBase58(r.Payer)
+	payer, err := readPrivateKey(r.Payer)
 	if err != nil {
-		payer, err = sgo.PrivateKeyFromSolanaKeygenFile(r.Payer)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 	log.Debugf("payer=%s", payer.PublicKey().String())
 
@@ -60,12 +57,9 @@ func (r *PipelineCreate) Run(kongCtx *CLIContext) error {
 	}
 
 	var pipeline sgo.PrivateKey
-	pipeline, err = sgo.PrivateKeyFromBase58(r.PipelineKey)
+	pipeline, err = readPrivateKey(r.PipelineKey)
 	if err != nil {
-		pipeline, err = sgo.PrivateKeyFromSolanaKeygenFile(r.PipelineKey)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 
 	crankerFee, err := readRate(r.CrankFee)
@@ -88,6 +82,7 @@ func (r *PipelineCreate) Run(kongCtx *CLIContext) error {
 		DEFAULT_VALIDATOR_ADMIN_SOCKET, // this line is irrelevant
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
 		return err
@@ -154,21 +149,15 @@ func (r *PipelineUpdate) Run(kongCtx *CLIContext) error {
 	if kongCtx.Clients == nil {
 		return errors.New("no rpc or ws client")
 	}
-	payer, err := sgo.PrivateKeyFromBase58(r.Payer)
+	payer, err := readPrivateKey(r.Payer)
 	if err != nil {
-		payer, err = sgo.PrivateKeyFromSolanaKeygenFile(r.Payer)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 	log.Debugf("payer=%s", payer.PublicKey().String())
 
-	admin, err := sgo.PrivateKeyFromBase58(r.AdminKey)
+	admin, err := readPrivateKey(r.AdminKey)
 	if err != nil {
-		admin, err = sgo.PrivateKeyFromSolanaKeygenFile(r.AdminKey)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 
 	pipeline, err := sgo.PublicKeyFromBase58(r.PipelineId)
@@ -196,6 +185,7 @@ func (r *PipelineUpdate) Run(kongCtx *CLIContext) error {
 		DEFAULT_VALIDATOR_ADMIN_SOCKET, // this line is irrelevant
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
 		return err
@@ -241,10 +231,15 @@ type PipelineAgent struct {
 	DecayRate        string        `option name:"decay_rate"  help:"the decay rate in the form NUMERATOR/DENOMINATOR"`
 	PayoutShare      string        `option name:"payout_share" help:"the payout share in the form NUMERATORDENOMINATOR"`
 	AdminUrl         string        `option name:"admin_url" help:"port on which to listen for Grpc connections from administrators."`
+	MeterDb          string        `option name:"meter_db" help:"sqlite file in which to record transactions so receipts survive a restart (default: in memory)"`
+	WaitCommitment   string        `option name:"wait_commitment" help:"commitment (processed, confirmed, finalized) at which relayed transactions count as landed (default: confirmed)"`
+	RouterSnapshot   string        `option name:"router_snapshot" help:"file in which to keep a snapshot of program accounts so restarts do not fetch them all again"`
+	Metrics          string        `option name:"metrics" help:"HOST:PORT on which to serve Prometheus metrics under /metrics"`
+	Trace            string        `option name:"trace" help:"OTLP/HTTP url (ie http://localhost:4318/v1/traces) or file to which transaction spans are exported"`
 	BalanceThreshold uint64        `option name:"balance"  help:"set the minimum balance threshold"`
 	ProgramIdCba     sgo.PublicKey `name:"program_id_cba" help:"Specify the program id for the CBA program"`
 	PipelineId       string        `arg name:"id" help:"the Pipeline ID"`
-	Admin            string        `arg name:"admin" help:"the Pipeline admin"`
+	Admin            string        `arg name:"admin" help:"the Pipeline admin; a key file, keystore or grpcs:// remote signer url"`
 	ConfigFilePath   string        `arg name:"configuration" help:"file path for the configuration file"`
 	BidSpace         uint16        `arg name:"bid_space" help:"how many spaces will there be for bids (affects rent in SOL)"`
 }
@@ -252,10 +247,12 @@ type PipelineAgent struct {
 func (r *PipelineAgent) Run(kongCtx *CLIContext) error {
 
 	ctx := kongCtx.Ctx
-	admin, err := sgo.PrivateKeyFromSolanaKeygenFile(r.Admin)
+	admin, err := readSigner(ctx, r.Admin)
 	if err != nil {
 		return err
 	}
+	metricsC := runMetrics(ctx, r.Metrics)
+	traceC := runTracing(ctx, r.Trace, "pipeline")
 
 	relayConfig := relay.CreateConfiguration(
 		kongCtx.Clients.Version,
@@ -266,6 +263,10 @@ func (r *PipelineAgent) Run(kongCtx *CLIContext) error {
 		r.AdminUrl,
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+	relayConfig.MeterFilePath = r.MeterDb
+	relayConfig.WaitCommitment = r.WaitCommitment
+	relayConfig.RouterSnapshot = r.RouterSnapshot
 	pipelineId, err := sgo.PublicKeyFromBase58(r.PipelineId)
 	if err != nil {
 		return err
@@ -321,7 +322,7 @@ func (r *PipelineAgent) Run(kongCtx *CLIContext) error {
 			Program: &ap.Configuration{
 				ProgramIdCba: cba.ProgramID.ToPointer(),
 				Pipeline:     pipeline.Id.ToPointer(),
-				Wallet:       &relayConfig.Admin,
+				Wallet:       relayConfig.Admin,
 				Settings: &pipe.PipelineSettings{
 					CrankFee:    crankFee,
 					PayoutShare: payoutShare,
@@ -339,9 +340,13 @@ func (r *PipelineAgent) Run(kongCtx *CLIContext) error {
 		return err
 	}
 	log.Debug("create - 9")
-	<-agent.CloseSignal()
+	select {
+	case err = <-agent.CloseSignal():
+	case err = <-metricsC:
+	case err = <-traceC:
+	}
 	log.Debug("create - 10")
-	return nil
+	return err
 }
 
 func loopCloseTor(ctx context.Context, torMgr *tor.Tor) {
@@ -376,6 +381,7 @@ func (r *PipelineStatus) Run(kongCtx *CLIContext) error {
 		"",
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 	pipelineId, err := sgo.PublicKeyFromBase58(r.PipelineId)
 	if err != nil {
 		return err
@@ -59,7 +59,7 @@ func TestPipeline(t *testing.T) {
 	args.Program = &ap.Configuration{
 		ProgramIdCba: cba.ProgramID.ToPointer(),
 		Pipeline:     nil,
-		Wallet:       &wallet,
+		Wallet:       wallet,
 		Settings: &pipe.PipelineSettings{
 			CrankFee:    &state.Rate{N: 1, D: 10},
 			PayoutShare: &state.Rate{N: 4, D: 10},
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	log "github.com/sirupsen/logrus"
+	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	"github.com/solpipe/solpipe-tool/state/replay"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+type Replay struct {
+	Record ReplayRecord `cmd name:"record" help:"record program accounts, slots and blocks to a file"`
+	Play   ReplayPlay   `cmd name:"play" help:"play back a recording and log what the router sees"`
+}
+
+type ReplayRecord struct {
+	File string `arg name:"file" help:"file to write the recording to"`
+}
+
+type ReplayPlay struct {
+	File  string  `arg name:"file" help:"recording to play back"`
+	Speed float64 `option name:"speed" default:"1" help:"how many times faster than recorded to play back"`
+}
+
+func (r *ReplayRecord) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		return errors.New("no rpc or ws client")
+	}
+	rpcClient := sgorpc.NewWithHeaders(kongCtx.Clients.RpcUrl, kongCtx.Clients.Headers.Clone())
+	wsClient, err := sub.Dial(ctx, kongCtx.Clients.WsUrl, kongCtx.Clients.Headers)
+	if err != nil {
+		return err
+	}
+	controller, err := ctr.CreateController(ctx, rpcClient, wsClient, kongCtx.Clients.Version)
+	if err != nil {
+		return err
+	}
+	network, err := ntk.Create(ctx, controller, rpcClient, wsClient)
+	if err != nil {
+		return err
+	}
+	router, err := rtr.CreateRouter(ctx, network, rpcClient, wsClient, nil, kongCtx.Clients.Version)
+	if err != nil {
+		return err
+	}
+	log.Infof("recording to %s", r.File)
+	return replay.Record(ctx, r.File, rpcClient, router)
+}
+
+func (r *ReplayPlay) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	x, err := replay.Play(ctx, r.File, r.Speed)
+	if err != nil {
+		return err
+	}
+	log.Infof("playing recording started at slot %d (controller version %s)", x.Header.Slot, x.Header.Version)
+	closeC := x.CloseSignal()
+	pipelineSub := x.Router.ObjectOnPipeline()
+	defer pipelineSub.Unsubscribe()
+	validatorSub := x.Router.OnValidator()
+	defer validatorSub.Unsubscribe()
+	blockSub := x.Network.OnBlock()
+	defer blockSub.Unsubscribe()
+
+	doneC := ctx.Done()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-closeC:
+			break out
+		case err = <-pipelineSub.ErrorC:
+			break out
+		case err = <-validatorSub.ErrorC:
+			break out
+		case err = <-blockSub.ErrorC:
+			break out
+		case p := <-pipelineSub.StreamC:
+			log.Infof("pipeline %s", p.Id.String())
+		case v := <-validatorSub.StreamC:
+			log.Infof("validator %s", v.Id.String())
+		case b := <-blockSub.StreamC:
+			log.Infof("slot=%d transactions=%d", b.Slot, b.TransactionCount)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	slot, err := x.Slot()
+	if err != nil {
+		return err
+	}
+	log.Infof("recording finished at slot %d", slot)
+	return nil
+}
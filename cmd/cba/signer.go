@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/signer"
+)
+
+const ENV_SIGNER_TOKEN = "SOLPIPE_SIGNER_TOKEN"
+
+type Signer struct {
+	Serve SignerServe `cmd name:"serve" help:"sign transactions for agents that dial grpcs://[token@]host:port/<public key>"`
+}
+
+type SignerServe struct {
+	Listen  string   `option name:"listen" default:"127.0.0.1:50051" help:"HOST:PORT on which to listen; without --tls_cert only loopback addresses are allowed"`
+	TlsCert string   `option name:"tls_cert" help:"PEM certificate file with which to serve tls"`
+	TlsKey  string   `option name:"tls_key" help:"PEM private key file for --tls_cert"`
+	Keys    []string `arg name:"keys" help:"key files or keystores with which to sign"`
+}
+
+func (r *SignerServe) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	token := os.Getenv(ENV_SIGNER_TOKEN)
+	if len(token) == 0 {
+		log.Infof("%s is not set; anyone who can reach %s can ask for signatures", ENV_SIGNER_TOKEN, r.Listen)
+	}
+	list := make([]signer.Signer, len(r.Keys))
+	for i, fp := range r.Keys {
+		s, err := readSigner(ctx, fp)
+		if err != nil {
+			return err
+		}
+		if _, ok := signer.Local(s); !ok {
+			return errors.New("the signing service must hold its keys locally")
+		}
+		list[i] = s
+		log.Infof("signing with key=%s", s.PublicKey().String())
+	}
+	var tlsConfig *tls.Config
+	if 0 < len(r.TlsCert) {
+		cert, err := tls.LoadX509KeyPair(r.TlsCert, r.TlsKey)
+		if err != nil {
+			return err
+		}
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	} else if 0 < len(r.TlsKey) {
+		return errors.New("--tls_key needs --tls_cert")
+	}
+	listener, err := net.Listen("tcp", r.Listen)
+	if err != nil {
+		return err
+	}
+	return <-signer.Serve(ctx, listener, tlsConfig, token, list...)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	cba "github.com/solpipe/cba"
+	skragent "github.com/solpipe/solpipe-tool/agent/staker"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+)
+
+type Staker struct {
+	Create StakerCreate `cmd name:"create" help:"register a stake account so it can be counted in validator receipts"`
+	Agent  StakerAgent  `cmd name:"agent" help:"run a Staker Agent"`
+	Status StakerStatus `cmd name:"status" help:"Print the validators, pipelines and receipts in which the stake is counted"`
+}
+
+type StakerCreate struct {
+	Payer    string `name:"payer" help:"The private key that owns the SOL to pay the transaction fees."`
+	StakeKey string `arg name:"stake" help:"The private key of the stake account."`
+	AdminKey string `arg name:"admin" help:"The admin key used to administrate the stake."`
+}
+
+func (r *StakerCreate) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		return errors.New("no rpc or ws client")
+	}
+
+	payer, err := readPrivateKey(r.Payer)
+	if err != nil {
+		return err
+	}
+	log.Debugf("payer=%s", payer.PublicKey().String())
+
+	stake, err := readPrivateKey(r.StakeKey)
+	if err != nil {
+		return err
+	}
+	log.Debugf("stake=%s", stake.PublicKey().String())
+
+	admin, err := readPrivateKey(r.AdminKey)
+	if err != nil {
+		return err
+	}
+	log.Debugf("admin=%s", admin.PublicKey().String())
+	relayConfig := relay.CreateConfiguration(
+		kongCtx.Clients.Version,
+		admin,
+		kongCtx.Clients.RpcUrl,
+		kongCtx.Clients.WsUrl,
+		kongCtx.Clients.Headers.Clone(),
+		"ignored",
+		nil,
+	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+
+	router, err := relayConfig.Router(ctx)
+	if err != nil {
+		return err
+	}
+	s1, err := relayConfig.ScriptBuilder(ctx)
+	if err != nil {
+		return err
+	}
+	err = s1.SetTx(payer)
+	if err != nil {
+		return err
+	}
+
+	err = s1.AddStaker(router.Controller.Id(), stake, admin)
+	if err != nil {
+		return err
+	}
+	err = s1.FinishTx(true)
+	if err != nil {
+		return err
+	}
+	managerId, _, err := skr.StakerManagerId(router.Controller.Id(), stake.PublicKey())
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write([]byte(fmt.Sprintf("staker manager=%s\n", managerId.String())))
+	return nil
+}
+
+type StakerAgent struct {
+	RouterSnapshot string `option name:"router_snapshot" help:"file in which to keep a snapshot of program accounts so restarts do not fetch them all again"`
+	Metrics        string `option name:"metrics" help:"HOST:PORT on which to serve Prometheus metrics under /metrics"`
+	StakeKey       string `arg name:"stake" help:"The stake account."`
+	AdminKey       string `arg name:"admin" help:"The admin key used to administrate the stake; a key file, keystore or grpcs:// remote signer url."`
+}
+
+func (r *StakerAgent) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		return errors.New("no rpc or ws client")
+	}
+
+	stake, err := sgo.PublicKeyFromBase58(r.StakeKey)
+	if err != nil {
+		return err
+	}
+
+	admin, err := readSigner(ctx, r.AdminKey)
+	if err != nil {
+		return err
+	}
+	metricsC := runMetrics(ctx, r.Metrics)
+
+	relayConfig := relay.CreateConfiguration(
+		kongCtx.Clients.Version,
+		admin,
+		kongCtx.Clients.RpcUrl,
+		kongCtx.Clients.WsUrl,
+		kongCtx.Clients.Headers.Clone(),
+		"ignored",
+		nil,
+	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+	relayConfig.RouterSnapshot = r.RouterSnapshot
+
+	router, err := relayConfig.Router(ctx)
+	if err != nil {
+		return err
+	}
+	wsClient, err := relayConfig.Ws(ctx)
+	if err != nil {
+		return err
+	}
+
+	agent, err := skragent.Create(
+		ctx,
+		&skragent.Configuration{Admin: admin, Stake: stake},
+		relayConfig.Rpc(),
+		wsClient,
+		router,
+	)
+	if err != nil {
+		return err
+	}
+	select {
+	case err = <-agent.CloseSignal():
+	case err = <-metricsC:
+	}
+	return err
+}
+
+type StakerStatus struct {
+	StakeKey string `name:"stake" help:"The stake account."`
+}
+
+func (r *StakerStatus) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		return errors.New("no rpc or ws client")
+	}
+
+	stake, err := sgo.PublicKeyFromBase58(r.StakeKey)
+	if err != nil {
+		return err
+	}
+
+	placeHolderDummyKey, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	relayConfig := relay.CreateConfiguration(
+		kongCtx.Clients.Version,
+		placeHolderDummyKey,
+		kongCtx.Clients.RpcUrl,
+		kongCtx.Clients.WsUrl,
+		kongCtx.Clients.Headers.Clone(),
+		"ignored",
+		nil,
+	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+
+	router, err := relayConfig.Router(ctx)
+	if err != nil {
+		return err
+	}
+
+	staker, err := router.StakerByStake(stake)
+	if err != nil {
+		return err
+	}
+
+	content, err := printStaker(router, staker)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write([]byte(content))
+	return nil
+}
+
+// List each receipt in which the stake is counted along with the validator and pipeline behind it.
+func printStaker(router rtr.Router, staker skr.Staker) (string, error) {
+	data, err := staker.Data()
+	if err != nil {
+		return "", err
+	}
+	list := make([]stakerReceiptInfo, len(data.Receipts))
+	for i, sr := range data.Receipts {
+		info := &list[i]
+		info.receipt = sr
+		r, err := router.ReceiptById(sr.Receipt)
+		if err != nil {
+			info.err = err
+			continue
+		}
+		rd, err := r.Data()
+		if err != nil {
+			return "", err
+		}
+		info.validator = rd.Validator
+		info.payout = rd.Payout
+		v, err := router.ValidatorById(rd.Validator)
+		if err == nil {
+			vd, err := v.Data()
+			if err != nil {
+				return "", err
+			}
+			info.vote = &vd.Vote
+		}
+		pwd, err := router.PayoutById(rd.Payout)
+		if err == nil {
+			info.payoutData = &pwd.Data
+		}
+	}
+	return formatStaker(staker.Id, data, list), nil
+}
+
+// What printStaker found out about one receipt.  vote and payoutData are nil
+// if the validator or payout could not be found.
+type stakerReceiptInfo struct {
+	receipt    cba.StakerReceipt
+	err        error // the receipt could not be found
+	validator  sgo.PublicKey
+	vote       *sgo.PublicKey
+	payout     sgo.PublicKey
+	payoutData *cba.Payout
+}
+
+func formatStaker(id sgo.PublicKey, data skr.StakerData, list []stakerReceiptInfo) string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "Staker:\n\tManager=%s\n\tStake=%s\n\tAdmin=%s\n", id.String(), data.Manager.Stake.String(), data.Manager.Admin.String())
+	if len(list) == 0 {
+		b.WriteString("\tnot counted in any receipt\n")
+		return b.String()
+	}
+	for _, info := range list {
+		fmt.Fprintf(b, "Receipt=%s\n\tDelegatedStake=%d\n", info.receipt.Receipt.String(), info.receipt.DelegatedStake)
+		if info.err != nil {
+			fmt.Fprintf(b, "\t%s\n", info.err.Error())
+			continue
+		}
+		if info.vote != nil {
+			fmt.Fprintf(b, "\tValidator=%s\n\tVote=%s\n", info.validator.String(), info.vote.String())
+		} else {
+			fmt.Fprintf(b, "\tValidator=%s\n", info.validator.String())
+		}
+		if info.payoutData != nil {
+			y := info.payoutData
+			fmt.Fprintf(b, "\tPayout=%s\n\tPipeline=%s\n\tPeriod=%d-%d\n", info.payout.String(), y.Pipeline.String(), y.Period.Start, y.Period.Start+y.Period.Length)
+		} else {
+			fmt.Fprintf(b, "\tPayout=%s\n", info.payout.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+)
+
+func TestFormatStaker(t *testing.T) {
+	id := sgo.NewWallet().PublicKey()
+	data := skr.StakerData{Manager: cba.StakerManager{
+		Admin: sgo.NewWallet().PublicKey(),
+		Stake: sgo.NewWallet().PublicKey(),
+	}}
+	header := fmt.Sprintf("Staker:\n\tManager=%s\n\tStake=%s\n\tAdmin=%s\n", id, data.Manager.Stake, data.Manager.Admin)
+
+	if x := formatStaker(id, data, nil); x != header+"\tnot counted in any receipt\n" {
+		t.Fatalf("without receipts:\n%s", x)
+	}
+
+	vote := sgo.NewWallet().PublicKey()
+	pipeline := sgo.NewWallet().PublicKey()
+	full := stakerReceiptInfo{
+		receipt:    cba.StakerReceipt{Receipt: sgo.NewWallet().PublicKey(), DelegatedStake: 500},
+		validator:  sgo.NewWallet().PublicKey(),
+		vote:       &vote,
+		payout:     sgo.NewWallet().PublicKey(),
+		payoutData: &cba.Payout{Pipeline: pipeline, Period: cba.Period{Start: 100, Length: 50}},
+	}
+	partial := stakerReceiptInfo{
+		receipt:   cba.StakerReceipt{Receipt: sgo.NewWallet().PublicKey(), DelegatedStake: 7},
+		validator: sgo.NewWallet().PublicKey(),
+		payout:    sgo.NewWallet().PublicKey(),
+	}
+	missing := stakerReceiptInfo{
+		receipt: cba.StakerReceipt{Receipt: sgo.NewWallet().PublicKey(), DelegatedStake: 1},
+		err:     errors.New("no receipt"),
+	}
+	expected := header +
+		fmt.Sprintf("Receipt=%s\n\tDelegatedStake=500\n", full.receipt.Receipt) +
+		fmt.Sprintf("\tValidator=%s\n\tVote=%s\n", full.validator, vote) +
+		fmt.Sprintf("\tPayout=%s\n\tPipeline=%s\n\tPeriod=100-150\n", full.payout, pipeline) +
+		fmt.Sprintf("Receipt=%s\n\tDelegatedStake=7\n", partial.receipt.Receipt) +
+		fmt.Sprintf("\tValidator=%s\n", partial.validator) +
+		fmt.Sprintf("\tPayout=%s\n", partial.payout) +
+		fmt.Sprintf("Receipt=%s\n\tDelegatedStake=1\n\tno receipt\n", missing.receipt.Receipt)
+	if x := formatStaker(id, data, []stakerReceiptInfo{full, partial, missing}); x != expected {
+		t.Fatalf("got:\n%s\nexpected:\n%s", x, expected)
+	}
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	val "github.com/solpipe/solpipe-tool/state/validator"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+// Transactions built here use a durable nonce so they can be signed on an
+// air-gapped machine and submitted long after they were built.  They travel
+// as envelopes (see script.Envelope) from one key holder to the next.
+type Tx struct {
+	Nonce  TxNonce  `cmd name:"nonce" help:"create a durable nonce account for use with tx build"`
+	Build  TxBuild  `cmd name:"build" help:"build an admin transaction and write it to an envelope without signing for keys held elsewhere"`
+	Show   TxShow   `cmd name:"show" help:"print what the transaction in an envelope does and who has yet to sign"`
+	Sign   TxSign   `cmd name:"sign" help:"add signatures to an envelope after confirming its summary"`
+	Submit TxSubmit `cmd name:"submit" help:"broadcast the transaction in a fully signed envelope and wait for it to land"`
+}
+
+type TxBuild struct {
+	Controller        TxBuildController        `cmd name:"controller-create" help:"create a controller"`
+	PipelineCreate    TxBuildPipelineCreate    `cmd name:"pipeline-create" help:"create a pipeline"`
+	PipelineUpdate    TxBuildPipelineUpdate    `cmd name:"pipeline-update" help:"change the settings on a pipeline"`
+	ValidatorPipeline TxBuildValidatorPipeline `cmd name:"validator-pipeline" help:"assign validator bandwidth to a pipeline payout"`
+	ClaimRefund       TxBuildClaimRefund       `cmd name:"claim-refund" help:"pay out refunds owed to bidders by a pipeline"`
+}
+
+// Flags shared by every tx build command.  Keys given as public keys sign
+// later with tx sign.
+type TxBuildFlags struct {
+	Payer          string `option name:"payer" short:"p" required:"" help:"the account paying SOL fees; a key file, keystore, grpcs:// remote signer url or a public key"`
+	Nonce          string `option name:"nonce" required:"" help:"the public key of the durable nonce account"`
+	NonceAuthority string `option name:"nonce_authority" help:"the nonce authority as a key or public key (default: the payer)"`
+	Out            string `option name:"out" short:"o" help:"file to which to write the envelope (default: stdout)"`
+	Note           string `option name:"note" help:"a note for the other signers, such as why the change is being made"`
+}
+
+// A public key stands for a key that signs on another machine.
+func readSignerOrPublicKey(ctx context.Context, input string) (signer.Signer, error) {
+	key, err := sgo.PublicKeyFromBase58(input)
+	if err == nil {
+		return signer.Offline(key), nil
+	}
+	return readSigner(ctx, input)
+}
+
+func txRelayConfig(kongCtx *CLIContext, admin signer.Signer) relay.Configuration {
+	relayConfig := relay.CreateConfiguration(
+		kongCtx.Clients.Version,
+		admin,
+		kongCtx.Clients.RpcUrl,
+		kongCtx.Clients.WsUrl,
+		kongCtx.Clients.Headers.Clone(),
+		"",
+		nil,
+	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+	return relayConfig
+}
+
+// Start a transaction that uses the durable nonce.
+func (f *TxBuildFlags) start(kongCtx *CLIContext) (s *script.Script, payer signer.Signer, relayConfig relay.Configuration, err error) {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		err = errors.New("no rpc or ws client")
+		return
+	}
+	payer, err = readSignerOrPublicKey(ctx, f.Payer)
+	if err != nil {
+		return
+	}
+	nonce, err := sgo.PublicKeyFromBase58(f.Nonce)
+	if err != nil {
+		return
+	}
+	authority := payer
+	if 0 < len(f.NonceAuthority) {
+		authority, err = readSignerOrPublicKey(ctx, f.NonceAuthority)
+		if err != nil {
+			return
+		}
+	}
+	relayConfig = txRelayConfig(kongCtx, payer)
+	s, err = relayConfig.ScriptBuilder(ctx)
+	if err != nil {
+		return
+	}
+	err = s.SetNonceTx(payer, nonce, authority)
+	return
+}
+
+func (f *TxBuildFlags) finish(s *script.Script) error {
+	env, err := s.Envelope(f.Note)
+	if err != nil {
+		return err
+	}
+	err = writeEnvelope(f.Out, env)
+	if err != nil {
+		return err
+	}
+	summary, err := env.Summary()
+	if err != nil {
+		return err
+	}
+	os.Stderr.WriteString(summary)
+	return nil
+}
+
+func writeEnvelope(fp string, env *script.Envelope) error {
+	data, err := env.MarshalIndent()
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if len(fp) == 0 {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(fp, data, 0600)
+}
+
+// A file holding a bare base64 transaction, as the solana CLI prints, is put
+// in a new envelope.
+func readEnvelope(fp string) (*script.Envelope, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "{") {
+		return script.ParseEnvelope([]byte(text))
+	}
+	raw, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := script.ParseTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+	return script.CreateEnvelope(tx, "")
+}
+
+func keyList(list []sgo.PublicKey) []string {
+	ans := make([]string, len(list))
+	for i, key := range list {
+		ans[i] = key.String()
+	}
+	return ans
+}
+
+// Read y or yes from stdin.
+func confirm(prompt string) (bool, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+type TxNonce struct {
+	Payer     string `option name:"payer" short:"p" required:"" help:"the account paying SOL fees and rent"`
+	Nonce     string `arg name:"nonce" help:"key file for the new nonce account"`
+	Authority string `arg name:"authority" help:"the key or public key that must sign transactions using the nonce"`
+}
+
+func (r *TxNonce) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		return errors.New("no rpc or ws client")
+	}
+	payer, err := readSigner(ctx, r.Payer)
+	if err != nil {
+		return err
+	}
+	nonce, err := readPrivateKey(r.Nonce)
+	if err != nil {
+		return err
+	}
+	authority, err := readSignerOrPublicKey(ctx, r.Authority)
+	if err != nil {
+		return err
+	}
+	s1, err := txRelayConfig(kongCtx, payer).ScriptBuilder(ctx)
+	if err != nil {
+		return err
+	}
+	err = s1.SetTx(payer)
+	if err != nil {
+		return err
+	}
+	err = s1.CreateNonce(payer, nonce, authority.PublicKey())
+	if err != nil {
+		return err
+	}
+	err = s1.FinishTx(true)
+	if err != nil {
+		return err
+	}
+	fmt.Println(nonce.PublicKey().String())
+	return nil
+}
+
+type TxBuildController struct {
+	TxBuildFlags
+	Admin   string `arg name:"admin" help:"the account with administrative privileges"`
+	Cranker string `option name:"cranker" help:"who is allowed to crank (default: the admin)"`
+	Mint    string `arg name:"mint" help:"the mint of the token account to which fees are paid to and by validators"`
+	Fee     string `arg name:"fee" help:"set the fee that the controller earns from Validator revenue."`
+}
+
+func (r *TxBuildController) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	s1, _, _, err := r.start(kongCtx)
+	if err != nil {
+		return err
+	}
+	admin, err := readSignerOrPublicKey(ctx, r.Admin)
+	if err != nil {
+		return err
+	}
+	cranker := admin
+	if 0 < len(r.Cranker) {
+		cranker, err = readSignerOrPublicKey(ctx, r.Cranker)
+		if err != nil {
+			return err
+		}
+	}
+	mint, err := sgo.PublicKeyFromBase58(r.Mint)
+	if err != nil {
+		return err
+	}
+	fee, err := readRate(r.Fee)
+	if err != nil {
+		return err
+	}
+	err = s1.CreateController(admin, cranker, mint, fee)
+	if err != nil {
+		return err
+	}
+	return r.finish(s1)
+}
+
+type TxBuildPipelineCreate struct {
+	TxBuildFlags
+	PipelineKey string `option name:"pipeline" help:"the Pipeline ID private key (default: a new key)"`
+	AdminKey    string `arg name:"admin" help:"the account with administrative privileges"`
+	CrankFee    string `arg name:"crank" help:"the crank fee in the form NUMERATOR/DENOMINATOR"`
+	PayoutShare string `arg name:"payout" help:"the validator payout share in the form NUMERATOR/DENOMINATOR"`
+	Allotment   uint16 `arg name:"allotment" help:"allotment"`
+	RefundSpace uint16 `arg name:"refund_space" help:"how many spaces will there be for refunds (affects rent in SOL)"`
+	TickSize    uint16 `option name:"tick_size" help:"what is the tick size (deposit modulo tick_size must be zero)"`
+}
+
+func (r *TxBuildPipelineCreate) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	s1, payer, relayConfig, err := r.start(kongCtx)
+	if err != nil {
+		return err
+	}
+	admin, err := readSignerOrPublicKey(ctx, r.AdminKey)
+	if err != nil {
+		return err
+	}
+	// the pipeline key only signs once, so it may as well sign here
+	var pipeline sgo.PrivateKey
+	if 0 < len(r.PipelineKey) {
+		pipeline, err = readPrivateKey(r.PipelineKey)
+	} else {
+		pipeline, err = sgo.NewRandomPrivateKey()
+	}
+	if err != nil {
+		return err
+	}
+	crankerFee, err := readRate(r.CrankFee)
+	if err != nil {
+		return err
+	}
+	payoutShare, err := readRate(r.PayoutShare)
+	if err != nil {
+		return err
+	}
+	wsClient, err := relayConfig.Ws(ctx)
+	if err != nil {
+		return err
+	}
+	controller, err := ctr.CreateController(ctx, relayConfig.Rpc(), wsClient, relayConfig.Version)
+	if err != nil {
+		return err
+	}
+	if r.TickSize == 0 {
+		r.TickSize = 1
+	}
+	_, err = s1.AddPipelineDirect(
+		pipeline,
+		controller,
+		payer,
+		admin,
+		*crankerFee,
+		r.Allotment,
+		*payoutShare,
+		r.TickSize,
+		r.RefundSpace,
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "pipeline=%s\n", pipeline.PublicKey().String())
+	return r.finish(s1)
+}
+
+type TxBuildPipelineUpdate struct {
+	TxBuildFlags
+	PipelineId  string `arg name:"pipeline" help:"the Pipeline ID public key"`
+	AdminKey    string `arg name:"admin" help:"the account with administrative privileges"`
+	CrankFee    string `arg name:"crank" help:"the crank fee in the form NUMERATOR/DENOMINATOR"`
+	PayoutShare string `arg name:"payout" help:"the validator payout share in the form NUMERATOR/DENOMINATOR"`
+	Allotment   uint16 `arg name:"allotment" help:"allotment"`
+	TickSize    uint16 `option name:"tick_size" help:"what is the tick size (deposit modulo tick_size must be zero)"`
+}
+
+func (r *TxBuildPipelineUpdate) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	s1, _, _, err := r.start(kongCtx)
+	if err != nil {
+		return err
+	}
+	pipeline, err := sgo.PublicKeyFromBase58(r.PipelineId)
+	if err != nil {
+		return err
+	}
+	admin, err := readSignerOrPublicKey(ctx, r.AdminKey)
+	if err != nil {
+		return err
+	}
+	crankerFee, err := readRate(r.CrankFee)
+	if err != nil {
+		return err
+	}
+	payoutShare, err := readRate(r.PayoutShare)
+	if err != nil {
+		return err
+	}
+	controllerId, _, err := vrs.ControllerId(kongCtx.Clients.Version)
+	if err != nil {
+		return err
+	}
+	if r.TickSize == 0 {
+		r.TickSize = 1
+	}
+	err = s1.UpdatePipeline(
+		controllerId,
+		pipeline,
+		admin,
+		*crankerFee,
+		r.Allotment,
+		*payoutShare,
+		r.TickSize,
+	)
+	if err != nil {
+		return err
+	}
+	return r.finish(s1)
+}
+
+type TxBuildValidatorPipeline struct {
+	TxBuildFlags
+	VoteKey     string `arg name:"vote" help:"the vote account of the validator"`
+	PipelineKey string `arg name:"pipeline" help:"the pipeline to which to assign the validator bandwidth"`
+	PayoutKey   string `arg name:"payout" help:"the pipeline payout in which the validator takes part"`
+	Admin       string `arg name:"admin" help:"the validator admin"`
+}
+
+func (r *TxBuildValidatorPipeline) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	s1, _, _, err := r.start(kongCtx)
+	if err != nil {
+		return err
+	}
+	vote, err := sgo.PublicKeyFromBase58(r.VoteKey)
+	if err != nil {
+		return err
+	}
+	pipeline, err := sgo.PublicKeyFromBase58(r.PipelineKey)
+	if err != nil {
+		return err
+	}
+	payout, err := sgo.PublicKeyFromBase58(r.PayoutKey)
+	if err != nil {
+		return err
+	}
+	admin, err := readSignerOrPublicKey(ctx, r.Admin)
+	if err != nil {
+		return err
+	}
+	controllerId, _, err := vrs.ControllerId(kongCtx.Clients.Version)
+	if err != nil {
+		return err
+	}
+	validatorId, _, err := val.ValidatorManagerId(controllerId, vote)
+	if err != nil {
+		return err
+	}
+	receipt, err := s1.ValidatorSetPipeline(controllerId, payout, pipeline, validatorId, admin)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "receipt=%s\n", receipt.String())
+	return r.finish(s1)
+}
+
+type TxBuildClaimRefund struct {
+	TxBuildFlags
+	PipelineKey string `arg name:"pipeline" help:"the pipeline holding the refunds"`
+	User        string `option name:"user" help:"only pay the refund owed to this bidder (default: every bidder)"`
+}
+
+func (r *TxBuildClaimRefund) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	s1, payer, relayConfig, err := r.start(kongCtx)
+	if err != nil {
+		return err
+	}
+	pipelineId, err := sgo.PublicKeyFromBase58(r.PipelineKey)
+	if err != nil {
+		return err
+	}
+	router, err := relayConfig.Router(ctx)
+	if err != nil {
+		return err
+	}
+	pipeline, err := router.PipelineById(pipelineId)
+	if err != nil {
+		return err
+	}
+	if len(r.User) == 0 {
+		err = s1.MultipleClaimRefund(router.Controller, pipeline, payer)
+		if err != nil {
+			return err
+		}
+		return r.finish(s1)
+	}
+	user, err := sgo.PublicKeyFromBase58(r.User)
+	if err != nil {
+		return err
+	}
+	list, err := pipeline.AllClaim()
+	if err != nil {
+		return err
+	}
+	var claim *cba.Claim
+	for i := range list {
+		if list[i].User.Equals(user) {
+			claim = &list[i]
+			break
+		}
+	}
+	if claim == nil {
+		return errors.New("no refund owed to user")
+	}
+	err = s1.ClaimRefund(router.Controller, pipeline, *claim, payer)
+	if err != nil {
+		return err
+	}
+	return r.finish(s1)
+}
+
+type TxShow struct {
+	File string `arg name:"file" help:"the envelope written by tx build or tx sign"`
+}
+
+func (r *TxShow) Run(kongCtx *CLIContext) error {
+	env, err := readEnvelope(r.File)
+	if err != nil {
+		return err
+	}
+	summary, err := env.Summary()
+	if err != nil {
+		return err
+	}
+	os.Stdout.WriteString(summary)
+	for _, x := range env.History {
+		fmt.Printf("signed by %s at %s\n", x.PublicKey, x.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+type TxSign struct {
+	File string   `arg name:"file" help:"the envelope written by tx build or tx sign"`
+	Keys []string `arg name:"keys" help:"key files, keystores or grpcs:// remote signer urls with which to sign"`
+	Out  string   `option name:"out" short:"o" help:"file to which to write the envelope (default: overwrite the input file)"`
+	Yes  bool     `option name:"yes" short:"y" help:"sign without asking for confirmation"`
+}
+
+type TxSignResponse struct {
+	Signature string   `json:"signature"`
+	Signed    []string `json:"signed"`
+	Missing   []string `json:"missing"`
+}
+
+func (r *TxSign) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	env, err := readEnvelope(r.File)
+	if err != nil {
+		return err
+	}
+	summary, err := env.Summary()
+	if err != nil {
+		return err
+	}
+	missing, err := env.Missing()
+	if err != nil {
+		return err
+	}
+	missingM := make(map[string]bool)
+	for _, key := range missing {
+		missingM[key.String()] = true
+	}
+	os.Stderr.WriteString(summary)
+
+	list := make([]signer.Signer, 0, len(r.Keys))
+	for _, fp := range r.Keys {
+		s, err := readSigner(ctx, fp)
+		if err != nil {
+			return err
+		}
+		if !missingM[s.PublicKey().String()] {
+			fmt.Fprintf(os.Stderr, "%s does not need to sign\n", s.PublicKey().String())
+			continue
+		}
+		if !r.Yes {
+			ok, err := confirm(fmt.Sprintf("sign as %s? [y/N] ", s.PublicKey().String()))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		list = append(list, s)
+	}
+	added, err := env.Sign(list...)
+	if err != nil {
+		return err
+	}
+	out := r.Out
+	if len(out) == 0 {
+		out = r.File
+	}
+	err = writeEnvelope(out, env)
+	if err != nil {
+		return err
+	}
+	missing, err = env.Missing()
+	if err != nil {
+		return err
+	}
+	tx, err := env.Tx()
+	if err != nil {
+		return err
+	}
+	ans := new(TxSignResponse)
+	ans.Signature = tx.Signatures[0].String()
+	ans.Signed = keyList(added)
+	ans.Missing = keyList(missing)
+	return json.NewEncoder(os.Stdout).Encode(ans)
+}
+
+type TxSubmit struct {
+	File          string `arg name:"file" help:"the fully signed envelope"`
+	SkipPreflight bool   `option name:"skip_preflight" help:"broadcast without simulating the transaction first"`
+}
+
+type TxSubmitResponse struct {
+	Signature  string `json:"signature"`
+	Slot       uint64 `json:"slot"`
+	Broadcasts int    `json:"broadcasts"`
+}
+
+func (r *TxSubmit) Run(kongCtx *CLIContext) error {
+	ctx := kongCtx.Ctx
+	if kongCtx.Clients == nil {
+		return errors.New("no rpc or ws client")
+	}
+	env, err := readEnvelope(r.File)
+	if err != nil {
+		return err
+	}
+	missing, err := env.Missing()
+	if err != nil {
+		return err
+	}
+	if 0 < len(missing) {
+		return fmt.Errorf("missing signatures: %s", strings.Join(keyList(missing), " "))
+	}
+	tx, err := env.Tx()
+	if err != nil {
+		return err
+	}
+	relayConfig := txRelayConfig(kongCtx, signer.Offline(tx.Message.AccountKeys[0]))
+	wsClient, err := relayConfig.Ws(ctx)
+	if err != nil {
+		return err
+	}
+	o := script.Send(ctx, relayConfig.Rpc(), wsClient, tx, script.SendOpts{Simulate: !r.SkipPreflight})
+	err = o.Error()
+	if err != nil {
+		return err
+	}
+	ans := new(TxSubmitResponse)
+	ans.Signature = o.Signature.String()
+	ans.Slot = o.Slot
+	ans.Broadcasts = o.Broadcasts
+	return json.NewEncoder(os.Stdout).Encode(ans)
+}
@@ -36,21 +36,15 @@ func (r *ValidatorCreate) Run(kongCtx *CLIContext) error {
 		return errors.New("no rpc or ws client")
 	}
 
-	payer, err := sgo.PrivateKeyFromBase58(r.Payer)
+	payer, err := readPrivateKey(r.Payer)
 	if err != nil {
-		payer, err = sgo.PrivateKeyFromSolanaKeygenFile(r.Payer)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 	log.Debugf("payer=%s", payer.PublicKey().String())
 
-	vote, err := sgo.PrivateKeyFromBase58(r.VoteKey)
+	vote, err := readPrivateKey(r.VoteKey)
 	if err != nil {
-		vote, err = sgo.PrivateKeyFromSolanaKeygenFile(r.VoteKey)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 	log.Debugf("vote=%s", vote.PublicKey().String())
 
@@ -60,12 +54,9 @@ func (r *ValidatorCreate) Run(kongCtx *CLIContext) error {
 	}
 	log.Debugf("stake=%s", stake.String())
 
-	admin, err := sgo.PrivateKeyFromBase58(r.AdminKey)
+	admin, err := readPrivateKey(r.AdminKey)
 	if err != nil {
-		admin, err = sgo.PrivateKeyFromSolanaKeygenFile(r.AdminKey)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 	log.Debugf("admin=%s", admin.PublicKey().String())
 	relayConfig := relay.CreateConfiguration(
@@ -77,6 +68,7 @@ func (r *ValidatorCreate) Run(kongCtx *CLIContext) error {
 		DEFAULT_VALIDATOR_ADMIN_SOCKET, // this line is irrelevant
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
@@ -101,8 +93,13 @@ func (r *ValidatorCreate) Run(kongCtx *CLIContext) error {
 type ValidatorAgent struct {
 	ClearListenUrl string `option name:"clear_listen"  help:"url to which clients can connect without tor"`
 	AdminListenUrl string `option name:"admin_url" help:"The url on which the admin grpc server listens."`
+	MeterDb        string `option name:"meter_db" help:"sqlite file in which to record transactions so receipts survive a restart (default: in memory)"`
+	WaitCommitment string `option name:"wait_commitment" help:"commitment (processed, confirmed, finalized) at which relayed transactions count as landed (default: confirmed)"`
+	RouterSnapshot string `option name:"router_snapshot" help:"file in which to keep a snapshot of program accounts so restarts do not fetch them all again"`
+	Metrics        string `option name:"metrics" help:"HOST:PORT on which to serve Prometheus metrics under /metrics"`
+	Trace          string `option name:"trace" help:"OTLP/HTTP url (ie http://localhost:4318/v1/traces) or file to which transaction spans are exported"`
 	VoteKey        string `arg name:"vote" help:"The vote account for the validator."`
-	AdminKey       string `arg name:"admin" help:"The admin key used to administrate the validator."`
+	AdminKey       string `arg name:"admin" help:"The admin key used to administrate the validator; a key file, keystore or grpcs:// remote signer url."`
 	ConfigFilePath string `arg name:"configuration" help:"The file path to the configuration."`
 }
 
@@ -120,18 +117,17 @@ func (r *ValidatorAgent) Run(kongCtx *CLIContext) error {
 		return err
 	}
 
-	admin, err := sgo.PrivateKeyFromBase58(r.AdminKey)
+	admin, err := readSigner(ctx, r.AdminKey)
 	if err != nil {
-		admin, err = sgo.PrivateKeyFromSolanaKeygenFile(r.AdminKey)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 
 	adminUrl := DEFAULT_VALIDATOR_ADMIN_SOCKET
 	if 0 < len(r.AdminListenUrl) {
 		adminUrl = r.AdminListenUrl
 	}
+	metricsC := runMetrics(ctx, r.Metrics)
+	traceC := runTracing(ctx, r.Trace, "validator")
 
 	relayConfig := relay.CreateConfiguration(
 		kongCtx.Clients.Version,
@@ -142,6 +138,10 @@ func (r *ValidatorAgent) Run(kongCtx *CLIContext) error {
 		adminUrl,
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
+	relayConfig.MeterFilePath = r.MeterDb
+	relayConfig.WaitCommitment = r.WaitCommitment
+	relayConfig.RouterSnapshot = r.RouterSnapshot
 
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
@@ -189,7 +189,12 @@ func (r *ValidatorAgent) Run(kongCtx *CLIContext) error {
 	if err != nil {
 		return err
 	}
-	return <-agent.CloseSignal()
+	select {
+	case err = <-agent.CloseSignal():
+	case err = <-metricsC:
+	case err = <-traceC:
+	}
+	return err
 }
 
 type ValidatorStatus struct {
@@ -221,6 +226,7 @@ func (r *ValidatorStatus) Run(kongCtx *CLIContext) error {
 		"ignored",
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
@@ -275,6 +281,7 @@ func (r *ValidatorPipeline) Run(kongCtx *CLIContext) error {
 		adminUrl,
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
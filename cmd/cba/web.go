@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	sgo "github.com/SolmateDev/solana-go"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
 	"github.com/solpipe/solpipe-tool/web"
 )
@@ -12,10 +13,14 @@ type Web struct {
 	Port        uint16 `arg name:"port" help:"the port number to listen on"`
 	FrontendUrl string `name:"frontend" help:"redirect to a front end web page"`
 	GrpcWebUrl  string `name:"grpc" help:"redirect to grpc web endpoint"`
+	DbType      string `name:"db_type" help:"time series database: lite (sqlite) or postgres" default:"lite"`
+	DbUrl       string `name:"db_url" help:"sqlite file path (blank for in memory) or postgres connection url"`
+	Metrics     string `name:"metrics" help:"HOST:PORT on which to serve Prometheus metrics under /metrics"`
 }
 
 func (r *Web) Run(kongCtx *CLIContext) error {
 	ctx := kongCtx.Ctx
+	metricsC := runMetrics(ctx, r.Metrics)
 	unnecessaryAdmin, err := sgo.NewRandomPrivateKey()
 	if err != nil {
 		return err
@@ -29,6 +34,7 @@ func (r *Web) Run(kongCtx *CLIContext) error {
 		DEFAULT_VALIDATOR_ADMIN_SOCKET, // this line is irrelevant
 		nil,
 	)
+	relayConfig.Fee = kongCtx.Clients.Fee
 	router, err := relayConfig.Router(ctx)
 	if err != nil {
 		return err
@@ -41,12 +47,18 @@ func (r *Web) Run(kongCtx *CLIContext) error {
 			GrpcWebUrl:  r.GrpcWebUrl,
 			RpcUrl:      kongCtx.Clients.RpcUrl,
 			WsUrl:       kongCtx.Clients.WsUrl,
+			Headers:     kongCtx.Clients.Headers.Clone(),
+			Db: ts.Configuration{
+				DbType: r.DbType,
+				Url:    r.DbUrl,
+			},
 		},
 		router,
 	)
 	select {
 	case <-ctx.Done():
 	case err = <-signalC:
+	case err = <-metricsC:
 	}
 	if err != nil {
 		return err
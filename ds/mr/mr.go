@@ -1,6 +1,7 @@
 package mr
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"errors"
 
@@ -12,10 +13,22 @@ type Tree struct {
 	t merkle.Tree
 }
 
+// the root of an empty tree is the zero hash
 func (tree *Tree) Root() sgo.Hash {
+	if tree.Len() == 0 {
+		return sgo.Hash{}
+	}
 	return sgo.HashFromBytes(tree.t.Root())
 }
 
+// the number of leafs
+func (tree *Tree) Len() int {
+	if len(tree.t) == 0 {
+		return 0
+	}
+	return len(tree.t[0])
+}
+
 func Create(hashList []sgo.Hash) (*Tree, error) {
 	if len(hashList) == 0 {
 		return nil, errors.New("blank list")
@@ -27,3 +40,52 @@ func Create(hashList []sgo.Hash) (*Tree, error) {
 	}
 	return &Tree{t: merkle.NewTree(sh, list...)}, nil
 }
+
+// Create a tree with no leafs.  Use Append to add leafs.
+func CreateEmpty() *Tree {
+	return &Tree{t: make(merkle.Tree, 0)}
+}
+
+// Add a leaf and update the root.  Only the right edge of the tree is
+// recalculated, so appending is O(log n).  The root matches that of Create
+// called with the same list.
+func (tree *Tree) Append(h sgo.Hash) {
+	sh := sha256.New()
+	sh.Write(h[:])
+	if len(tree.t) == 0 {
+		tree.t = append(tree.t, make([][]byte, 0))
+	}
+	tree.t[0] = append(tree.t[0], sh.Sum(nil))
+
+	for level := 0; len(tree.t[level]) > 1; level++ {
+		row := tree.t[level]
+		if len(tree.t) == level+1 {
+			tree.t = append(tree.t, make([][]byte, 0))
+		}
+		last := len(row) - 1
+		i := last / 2
+		var node []byte
+		if last%2 == 0 {
+			// odd node out moves up a level unchanged
+			node = row[last]
+		} else {
+			sh.Reset()
+			// smaller one first, same as go-merkle
+			if bytes.Compare(row[last-1], row[last]) < 0 {
+				sh.Write(row[last-1])
+				sh.Write(row[last])
+			} else {
+				sh.Write(row[last])
+				sh.Write(row[last-1])
+			}
+			node = sh.Sum(nil)
+		}
+		next := tree.t[level+1]
+		if i < len(next) {
+			next[i] = node
+		} else {
+			next = append(next, node)
+		}
+		tree.t[level+1] = next
+	}
+}
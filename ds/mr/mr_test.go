@@ -0,0 +1,35 @@
+package mr_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/ds/mr"
+)
+
+func TestAppend(t *testing.T) {
+	tree := mr.CreateEmpty()
+	if !tree.Root().Equals(sgo.Hash{}) {
+		t.Fatal("empty tree should have a zero root")
+	}
+	list := make([]sgo.Hash, 0)
+	for i := 0; i < 70; i++ {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(i))
+		h := sgo.Hash(sha256.Sum256(b[:]))
+		list = append(list, h)
+		tree.Append(h)
+		full, err := mr.Create(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tree.Root().Equals(full.Root()) {
+			t.Fatalf("root mismatch at %d leafs", i+1)
+		}
+		if tree.Len() != i+1 {
+			t.Fatalf("expected %d leafs, have %d", i+1, tree.Len())
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Pick the time-series backend named in ts.Configuration.
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/ds/ts/lite"
+	"github.com/solpipe/solpipe-tool/ds/ts/pg"
+)
+
+// A blank DbType means DB_TYPE_LITE.
+func Create(ctx context.Context, config ts.Configuration) (ts.Handle, error) {
+	switch config.DbType {
+	case ts.DB_TYPE_LITE, "":
+		fp := config.Url
+		if len(fp) == 0 {
+			fp = ":memory:"
+		}
+		return lite.Create(ctx, fp)
+	case ts.DB_TYPE_POSTGRES:
+		if len(config.Url) == 0 {
+			return nil, fmt.Errorf("no url for db type %s", config.DbType)
+		}
+		return pg.Create(ctx, config.Url)
+	default:
+		return nil, fmt.Errorf("unknown db type %s", config.DbType)
+	}
+}
@@ -0,0 +1,353 @@
+// Tests that every ds/ts backend must pass.  Call Run from a _test.go file in
+// the package of the backend.
+package conformance
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+// create must return a handle on an empty database every time it is called.
+type CreateFunc = func(ctx context.Context) (ts.Handle, error)
+
+func Run(t *testing.T, create CreateFunc) {
+	t.Run("Time", func(t *testing.T) { testTime(t, open(t, create)) })
+	t.Run("Pipeline", func(t *testing.T) { testPipeline(t, open(t, create)) })
+	t.Run("Network", func(t *testing.T) { testNetwork(t, open(t, create)) })
+	t.Run("Stake", func(t *testing.T) { testStake(t, open(t, create)) })
+	t.Run("Payout", func(t *testing.T) { testPayout(t, open(t, create)) })
+	t.Run("Bid", func(t *testing.T) { testBid(t, open(t, create)) })
+}
+
+func open(t *testing.T, create CreateFunc) ts.Handle {
+	ctx, cancel := context.WithCancel(context.Background())
+	handle, err := create(ctx)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	finishC := handle.CloseSignal()
+	t.Cleanup(func() {
+		cancel()
+		<-finishC
+	})
+	return handle
+}
+
+func randomKey(t *testing.T) sgo.PublicKey {
+	key, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key.PublicKey()
+}
+
+func testTime(t *testing.T, handle ts.Handle) {
+	high1, err := handle.TimeAppend(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high1 < 10 {
+		t.Fatalf("high=%d is less than the appended length", high1)
+	}
+	high2, err := handle.TimeAppend(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high2 != high1+5 {
+		t.Fatalf("expected high=%d, got %d", high1+5, high2)
+	}
+}
+
+func testPipeline(t *testing.T, handle ts.Handle) {
+	idList := make([]sgo.PublicKey, 5)
+	for i := 0; i < len(idList); i++ {
+		idList[i] = randomKey(t)
+	}
+	err := handle.PipelineAdd(idList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adding again must not fail or create duplicates
+	err = handle.PipelineAdd(idList[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := handle.PipelineList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(idList) {
+		t.Fatalf("expected %d pipelines, got %d", len(idList), len(list))
+	}
+	m := make(map[string]bool)
+	for _, id := range list {
+		m[id.String()] = true
+	}
+	for _, id := range idList {
+		if !m[id.String()] {
+			t.Fatalf("pipeline=%s is missing", id.String())
+		}
+	}
+}
+
+func networkPoint(slot uint64, tps float64) ts.NetworkPoint {
+	return ts.NetworkPoint{
+		Slot: slot,
+		Status: ntk.NetworkStatus{
+			WindowSize:                       1,
+			AverageTransactionsPerBlock:      tps * 0.4,
+			AverageTransactionsPerSecond:     tps,
+			AverageTransactionsSizePerSecond: tps * 200,
+		},
+	}
+}
+
+func testNetwork(t *testing.T, handle ts.Handle) {
+	err := handle.NetworkAppend([]ts.NetworkPoint{
+		networkPoint(1, 1000),
+		networkPoint(2, 1200),
+		networkPoint(3, 900),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handle.NetworkAppend([]ts.NetworkPoint{networkPoint(2, 1500)})
+	if err != nil {
+		t.Fatalf("failed to replace point: %s", err.Error())
+	}
+
+	list, err := handle.NetworkSeries(ts.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(list))
+	}
+	if list[1] != networkPoint(2, 1500) {
+		t.Fatalf("point was not replaced: %+v", list[1])
+	}
+	// buckets are [1,2] and [3]; the last point in each bucket is kept
+	list, err = handle.NetworkSeries(ts.Query{Start: 1, Step: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Slot != 2 || list[1].Slot != 3 {
+		t.Fatalf("bad downsample: %+v", list)
+	}
+	list, err = handle.NetworkSeries(ts.Query{Start: 2, Finish: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Slot != 2 {
+		t.Fatalf("bad window: %+v", list)
+	}
+	_, err = handle.NetworkSeries(ts.Query{Start: 3, Finish: 2})
+	if err == nil {
+		t.Fatal("finish before start should fail")
+	}
+}
+
+func stakePoint(slot uint64, pipelineId sgo.PublicKey, activated int64, total int64) ts.StakePoint {
+	return ts.StakePoint{
+		Slot:       slot,
+		PipelineId: pipelineId,
+		Stake: sub.StakeUpdate{
+			ActivatedStake: big.NewInt(activated),
+			TotalStake:     big.NewInt(total),
+		},
+	}
+}
+
+func testStake(t *testing.T, handle ts.Handle) {
+	pipelineId := randomKey(t)
+	err := handle.PipelineAdd([]sgo.PublicKey{pipelineId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handle.StakeAppend([]ts.StakePoint{
+		stakePoint(1, pipelineId, 10, 100),
+		stakePoint(2, pipelineId, 20, 100),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handle.StakeAppend([]ts.StakePoint{stakePoint(2, pipelineId, 30, 100)})
+	if err != nil {
+		t.Fatalf("failed to replace point: %s", err.Error())
+	}
+	err = handle.StakeAppend([]ts.StakePoint{stakePoint(3, randomKey(t), 1, 2)})
+	if err == nil {
+		t.Fatal("stake for an unknown pipeline should fail")
+	}
+
+	list, err := handle.StakeSeries(pipelineId, ts.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(list))
+	}
+	x := list[1]
+	if x.Slot != 2 || !x.PipelineId.Equals(pipelineId) || x.Stake.ActivatedStake.Int64() != 30 || x.Stake.TotalStake.Int64() != 100 {
+		t.Fatalf("bad point: %+v", x)
+	}
+	list, err = handle.StakeSeries(randomKey(t), ts.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected no points for an unknown pipeline, got %d", len(list))
+	}
+}
+
+func payout(pipelineId sgo.PublicKey, payoutId sgo.PublicKey, start uint64) sub.PayoutWithData {
+	return sub.PayoutWithData{
+		Id: payoutId,
+		Data: cba.Payout{
+			TickSize:      1,
+			CrankFee:      [2]uint64{1, 100},
+			ControllerFee: [2]uint64{2, 100},
+			Pipeline:      pipelineId,
+			Period: cba.Period{
+				IsBlank: false,
+				Start:   start,
+				Length:  150,
+			},
+		},
+		IsOpen: true,
+	}
+}
+
+func testPayout(t *testing.T, handle ts.Handle) {
+	pipelineId := randomKey(t)
+	err := handle.PipelineAdd([]sgo.PublicKey{pipelineId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []sub.PayoutWithData{
+		payout(pipelineId, randomKey(t), 1000),
+		payout(pipelineId, randomKey(t), 1150),
+	}
+	err = handle.PayoutAppend(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// payouts are appended again whenever the account changes
+	err = handle.PayoutAppend(list[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handle.PayoutAppend([]sub.PayoutWithData{payout(randomKey(t), randomKey(t), 1000)})
+	if err == nil {
+		t.Fatal("payout for an unknown pipeline should fail")
+	}
+
+	periodList, err := handle.PeriodSeries(pipelineId, ts.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periodList) != len(list) {
+		t.Fatalf("expected %d periods, got %d", len(list), len(periodList))
+	}
+	for i, p := range periodList {
+		if !p.PayoutId.Equals(list[i].Id) || !p.PipelineId.Equals(pipelineId) || p.Period != list[i].Data.Period {
+			t.Fatalf("bad period: %+v", p)
+		}
+	}
+	periodList, err = handle.PeriodSeries(pipelineId, ts.Query{Start: 1000, Step: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periodList) != 1 || periodList[0].Period.Start != 1150 {
+		t.Fatalf("bad downsample: %+v", periodList)
+	}
+}
+
+func testBid(t *testing.T, handle ts.Handle) {
+	pipelineId := randomKey(t)
+	payoutId := randomKey(t)
+	err := handle.PipelineAdd([]sgo.PublicKey{pipelineId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handle.PayoutAppend([]sub.PayoutWithData{payout(pipelineId, payoutId, 1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bidderA := randomKey(t)
+	bidderB := randomKey(t)
+	bp := ts.BidPoint{
+		Slot:     900,
+		PayoutId: payoutId,
+		Status: pyt.BidStatus{
+			Bid: []cba.Bid{
+				{User: bidderA, Deposit: 100, IsBlank: false},
+				{User: bidderB, Deposit: 300, IsBlank: false},
+				{IsBlank: true},
+			},
+			TotalDeposits: 400,
+			IsOpen:        true,
+		},
+	}
+	err = handle.BidAppend(bp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp.Status.Bid[0].Deposit = 200
+	bp.Status.TotalDeposits = 500
+	err = handle.BidAppend(bp)
+	if err != nil {
+		t.Fatalf("failed to replace point: %s", err.Error())
+	}
+	bp.Slot = 901
+	err = handle.BidAppend(bp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp.PayoutId = randomKey(t)
+	err = handle.BidAppend(bp)
+	if err == nil {
+		t.Fatal("bid on an unknown payout should fail")
+	}
+
+	list, err := handle.BidSeries(payoutId, ts.Query{Start: 900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(list))
+	}
+	for _, x := range list {
+		if !x.PayoutId.Equals(payoutId) || len(x.Status.Bid) != 2 || x.Status.TotalDeposits != 500 {
+			t.Fatalf("bad point: %+v", x)
+		}
+		for _, b := range x.Status.Bid {
+			if b.User.Equals(bidderA) && b.Deposit != 200 {
+				t.Fatalf("deposit was not replaced: %+v", b)
+			}
+		}
+	}
+	list, err = handle.BidSeries(payoutId, ts.Query{Start: 900, Step: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Slot != 901 {
+		t.Fatalf("bad downsample: %+v", list)
+	}
+	list, err = handle.BidSeries(randomKey(t), ts.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected no points for an unknown payout, got %d", len(list))
+	}
+}
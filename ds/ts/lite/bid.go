@@ -13,19 +13,24 @@ func (e1 external) BidAppend(bp ts.BidPoint) error {
 	})
 }
 
+const SQL_BIDDER_INSERT_1 string = `
+INSERT INTO "bidder" (bidder_id) VALUES (?1) ON CONFLICT DO NOTHING;
+`
+
 const SQL_BID_INSERT_1 string = `
-WITH x as (
-	SELECT b1."id" FROM "bidders" b1 WHERE b1.bidder_id = $1
-	UNION
-	INSERT INTO "bidder" (bidder_id) VALUES ($1) RETURNING "id"
-),
 INSERT INTO "bid_snapshot" (bidder,pipeline,start,slot,deposit)
-SELECT x."id", p1.pipeline, p1.start, $3, $4
-FROM x CROSS JOIN "payout" p1
-WHERE p1.payout_id = $2
+SELECT b1."id", p1.pipeline, p1.start, ?3, ?4
+FROM "bidder" b1 CROSS JOIN "payout" p1
+WHERE b1.bidder_id = ?1 AND p1.payout_id = ?2
+ON CONFLICT (slot,pipeline,start,bidder) DO UPDATE SET deposit = excluded.deposit;
 `
 
 func (e1 external) inside_bidappend(tx *sql.Tx, bp ts.BidPoint) error {
+	bidderStmt, err := tx.PrepareContext(e1.ctx, SQL_BIDDER_INSERT_1)
+	if err != nil {
+		return err
+	}
+	defer bidderStmt.Close()
 	insertStmt, err := tx.PrepareContext(e1.ctx, SQL_BID_INSERT_1)
 	if err != nil {
 		return err
@@ -35,6 +40,10 @@ func (e1 external) inside_bidappend(tx *sql.Tx, bp ts.BidPoint) error {
 	var result sql.Result
 	for _, bid := range bp.Status.Bid {
 		if !bid.IsBlank {
+			_, err = bidderStmt.ExecContext(e1.ctx, bid.User.String())
+			if err != nil {
+				return err
+			}
 			result, err = insertStmt.ExecContext(e1.ctx,
 				bid.User.String(),
 				bp.PayoutId.String(),
@@ -49,7 +58,7 @@ func (e1 external) inside_bidappend(tx *sql.Tx, bp ts.BidPoint) error {
 				return err
 			}
 			if n == 0 {
-				return errors.New("failed to insert")
+				return errors.New("unknown payout")
 			}
 		}
 	}
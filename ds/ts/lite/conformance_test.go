@@ -0,0 +1,16 @@
+package lite_test
+
+import (
+	"context"
+	"testing"
+
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/ds/ts/conformance"
+	"github.com/solpipe/solpipe-tool/ds/ts/lite"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ctx context.Context) (ts.Handle, error) {
+		return lite.Create(ctx, ":memory:")
+	})
+}
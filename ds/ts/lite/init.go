@@ -3,9 +3,11 @@ package lite
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	log "github.com/sirupsen/logrus"
 	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/ds/ts/series"
 )
 
 func (e1 external) Initialize(initialState ts.InitialState) error {
@@ -26,6 +28,10 @@ func (e1 external) Initialize(initialState ts.InitialState) error {
 			return err
 		}
 	}
+	err = e1.migrate()
+	if err != nil {
+		return err
+	}
 
 	err = e1.insert_time(initialState)
 	if err != nil {
@@ -36,6 +42,43 @@ func (e1 external) Initialize(initialState ts.InitialState) error {
 	return nil
 }
 
+// Columns added after the first release.  CREATE TABLE IF NOT EXISTS leaves
+// older tables as they are, so these are added to them with ALTER TABLE.
+var SQL_MIGRATE_COLUMN = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"network", "window_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"network", "tx_per_block", "numeric NOT NULL DEFAULT 0"},
+	{"network", "tx_size_per_second", "numeric NOT NULL DEFAULT 0"},
+	{"stake", "activated_stake", "bigint NOT NULL DEFAULT 0"},
+	{"stake", "total_stake", "bigint NOT NULL DEFAULT 0"},
+}
+
+const SQL_COLUMN_EXISTS string = `
+SELECT COUNT(*) FROM pragma_table_info(?1) WHERE name = ?2;
+`
+
+func (e1 external) migrate() error {
+	for _, c := range SQL_MIGRATE_COLUMN {
+		var n int
+		err := e1.db.QueryRow(SQL_COLUMN_EXISTS, c.table, c.column).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if 0 < n {
+			continue
+		}
+		log.Infof("adding column %s to table %s", c.column, c.table)
+		_, err = e1.db.Exec(fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN %s %s;`, c.table, c.column, c.definition))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const SQL_TIME_CREATE string = `
 CREATE TABLE IF NOT EXISTS "time"
 (
@@ -86,7 +129,10 @@ const SQL_NETWORK_CREATE_1 string = `
 CREATE TABLE IF NOT EXISTS "network"
 (
     slot INTEGER NOT NULL,
+    window_size INTEGER NOT NULL,
+    tx_per_block numeric NOT NULL,
     tps numeric NOT NULL,
+    tx_size_per_second numeric NOT NULL,
     CONSTRAINT network_pkey PRIMARY KEY (slot),
     CONSTRAINT network_slot_fk FOREIGN KEY (slot)
         REFERENCES "time" (slot) MATCH SIMPLE
@@ -140,7 +186,7 @@ CREATE TABLE IF NOT EXISTS "bid_snapshot"
 	start INTEGER NOT NULL,
     bidder INTEGER NOT NULL,
 	deposit bigint NOT NULL,
-    CONSTRAINT bid_snapshot_pkey PRIMARY KEY (slot,pipeline,start)
+    CONSTRAINT bid_snapshot_pkey PRIMARY KEY (slot,pipeline,start,bidder)
 	CONSTRAINT bid_payout_pipeline_fk FOREIGN KEY (pipeline)
 		REFERENCES "pipeline" ("id") MATCH SIMPLE
 		ON UPDATE NO ACTION
@@ -164,11 +210,11 @@ CREATE TABLE IF NOT EXISTS "bid_snapshot"
    
 );
 
-CREATE UNIQUE INDEX IF NOT EXISTS bidsnapshot_slot_pipeline_start_idx
+CREATE INDEX IF NOT EXISTS bidsnapshot_slot_pipeline_start_idx
     ON "bid_snapshot"
     (pipeline ASC, start ASC,slot ASC);
 
-CREATE UNIQUE INDEX IF NOT EXISTS bidsnapshot_bidder_idx
+CREATE INDEX IF NOT EXISTS bidsnapshot_bidder_idx
     ON "bid_snapshot"
     (bidder ASC);
 `
@@ -179,6 +225,8 @@ CREATE TABLE IF NOT EXISTS "stake"
 	slot INTEGER NOT NULL,
 	pipeline INTEGER NOT NULL,
 	relative_stake numeric NOT NULL,
+	activated_stake bigint NOT NULL,
+	total_stake bigint NOT NULL,
     CONSTRAINT stake_pkey PRIMARY KEY (slot,pipeline)
 	CONSTRAINT stake_id_fk FOREIGN KEY (slot)
 		REFERENCES "time" ("slot") MATCH SIMPLE
@@ -205,6 +253,7 @@ type sqlGroup struct {
 	db               *sql.DB
 	pipeline_select  *sql.Stmt
 	stake_select_all *sql.Stmt
+	series           *series.Group
 	slot_max         *sql.Stmt
 }
 
@@ -225,6 +274,10 @@ func sql_init(ctx context.Context, db *sql.DB) (*sqlGroup, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = ans.query()
+	if err != nil {
+		return nil, err
+	}
 
 	return ans, nil
 }
@@ -0,0 +1,65 @@
+package lite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/ds/ts/lite"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+)
+
+// tables as created before the series queries were added
+const SQL_OLD_SCHEMA string = `
+CREATE TABLE "network" (slot INTEGER NOT NULL, tps numeric NOT NULL, CONSTRAINT network_pkey PRIMARY KEY (slot));
+CREATE TABLE "stake" (slot INTEGER NOT NULL, pipeline INTEGER NOT NULL, relative_stake numeric NOT NULL, CONSTRAINT stake_pkey PRIMARY KEY (slot,pipeline));
+INSERT INTO "network" (slot,tps) VALUES (1,10.5);
+`
+
+func TestMigrate(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "ts.db")
+	db, err := sql.Open("sqlite3", fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(SQL_OLD_SCHEMA)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handle, err := lite.Create(ctx, fp)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	finishC := handle.CloseSignal()
+	t.Cleanup(func() {
+		cancel()
+		<-finishC
+	})
+
+	err = handle.NetworkAppend([]ts.NetworkPoint{{
+		Slot:   2,
+		Status: ntk.NetworkStatus{WindowSize: 3, AverageTransactionsPerSecond: 20},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := handle.NetworkSeries(ts.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("series %+v", list)
+	}
+	if list[0].Status.WindowSize != 0 || list[0].Status.AverageTransactionsPerSecond != 10.5 {
+		t.Fatalf("old point %+v", list[0])
+	}
+	if list[1].Status.WindowSize != 3 || list[1].Status.AverageTransactionsPerSecond != 20 {
+		t.Fatalf("new point %+v", list[1])
+	}
+}
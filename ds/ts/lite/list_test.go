@@ -14,7 +14,7 @@ import (
 func TestBasic(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handle, err := lite.Create(ctx)
+	handle, err := lite.Create(ctx, ":memory:")
 	if err != nil {
 		cancel()
 		t.Fatal(err)
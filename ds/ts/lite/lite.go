@@ -16,16 +16,21 @@ type external struct {
 	sg        *sqlGroup
 }
 
-// create an in-memory sqlite instance
-func Create(ctx context.Context) (main ts.Handle, err error) {
-	db, err := sql.Open("sqlite3", ":memory:")
+// Open a sqlite instance at filePath.  Use ":memory:" for a database that does
+// not survive a restart.
+func Create(ctx context.Context, filePath string) (main ts.Handle, err error) {
+	db, err := sql.Open("sqlite3", filePath)
 	if err != nil {
 		return
 	}
+	// every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
 
 	ctxC, cancel := context.WithCancel(ctx)
 	main, err = create_with_init(ctxC, cancel, db)
 	if err != nil {
+		cancel()
+		db.Close()
 		return
 	}
 	return
@@ -41,6 +46,7 @@ func create_with_init(
 
 	e1 := &external{
 		ctx:       ctx,
+		cancel:    cancel,
 		db:        db,
 		internalC: internalC,
 	}
@@ -7,7 +7,12 @@ import (
 )
 
 const SQL_NETWORK_INSERT_1 string = `
-INSERT INTO "network" (slot,tps) VALUES ($1,$2);
+INSERT INTO "network" (slot,window_size,tx_per_block,tps,tx_size_per_second) VALUES (?1,?2,?3,?4,?5)
+ON CONFLICT (slot) DO UPDATE SET
+	window_size = excluded.window_size,
+	tx_per_block = excluded.tx_per_block,
+	tps = excluded.tps,
+	tx_size_per_second = excluded.tx_size_per_second;
 `
 
 func (e1 external) NetworkAppend(list []ts.NetworkPoint) error {
@@ -23,7 +28,13 @@ func (e1 external) inside_network_append(tx *sql.Tx, list []ts.NetworkPoint) err
 	}
 	defer insertStmt.Close()
 	for _, s := range list {
-		_, err = insertStmt.Exec(s.Slot, s.Status.AverageTransactionsPerSecond)
+		_, err = insertStmt.Exec(
+			s.Slot,
+			s.Status.WindowSize,
+			s.Status.AverageTransactionsPerBlock,
+			s.Status.AverageTransactionsPerSecond,
+			s.Status.AverageTransactionsSizePerSecond,
+		)
 		if err != nil {
 			return err
 		}
@@ -2,6 +2,7 @@ package lite
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/solpipe/solpipe-tool/proto/admin"
 	"github.com/solpipe/solpipe-tool/state"
@@ -17,7 +18,15 @@ func (e1 external) PayoutAppend(list []sub.PayoutWithData) error {
 
 const SQL_PAYOUT_INSERT_1 string = `
 INSERT INTO "payout" (pipeline,start,payout_id,length,controller_fee,crank_fee,tick_size)
-VALUES ($1,$2,$3,$4,$5,$6,$7);
+SELECT p1."id", ?2, ?3, ?4, ?5, ?6, ?7
+FROM "pipeline" p1
+WHERE p1.pipeline_id = ?1
+ON CONFLICT (pipeline,start) DO UPDATE SET
+	payout_id = excluded.payout_id,
+	length = excluded.length,
+	controller_fee = excluded.controller_fee,
+	crank_fee = excluded.crank_fee,
+	tick_size = excluded.tick_size;
 `
 
 func (e1 external) inside_payout_append(tx *sql.Tx, list []sub.PayoutWithData) error {
@@ -29,6 +38,8 @@ func (e1 external) inside_payout_append(tx *sql.Tx, list []sub.PayoutWithData) e
 	defer insertStmt.Close()
 	var controllerFee float64
 	var crankerFee float64
+	var r sql.Result
+	var n int64
 	for _, x := range list {
 		controllerFee, err = state.RateFromProto(&admin.Rate{
 			Numerator:   x.Data.ControllerFee[0],
@@ -44,7 +55,7 @@ func (e1 external) inside_payout_append(tx *sql.Tx, list []sub.PayoutWithData) e
 		if err != nil {
 			return err
 		}
-		_, err = insertStmt.ExecContext(ctx,
+		r, err = insertStmt.ExecContext(ctx,
 			x.Data.Pipeline.String(),
 			x.Data.Period.Start,
 			x.Id.String(),
@@ -56,6 +67,13 @@ func (e1 external) inside_payout_append(tx *sql.Tx, list []sub.PayoutWithData) e
 		if err != nil {
 			return err
 		}
+		n, err = r.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("unknown pipeline")
+		}
 	}
 	return nil
 }
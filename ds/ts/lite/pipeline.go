@@ -13,7 +13,7 @@ func (e1 external) PipelineAdd(idList []sgo.PublicKey) error {
 }
 
 const SQL_PIPELINE_INSERT string = `
-INSERT INTO pipeline (pipeline_id) VALUES (?);
+INSERT INTO pipeline (pipeline_id) VALUES (?1) ON CONFLICT DO NOTHING;
 `
 
 func (e1 external) inside_pipeline_add(tx *sql.Tx, idList []sgo.PublicKey) error {
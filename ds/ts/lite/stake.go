@@ -20,10 +20,14 @@ func (e1 external) StakeAppend(list []ts.StakePoint) error {
 }
 
 const SQL_STAKE_INSERT_1 string = `
-INSERT INTO "stake" (pipeline,slot,relative_stake)
-SELECT p1."id", $2, $3
+INSERT INTO "stake" (pipeline,slot,relative_stake,activated_stake,total_stake)
+SELECT p1."id", ?2, ?3, ?4, ?5
 FROM "pipeline" p1
-WHERE p1.pipeline_id = $1;
+WHERE p1.pipeline_id = ?1
+ON CONFLICT (slot,pipeline) DO UPDATE SET
+	relative_stake = excluded.relative_stake,
+	activated_stake = excluded.activated_stake,
+	total_stake = excluded.total_stake;
 `
 
 func (e1 external) inside_stake_append(tx *sql.Tx, list []ts.StakePoint) error {
@@ -40,6 +44,8 @@ func (e1 external) inside_stake_append(tx *sql.Tx, list []ts.StakePoint) error {
 			sp.PipelineId.String(),
 			sp.Slot,
 			sp.Stake.Share(),
+			sp.Stake.ActivatedStake.Int64(),
+			sp.Stake.TotalStake.Int64(),
 		)
 		if err != nil {
 			return err
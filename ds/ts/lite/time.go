@@ -7,7 +7,7 @@ import (
 )
 
 const SQL_TIME_INSERT string = `
-INSERT INTO "time" (slot) VALUES ($1);
+INSERT INTO "time" (slot) VALUES (?1) ON CONFLICT DO NOTHING;
 `
 
 func (e1 external) insert_time(is ts.InitialState) error {
@@ -91,13 +91,8 @@ func (e1 external) inside_time_append(tx *sql.Tx, start uint64, length uint64) e
 	for i := uint64(0); i < length; i++ {
 		_, err = sqlStmt.ExecContext(ctx, start+i)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
 	return nil
 }
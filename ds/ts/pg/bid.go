@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+)
+
+func (e1 external) BidAppend(bp ts.BidPoint) error {
+	return e1.tx(func(tx *sql.Tx) error {
+		return e1.inside_bidappend(tx, bp)
+	})
+}
+
+const SQL_BIDDER_INSERT_1 string = `
+INSERT INTO "bidder" (bidder_id) VALUES ($1) ON CONFLICT DO NOTHING;
+`
+
+const SQL_BID_INSERT_1 string = `
+INSERT INTO "bid_snapshot" (bidder,pipeline,start,slot,deposit)
+SELECT b1."id", p1.pipeline, p1.start, $3::bigint, $4::numeric
+FROM "bidder" b1 CROSS JOIN "payout" p1
+WHERE b1.bidder_id = $1 AND p1.payout_id = $2
+ON CONFLICT (slot,pipeline,start,bidder) DO UPDATE SET deposit = excluded.deposit;
+`
+
+func (e1 external) inside_bidappend(tx *sql.Tx, bp ts.BidPoint) error {
+	bidderStmt, err := tx.PrepareContext(e1.ctx, SQL_BIDDER_INSERT_1)
+	if err != nil {
+		return err
+	}
+	defer bidderStmt.Close()
+	insertStmt, err := tx.PrepareContext(e1.ctx, SQL_BID_INSERT_1)
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+
+	var result sql.Result
+	for _, bid := range bp.Status.Bid {
+		if !bid.IsBlank {
+			_, err = bidderStmt.ExecContext(e1.ctx, bid.User.String())
+			if err != nil {
+				return err
+			}
+			result, err = insertStmt.ExecContext(e1.ctx,
+				bid.User.String(),
+				bp.PayoutId.String(),
+				bp.Slot,
+				bid.Deposit,
+			)
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errors.New("unknown payout")
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	log "github.com/sirupsen/logrus"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/ds/ts/series"
+)
+
+func (e1 external) Initialize(initialState ts.InitialState) error {
+	var err error
+	list := []string{
+		SQL_TIME_CREATE,
+		SQL_BIDDER_CREATE_1,
+		SQL_PIPELINE_CREATE_1,
+		SQL_NETWORK_CREATE_1,
+		SQL_PAYOUT_CREATE_1,
+		SQL_PAYOUT_BID_CREATE_1,
+		SQL_STAKE_CREATE_1,
+		SQL_MIGRATE_1,
+	}
+	for _, sqlStmt := range list {
+		_, err = e1.db.ExecContext(e1.ctx, sqlStmt)
+		if err != nil {
+			log.Infof("failed to run: %s", sqlStmt)
+			return err
+		}
+	}
+
+	return e1.tx(func(tx *sql.Tx) error {
+		return e1.inside_time_insert(tx, initialState.Start, initialState.Finish-1)
+	})
+}
+
+// Slots are not foreign keys as in the sqlite schema so that points can be
+// appended before the time table catches up (and so that tables can be turned
+// into TimescaleDB hypertables).
+const SQL_TIME_CREATE string = `
+CREATE TABLE IF NOT EXISTS "time"
+(
+    slot bigint NOT NULL,
+    CONSTRAINT time_pkey PRIMARY KEY (slot)
+);
+`
+
+// Columns added after the first release.  CREATE TABLE IF NOT EXISTS leaves
+// older tables as they are, so these are added to them here.
+const SQL_MIGRATE_1 string = `
+ALTER TABLE "network"
+    ADD COLUMN IF NOT EXISTS window_size integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tx_per_block double precision NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tx_size_per_second double precision NOT NULL DEFAULT 0;
+
+ALTER TABLE "stake"
+    ADD COLUMN IF NOT EXISTS activated_stake bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS total_stake bigint NOT NULL DEFAULT 0;
+`
+
+const SQL_PIPELINE_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS pipeline
+(
+    id bigserial PRIMARY KEY,
+    pipeline_id character varying(128) NOT NULL,
+    CONSTRAINT pipeline_id_uq UNIQUE (pipeline_id)
+);
+`
+
+const SQL_BIDDER_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS bidder
+(
+    id bigserial PRIMARY KEY,
+    bidder_id character varying(128) NOT NULL,
+    CONSTRAINT bidder_id_uq UNIQUE (bidder_id)
+);
+`
+
+const SQL_NETWORK_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS "network"
+(
+    slot bigint NOT NULL,
+    window_size integer NOT NULL,
+    tx_per_block double precision NOT NULL,
+    tps double precision NOT NULL,
+    tx_size_per_second double precision NOT NULL,
+    CONSTRAINT network_pkey PRIMARY KEY (slot)
+);
+`
+
+const SQL_PAYOUT_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS "payout"
+(
+    pipeline bigint NOT NULL,
+    start bigint NOT NULL,
+    payout_id character varying(128) NOT NULL,
+    length bigint NOT NULL,
+    controller_fee double precision NOT NULL,
+    crank_fee double precision NOT NULL,
+    tick_size integer NOT NULL,
+    CONSTRAINT payout_pkey PRIMARY KEY (pipeline,start),
+    CONSTRAINT payout_pipeline_fk FOREIGN KEY (pipeline)
+        REFERENCES "pipeline" ("id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payout_pubkey_idx
+    ON "payout"
+    (payout_id ASC);
+`
+
+const SQL_PAYOUT_BID_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS "bid_snapshot"
+(
+    slot bigint NOT NULL,
+    pipeline bigint NOT NULL,
+    start bigint NOT NULL,
+    bidder bigint NOT NULL,
+    deposit numeric(20,0) NOT NULL,
+    CONSTRAINT bid_snapshot_pkey PRIMARY KEY (slot,pipeline,start,bidder),
+    CONSTRAINT bid_payout_fk FOREIGN KEY (pipeline,start)
+        REFERENCES "payout" (pipeline,start),
+    CONSTRAINT bid_bidder_fk FOREIGN KEY (bidder)
+        REFERENCES "bidder" ("id")
+);
+
+CREATE INDEX IF NOT EXISTS bidsnapshot_slot_pipeline_start_idx
+    ON "bid_snapshot"
+    (pipeline ASC, start ASC, slot ASC);
+
+CREATE INDEX IF NOT EXISTS bidsnapshot_bidder_idx
+    ON "bid_snapshot"
+    (bidder ASC);
+`
+
+const SQL_STAKE_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS "stake"
+(
+    slot bigint NOT NULL,
+    pipeline bigint NOT NULL,
+    relative_stake double precision NOT NULL,
+    activated_stake bigint NOT NULL,
+    total_stake bigint NOT NULL,
+    CONSTRAINT stake_pkey PRIMARY KEY (slot,pipeline),
+    CONSTRAINT stake_pipeline_fk FOREIGN KEY (pipeline)
+        REFERENCES "pipeline" ("id")
+);
+
+CREATE INDEX IF NOT EXISTS stake_pipeline_idx
+    ON "stake"
+    (pipeline ASC, slot ASC);
+`
+
+type sqlGroup struct {
+	ctx             context.Context
+	db              *sql.DB
+	pipeline_select *sql.Stmt
+	series          *series.Group
+	slot_max        *sql.Stmt
+}
+
+func sql_init(ctx context.Context, db *sql.DB) (*sqlGroup, error) {
+	ans := new(sqlGroup)
+	ans.ctx = ctx
+	ans.db = db
+
+	err := ans.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	err = ans.time()
+	if err != nil {
+		return nil, err
+	}
+	err = ans.query()
+	if err != nil {
+		return nil, err
+	}
+
+	return ans, nil
+}
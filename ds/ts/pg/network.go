@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"database/sql"
+
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+)
+
+const SQL_NETWORK_INSERT_1 string = `
+INSERT INTO "network" (slot,window_size,tx_per_block,tps,tx_size_per_second) VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (slot) DO UPDATE SET
+	window_size = excluded.window_size,
+	tx_per_block = excluded.tx_per_block,
+	tps = excluded.tps,
+	tx_size_per_second = excluded.tx_size_per_second;
+`
+
+func (e1 external) NetworkAppend(list []ts.NetworkPoint) error {
+	return e1.tx(func(tx *sql.Tx) error {
+		return e1.inside_network_append(tx, list)
+	})
+}
+
+func (e1 external) inside_network_append(tx *sql.Tx, list []ts.NetworkPoint) error {
+	insertStmt, err := tx.PrepareContext(e1.ctx, SQL_NETWORK_INSERT_1)
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+	for _, s := range list {
+		_, err = insertStmt.ExecContext(e1.ctx,
+			s.Slot,
+			s.Status.WindowSize,
+			s.Status.AverageTransactionsPerBlock,
+			s.Status.AverageTransactionsPerSecond,
+			s.Status.AverageTransactionsSizePerSecond,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
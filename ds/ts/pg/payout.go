@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/solpipe/solpipe-tool/proto/admin"
+	"github.com/solpipe/solpipe-tool/state"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+func (e1 external) PayoutAppend(list []sub.PayoutWithData) error {
+
+	return e1.tx(func(tx *sql.Tx) error {
+		return e1.inside_payout_append(tx, list)
+	})
+}
+
+const SQL_PAYOUT_INSERT_1 string = `
+INSERT INTO "payout" (pipeline,start,payout_id,length,controller_fee,crank_fee,tick_size)
+SELECT p1."id", $2::bigint, $3, $4::bigint, $5::double precision, $6::double precision, $7::integer
+FROM "pipeline" p1
+WHERE p1.pipeline_id = $1
+ON CONFLICT (pipeline,start) DO UPDATE SET
+	payout_id = excluded.payout_id,
+	length = excluded.length,
+	controller_fee = excluded.controller_fee,
+	crank_fee = excluded.crank_fee,
+	tick_size = excluded.tick_size;
+`
+
+func (e1 external) inside_payout_append(tx *sql.Tx, list []sub.PayoutWithData) error {
+	ctx := e1.ctx
+	insertStmt, err := tx.PrepareContext(ctx, SQL_PAYOUT_INSERT_1)
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+	var controllerFee float64
+	var crankerFee float64
+	var r sql.Result
+	var n int64
+	for _, x := range list {
+		controllerFee, err = state.RateFromProto(&admin.Rate{
+			Numerator:   x.Data.ControllerFee[0],
+			Denominator: x.Data.ControllerFee[1],
+		}).Float()
+		if err != nil {
+			return err
+		}
+		crankerFee, err = state.RateFromProto(&admin.Rate{
+			Numerator:   x.Data.CrankFee[0],
+			Denominator: x.Data.CrankFee[1],
+		}).Float()
+		if err != nil {
+			return err
+		}
+		r, err = insertStmt.ExecContext(ctx,
+			x.Data.Pipeline.String(),
+			x.Data.Period.Start,
+			x.Id.String(),
+			x.Data.Period.Length,
+			controllerFee,
+			crankerFee,
+			x.Data.TickSize,
+		)
+		if err != nil {
+			return err
+		}
+		n, err = r.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("unknown pipeline")
+		}
+	}
+	return nil
+}
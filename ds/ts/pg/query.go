@@ -0,0 +1,30 @@
+package pg
+
+import (
+	sgo "github.com/SolmateDev/solana-go"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/ds/ts/series"
+)
+
+var SERIES_PLACEHOLDERS = series.Placeholders{Start: "$1::bigint", Finish: "$2::bigint", Step: "$3::bigint", Id: "$4::text"}
+
+func (sg *sqlGroup) query() (err error) {
+	sg.series, err = series.Prepare(sg.ctx, sg.db, SERIES_PLACEHOLDERS)
+	return
+}
+
+func (e1 external) NetworkSeries(q ts.Query) ([]ts.NetworkPoint, error) {
+	return e1.sg.series.Network(e1.ctx, q)
+}
+
+func (e1 external) StakeSeries(pipelineId sgo.PublicKey, q ts.Query) ([]ts.StakePoint, error) {
+	return e1.sg.series.Stake(e1.ctx, pipelineId, q)
+}
+
+func (e1 external) BidSeries(payoutId sgo.PublicKey, q ts.Query) ([]ts.BidPoint, error) {
+	return e1.sg.series.Bid(e1.ctx, payoutId, q)
+}
+
+func (e1 external) PeriodSeries(pipelineId sgo.PublicKey, q ts.Query) ([]ts.PeriodPoint, error) {
+	return e1.sg.series.Period(e1.ctx, pipelineId, q)
+}
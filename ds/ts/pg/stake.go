@@ -0,0 +1,55 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+)
+
+func (e1 external) StakeAppend(list []ts.StakePoint) error {
+	return e1.tx(func(tx *sql.Tx) error {
+		return e1.inside_stake_append(tx, list)
+	})
+}
+
+const SQL_STAKE_INSERT_1 string = `
+INSERT INTO "stake" (pipeline,slot,relative_stake,activated_stake,total_stake)
+SELECT p1."id", $2::bigint, $3::double precision, $4::bigint, $5::bigint
+FROM "pipeline" p1
+WHERE p1.pipeline_id = $1
+ON CONFLICT (slot,pipeline) DO UPDATE SET
+	relative_stake = excluded.relative_stake,
+	activated_stake = excluded.activated_stake,
+	total_stake = excluded.total_stake;
+`
+
+func (e1 external) inside_stake_append(tx *sql.Tx, list []ts.StakePoint) error {
+	insertStmt, err := tx.PrepareContext(e1.ctx, SQL_STAKE_INSERT_1)
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+	var r sql.Result
+	var n int64
+	for _, sp := range list {
+		r, err = insertStmt.ExecContext(e1.ctx,
+			sp.PipelineId.String(),
+			sp.Slot,
+			sp.Stake.Share(),
+			sp.Stake.ActivatedStake.Int64(),
+			sp.Stake.TotalStake.Int64(),
+		)
+		if err != nil {
+			return err
+		}
+		n, err = r.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("unknown pipeline")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package pg
+
+import (
+	"database/sql"
+)
+
+const SQL_TIME_INSERT string = `
+INSERT INTO "time" (slot)
+SELECT generate_series($1::bigint, $2::bigint)
+ON CONFLICT DO NOTHING;
+`
+
+// insert slots from start to finish inclusive
+func (e1 external) inside_time_insert(tx *sql.Tx, start uint64, finish uint64) error {
+	_, err := tx.ExecContext(e1.ctx, SQL_TIME_INSERT, start, finish)
+	return err
+}
+
+const SQL_TIME_SELECT_MAX string = `
+SELECT COALESCE(max(t1.slot), 0) FROM "time" t1;
+`
+
+func (sg *sqlGroup) time() error {
+	var err error
+	sg.slot_max, err = sg.db.PrepareContext(sg.ctx, SQL_TIME_SELECT_MAX)
+	return err
+}
+
+func (e1 external) slot_max() (max uint64, err error) {
+	err = e1.sg.slot_max.QueryRowContext(e1.ctx).Scan(&max)
+	return
+}
+
+func (e1 external) TimeAppend(length uint64) (high uint64, err error) {
+	if length == 0 {
+		return e1.slot_max()
+	}
+	high, err = e1.slot_max()
+	if err != nil {
+		return
+	}
+	err = e1.tx(func(tx *sql.Tx) error {
+		return e1.inside_time_insert(tx, high+1, high+length)
+	})
+	if err != nil {
+		return
+	}
+	return e1.slot_max()
+}
@@ -0,0 +1,238 @@
+// Slot window queries shared by the sqlite and Postgres backends.
+package series
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"strings"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+// How each backend writes the query arguments.  The arguments are passed in
+// the order start, finish, step, id.
+type Placeholders struct {
+	Start  string
+	Finish string
+	Step   string
+	Id     string
+}
+
+// each bucket of :step slots between :start and :finish keeps its last point
+const SQL_NETWORK_SERIES string = `
+SELECT n1.slot, n1.window_size, n1.tx_per_block, n1.tps, n1.tx_size_per_second
+FROM "network" n1
+WHERE n1.slot IN (
+	SELECT max(n2.slot)
+	FROM "network" n2
+	WHERE :start <= n2.slot AND n2.slot <= :finish
+	GROUP BY (n2.slot - :start) / :step
+)
+ORDER BY n1.slot ASC;
+`
+
+const SQL_STAKE_SERIES string = `
+SELECT s1.slot, s1.activated_stake, s1.total_stake
+FROM "stake" s1
+INNER JOIN "pipeline" p1 ON s1.pipeline = p1."id"
+WHERE p1.pipeline_id = :id AND s1.slot IN (
+	SELECT max(s2.slot)
+	FROM "stake" s2
+	WHERE s2.pipeline = p1."id" AND :start <= s2.slot AND s2.slot <= :finish
+	GROUP BY (s2.slot - :start) / :step
+)
+ORDER BY s1.slot ASC;
+`
+
+const SQL_BID_SERIES string = `
+SELECT b1.slot, d1.bidder_id, b1.deposit
+FROM "bid_snapshot" b1
+INNER JOIN "payout" p1 ON b1.pipeline = p1.pipeline AND b1.start = p1.start
+INNER JOIN "bidder" d1 ON b1.bidder = d1."id"
+WHERE p1.payout_id = :id AND b1.slot IN (
+	SELECT max(b2.slot)
+	FROM "bid_snapshot" b2
+	WHERE b2.pipeline = p1.pipeline AND b2.start = p1.start AND :start <= b2.slot AND b2.slot <= :finish
+	GROUP BY (b2.slot - :start) / :step
+)
+ORDER BY b1.slot ASC, d1.bidder_id ASC;
+`
+
+const SQL_PERIOD_SERIES string = `
+SELECT y1.payout_id, y1.start, y1.length
+FROM "payout" y1
+INNER JOIN "pipeline" p1 ON y1.pipeline = p1."id"
+WHERE p1.pipeline_id = :id AND y1.start IN (
+	SELECT max(y2.start)
+	FROM "payout" y2
+	WHERE y2.pipeline = p1."id" AND :start <= y2.start AND y2.start <= :finish
+	GROUP BY (y2.start - :start) / :step
+)
+ORDER BY y1.start ASC;
+`
+
+func (p Placeholders) Sql(query string) string {
+	return strings.NewReplacer(
+		":start", p.Start,
+		":finish", p.Finish,
+		":step", p.Step,
+		":id", p.Id,
+	).Replace(query)
+}
+
+type Group struct {
+	network *sql.Stmt
+	stake   *sql.Stmt
+	bid     *sql.Stmt
+	period  *sql.Stmt
+}
+
+func Prepare(ctx context.Context, db *sql.DB, p Placeholders) (*Group, error) {
+	var err error
+	g := new(Group)
+	g.network, err = db.PrepareContext(ctx, p.Sql(SQL_NETWORK_SERIES))
+	if err != nil {
+		return nil, err
+	}
+	g.stake, err = db.PrepareContext(ctx, p.Sql(SQL_STAKE_SERIES))
+	if err != nil {
+		return nil, err
+	}
+	g.bid, err = db.PrepareContext(ctx, p.Sql(SQL_BID_SERIES))
+	if err != nil {
+		return nil, err
+	}
+	g.period, err = db.PrepareContext(ctx, p.Sql(SQL_PERIOD_SERIES))
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *Group) Network(ctx context.Context, q ts.Query) (list []ts.NetworkPoint, err error) {
+	q, err = q.Normalize()
+	if err != nil {
+		return
+	}
+	results, err := g.network.QueryContext(ctx, q.Start, q.Finish, q.Step)
+	if err != nil {
+		return
+	}
+	defer results.Close()
+	list = make([]ts.NetworkPoint, 0)
+	for results.Next() {
+		var p ts.NetworkPoint
+		err = results.Scan(
+			&p.Slot,
+			&p.Status.WindowSize,
+			&p.Status.AverageTransactionsPerBlock,
+			&p.Status.AverageTransactionsPerSecond,
+			&p.Status.AverageTransactionsSizePerSecond,
+		)
+		if err != nil {
+			return
+		}
+		list = append(list, p)
+	}
+	err = results.Err()
+	return
+}
+
+func (g *Group) Stake(ctx context.Context, pipelineId sgo.PublicKey, q ts.Query) (list []ts.StakePoint, err error) {
+	q, err = q.Normalize()
+	if err != nil {
+		return
+	}
+	results, err := g.stake.QueryContext(ctx, q.Start, q.Finish, q.Step, pipelineId.String())
+	if err != nil {
+		return
+	}
+	defer results.Close()
+	list = make([]ts.StakePoint, 0)
+	var slot uint64
+	var activated, total int64
+	for results.Next() {
+		err = results.Scan(&slot, &activated, &total)
+		if err != nil {
+			return
+		}
+		list = append(list, ts.StakePoint{
+			Slot:       slot,
+			PipelineId: pipelineId,
+			Stake: sub.StakeUpdate{
+				ActivatedStake: big.NewInt(activated),
+				TotalStake:     big.NewInt(total),
+			},
+		})
+	}
+	err = results.Err()
+	return
+}
+
+func (g *Group) Bid(ctx context.Context, payoutId sgo.PublicKey, q ts.Query) (list []ts.BidPoint, err error) {
+	q, err = q.Normalize()
+	if err != nil {
+		return
+	}
+	results, err := g.bid.QueryContext(ctx, q.Start, q.Finish, q.Step, payoutId.String())
+	if err != nil {
+		return
+	}
+	defer results.Close()
+	list = make([]ts.BidPoint, 0)
+	var slot uint64
+	var bidderId string
+	var deposit uint64
+	var user sgo.PublicKey
+	for results.Next() {
+		err = results.Scan(&slot, &bidderId, &deposit)
+		if err != nil {
+			return
+		}
+		user, err = sgo.PublicKeyFromBase58(bidderId)
+		if err != nil {
+			return
+		}
+		// rows are sorted by slot, so a new slot starts a new point
+		if len(list) == 0 || list[len(list)-1].Slot != slot {
+			list = append(list, ts.BidPoint{Slot: slot, PayoutId: payoutId})
+		}
+		bp := &list[len(list)-1]
+		bp.Status.Bid = append(bp.Status.Bid, cba.Bid{User: user, Deposit: deposit})
+		bp.Status.TotalDeposits += deposit
+	}
+	err = results.Err()
+	return
+}
+
+func (g *Group) Period(ctx context.Context, pipelineId sgo.PublicKey, q ts.Query) (list []ts.PeriodPoint, err error) {
+	q, err = q.Normalize()
+	if err != nil {
+		return
+	}
+	results, err := g.period.QueryContext(ctx, q.Start, q.Finish, q.Step, pipelineId.String())
+	if err != nil {
+		return
+	}
+	defer results.Close()
+	list = make([]ts.PeriodPoint, 0)
+	var payoutId string
+	for results.Next() {
+		p := ts.PeriodPoint{PipelineId: pipelineId}
+		err = results.Scan(&payoutId, &p.Period.Start, &p.Period.Length)
+		if err != nil {
+			return
+		}
+		p.PayoutId, err = sgo.PublicKeyFromBase58(payoutId)
+		if err != nil {
+			return
+		}
+		list = append(list, p)
+	}
+	err = results.Err()
+	return
+}
@@ -2,25 +2,72 @@ package db
 
 import (
 	"database/sql"
+	"errors"
+	"math"
 
 	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
 	ntk "github.com/solpipe/solpipe-tool/state/network"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 	"github.com/solpipe/solpipe-tool/state/sub"
 	"github.com/solpipe/solpipe-tool/util"
 )
 
+// Every backend must pass ds/ts/conformance.  Appending a point at a slot that
+// already has a point replaces it, so callers can append on every update.
 type Handle interface {
 	util.Base
 	Type() DbType
 	Db() *sql.DB
+	// add length slots after the highest slot; returns the new highest slot
 	TimeAppend(length uint64) (high uint64, err error)
+	// pipelines that have already been added are ignored
 	PipelineAdd(idList []sgo.PublicKey) error
+	// the pipeline of each payout must have been added
 	PayoutAppend(list []sub.PayoutWithData) error
+	// the payout must have been added
 	BidAppend(bp BidPoint) error
 	PipelineList() ([]sgo.PublicKey, error)
 	NetworkAppend(list []NetworkPoint) error
+	// the pipeline must have been added
 	StakeAppend(list []StakePoint) error
+	NetworkSeries(q Query) ([]NetworkPoint, error)
+	StakeSeries(pipelineId sgo.PublicKey, q Query) ([]StakePoint, error)
+	// only Bid and TotalDeposits are filled in on BidStatus
+	BidSeries(payoutId sgo.PublicKey, q Query) ([]BidPoint, error)
+	// periods are placed in the window by their start slot
+	PeriodSeries(pipelineId sgo.PublicKey, q Query) ([]PeriodPoint, error)
+}
+
+// A slot window used to read back a series.  With Step above 1, the series is
+// downsampled to the last point in each bucket of Step slots counted from Start.
+type Query struct {
+	Start  uint64 // inclusive
+	Finish uint64 // inclusive; 0 means there is no upper bound
+	Step   uint64 // 0 means every point
+}
+
+// Fill in the defaults so that backends can pass the fields straight to SQL.
+func (q Query) Normalize() (Query, error) {
+	if q.Finish == 0 || math.MaxInt64 < q.Finish {
+		q.Finish = math.MaxInt64
+	}
+	if q.Step == 0 {
+		q.Step = 1
+	}
+	if math.MaxInt64 < q.Start || math.MaxInt64 < q.Step {
+		return q, errors.New("query out of range")
+	}
+	if q.Finish < q.Start {
+		return q, errors.New("finish is before start")
+	}
+	return q, nil
+}
+
+type Configuration struct {
+	DbType DbType
+	// file path for DB_TYPE_LITE (blank means in memory); connection url for DB_TYPE_POSTGRES
+	Url string
 }
 
 type InitialState struct {
@@ -45,8 +92,15 @@ type BidPoint struct {
 	Status   pyt.BidStatus
 }
 
+type PeriodPoint struct {
+	PayoutId   sgo.PublicKey
+	PipelineId sgo.PublicKey
+	Period     cba.Period
+}
+
 type DbType = string
 
 const (
-	DB_TYPE_LITE DbType = "lite"
+	DB_TYPE_LITE     DbType = "lite"
+	DB_TYPE_POSTGRES DbType = "postgres"
 )
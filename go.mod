@@ -8,17 +8,18 @@ replace github.com/SolmateDev/go-rust-translator => ../tpu-client/go
 
 require (
 	github.com/SolmateDev/solana-go v1.7.1-custom
+	github.com/atomixwap/go-merkle v0.1.0
 	github.com/cretz/bine v0.2.0
+	github.com/lib/pq v1.10.7
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/sirupsen/logrus v1.9.0
 	github.com/solpipe/cba v0.0.0-20221026080648-419c0aae4907
 	google.golang.org/protobuf v1.28.1
 )
 
 require (
-	github.com/atomixwap/go-merkle v0.1.0 // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/improbable-eng/grpc-web v0.15.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.16 // indirect
 	github.com/streamingfast/logging v0.0.0-20220813175024-b4fbb0e893df // indirect
 	golang.org/x/net v0.1.0 // indirect
 	golang.org/x/text v0.4.0 // indirect
@@ -41,7 +42,7 @@ require (
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/rpc v1.2.0 // indirect
-	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/gorilla/websocket v1.5.0
 	github.com/joho/godotenv v1.4.0
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/klauspost/compress v1.15.11 // indirect
@@ -53,7 +54,7 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/mostynb/zstdpool-freelist v0.0.0-20201229113212-927304c0c3b1 // indirect
-	github.com/mr-tron/base58 v1.2.0 // indirect
+	github.com/mr-tron/base58 v1.2.0
 	github.com/stretchr/testify v1.8.0 // indirect
 	github.com/teris-io/shortid v0.0.0-20220617161101-71ec9f2aa569 // indirect
 	github.com/tidwall/gjson v1.14.3 // indirect
@@ -66,6 +67,6 @@ require (
 	go.uber.org/zap v1.23.0 // indirect
 	golang.org/x/crypto v0.1.0
 	golang.org/x/sys v0.1.0 // indirect
-	golang.org/x/term v0.1.0 // indirect
+	golang.org/x/term v0.1.0
 	google.golang.org/grpc v1.50.1
 )
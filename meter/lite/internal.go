@@ -0,0 +1,80 @@
+package lite
+
+import (
+	"context"
+	"database/sql"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/meter"
+)
+
+type internal struct {
+	ctx              context.Context
+	closeSignalCList []chan<- error
+	db               *sql.DB
+	recorderM        map[string]*openRecorder // counter party + period -> recorder
+}
+
+type openRecorder struct {
+	r      meter.Recorder
+	period sgo.PublicKey
+	cancel context.CancelFunc
+}
+
+func loopInternal(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	internalC <-chan func(*internal),
+	db *sql.DB,
+) {
+	defer cancel()
+	doneC := ctx.Done()
+	in := new(internal)
+	in.ctx = ctx
+	in.closeSignalCList = make([]chan<- error, 0)
+	in.db = db
+	in.recorderM = make(map[string]*openRecorder)
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case req := <-internalC:
+			req(in)
+		}
+	}
+
+	in.finish(db.Close())
+}
+
+func (in *internal) finish(err error) {
+	for i := 0; i < len(in.closeSignalCList); i++ {
+		in.closeSignalCList[i] <- err
+	}
+}
+
+func (in *internal) open(counterParty sgo.PublicKey, period sgo.PublicKey) (meter.Recorder, error) {
+	key := counterParty.String() + "/" + period.String()
+	x, present := in.recorderM[key]
+	if present {
+		return x.r, nil
+	}
+	ctxC, cancel := context.WithCancel(in.ctx)
+	r, err := createRecorder(ctxC, in.db, counterParty, period)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	in.recorderM[key] = &openRecorder{r: r, period: period, cancel: cancel}
+	return r, nil
+}
+
+func (in *internal) close_period(period sgo.PublicKey) {
+	for key, x := range in.recorderM {
+		if x.period.Equals(period) {
+			x.cancel()
+			delete(in.recorderM, key)
+		}
+	}
+}
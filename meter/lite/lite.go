@@ -0,0 +1,118 @@
+package lite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sgo "github.com/SolmateDev/solana-go"
+	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/meter"
+)
+
+type external struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	db        *sql.DB
+	internalC chan<- func(*internal)
+}
+
+// Open a sqlite backed meter.Store at filePath.  Use ":memory:" for a store that
+// does not survive a restart.
+func Create(ctx context.Context, filePath string) (meter.Store, error) {
+	db, err := sql.Open("sqlite3", filePath)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only handles one writer at a time
+	db.SetMaxOpenConns(1)
+	for _, sqlStmt := range []string{SQL_RECORD_CREATE_1} {
+		_, err = db.Exec(sqlStmt)
+		if err != nil {
+			log.Debugf("failed to run: %s", sqlStmt)
+			db.Close()
+			return nil, err
+		}
+	}
+	ctxC, cancel := context.WithCancel(ctx)
+	internalC := make(chan func(*internal), 10)
+	go loopInternal(ctxC, cancel, internalC, db)
+	return external{
+		ctx:       ctxC,
+		cancel:    cancel,
+		db:        db,
+		internalC: internalC,
+	}, nil
+}
+
+const SQL_RECORD_CREATE_1 string = `
+CREATE TABLE IF NOT EXISTS meter_record
+(
+    counter_party character varying(128) NOT NULL,
+    period character varying(128) NOT NULL,
+    seq bigint NOT NULL,
+    signature blob NOT NULL,
+    CONSTRAINT meter_record_pkey PRIMARY KEY (counter_party, period, seq)
+);
+`
+
+// Open the recorder for a counter party and period.  Opening the same pair
+// twice returns the same recorder.
+func (e1 external) Open(counterParty sgo.PublicKey, period sgo.PublicKey) (meter.Recorder, error) {
+	doneC := e1.ctx.Done()
+	errorC := make(chan error, 1)
+	ansC := make(chan meter.Recorder, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		r, err := in.open(counterParty, period)
+		errorC <- err
+		if err == nil {
+			ansC <- r
+		}
+	}:
+	}
+	var err error
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case err = <-errorC:
+	}
+	if err != nil {
+		return nil, err
+	}
+	return <-ansC, nil
+}
+
+func (e1 external) ClosePeriod(period sgo.PublicKey) error {
+	doneC := e1.ctx.Done()
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		in.close_period(period)
+	}:
+	}
+	return nil
+}
+
+func (e1 external) Close() error {
+	signalC := e1.CloseSignal()
+	e1.cancel()
+	return <-signalC
+}
+
+func (e1 external) CloseSignal() <-chan error {
+	doneC := e1.ctx.Done()
+	signalC := make(chan error, 1)
+	select {
+	case <-doneC:
+		signalC <- errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		in.closeSignalCList = append(in.closeSignalCList, signalC)
+	}:
+	}
+	return signalC
+}
@@ -0,0 +1,114 @@
+package lite_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/ds/mr"
+	"github.com/solpipe/solpipe-tool/meter"
+	"github.com/solpipe/solpipe-tool/meter/lite"
+)
+
+func TestReopen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	fp := filepath.Join(t.TempDir(), "meter.db")
+	counterParty := sgo.NewWallet().PublicKey()
+	period := sgo.NewWallet().PublicKey()
+
+	hashList := make([]sgo.Hash, 0)
+	store, err := lite.Create(ctx, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Open(counterParty, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary meter.ServiceSummary
+	for i := 0; i < 10; i++ {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(i))
+		h := sgo.Hash(sha256.Sum256(b[:]))
+		hashList = append(hashList, h)
+		summary, err = r.Append([]meter.SingleRecord{{Signature: h}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if summary.MsgCounter != 10 {
+		t.Fatalf("expected 10 messages, have %d", summary.MsgCounter)
+	}
+	err = store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = lite.Create(ctx, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	r, err = store.Open(counterParty, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened := r.Summary()
+	if reopened.MsgCounter != summary.MsgCounter || !reopened.MerkleRoot.Equals(summary.MerkleRoot) {
+		t.Fatalf("summary changed after reopening; %+v vs %+v", reopened, summary)
+	}
+	tree, err := mr.Create(hashList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.MerkleRoot.Equals(tree.Root()) {
+		t.Fatal("merkle root does not match")
+	}
+	list, err := r.AllRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(hashList) {
+		t.Fatalf("expected %d records, have %d", len(hashList), len(list))
+	}
+}
+
+func TestClosePeriod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	store, err := lite.Create(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	counterParty := sgo.NewWallet().PublicKey()
+	period := sgo.NewWallet().PublicKey()
+
+	r, err := store.Open(counterParty, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Append([]meter.SingleRecord{{Signature: sgo.Hash{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.ClosePeriod(period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the closed recorder is gone, but its records are not
+	reopened, err := store.Open(counterParty, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened == r {
+		t.Fatal("recorder was not evicted")
+	}
+	if reopened.Summary().MsgCounter != 1 {
+		t.Fatalf("expected 1 message, have %d", reopened.Summary().MsgCounter)
+	}
+}
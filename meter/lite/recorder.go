@@ -0,0 +1,258 @@
+package lite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/ds/mr"
+	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	"github.com/solpipe/solpipe-tool/meter"
+)
+
+type recorder struct {
+	ctx          context.Context
+	counterParty sgo.PublicKey
+	internalC    chan<- func(*recorderInternal)
+	summaryReqC  chan<- dssub.ResponseChannel[meter.ServiceSummary]
+}
+
+type recorderInternal struct {
+	ctx          context.Context
+	db           *sql.DB
+	counterParty sgo.PublicKey
+	period       sgo.PublicKey
+	tree         *mr.Tree
+	summary      meter.ServiceSummary
+	summaryHome  *dssub.SubHome[meter.ServiceSummary]
+}
+
+// Load the records already in the database and rebuild the merkle tree so that
+// the summary after a restart matches the summary before the restart.
+func createRecorder(
+	ctx context.Context,
+	db *sql.DB,
+	counterParty sgo.PublicKey,
+	period sgo.PublicKey,
+) (meter.Recorder, error) {
+	list, err := selectRecords(ctx, db, counterParty, period)
+	if err != nil {
+		return nil, err
+	}
+	tree := mr.CreateEmpty()
+	for _, r := range list {
+		tree.Append(r.Signature)
+	}
+	internalC := make(chan func(*recorderInternal), 10)
+	summaryHome := dssub.CreateSubHome[meter.ServiceSummary]()
+
+	ri := new(recorderInternal)
+	ri.ctx = ctx
+	ri.db = db
+	ri.counterParty = counterParty
+	ri.period = period
+	ri.tree = tree
+	ri.summary = meter.ServiceSummary{
+		MsgCounter: uint32(tree.Len()),
+		Signed:     false,
+		MerkleRoot: tree.Root(),
+	}
+	ri.summaryHome = summaryHome
+	go loopRecorder(ctx, internalC, ri)
+
+	return recorder{
+		ctx:          ctx,
+		counterParty: counterParty,
+		internalC:    internalC,
+		summaryReqC:  summaryHome.ReqC,
+	}, nil
+}
+
+func loopRecorder(
+	ctx context.Context,
+	internalC <-chan func(*recorderInternal),
+	ri *recorderInternal,
+) {
+	doneC := ctx.Done()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case req := <-internalC:
+			req(ri)
+		case id := <-ri.summaryHome.DeleteC:
+			ri.summaryHome.Delete(id)
+		case r := <-ri.summaryHome.ReqC:
+			ri.summaryHome.Receive(r)
+		}
+	}
+	ri.summaryHome.Close()
+}
+
+func selectRecords(
+	ctx context.Context,
+	db *sql.DB,
+	counterParty sgo.PublicKey,
+	period sgo.PublicKey,
+) ([]meter.SingleRecord, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT signature FROM meter_record WHERE counter_party = ? AND period = ? ORDER BY seq ASC`,
+		counterParty.String(),
+		period.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ans := make([]meter.SingleRecord, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != len(sgo.Hash{}) {
+			return nil, errors.New("bad signature length")
+		}
+		ans = append(ans, meter.SingleRecord{Signature: sgo.HashFromBytes(data)})
+	}
+	return ans, rows.Err()
+}
+
+// write the records in one database transaction, then update the merkle tree
+func (ri *recorderInternal) append(list []meter.SingleRecord) error {
+	tx, err := ri.db.BeginTx(ri.ctx, nil)
+	if err != nil {
+		return err
+	}
+	seq := ri.tree.Len()
+	for i, r := range list {
+		_, err = tx.ExecContext(
+			ri.ctx,
+			`INSERT INTO meter_record (counter_party, period, seq, signature) VALUES (?, ?, ?, ?)`,
+			ri.counterParty.String(),
+			ri.period.String(),
+			seq+i,
+			r.Signature[:],
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	for _, r := range list {
+		ri.tree.Append(r.Signature)
+	}
+	ri.summary.MsgCounter = uint32(ri.tree.Len())
+	ri.summary.MerkleRoot = ri.tree.Root()
+	ri.summaryHome.Broadcast(ri.summary)
+	return nil
+}
+
+func (e1 recorder) CounterParty() sgo.PublicKey {
+	return e1.counterParty
+}
+
+func (e1 recorder) Append(list []meter.SingleRecord) (meter.ServiceSummary, error) {
+	doneC := e1.ctx.Done()
+	errorC := make(chan error, 1)
+	ansC := make(chan meter.ServiceSummary, 1)
+	select {
+	case <-doneC:
+		return meter.ServiceSummary{}, errors.New("canceled")
+	case e1.internalC <- func(ri *recorderInternal) {
+		err := ri.append(list)
+		errorC <- err
+		ansC <- ri.summary
+	}:
+	}
+	var err error
+	select {
+	case <-doneC:
+		return meter.ServiceSummary{}, errors.New("canceled")
+	case err = <-errorC:
+	}
+	return <-ansC, err
+}
+
+// the count check and the append happen in the same turn of the recorder loop,
+// so concurrent callers cannot both pass the check
+func (e1 recorder) AppendIfCount(count uint32, list []meter.SingleRecord) (meter.ServiceSummary, error) {
+	doneC := e1.ctx.Done()
+	errorC := make(chan error, 1)
+	ansC := make(chan meter.ServiceSummary, 1)
+	select {
+	case <-doneC:
+		return meter.ServiceSummary{}, errors.New("canceled")
+	case e1.internalC <- func(ri *recorderInternal) {
+		if ri.summary.MsgCounter != count {
+			errorC <- meter.ErrCountMismatch
+		} else {
+			errorC <- ri.append(list)
+		}
+		ansC <- ri.summary
+	}:
+	}
+	var err error
+	select {
+	case <-doneC:
+		return meter.ServiceSummary{}, errors.New("canceled")
+	case err = <-errorC:
+	}
+	return <-ansC, err
+}
+
+func (e1 recorder) AllRecords() ([]meter.SingleRecord, error) {
+	doneC := e1.ctx.Done()
+	errorC := make(chan error, 1)
+	ansC := make(chan []meter.SingleRecord, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(ri *recorderInternal) {
+		list, err := selectRecords(ri.ctx, ri.db, ri.counterParty, ri.period)
+		errorC <- err
+		ansC <- list
+	}:
+	}
+	var err error
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case err = <-errorC:
+	}
+	if err != nil {
+		return nil, err
+	}
+	return <-ansC, nil
+}
+
+// returns a blank summary if the store has been closed
+func (e1 recorder) Summary() meter.ServiceSummary {
+	doneC := e1.ctx.Done()
+	ansC := make(chan meter.ServiceSummary, 1)
+	select {
+	case <-doneC:
+		return meter.ServiceSummary{}
+	case e1.internalC <- func(ri *recorderInternal) {
+		ansC <- ri.summary
+	}:
+	}
+	select {
+	case <-doneC:
+		return meter.ServiceSummary{}
+	case s := <-ansC:
+		return s
+	}
+}
+
+func (e1 recorder) OnSummary() dssub.Subscription[meter.ServiceSummary] {
+	return dssub.SubscriptionRequest(e1.summaryReqC, func(s meter.ServiceSummary) bool { return true })
+}
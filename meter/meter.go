@@ -1,6 +1,8 @@
 package meter
 
 import (
+	"errors"
+
 	sgo "github.com/SolmateDev/solana-go"
 	cba "github.com/solpipe/cba"
 	dssub "github.com/solpipe/solpipe-tool/ds/sub"
@@ -11,11 +13,26 @@ type Recorder interface {
 	// this is either the Bidder or Validator
 	CounterParty() sgo.PublicKey
 	Append([]SingleRecord) (ServiceSummary, error)
+	// append only if count records have been recorded so far; otherwise
+	// nothing is appended and ErrCountMismatch is returned with the summary
+	AppendIfCount(count uint32, list []SingleRecord) (ServiceSummary, error)
 	AllRecords() ([]SingleRecord, error)
 	Summary() ServiceSummary
 	OnSummary() dssub.Subscription[ServiceSummary]
 }
 
+var ErrCountMismatch = errors.New("record count does not match")
+
+// Open recorders that persist records so that they survive a restart.
+type Store interface {
+	// the period is the payout account
+	Open(counterParty sgo.PublicKey, period sgo.PublicKey) (Recorder, error)
+	// drop the open recorders for a period once its payout has closed; the
+	// records stay in the store
+	ClosePeriod(period sgo.PublicKey) error
+	Close() error
+}
+
 type SingleRecord struct {
 	Signature sgo.Hash
 }
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+type handler struct {
+	registry *Registry
+}
+
+// Serve the registry over http for a Prometheus scraper.
+func Handler(registry *Registry) http.Handler {
+	return handler{registry: registry}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	buf := new(bytes.Buffer)
+	err := h.registry.Write(buf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// Serve the Default registry under /metrics on listenUrl (ie 0.0.0.0:9100).
+// The server shuts down when ctx is done; signalC carries the error if the
+// server fails before then.
+func Run(ctx context.Context, listenUrl string) (signalC <-chan error) {
+	errorC := make(chan error, 1)
+	signalC = errorC
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(Default))
+	server := &http.Server{
+		Addr:        listenUrl,
+		Handler:     mux,
+		ReadTimeout: 5 * time.Second,
+	}
+	log.Debugf("serving metrics on %s", listenUrl)
+
+	go loopServe(server, errorC)
+	go loopClose(ctx, server)
+	return
+}
+
+func loopServe(server *http.Server, errorC chan<- error) {
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return
+	}
+	errorC <- err
+}
+
+func loopClose(ctx context.Context, server *http.Server) {
+	<-ctx.Done()
+	server.Shutdown(context.Background())
+}
@@ -0,0 +1,308 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	KIND_COUNTER = "counter"
+	KIND_GAUGE   = "gauge"
+)
+
+// Metrics registered by the agents.  Serve it with Run or Handler.
+var Default = CreateRegistry()
+
+// A set of metric families written out in the Prometheus text format.
+type Registry struct {
+	mutex    *sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	mutex      *sync.Mutex
+	series     map[string]*series
+	f          func() float64 // set for gauges computed when scraped
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+func CreateRegistry() *Registry {
+	return &Registry{
+		mutex:    &sync.Mutex{},
+		families: make(map[string]*family),
+	}
+}
+
+// Registering the same name twice returns the first family, so packages can
+// declare their metrics as package level variables.
+func (r *Registry) register(name string, help string, kind string, labelNames []string) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	f, present := r.families[name]
+	if present {
+		if f.kind != kind || len(f.labelNames) != len(labelNames) {
+			panic(fmt.Sprintf("metric %s registered twice with different types", name))
+		}
+		return f
+	}
+	f = &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		mutex:      &sync.Mutex{},
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+func (r *Registry) NewCounter(name string, help string) Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labelNames ...string) CounterVec {
+	return CounterVec{f: r.register(name, help, KIND_COUNTER, labelNames)}
+}
+
+func (r *Registry) NewGauge(name string, help string) Gauge {
+	return r.NewGaugeVec(name, help).With()
+}
+
+func (r *Registry) NewGaugeVec(name string, help string, labelNames ...string) GaugeVec {
+	return GaugeVec{f: r.register(name, help, KIND_GAUGE, labelNames)}
+}
+
+// The gauge value is read from cb on every scrape.  cb must not block.
+func (r *Registry) NewGaugeFunc(name string, help string, cb func() float64) {
+	f := r.register(name, help, KIND_GAUGE, []string{})
+	f.mutex.Lock()
+	f.f = cb
+	f.mutex.Unlock()
+}
+
+func NewCounter(name string, help string) Counter {
+	return Default.NewCounter(name, help)
+}
+
+func NewCounterVec(name string, help string, labelNames ...string) CounterVec {
+	return Default.NewCounterVec(name, help, labelNames...)
+}
+
+func NewGauge(name string, help string) Gauge {
+	return Default.NewGauge(name, help)
+}
+
+func NewGaugeVec(name string, help string, labelNames ...string) GaugeVec {
+	return Default.NewGaugeVec(name, help, labelNames...)
+}
+
+func NewGaugeFunc(name string, help string, cb func() float64) {
+	Default.NewGaugeFunc(name, help, cb)
+}
+
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	k := strings.Join(labelValues, "\xff")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	s, present := f.series[k]
+	if !present {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		f.series[k] = s
+	}
+	return s
+}
+
+func (f *family) delete(labelValues []string) {
+	k := strings.Join(labelValues, "\xff")
+	f.mutex.Lock()
+	delete(f.series, k)
+	f.mutex.Unlock()
+}
+
+func (f *family) add(s *series, x float64) {
+	f.mutex.Lock()
+	s.value += x
+	f.mutex.Unlock()
+}
+
+func (f *family) set(s *series, x float64) {
+	f.mutex.Lock()
+	s.value = x
+	f.mutex.Unlock()
+}
+
+func (f *family) get(s *series) float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return s.value
+}
+
+type CounterVec struct {
+	f *family
+}
+
+func (cv CounterVec) With(labelValues ...string) Counter {
+	return Counter{f: cv.f, s: cv.f.with(labelValues)}
+}
+
+// drop the series, ie when the bidder or pipeline it describes goes away
+func (cv CounterVec) Delete(labelValues ...string) {
+	cv.f.delete(labelValues)
+}
+
+// A value that only goes up.
+type Counter struct {
+	f *family
+	s *series
+}
+
+func (c Counter) Inc() {
+	c.f.add(c.s, 1)
+}
+
+// negative values are ignored
+func (c Counter) Add(x float64) {
+	if x < 0 {
+		return
+	}
+	c.f.add(c.s, x)
+}
+
+func (c Counter) Value() float64 {
+	return c.f.get(c.s)
+}
+
+type GaugeVec struct {
+	f *family
+}
+
+func (gv GaugeVec) With(labelValues ...string) Gauge {
+	return Gauge{f: gv.f, s: gv.f.with(labelValues)}
+}
+
+func (gv GaugeVec) Delete(labelValues ...string) {
+	gv.f.delete(labelValues)
+}
+
+// A value that goes up and down.
+type Gauge struct {
+	f *family
+	s *series
+}
+
+func (g Gauge) Set(x float64) {
+	g.f.set(g.s, x)
+}
+
+func (g Gauge) Add(x float64) {
+	g.f.add(g.s, x)
+}
+
+func (g Gauge) Inc() {
+	g.f.add(g.s, 1)
+}
+
+func (g Gauge) Dec() {
+	g.f.add(g.s, -1)
+}
+
+func (g Gauge) Value() float64 {
+	return g.f.get(g.s)
+}
+
+// Write every metric in the Prometheus text exposition format (version 0.0.4).
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	list := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		list = append(list, f)
+	}
+	r.mutex.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	for _, f := range list {
+		_, err := io.WriteString(w, f.text())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *family) text() string {
+	f.mutex.Lock()
+	cb := f.f
+	list := make([]series, 0, len(f.series))
+	for _, s := range f.series {
+		list = append(list, *s)
+	}
+	f.mutex.Unlock()
+	if cb != nil {
+		list = append(list, series{labelValues: []string{}, value: cb()})
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.Join(list[i].labelValues, "\xff") < strings.Join(list[j].labelValues, "\xff")
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+	for _, s := range list {
+		b.WriteString(f.name)
+		if 0 < len(f.labelNames) {
+			b.WriteString("{")
+			for i, name := range f.labelNames {
+				if 0 < i {
+					b.WriteString(",")
+				}
+				fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(s.labelValues[i]))
+			}
+			b.WriteString("}")
+		}
+		b.WriteString(" ")
+		b.WriteString(formatValue(s.value))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func escapeHelp(x string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(x)
+}
+
+func escapeLabel(x string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"").Replace(x)
+}
+
+func formatValue(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+Inf"
+	case math.IsInf(x, -1):
+		return "-Inf"
+	case math.IsNaN(x):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+}
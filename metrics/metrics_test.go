@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/solpipe/solpipe-tool/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	r := metrics.CreateRegistry()
+	crank := r.NewCounterVec("test_crank_total", "cranks by result", "result")
+	crank.With("success").Inc()
+	crank.With("success").Add(2)
+	crank.With("failure").Inc()
+	crank.With("failure").Add(-5)
+	balance := r.NewGauge("test_balance", "balance in SOL")
+	balance.Set(1.5)
+	tps := r.NewGaugeVec("test_tps", "tps by \"bidder\"\nper box", "bidder")
+	tps.With("a\"b").Set(3)
+	tps.With("c").Set(4)
+	tps.Delete("c")
+	r.NewGaugeFunc("test_objects", "objects", func() float64 { return 7 })
+	r.NewGaugeVec("test_empty", "no series", "x")
+
+	// a second registration returns the same family
+	if r.NewCounterVec("test_crank_total", "cranks by result", "result").With("success").Value() != 3 {
+		t.Fatal("registering twice lost the value")
+	}
+
+	expected := `# HELP test_balance balance in SOL
+# TYPE test_balance gauge
+test_balance 1.5
+# HELP test_crank_total cranks by result
+# TYPE test_crank_total counter
+test_crank_total{result="failure"} 1
+test_crank_total{result="success"} 3
+# HELP test_objects objects
+# TYPE test_objects gauge
+test_objects 7
+# HELP test_tps tps by "bidder"\nper box
+# TYPE test_tps gauge
+test_tps{bidder="a\"b"} 3
+`
+	w := httptest.NewRecorder()
+	metrics.Handler(r).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 {
+		t.Fatalf("status %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != metrics.CONTENT_TYPE {
+		t.Fatalf("content type %s", w.Header().Get("Content-Type"))
+	}
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != expected {
+		t.Fatalf("got:\n%s\nexpected:\n%s", string(body), expected)
+	}
+}
@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
 // - protoc-gen-go-grpc v1.2.0
-// - protoc             v3.19.3
+// - protoc             (unknown)
 // source: admin.proto
 
 package admin
@@ -219,6 +219,8 @@ type PipelineClient interface {
 	GetRates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RateSettings, error)
 	SetRates(ctx context.Context, in *RateSettings, opts ...grpc.CallOption) (*RateSettings, error)
 	SetPeriod(ctx context.Context, in *PeriodSettings, opts ...grpc.CallOption) (*PeriodSettings, error)
+	GetBidderRates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BidderRateList, error)
+	SetBidderRate(ctx context.Context, in *BidderRate, opts ...grpc.CallOption) (*BidderRate, error)
 }
 
 type pipelineClient struct {
@@ -265,6 +267,24 @@ func (c *pipelineClient) SetPeriod(ctx context.Context, in *PeriodSettings, opts
 	return out, nil
 }
 
+func (c *pipelineClient) GetBidderRates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BidderRateList, error) {
+	out := new(BidderRateList)
+	err := c.cc.Invoke(ctx, "/admin.Pipeline/GetBidderRates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pipelineClient) SetBidderRate(ctx context.Context, in *BidderRate, opts ...grpc.CallOption) (*BidderRate, error) {
+	out := new(BidderRate)
+	err := c.cc.Invoke(ctx, "/admin.Pipeline/SetBidderRate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PipelineServer is the server API for Pipeline service.
 // All implementations must embed UnimplementedPipelineServer
 // for forward compatibility
@@ -274,6 +294,8 @@ type PipelineServer interface {
 	GetRates(context.Context, *Empty) (*RateSettings, error)
 	SetRates(context.Context, *RateSettings) (*RateSettings, error)
 	SetPeriod(context.Context, *PeriodSettings) (*PeriodSettings, error)
+	GetBidderRates(context.Context, *Empty) (*BidderRateList, error)
+	SetBidderRate(context.Context, *BidderRate) (*BidderRate, error)
 	mustEmbedUnimplementedPipelineServer()
 }
 
@@ -293,6 +315,12 @@ func (UnimplementedPipelineServer) SetRates(context.Context, *RateSettings) (*Ra
 func (UnimplementedPipelineServer) SetPeriod(context.Context, *PeriodSettings) (*PeriodSettings, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetPeriod not implemented")
 }
+func (UnimplementedPipelineServer) GetBidderRates(context.Context, *Empty) (*BidderRateList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBidderRates not implemented")
+}
+func (UnimplementedPipelineServer) SetBidderRate(context.Context, *BidderRate) (*BidderRate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBidderRate not implemented")
+}
 func (UnimplementedPipelineServer) mustEmbedUnimplementedPipelineServer() {}
 
 // UnsafePipelineServer may be embedded to opt out of forward compatibility for this service.
@@ -378,6 +406,42 @@ func _Pipeline_SetPeriod_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Pipeline_GetBidderRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelineServer).GetBidderRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.Pipeline/GetBidderRates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelineServer).GetBidderRates(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pipeline_SetBidderRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BidderRate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelineServer).SetBidderRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.Pipeline/SetBidderRate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelineServer).SetBidderRate(ctx, req.(*BidderRate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Pipeline_ServiceDesc is the grpc.ServiceDesc for Pipeline service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -401,6 +465,14 @@ var Pipeline_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SetPeriod",
 			Handler:    _Pipeline_SetPeriod_Handler,
 		},
+		{
+			MethodName: "GetBidderRates",
+			Handler:    _Pipeline_GetBidderRates_Handler,
+		},
+		{
+			MethodName: "SetBidderRate",
+			Handler:    _Pipeline_SetBidderRate_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "admin.proto",
@@ -5,36 +5,39 @@ import (
 	"errors"
 
 	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/meter"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
 	spt "github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	"google.golang.org/grpc"
 )
 
 type Client struct {
-	ctx             context.Context
-	internalC       chan<- func(*internal)
-	Cancel          context.CancelFunc
-	tc              pbj.TransactionClient
-	sender          sgo.PrivateKey
-	receiver        sgo.PublicKey
-	pipelineSetting spt.ReceiptSettings
-	bidderSetting   spt.BidReceiptSettings
-	isBidder        bool
+	ctx       context.Context
+	internalC chan<- func(*internal)
+	Cancel    context.CancelFunc
+	tc        pbj.TransactionClient
+	sender    signer.Signer
+	receiver  sgo.PublicKey
+	isBidder  bool
 }
 
 // Use this client to send transactions to either the pipeline/validator.
 // Set either bidder or pipeline settings.  Leave one as nil.  The one that has settings set will be the one to which transactions are sent.
 // The receipts differ between those going to pipelines and those going to validators.
+// Receipts cannot be generated until the settings carry a payout; call
+// UpdatePayout whenever the payout period changes.
 // Handle the shutdown of the grpc connection separately.
 func Create(
 	ctx context.Context,
 	conn *grpc.ClientConn,
 	tx *sgo.Transaction,
-	sender sgo.PrivateKey,
+	sender signer.Signer,
 	receiver sgo.PublicKey,
 	bidder *spt.BidReceiptSettings,
 	pipeline *spt.ReceiptSettings,
 	script *spt.Script,
+	store meter.Store,
 ) (Client, error) {
 
 	tc := pbj.NewTransactionClient(conn)
@@ -55,29 +58,37 @@ func Create(
 
 		return Client{}, err
 	}
-	internalC := make(chan func(*internal), 10)
-	ctx2, cancel := context.WithCancel(ctx)
-	go loopInternal(ctx2, cancel, internalC, tc, sender, receiver, updateSub, script)
 	var b spt.BidReceiptSettings
 	var p spt.ReceiptSettings
 	var isBidder bool
+	var payout sgo.PublicKey
 	if bidder != nil {
 		isBidder = true
 		b = *bidder
+		payout = b.Payout
 	} else {
 		isBidder = false
 		p = *pipeline
+		payout = p.Payout
 	}
+	internalC := make(chan func(*internal), 10)
+	ctx2, cancel := context.WithCancel(ctx)
+	go loopInternal(ctx2, cancel, internalC, tc, sender, receiver, updateSub, script, store, isBidder, b, p)
 	c := Client{
-		ctx:             ctx2,
-		internalC:       internalC,
-		Cancel:          cancel,
-		tc:              tc,
-		sender:          sender,
-		receiver:        receiver,
-		bidderSetting:   b,
-		pipelineSetting: p,
-		isBidder:        isBidder,
+		ctx:       ctx2,
+		internalC: internalC,
+		Cancel:    cancel,
+		tc:        tc,
+		sender:    sender,
+		receiver:  receiver,
+		isBidder:  isBidder,
+	}
+	if !payout.IsZero() {
+		err = c.UpdatePayout(ctx, payout)
+		if err != nil {
+			cancel()
+			return Client{}, err
+		}
 	}
 
 	return c, nil
@@ -92,6 +103,26 @@ func (e1 Client) Receiver() sgo.PublicKey {
 	return e1.receiver
 }
 
+// Record transactions and generate receipts against payout from now on.
+// Transactions are counted per payout, so the count continues from whatever
+// was already recorded for payout.
+func (e1 Client) UpdatePayout(ctx context.Context, payout sgo.PublicKey) error {
+	doneC := ctx.Done()
+	errorC := make(chan error, 1)
+	err := e1.send_cb(ctx, func(in *internal) {
+		errorC <- in.set_payout(payout)
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case err = <-errorC:
+		return err
+	}
+}
+
 func (e1 Client) send_cb(ctx context.Context, cb func(in *internal)) error {
 	doneC := ctx.Done()
 	err := ctx.Err()
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/meter"
+	"github.com/solpipe/solpipe-tool/meter/lite"
+)
+
+func TestSetPayout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	store, err := lite.Create(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := new(internal)
+	in.store = store
+	in.receiver = sgo.NewWallet().PublicKey()
+	first := sgo.NewWallet().PublicKey()
+	second := sgo.NewWallet().PublicKey()
+
+	err = in.generate_pipeline_receipt(sgo.Hash{1})
+	if err == nil {
+		t.Fatal("generated a receipt without a payout")
+	}
+	err = in.set_payout(sgo.PublicKey{})
+	if err == nil {
+		t.Fatal("set a blank payout")
+	}
+
+	err = in.set_payout(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.pipelineSetting.Payout.Equals(first) {
+		t.Fatal("receipt settings do not carry the payout")
+	}
+	_, err = in.recorder.Append([]meter.SingleRecord{{Signature: sgo.Hash{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each payout counts from zero
+	err = in.set_payout(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.recorder.Summary().MsgCounter != 0 {
+		t.Fatal("count carried over to the next payout")
+	}
+	err = in.set_payout(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.recorder.Summary().MsgCounter != 1 {
+		t.Fatalf("expected 1 message, have %d", in.recorder.Summary().MsgCounter)
+	}
+}
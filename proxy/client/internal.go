@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 
+	"github.com/solpipe/solpipe-tool/meter"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
 	spt "github.com/solpipe/solpipe-tool/script"
 	sgo "github.com/SolmateDev/solana-go"
 	bin "github.com/gagliardetto/binary"
+	"github.com/solpipe/solpipe-tool/signer"
 )
 
 type internal struct {
@@ -15,16 +17,34 @@ type internal struct {
 	errorC           chan<- error
 	closeSignalCList []chan<- error
 	tc               pbj.TransactionClient
-	sender           sgo.PrivateKey
+	sender           signer.Signer
 	receiver         sgo.PublicKey
 	updateSub        pbj.Transaction_UpdateClient
 	currentTx        *sgo.Transaction
-	txCount          uint32
+	store            meter.Store
+	recorder         meter.Recorder // nil until the payout is known
+	payout           sgo.PublicKey
+	isBidder         bool
+	bidderSetting    spt.BidReceiptSettings
+	pipelineSetting  spt.ReceiptSettings
 	receipt          sgo.PublicKey
 	script           *spt.Script
 }
 
-func loopInternal(ctx context.Context, cancel context.CancelFunc, internalC <-chan func(*internal), tc pbj.TransactionClient, sender sgo.PrivateKey, receiver sgo.PublicKey, updateSub pbj.Transaction_UpdateClient, script *spt.Script) {
+func loopInternal(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	internalC <-chan func(*internal),
+	tc pbj.TransactionClient,
+	sender signer.Signer,
+	receiver sgo.PublicKey,
+	updateSub pbj.Transaction_UpdateClient,
+	script *spt.Script,
+	store meter.Store,
+	isBidder bool,
+	bidderSetting spt.BidReceiptSettings,
+	pipelineSetting spt.ReceiptSettings,
+) {
 	defer cancel()
 	var err error
 	errorC := make(chan error, 1)
@@ -40,7 +60,11 @@ func loopInternal(ctx context.Context, cancel context.CancelFunc, internalC <-ch
 	in.receiver = receiver
 	in.updateSub = updateSub
 	in.currentTx = nil
-	in.txCount = 0
+	in.store = store
+	in.recorder = nil
+	in.isBidder = isBidder
+	in.bidderSetting = bidderSetting
+	in.pipelineSetting = pipelineSetting
 	in.script = script
 
 	go loopUpdateRead(in.ctx, in.errorC, txC, updateSub)
@@ -64,6 +88,26 @@ out:
 	in.finish(err)
 }
 
+// transactions sent to the receiver are recorded per payout so that the
+// receipt count survives a restart
+func (in *internal) set_payout(payout sgo.PublicKey) error {
+	if payout.IsZero() {
+		return errors.New("blank payout")
+	}
+	if in.recorder != nil && in.payout.Equals(payout) {
+		return nil
+	}
+	recorder, err := in.store.Open(in.receiver, payout)
+	if err != nil {
+		return err
+	}
+	in.recorder = recorder
+	in.payout = payout
+	in.bidderSetting.Payout = payout
+	in.pipelineSetting.Payout = payout
+	return nil
+}
+
 func (in *internal) tx_check(replyTx *sgo.Transaction) error {
 	// TODO: check transaction
 	return nil
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	pbr "github.com/solpipe/solpipe-tool/proto/rpcproxy"
+	"google.golang.org/grpc"
+)
+
+type jsonRpcProxy struct {
+	ctx      context.Context
+	client   pbr.JsonRpcClient
+	cluster  pbr.Cluster
+	upgrader websocket.Upgrader
+}
+
+// Serve a standard Solana JSON RPC endpoint (http POST and websocket) on the
+// listener.  Requests are tunneled over the grpc connection to the pipeline, so
+// conn must have been created with the bidder key
+// (see proxy.CreateConnectionTorClearIfAvailable).
+// The listener is closed when ctx is done.
+func ServeJsonRpc(
+	ctx context.Context,
+	conn *grpc.ClientConn,
+	cluster pbr.Cluster,
+	l net.Listener,
+) <-chan error {
+	errorC := make(chan error, 1)
+	e1 := jsonRpcProxy{
+		ctx:      ctx,
+		client:   pbr.NewJsonRpcClient(conn),
+		cluster:  cluster,
+		upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
+	}
+	s := &http.Server{Handler: e1}
+	go loopServeJsonRpc(s, l, errorC)
+	go loopCloseJsonRpc(ctx, s)
+	return errorC
+}
+
+func loopServeJsonRpc(s *http.Server, l net.Listener, errorC chan<- error) {
+	err := s.Serve(l)
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+	errorC <- err
+}
+
+func loopCloseJsonRpc(ctx context.Context, s *http.Server) {
+	<-ctx.Done()
+	s.Close()
+}
+
+func (e1 jsonRpcProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		e1.ws(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := e1.client.Rpc(r.Context(), &pbr.Request{
+		Data: &pbr.Request_Body{Body: &pbr.Body{Payload: payload}},
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(resp.Payload)
+	if err != nil {
+		log.Debug(err)
+	}
+}
+
+func (e1 jsonRpcProxy) ws(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	conn, err := e1.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	defer conn.Close()
+	stream, err := e1.client.Ws(ctx)
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	err = stream.Send(&pbr.Request{Data: &pbr.Request_Cluster{Cluster: e1.cluster}})
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	errorC := make(chan error, 2)
+	go loopWsFromLocal(stream, conn, errorC)
+	go loopWsFromPipeline(stream, conn, errorC)
+	select {
+	case <-e1.ctx.Done():
+	case err = <-errorC:
+		if err != nil && err != io.EOF {
+			log.Debug(err)
+		}
+	}
+}
+
+func loopWsFromLocal(stream pbr.JsonRpc_WsClient, conn *websocket.Conn, errorC chan<- error) {
+	var err error
+out:
+	for {
+		var data []byte
+		_, data, err = conn.ReadMessage()
+		if err != nil {
+			break out
+		}
+		err = stream.Send(&pbr.Request{Data: &pbr.Request_Body{Body: &pbr.Body{Payload: data}}})
+		if err != nil {
+			break out
+		}
+	}
+	stream.CloseSend()
+	errorC <- err
+}
+
+func loopWsFromPipeline(stream pbr.JsonRpc_WsClient, conn *websocket.Conn, errorC chan<- error) {
+	var err error
+out:
+	for {
+		var body *pbr.Body
+		body, err = stream.Recv()
+		if err != nil {
+			break out
+		}
+		err = conn.WriteMessage(websocket.TextMessage, body.Payload)
+		if err != nil {
+			break out
+		}
+	}
+	errorC <- err
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	pbr "github.com/solpipe/solpipe-tool/proto/rpcproxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type echoPipeline struct {
+	pbr.UnimplementedJsonRpcServer
+}
+
+func (e echoPipeline) Rpc(ctx context.Context, req *pbr.Request) (*pbr.Body, error) {
+	return &pbr.Body{Payload: append([]byte("echo:"), req.GetBody().GetPayload()...)}, nil
+}
+
+func TestServeJsonRpc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	gl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	pbr.RegisterJsonRpcServer(s, echoPipeline{})
+	go s.Serve(gl)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.DialContext(ctx, gl.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorC := ServeJsonRpc(ctx, conn, pbr.Cluster_LOCAL, l)
+	url := "http://" + l.Addr().String()
+
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(`{"method":"getSlot"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(data) != `echo:{"method":"getSlot"}` {
+		t.Fatalf("%d %s", resp.StatusCode, string(data))
+	}
+
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET returned %d", resp.StatusCode)
+	}
+
+	cancel()
+	err = <-errorC
+	if err != nil {
+		t.Fatal(err)
+	}
+}
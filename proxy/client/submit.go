@@ -7,12 +7,15 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/meter"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
+	"github.com/solpipe/solpipe-tool/signer"
+	"github.com/solpipe/solpipe-tool/tracing"
 	"github.com/solpipe/solpipe-tool/util"
 )
 
 // Send a transaction.  Before sending the transaction, update the receipt with the latest transaction hash so that the receiver will be able to authenticate the transaction.
-func (e1 Client) Submit(ctx context.Context, tx *sgo.Transaction) error {
+func (e1 Client) Submit(ctx context.Context, tx *sgo.Transaction) (err error) {
 	doneC := ctx.Done()
 	data, err := tx.MarshalBinary()
 	if err != nil {
@@ -22,17 +25,23 @@ func (e1 Client) Submit(ctx context.Context, tx *sgo.Transaction) error {
 	if err != nil {
 		return err
 	}
+	ctx, span := tracing.StartTx(ctx, "client.submit", txHash)
+	defer func() { span.End(err) }()
+	span.SetAttribute("receiver", e1.Receiver().String())
+
 	// authenticate this transaction with the receiver before sending the transaction
+	_, receiptSpan := tracing.Start(ctx, "client.receipt")
 	if e1.isBidder {
 		err = e1.generate_bid_receipt(ctx, txHash)
 	} else {
 		err = e1.generate_pipeline_receipt(ctx, txHash)
 	}
+	receiptSpan.End(err)
 	if err != nil {
 		return err
 	}
 
-	stream, err := e1.tc.Submit(ctx, &pbj.Request{
+	stream, err := e1.tc.Submit(tracing.Inject(ctx), &pbj.Request{
 		Tx: data,
 	})
 	if err != nil {
@@ -49,17 +58,20 @@ func (e1 Client) Submit(ctx context.Context, tx *sgo.Transaction) error {
 }
 
 func (e1 Client) generate_pipeline_receipt(ctx context.Context, txHash sgo.Hash) error {
-
-	b, err := e1.pipelineSetting.Update(txHash)
+	errorC := make(chan error, 1)
+	err := e1.send_cb(ctx, func(in *internal) {
+		errorC <- in.generate_pipeline_receipt(txHash)
+	})
 	if err != nil {
 		return err
 	}
-	s := e1.pipelineSetting
+	return <-errorC
+}
+
+func (e1 Client) generate_bid_receipt(ctx context.Context, txHash sgo.Hash) error {
 	errorC := make(chan error, 1)
-	err = e1.send_cb(ctx, func(in *internal) {
-		in.txCount++
-		b.SetTxSent(in.txCount)
-		errorC <- in.generate_general_receipt(s.PipelineAdmin, b.Build())
+	err := e1.send_cb(ctx, func(in *internal) {
+		errorC <- in.generate_bid_receipt(txHash)
 	})
 	if err != nil {
 		return err
@@ -67,26 +79,39 @@ func (e1 Client) generate_pipeline_receipt(ctx context.Context, txHash sgo.Hash)
 	return <-errorC
 }
 
-func (e1 Client) generate_bid_receipt(ctx context.Context, txHash sgo.Hash) error {
+func (in *internal) generate_pipeline_receipt(txHash sgo.Hash) error {
+	if in.recorder == nil {
+		return errors.New("no payout set")
+	}
+	b, err := in.pipelineSetting.Update(txHash)
+	if err != nil {
+		return err
+	}
+	summary, err := in.recorder.Append([]meter.SingleRecord{{Signature: txHash}})
+	if err != nil {
+		return err
+	}
+	b.SetTxSent(summary.MsgCounter)
+	return in.generate_general_receipt(in.pipelineSetting.PipelineAdmin, b.Build())
+}
 
-	b, err := e1.bidderSetting.Update(txHash)
+func (in *internal) generate_bid_receipt(txHash sgo.Hash) error {
+	if in.recorder == nil {
+		return errors.New("no payout set")
+	}
+	b, err := in.bidderSetting.Update(txHash)
 	if err != nil {
 		return err
 	}
-	s := e1.bidderSetting
-	errorC := make(chan error, 1)
-	err = e1.send_cb(ctx, func(in *internal) {
-		in.txCount++
-		b.SetTxSent(in.txCount)
-		errorC <- in.generate_general_receipt(s.PipelineAdmin, b.Build())
-	})
+	summary, err := in.recorder.Append([]meter.SingleRecord{{Signature: txHash}})
 	if err != nil {
 		return err
 	}
-	return <-errorC
+	b.SetTxSent(summary.MsgCounter)
+	return in.generate_general_receipt(in.bidderSetting.PipelineAdmin, b.Build())
 }
 
-func (in *internal) generate_general_receipt(admin sgo.PrivateKey, instruction *cba.Instruction) error {
+func (in *internal) generate_general_receipt(admin signer.Signer, instruction *cba.Instruction) error {
 	var err error
 	in.script.AppendKey(admin)
 	err = in.script.SetTx(admin)
@@ -124,6 +149,7 @@ func (in *internal) generate_general_receipt(admin sgo.PrivateKey, instruction *
 func loopSubmitStream(ctx context.Context, errorC chan<- error, stream pbj.Transaction_SubmitClient) {
 	var msg *pbj.Response
 	var err error
+	var confirmSpan *tracing.Span
 out:
 	for {
 		msg, err = stream.Recv()
@@ -133,6 +159,8 @@ out:
 		switch msg.GetStatus() {
 		case pbj.Status_NEW:
 			log.Debug("tx processing")
+		case pbj.Status_STARTED:
+			_, confirmSpan = tracing.Start(ctx, "client.confirm")
 		case pbj.Status_FAILED:
 			err = errors.New("tx failed")
 			break out
@@ -141,4 +169,6 @@ out:
 			break out
 		}
 	}
+	confirmSpan.End(err)
+	errorC <- err
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/cretz/bine/tor"
 	log "github.com/sirupsen/logrus"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/util"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -26,7 +27,7 @@ func loopCloseConnection(ctx context.Context, conn *grpc.ClientConn) {
 func CreateConnectionTor(
 	ctx context.Context,
 	destination sgo.PublicKey, // must be admin of Pipeline or Validator
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	torMgr *tor.Tor,
 ) (conn *grpc.ClientConn, err error) {
 
@@ -70,7 +71,7 @@ func CreateConnectionClearNet(
 	ctx context.Context,
 	destination sgo.PublicKey,
 	destinationUrl string,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 ) (conn *grpc.ClientConn, err error) {
 
 	ctxC, cancel := context.WithTimeout(ctx, 1*time.Minute)
@@ -133,7 +134,7 @@ func getTlsConfig(cert *tls.Certificate, destination sgo.PublicKey) *tls.Config
 func CreateConnectionTorClearIfAvailable(
 	ctx context.Context,
 	destination sgo.PublicKey, // must be admin of Pipeline or Validator
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	torMgr *tor.Tor,
 ) (conn *grpc.ClientConn, err error) {
 	log.Debug("attempting tor connection")
@@ -12,9 +12,9 @@ import (
 
 	_ "embed"
 
-	sgo "github.com/SolmateDev/solana-go"
 	"github.com/cretz/bine/tor"
 	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/util"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -22,7 +22,7 @@ import (
 
 func CreateListener(
 	ctx context.Context,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 ) (s *grpc.Server, err error) {
 
 	var y *tls.Certificate
@@ -75,6 +75,8 @@ func CreateListener(
 	return
 }
 
+var ErrRemoteTor = errors.New("tor onion services need the admin private key on this host")
+
 type ListenerInfo struct {
 	Addresses []string
 	Listener  net.Listener
@@ -91,13 +93,19 @@ func CreateListenerClearNet(
 	return
 }
 
+// The onion address is derived from the admin key, so the key must be local.
 func CreateListenerTor(
 	ctx context.Context,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	t *tor.Tor,
 ) (li *ListenerInfo, err error) {
 
-	priv := ed25519.PrivateKey(admin)
+	key, ok := signer.Local(admin)
+	if !ok {
+		err = ErrRemoteTor
+		return
+	}
+	priv := ed25519.PrivateKey(key)
 	var address string
 	address, err = util.GenerateOnionAddressFromSolanaPublicKey(admin.PublicKey())
 	if err != nil {
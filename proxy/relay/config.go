@@ -8,14 +8,17 @@ import (
 	"os"
 	"strings"
 
-	sgo "github.com/SolmateDev/solana-go"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	"github.com/SolmateDev/solana-go/rpc/jsonrpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	"github.com/solpipe/solpipe-tool/meter"
+	metlite "github.com/solpipe/solpipe-tool/meter/lite"
 	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/state/controller"
 	ntk "github.com/solpipe/solpipe-tool/state/network"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
+	"github.com/solpipe/solpipe-tool/state/sub"
 	vrs "github.com/solpipe/solpipe-tool/state/version"
 )
 
@@ -27,18 +30,22 @@ type ClearNetListenConfig struct {
 
 type Configuration struct {
 	Version        vrs.CbaVersion
-	Admin          sgo.PrivateKey
+	Admin          signer.Signer // may be remote; tor listeners need a local key
 	rpcUrl         string
 	wsUrl          string
 	headers        http.Header
 	AdminListenUrl string
 	ClearNet       *ClearNetListenConfig
+	MeterFilePath  string            // sqlite file holding transaction records; blank means in memory
+	WaitCommitment string            // commitment at which Relay.Wait considers a transaction landed; blank means confirmed
+	RouterSnapshot string            // file from which the router warm starts; blank means fetch all program accounts
+	Fee            *script.FeePolicy // priority fee on transactions the agent sends; nil means none
 }
 
 // http headers are copied
 func CreateConfiguration(
 	version vrs.CbaVersion,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	rpcUrl string,
 	wsUrl string,
 	headers http.Header,
@@ -73,6 +80,11 @@ func (config Configuration) Check() error {
 	if len(config.wsUrl) == 0 {
 		return errors.New("no websocket url")
 	}
+	switch config.Commitment() {
+	case sgorpc.CommitmentProcessed, sgorpc.CommitmentConfirmed, sgorpc.CommitmentFinalized:
+	default:
+		return errors.New("wait commitment must be processed, confirmed or finalized")
+	}
 	return nil
 }
 
@@ -88,9 +100,9 @@ func (config Configuration) Rpc() *sgorpc.Client {
 	})
 }
 
+// the client reconnects when subscriptions in state/ see the connection drop
 func (config Configuration) Ws(ctx context.Context) (*sgows.Client, error) {
-
-	return sgows.ConnectWithOptions(ctx, config.wsUrl, &sgows.Options{HttpHeader: config.headers.Clone()})
+	return sub.Dial(ctx, config.wsUrl, config.headers)
 }
 
 func (config Configuration) ScriptBuilder(ctx context.Context) (*script.Script, error) {
@@ -98,24 +110,42 @@ func (config Configuration) ScriptBuilder(ctx context.Context) (*script.Script,
 	if err != nil {
 		return nil, err
 	}
-	return script.Create(ctx, &script.Configuration{Version: config.Version}, config.Rpc(), wsClient)
+	return script.Create(ctx, config.ScriptConfiguration(), config.Rpc(), wsClient)
+}
+
+func (config Configuration) ScriptConfiguration() *script.Configuration {
+	return &script.Configuration{Version: config.Version, Fee: config.Fee}
+}
+
+// open the store that records transactions sent to and received from counter parties
+func (config Configuration) MeterStore(ctx context.Context) (meter.Store, error) {
+	fp := config.MeterFilePath
+	if len(fp) == 0 {
+		fp = ":memory:"
+	}
+	return metlite.Create(ctx, fp)
 }
 
 func (config Configuration) AdminListener(ctx context.Context) (l net.Listener, err error) {
+	return Listen(ctx, config.AdminListenUrl)
+}
 
-	if strings.HasPrefix(config.AdminListenUrl, "tcp") {
-		l, err = net.Listen("tcp", config.AdminListenUrl[len("tcp://"):])
+// Listen on a url in the form of tcp://HOST:PORT or unix:///my/file/path.
+// The listener is closed when ctx is done.
+func Listen(ctx context.Context, listenUrl string) (l net.Listener, err error) {
+	if strings.HasPrefix(listenUrl, "tcp://") {
+		l, err = net.Listen("tcp", listenUrl[len("tcp://"):])
 		if err != nil {
 			return
 		}
 		go loopCloseListener(ctx, l, "")
 		return
-	} else if strings.HasPrefix(config.AdminListenUrl, "unix") {
-		l, err = net.Listen("unix", config.AdminListenUrl[len("unix://"):])
+	} else if strings.HasPrefix(listenUrl, "unix://") {
+		l, err = net.Listen("unix", listenUrl[len("unix://"):])
 		if err != nil {
 			return
 		}
-		go loopCloseListener(ctx, l, config.AdminListenUrl[len("unix://"):])
+		go loopCloseListener(ctx, l, listenUrl[len("unix://"):])
 		return
 	} else {
 		return nil, errors.New("url must be in form of tcp://HOST:PORT or unix:///my/file/path")
@@ -144,9 +174,34 @@ func (config Configuration) Router(ctx context.Context) (r rtr.Router, err error
 	if err != nil {
 		return
 	}
-	r, err = rtr.CreateRouter(ctx, network, rpcClient, wsClient, nil, config.Version)
+	if 0 < len(config.RouterSnapshot) {
+		r, err = rtr.CreateRouterWithSnapshot(ctx, network, rpcClient, wsClient, config.RouterSnapshot, config.Version)
+	} else {
+		r, err = rtr.CreateRouter(ctx, network, rpcClient, wsClient, nil, config.Version)
+	}
 	if err != nil {
 		return
 	}
 	return
 }
+
+// the commitment used when waiting on transactions sent through a relay
+func (config Configuration) Commitment() sgorpc.CommitmentType {
+	if len(config.WaitCommitment) == 0 {
+		return sgorpc.CommitmentConfirmed
+	}
+	return sgorpc.CommitmentType(config.WaitCommitment)
+}
+
+func (config Configuration) RpcUrl() string {
+	return config.rpcUrl
+}
+
+func (config Configuration) WsUrl() string {
+	return config.wsUrl
+}
+
+// the http headers are copied
+func (config Configuration) Headers() http.Header {
+	return config.headers.Clone()
+}
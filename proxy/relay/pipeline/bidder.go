@@ -9,6 +9,7 @@ import (
 	cba "github.com/solpipe/cba"
 	ll "github.com/solpipe/solpipe-tool/ds/list"
 	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
 )
 
 type bidderFeed struct {
@@ -16,6 +17,7 @@ type bidderFeed struct {
 	cancel  context.CancelFunc
 	bidC    chan<- BidWithTotal
 	submitC chan<- submitInfo
+	rateC   chan float64 // only the latest rate override matters
 }
 
 type bidderInfo struct {
@@ -25,6 +27,7 @@ type bidderInfo struct {
 
 func (in *internal) init_bid() error {
 	in.bidderMap = make(map[string]*bidderFeed)
+	in.rateOverrideM = make(map[string]float64)
 	return nil
 }
 
@@ -90,15 +93,22 @@ func (in *internal) bidder_create(
 	ctx2, cancel := context.WithCancel(in.ctx)
 	bidC := make(chan BidWithTotal, 1)
 	txSubmitC := make(chan submitInfo) // no buffer
+	rateC := make(chan float64, 1)
+	override, present := in.rateOverrideM[bt.User().String()]
+	if !present {
+		override = relay.RATE_BID_SHARE
+	}
 
 	go loopBidderInternal(
 		ctx2,
 		cancel,
 		bidC,
 		txSubmitC,
+		rateC,
 		pipelineTpsReqC,
 		txFromBidderToValidatorC,
 		bt,
+		override,
 	)
 
 	return &bidderFeed{
@@ -106,15 +116,18 @@ func (in *internal) bidder_create(
 		cancel:  cancel,
 		submitC: txSubmitC,
 		bidC:    bidC,
+		rateC:   rateC,
 	}
 }
 
 type bidderInternal struct {
 	ctx           context.Context
+	user          string
 	errorC        chan<- error
 	allottedShare float64
 	pipelineTps   float64
 	allotedTps    float64
+	override      float64 // set by the operator; RATE_BID_SHARE if there is no override
 	txCount       float64
 	//	keepReading   bool
 	keepReadingC chan<- bool
@@ -127,9 +140,11 @@ func loopBidderInternal(
 	cancel context.CancelFunc,
 	bidC <-chan BidWithTotal,
 	txSubmitC <-chan submitInfo,
+	rateC <-chan float64,
 	pipelineTpsReqC chan dssub.ResponseChannel[float64],
 	txFromBidderToValidatorC chan<- submitInfo,
 	bt BidWithTotal,
+	override float64,
 ) {
 	defer cancel()
 	var err error
@@ -142,12 +157,17 @@ func loopBidderInternal(
 	bi := new(bidderInternal)
 	bi.ctx = ctx
 	bi.errorC = errorC
+	bi.user = bt.User().String()
+	defer metricAllottedTps.Delete(bi.user)
+	defer metricActualTps.Delete(bi.user)
+	defer metricBidderTx.Delete(bi.user)
 
 	// bid.BandwidthAllocation
 	//bi.allottedShare = float64(bid.BandwidthAllocation) / float64(initPayout.Period.BandwidthAllotment)
 	bi.allottedShare = float64(bt.Bid.Deposit) / float64(bt.TotalDeposit)
 	bi.pipelineTps = float64(0)
 	bi.allotedTps = float64(0)
+	bi.override = override
 	bi.txCount = float64(0)
 	bi.boxInterval = 10 * time.Second
 	bi.nextBoxC = time.After(bi.boxInterval)
@@ -158,17 +178,28 @@ func loopBidderInternal(
 	bi.keepReadingC = keepReadingC
 	// implement a "dynamic" select with this goroutine
 	go loopBidderSubmitRateLimiter(bi.ctx, txSubmitC, loopTxSubmitC, keepReadingC)
+	bi.update_allotment()
+	if bi.is_suspended() {
+		bi.keepReadingC <- false
+	}
 
 out:
 	for {
 		select {
 		case <-bi.nextBoxC:
+			metricActualTps.With(bi.user).Set(bi.txCount / bi.boxInterval.Seconds())
 			bi.txCount = 0
-			bi.keepReadingC <- true
+			bi.keepReadingC <- !bi.is_suspended()
 			bi.nextBoxC = time.After(bi.boxInterval)
 		case bt = <-bidC:
 			bi.allottedShare = float64(bt.Bid.Deposit) / float64(bt.TotalDeposit)
-			bi.allotedTps = bi.pipelineTps * bi.allottedShare
+			bi.update_allotment()
+		case bi.override = <-rateC:
+			bi.update_allotment()
+			select {
+			case <-doneC:
+			case bi.keepReadingC <- !bi.is_suspended():
+			}
 		case s := <-loopTxSubmitC:
 			select {
 			case <-doneC:
@@ -178,7 +209,7 @@ out:
 		case err = <-pipelineTpsSub.ErrorC:
 			break out
 		case bi.pipelineTps = <-pipelineTpsSub.StreamC:
-			bi.allotedTps = bi.pipelineTps * bi.allottedShare
+			bi.update_allotment()
 		case <-doneC:
 			break out
 		case err = <-errorC:
@@ -188,9 +219,24 @@ out:
 	log.Debug(err)
 }
 
+// The bid share sets the TPS unless the operator has overridden it.
+func (bi *bidderInternal) update_allotment() {
+	if 0 <= bi.override {
+		bi.allotedTps = bi.override
+	} else {
+		bi.allotedTps = bi.pipelineTps * bi.allottedShare
+	}
+	metricAllottedTps.With(bi.user).Set(bi.allotedTps)
+}
+
+func (bi *bidderInternal) is_suspended() bool {
+	return bi.override == 0
+}
+
 // update tps from usage by bidder of capacity
 func (bi *bidderInternal) update_tps() {
 	bi.txCount += 1
+	metricBidderTx.With(bi.user).Inc()
 	if bi.allotedTps < (bi.txCount / bi.allotedTps) {
 		select {
 		case <-bi.ctx.Done():
@@ -8,9 +8,11 @@ import (
 	"github.com/cretz/bine/tor"
 	log "github.com/sirupsen/logrus"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/meter"
 	"github.com/solpipe/solpipe-tool/proxy"
 	pxyclt "github.com/solpipe/solpipe-tool/proxy/client"
 	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	val "github.com/solpipe/solpipe-tool/state/validator"
 	"google.golang.org/grpc"
 )
@@ -30,8 +32,10 @@ func loopValidatorConnect(
 	connC chan<- validatorClientWithId,
 	validator val.Validator,
 	data cba.ValidatorManager,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
+	settings script.ReceiptSettings,
 	scriptBuidler *script.Script,
+	store meter.Store,
 ) {
 	defer cancel()
 	pipelineSender := admin
@@ -71,8 +75,9 @@ out:
 					admin,
 					data.Admin,
 					nil,
-					&script.ReceiptSettings{},
+					&settings,
 					scriptBuidler,
+					store,
 				)
 				if err != nil {
 					break out
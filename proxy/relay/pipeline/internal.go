@@ -8,7 +8,9 @@ import (
 	"github.com/cretz/bine/tor"
 	log "github.com/sirupsen/logrus"
 	"github.com/solpipe/solpipe-tool/ds/sub"
+	"github.com/solpipe/solpipe-tool/meter"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/script"
 	ntk "github.com/solpipe/solpipe-tool/state/network"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
@@ -26,9 +28,12 @@ type internal struct {
 	tor    *tor.Tor
 	dialer *tor.Dialer
 	config relay.Configuration
+	store  meter.Store
 
 	// solana state related
 	slot            uint64
+	controller      sgo.PublicKey
+	payout          sgo.PublicKey // the payout whose period contains slot
 	network         ntk.Network
 	pipeline        pipe.Pipeline
 	pipelineTps     float64               // real time TPS calculation
@@ -44,6 +49,7 @@ type internal struct {
 	periodInfo             *periodInfo
 	validatorConnectionMap map[string]*validatorConnection // map validator mgr id -> validator connection; connect to all validators
 	bidderMap              map[string]*bidderFeed          // user_id->bidder
+	rateOverrideM          map[string]float64              // user_id->tps set by the operator
 	bidStatusC             chan<- bidStatusWithStartTime
 	deletePayoutC          chan<- uint64
 	//validatorMap           map[string]*validatorFeed       // map vote -> validator
@@ -60,6 +66,7 @@ func loopInternal(
 	router rtr.Router,
 	pipeline pipe.Pipeline,
 	config relay.Configuration,
+	store meter.Store,
 ) {
 	defer cancel()
 	var err error
@@ -80,6 +87,7 @@ func loopInternal(
 	in.ctx = ctx
 	in.closeSignalCList = make([]chan<- error, 0)
 	in.config = config
+	in.store = store
 
 	in.slotHome = slotHome
 	in.tor = torMgr
@@ -94,6 +102,7 @@ func loopInternal(
 	in.bidStatusC = bidStatusC
 
 	in.slot = 0
+	in.controller = router.Controller.Id()
 	in.network = network
 	in.pipeline = pipeline
 	in.pipelineTps = 0
@@ -142,7 +151,7 @@ out:
 				if err2 != nil {
 					log.Debug(err2)
 				} else {
-					script, err := in.config.ScriptBuilder(in.ctx)
+					scriptBuilder, err := in.config.ScriptBuilder(in.ctx)
 					if err != nil {
 						break out
 					}
@@ -169,7 +178,15 @@ out:
 						validator,
 						x.Data,
 						in.config.Admin,
-						script,
+						script.ReceiptSettings{
+							Controller:      in.controller,
+							Pipeline:        in.pipeline.Id,
+							PipelineAdmin:   in.config.Admin,
+							Payout:          in.payout,
+							ValidatorMember: x.Id,
+						},
+						scriptBuilder,
+						in.store,
 					)
 				}
 			}
@@ -197,6 +214,10 @@ out:
 			if present {
 				y.connectionTime = time.Now()
 				y.client = &x.client
+				// the payout may have changed while connecting
+				if !in.payout.IsZero() {
+					go loopUpdatePayout(in.ctx, x.client, in.payout)
+				}
 			}
 
 		// update the Slot Clock
@@ -204,6 +225,7 @@ out:
 			break out
 		case slot := <-slotSub.StreamC:
 			in.slot = slot
+			in.update_payout()
 
 		// broadcast TPS updates so we know bidder TPS
 		case id := <-in.pipelineTpsHome.DeleteC:
@@ -212,6 +234,7 @@ out:
 			in.pipelineTpsHome.Receive(r)
 		case changeInTps := <-pipelineTpsC:
 			in.pipelineTps += changeInTps
+			metricPipelineTps.Set(in.pipelineTps)
 			in.pipelineTpsHome.Broadcast(in.pipelineTps)
 
 		// send channel of bidder to Submit() function so that we do not burden
@@ -233,8 +256,10 @@ out:
 			break out
 		case pwd := <-payoutSub.StreamC:
 			in.on_payout(pwd, slotHome)
+			in.update_payout()
 		case pwd := <-pwdC:
 			in.on_payout(pwd, slotHome)
+			in.update_payout()
 		case startTime := <-deletePayoutC:
 			node, present := in.periodInfo.m[startTime]
 			if present {
@@ -242,6 +267,10 @@ out:
 				in.periodInfo.list.Remove(node)
 				node.Value().cancel()
 				delete(in.periodInfo.m, startTime)
+				err = in.store.ClosePeriod(node.Value().Pwd.Id)
+				if err != nil {
+					break out
+				}
 			}
 
 		}
@@ -0,0 +1,11 @@
+package pipeline
+
+import "github.com/solpipe/solpipe-tool/metrics"
+
+var (
+	metricPipelineTps = metrics.NewGauge("solpipe_relay_pipeline_tps", "TPS the connected validators give the pipeline")
+	metricAllottedTps = metrics.NewGaugeVec("solpipe_relay_bidder_allotted_tps", "TPS allotted to the bidder", "bidder")
+	metricActualTps   = metrics.NewGaugeVec("solpipe_relay_bidder_actual_tps", "TPS used by the bidder over the last rate limiting window", "bidder")
+	metricBidderTx    = metrics.NewCounterVec("solpipe_relay_bidder_tx_total", "transactions forwarded for the bidder", "bidder")
+	metricQueue       = metrics.NewGaugeVec("solpipe_relay_queue_depth", "transactions waiting to be accepted by the relay", "relay")
+)
@@ -3,8 +3,10 @@ package pipeline
 import (
 	"context"
 
+	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	ll "github.com/solpipe/solpipe-tool/ds/list"
+	pxyclt "github.com/solpipe/solpipe-tool/proxy/client"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	"github.com/solpipe/solpipe-tool/state/slot"
@@ -116,6 +118,40 @@ func (in *internal) on_payout(pwd pipe.PayoutWithData, slotHome slot.SlotHome) {
 
 }
 
+// Find the payout whose period contains the current slot.
+func (in *internal) current_payout() sgo.PublicKey {
+	for node := in.periodInfo.list.HeadNode(); node != nil; node = node.Next() {
+		period := node.Value().Pwd.Data.Period
+		if period.Start <= in.slot && in.slot < period.Start+period.Length {
+			return node.Value().Pwd.Id
+		}
+	}
+	return sgo.PublicKey{}
+}
+
+// Validator receipts are per payout, so move the validator clients on to the
+// next payout as soon as its period starts.
+func (in *internal) update_payout() {
+	payout := in.current_payout()
+	if payout.IsZero() || payout.Equals(in.payout) {
+		return
+	}
+	log.Debugf("current payout=%s", payout.String())
+	in.payout = payout
+	for _, valconn := range in.validatorConnectionMap {
+		if valconn.client != nil {
+			go loopUpdatePayout(in.ctx, *valconn.client, payout)
+		}
+	}
+}
+
+func loopUpdatePayout(ctx context.Context, client pxyclt.Client, payout sgo.PublicKey) {
+	err := client.UpdatePayout(ctx, payout)
+	if err != nil {
+		log.Debugf("failed to move validator=%s to payout=%s: %s", client.Receiver().String(), payout.String(), err.Error())
+	}
+}
+
 func (in *internal) pwbs_init_bid(pwbs *payoutWithBidStatus) error {
 	bi := new(bidderInfo)
 	bi.list = ll.CreateGeneric[*bidderFeed]()
@@ -5,6 +5,7 @@ import (
 
 	"github.com/cretz/bine/tor"
 	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/meter"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
 	ntk "github.com/solpipe/solpipe-tool/state/network"
@@ -20,6 +21,7 @@ type external struct {
 	network                 ntk.Network
 	pipeline                pipe.Pipeline
 	requestTxSubmitChannelC chan<- requestForSubmitChannel
+	waiter                  relay.Waiter
 }
 
 // Submit transactions from bidders and relay those transactions to validators.
@@ -29,6 +31,7 @@ func Create(
 	router rtr.Router,
 	pipeline pipe.Pipeline,
 	torMgr *tor.Tor,
+	store meter.Store,
 ) (relay.Relay, error) {
 	log.Debugf("starting relay for pipeline=%s", pipeline.Id.String())
 	ctx2, cancel := context.WithCancel(ctx)
@@ -41,6 +44,11 @@ func Create(
 		cancel()
 		return nil, err
 	}
+	waiter, err := relay.CreateWaiter(ctx2, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
 	go loopInternal(
 		ctx2,
@@ -53,6 +61,7 @@ func Create(
 		router,
 		pipeline,
 		config,
+		store,
 	)
 	e1 := external{
 		ctx:                     ctx,
@@ -61,6 +70,7 @@ func Create(
 		network:                 router.Network,
 		pipeline:                pipeline,
 		requestTxSubmitChannelC: txSubmitC,
+		waiter:                  waiter,
 	}
 	return e1, nil
 }
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+)
+
+// Override the TPS of a bidder regardless of the size of its bid.  Overrides
+// are kept for bidders that have not bid yet so that they apply once they do.
+func (e1 external) AdjustRate(
+	ctx context.Context,
+	sender sgo.PublicKey,
+	newRate float64,
+) error {
+	if math.IsNaN(newRate) || math.IsInf(newRate, 0) {
+		return errors.New("rate is not a number")
+	}
+	if newRate < 0 && newRate != relay.RATE_BID_SHARE {
+		return errors.New("rate cannot be negative")
+	}
+	doneC := ctx.Done()
+	errorC := make(chan error, 1)
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case <-e1.ctx.Done():
+		return errors.New("relay has closed")
+	case e1.internalC <- func(in *internal) {
+		in.adjust_rate(sender, newRate)
+		errorC <- nil
+	}:
+	}
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case err := <-errorC:
+		return err
+	}
+}
+
+func (in *internal) adjust_rate(sender sgo.PublicKey, newRate float64) {
+	if newRate < 0 {
+		log.Debugf("bidder=%s rate follows bid share", sender.String())
+		delete(in.rateOverrideM, sender.String())
+	} else {
+		log.Debugf("bidder=%s rate set to %f tps", sender.String(), newRate)
+		in.rateOverrideM[sender.String()] = newRate
+	}
+	bf, present := in.bidderMap[sender.String()]
+	if !present {
+		return
+	}
+	// replace any override that the bidder goroutine has not read yet
+	for {
+		select {
+		case bf.rateC <- newRate:
+			return
+		default:
+			select {
+			case <-bf.rateC:
+			default:
+			}
+		}
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"errors"
 
 	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/tracing"
 )
 
 type submitInfo struct {
-	ctx    context.Context
-	tx     *sgo.Transaction
-	errorC chan<- error
-	sigC   chan<- sgo.Signature
+	ctx      context.Context
+	tx       *sgo.Transaction
+	errorC   chan<- error
+	sigC     chan<- sgo.Signature
+	waitSpan *tracing.Span // ends once the transaction clears rate limiting
 }
 
 type requestForSubmitChannel struct {
@@ -26,10 +29,11 @@ func (e1 external) Submit(
 	ctx context.Context,
 	sender sgo.PublicKey,
 	tx *sgo.Transaction,
-) (sgo.Signature, error) {
+) (sig sgo.Signature, err error) {
+	ctx, span := tracing.Start(ctx, "relay.pipeline.submit")
+	defer func() { span.End(err) }()
+	span.SetAttribute("sender", sender.String())
 	doneC := ctx.Done()
-	var sig sgo.Signature
-	var err error
 	bidderFoundC := make(chan bool, 1)
 	respC := make(chan chan<- submitInfo, 1)
 	select {
@@ -54,25 +58,33 @@ func (e1 external) Submit(
 
 	sigC := make(chan sgo.Signature, 1)
 	errorC := make(chan error, 1)
+	_, waitSpan := tracing.Start(ctx, "relay.pipeline.rate_limit")
+	queue := metricQueue.With("pipeline")
+	queue.Inc()
 	select {
 	case <-doneC:
+		queue.Dec()
+		waitSpan.End(errors.New("canceled"))
 		return sig, errors.New("canceled")
 	case submitC <- submitInfo{
-		ctx:    ctx,
-		tx:     tx,
-		errorC: errorC,
-		sigC:   sigC,
+		ctx:      ctx,
+		tx:       tx,
+		errorC:   errorC,
+		sigC:     sigC,
+		waitSpan: waitSpan,
 	}:
 	}
+	queue.Dec()
 
 	err = <-errorC
 	if err != nil {
 		return sig, err
 	}
 	sig = <-sigC
+	e1.waiter.Track(tx)
 	return sig, nil
 }
 
-func (e1 external) Wait(ctx context.Context, signature sgo.Signature) error {
-	return errors.New("not implemented yet")
+func (e1 external) Wait(ctx context.Context, signature sgo.Signature) (relay.WaitResult, error) {
+	return e1.waiter.Wait(ctx, signature)
 }
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	sgo "github.com/SolmateDev/solana-go"
@@ -9,10 +10,12 @@ import (
 	cba "github.com/solpipe/cba"
 	pxyclt "github.com/solpipe/solpipe-tool/proxy/client"
 	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
 	ntk "github.com/solpipe/solpipe-tool/state/network"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	"github.com/solpipe/solpipe-tool/state/slot"
 	val "github.com/solpipe/solpipe-tool/state/validator"
+	"github.com/solpipe/solpipe-tool/tracing"
 )
 
 type validatorInsertInfo struct {
@@ -215,7 +218,7 @@ func loopValidatorInternal(
 	txSubmitC <-chan submitInfo,
 	network ntk.Network,
 	validator val.Validator,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	scriptBuilder *script.Script,
 	data cba.ValidatorManager,
 	start uint64,
@@ -262,7 +265,8 @@ out:
 			case vi.readOkC <- true:
 			}
 		case si := <-txReadyToSendC:
-			go loopSendTx(vi.ctx, *vi.client, si)
+			si.waitSpan.End(nil)
+			go loopSendTx(tracing.WithSpan(vi.ctx, tracing.FromContext(si.ctx)), *vi.client, si)
 			vi.update_actual_tps()
 		case client := <-clientConnC:
 			vi.client = &client
@@ -312,7 +316,14 @@ func (vi *validatorInternal) finish(err error) {
 }
 
 func loopSendTx(ctx context.Context, client pxyclt.Client, si submitInfo) {
-	si.errorC <- client.Submit(ctx, si.tx)
+	err := client.Submit(ctx, si.tx)
+	if err == nil && len(si.tx.Signatures) == 0 {
+		err = errors.New("transaction has no signatures")
+	}
+	si.errorC <- err
+	if err == nil {
+		si.sigC <- si.tx.Signatures[0]
+	}
 }
 
 // only read from txC when the validator has spare tps capacity.
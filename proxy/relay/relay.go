@@ -6,12 +6,17 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 )
 
+// pass to AdjustRate to remove an override
+const RATE_BID_SHARE float64 = -1
+
 // send transactions and apply rate limiting
 type Relay interface {
 	// send a transaction
 	Submit(ctx context.Context, sender sgo.PublicKey, tx *sgo.Transaction) (sgo.Signature, error)
-	// set the transactions per second for a given sender (does not apply to Validator relay)
-	//AdjustRate(ctx context.Context, sender sgo.PublicKey, newRate float64) error
-	// wait for the transaction to show up in a block
-	Wait(ctx context.Context, signature sgo.Signature) error
+	// set the transactions per second for a given sender (does not apply to Validator relay);
+	// a rate of 0 suspends the sender and RATE_BID_SHARE reverts to the share implied by the bid
+	AdjustRate(ctx context.Context, sender sgo.PublicKey, newRate float64) error
+	// wait for a transaction sent with Submit to show up in a block at the configured commitment;
+	// the result is WAIT_EXPIRED if the blockhash expires first
+	Wait(ctx context.Context, signature sgo.Signature) (WaitResult, error)
 }
@@ -73,6 +73,7 @@ out:
 					in.readTx = false
 				}
 			case <-nextBoxC:
+				metricActualTps.Set(in.txCountInPeriod / interval.Seconds())
 				in.txCountInPeriod = 0
 				in.readTx = true
 				nextBoxC = time.After(interval)
@@ -81,7 +82,7 @@ out:
 			case n := <-networkSub.StreamC:
 				// add 10% buffer
 				in.networkTps = n.AverageTransactionsPerSecond * plusBuffer
-				in.allowedTps = in.networkTps * in.stakeShare
+				in.update_allowed_tps()
 			case err = <-validatorSub.ErrorC:
 				break out
 			case s := <-validatorSub.StreamC:
@@ -89,7 +90,7 @@ out:
 					in.stakeShare = 0
 				} else {
 					in.stakeShare = float64(s.Activated) / float64(s.Total)
-					in.allowedTps = in.networkTps * in.stakeShare
+					in.update_allowed_tps()
 				}
 			case <-doneC:
 				break out
@@ -101,6 +102,7 @@ out:
 		} else {
 			select {
 			case <-nextBoxC:
+				metricActualTps.Set(in.txCountInPeriod / interval.Seconds())
 				in.txCountInPeriod = 0
 				in.readTx = true
 				nextBoxC = time.After(interval)
@@ -108,7 +110,7 @@ out:
 				break out
 			case n := <-networkSub.StreamC:
 				in.networkTps = n.AverageTransactionsPerSecond * plusBuffer
-				in.allowedTps = in.networkTps * in.stakeShare
+				in.update_allowed_tps()
 			case err = <-validatorSub.ErrorC:
 				break out
 			case s := <-validatorSub.StreamC:
@@ -116,7 +118,7 @@ out:
 					in.stakeShare = 0
 				} else {
 					in.stakeShare = float64(s.Activated) / float64(s.Total)
-					in.allowedTps = in.networkTps * in.stakeShare
+					in.update_allowed_tps()
 				}
 			case <-doneC:
 				break out
@@ -131,6 +133,11 @@ out:
 	in.finish(err)
 }
 
+func (in *internal) update_allowed_tps() {
+	in.allowedTps = in.networkTps * in.stakeShare
+	metricAllowedTps.Set(in.allowedTps)
+}
+
 func (in *internal) finish(err error) {
 	log.Debug(err)
 	for i := 0; i < len(in.closeSignalCList); i++ {
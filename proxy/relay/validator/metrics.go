@@ -0,0 +1,10 @@
+package validator
+
+import "github.com/solpipe/solpipe-tool/metrics"
+
+var (
+	metricAllowedTps = metrics.NewGauge("solpipe_relay_validator_allowed_tps", "TPS the validator accepts from pipelines, from its share of stake")
+	metricActualTps  = metrics.NewGauge("solpipe_relay_validator_actual_tps", "TPS received from pipelines over the last rate limiting window")
+	metricTx         = metrics.NewCounterVec("solpipe_relay_validator_tx_total", "transactions received by sending pipeline", "sender")
+	metricQueue      = metrics.NewGaugeVec("solpipe_relay_queue_depth", "transactions waiting to be accepted by the relay", "relay")
+)
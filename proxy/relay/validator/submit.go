@@ -7,6 +7,7 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/tracing"
 )
 
 type submitInfo struct {
@@ -15,32 +16,41 @@ type submitInfo struct {
 	errorC chan<- error
 	sigC   chan<- sgo.Signature
 	bidder sgo.PublicKey
+	// ends once the transaction clears rate limiting
+	waitSpan *tracing.Span
 }
 
 func (e1 external) Submit(
 	ctx context.Context,
 	sender sgo.PublicKey,
 	tx *sgo.Transaction,
-) (sgo.Signature, error) {
+) (sig sgo.Signature, err error) {
+	ctx, span := tracing.Start(ctx, "relay.validator.submit")
+	defer func() { span.End(err) }()
 	pubkey, err := relay.GetPeerPubkey(ctx)
 	if err != nil {
 		return sgo.Signature{}, err
 	}
+	span.SetAttribute("sender", pubkey.String())
 	doneC := ctx.Done()
 	errorC := make(chan error, 1)
 	sigC := make(chan sgo.Signature, 1)
-	si := &submitInfo{ctx: ctx, tx: tx, errorC: errorC, sigC: sigC, bidder: pubkey}
-
-	var sig sgo.Signature
+	_, waitSpan := tracing.Start(ctx, "relay.validator.rate_limit")
+	si := &submitInfo{ctx: ctx, tx: tx, errorC: errorC, sigC: sigC, bidder: pubkey, waitSpan: waitSpan}
 
+	queue := metricQueue.With("validator")
+	queue.Inc()
 	select {
 	case <-doneC:
 		err = errors.New("canceled")
 	case e1.txC <- si:
 	}
+	queue.Dec()
 	if err != nil {
+		waitSpan.End(err)
 		return sig, err
 	}
+	metricTx.With(pubkey.String()).Inc()
 
 	select {
 	case <-doneC:
@@ -50,11 +60,18 @@ func (e1 external) Submit(
 	if err != nil {
 		return sig, err
 	}
-	return <-sigC, nil
+	sig = <-sigC
+	e1.waiter.Track(tx)
+	return sig, nil
+}
+
+// validators get their rate from stake, so there is nothing to adjust
+func (e1 external) AdjustRate(ctx context.Context, sender sgo.PublicKey, newRate float64) error {
+	return errors.New("rates cannot be adjusted on a validator relay")
 }
 
-func (e1 external) Wait(ctx context.Context, signature sgo.Signature) error {
-	return errors.New("not implemented yet")
+func (e1 external) Wait(ctx context.Context, signature sgo.Signature) (relay.WaitResult, error) {
+	return e1.waiter.Wait(ctx, signature)
 }
 
 // do a json rpc connection
@@ -62,6 +79,7 @@ func (in *internal) process(si *submitInfo) {
 	if si == nil {
 		return
 	}
+	si.waitSpan.End(nil)
 	if si.tx == nil {
 		select {
 		case si.errorC <- errors.New("blank transaction"):
@@ -75,9 +93,11 @@ func (in *internal) process(si *submitInfo) {
 func loopRpcSendTx(ctx context.Context, tx *sgo.Transaction, rpcClient *sgorpc.Client, errorC chan<- error, sigC chan<- sgo.Signature) {
 	var err error
 	var sig sgo.Signature
+	_, span := tracing.Start(ctx, "rpc.send_transaction")
 	sig, err = rpcClient.SendTransactionWithOpts(ctx, tx, sgorpc.TransactionOpts{
 		SkipPreflight: true,
 	})
+	span.End(err)
 	errorC <- err
 	if err == nil {
 		sigC <- sig
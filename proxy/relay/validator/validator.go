@@ -15,6 +15,7 @@ type external struct {
 	Cancel    context.CancelFunc
 	validator val.Validator
 	txC       chan<- *submitInfo
+	waiter    relay.Waiter
 }
 
 func Create(
@@ -29,6 +30,11 @@ func Create(
 		return nil, err
 	}
 	ctx2, cancel := context.WithCancel(ctx)
+	waiter, err := relay.CreateWaiter(ctx2, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 	// do not put a buffer here as we want to do rate limiting
 	txC := make(chan *submitInfo)
 	internalC := make(chan func(*internal), 10)
@@ -39,6 +45,7 @@ func Create(
 		Cancel:    cancel,
 		validator: validator,
 		txC:       txC,
+		waiter:    waiter,
 	}
 	return e1, nil
 }
@@ -0,0 +1,285 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	log "github.com/sirupsen/logrus"
+)
+
+type WaitStatus uint8
+
+const (
+	WAIT_LANDED  WaitStatus = 0 // the transaction is in a block and succeeded
+	WAIT_FAILED  WaitStatus = 1 // the transaction is in a block, but failed
+	WAIT_EXPIRED WaitStatus = 2 // the blockhash expired before the transaction landed
+)
+
+func (s WaitStatus) String() string {
+	switch s {
+	case WAIT_LANDED:
+		return "landed"
+	case WAIT_FAILED:
+		return "failed"
+	case WAIT_EXPIRED:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// The outcome of waiting on a transaction.
+type WaitResult struct {
+	Status WaitStatus
+	Slot   uint64      // slot in which the transaction landed; 0 if expired
+	TxErr  interface{} // error from the runtime if the transaction failed
+}
+
+// how often to check if the blockhash of a pending transaction is still valid
+const WAIT_EXPIRY_CHECK_INTERVAL = 5 * time.Second
+
+// forget the blockhash of a transaction this long after it was sent;
+// a blockhash expires after 150 blocks, so this is well past expiry.
+const WAIT_TRACK_TTL = 5 * time.Minute
+
+// Waiter remembers the recent blockhash of transactions sent by a relay so that
+// Wait knows when to give up on a transaction.
+type Waiter struct {
+	ctx        context.Context
+	internalC  chan<- func(*waiterInternal)
+	rpc        *sgorpc.Client
+	ws         *sgows.Client
+	commitment sgorpc.CommitmentType
+}
+
+type waiterInternal struct {
+	ctx        context.Context
+	deleteC    chan<- string
+	blockhashM map[string]sgo.Hash // signature -> recent blockhash
+}
+
+func CreateWaiter(ctx context.Context, config Configuration) (Waiter, error) {
+	wsClient, err := config.Ws(ctx)
+	if err != nil {
+		return Waiter{}, err
+	}
+	internalC := make(chan func(*waiterInternal), 10)
+	go loopWaiterInternal(ctx, internalC)
+	return Waiter{
+		ctx:        ctx,
+		internalC:  internalC,
+		rpc:        config.Rpc(),
+		ws:         wsClient,
+		commitment: config.Commitment(),
+	}, nil
+}
+
+func loopWaiterInternal(ctx context.Context, internalC <-chan func(*waiterInternal)) {
+	doneC := ctx.Done()
+	deleteC := make(chan string, 10)
+
+	in := new(waiterInternal)
+	in.ctx = ctx
+	in.deleteC = deleteC
+	in.blockhashM = make(map[string]sgo.Hash)
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case req := <-internalC:
+			req(in)
+		case sig := <-deleteC:
+			delete(in.blockhashM, sig)
+		}
+	}
+}
+
+func loopWaiterDelete(ctx context.Context, sig string, deleteC chan<- string) {
+	doneC := ctx.Done()
+	select {
+	case <-doneC:
+		return
+	case <-time.After(WAIT_TRACK_TTL):
+	}
+	select {
+	case <-doneC:
+	case deleteC <- sig:
+	}
+}
+
+// Track a transaction that has been sent.  Call this before handing the
+// signature to anyone that might call Wait.
+func (w Waiter) Track(tx *sgo.Transaction) {
+	if tx == nil || len(tx.Signatures) == 0 {
+		return
+	}
+	sig := tx.Signatures[0].String()
+	blockhash := tx.Message.RecentBlockhash
+	select {
+	case <-w.ctx.Done():
+	case w.internalC <- func(in *waiterInternal) {
+		_, present := in.blockhashM[sig]
+		in.blockhashM[sig] = blockhash
+		if !present {
+			go loopWaiterDelete(in.ctx, sig, in.deleteC)
+		}
+	}:
+	}
+}
+
+func (w Waiter) blockhash(ctx context.Context, signature sgo.Signature) (sgo.Hash, error) {
+	doneC := ctx.Done()
+	errorC := make(chan error, 1)
+	ansC := make(chan sgo.Hash, 1)
+	select {
+	case <-doneC:
+		return sgo.Hash{}, errors.New("canceled")
+	case <-w.ctx.Done():
+		return sgo.Hash{}, errors.New("canceled")
+	case w.internalC <- func(in *waiterInternal) {
+		h, present := in.blockhashM[signature.String()]
+		if present {
+			errorC <- nil
+			ansC <- h
+		} else {
+			errorC <- errors.New("unknown signature")
+		}
+	}:
+	}
+	var err error
+	select {
+	case <-doneC:
+		err = errors.New("canceled")
+	case err = <-errorC:
+	}
+	if err != nil {
+		return sgo.Hash{}, err
+	}
+	return <-ansC, nil
+}
+
+// Wait for a tracked transaction to land or for its blockhash to expire.
+func (w Waiter) Wait(ctx context.Context, signature sgo.Signature) (WaitResult, error) {
+	blockhash, err := w.blockhash(ctx, signature)
+	if err != nil {
+		return WaitResult{}, err
+	}
+	return WaitForSignature(ctx, w.rpc, w.ws, signature, blockhash, w.commitment)
+}
+
+// Subscribe to the signature and return once the transaction lands at the given
+// commitment.  The blockhash is checked periodically; once it is no longer
+// valid, the transaction can never land and WAIT_EXPIRED is returned.
+func WaitForSignature(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
+	signature sgo.Signature,
+	blockhash sgo.Hash,
+	commitment sgorpc.CommitmentType,
+) (ans WaitResult, err error) {
+	doneC := ctx.Done()
+	sub, err := wsClient.SignatureSubscribe(signature, commitment)
+	if err != nil {
+		return
+	}
+	defer sub.Unsubscribe()
+	streamC := sub.RecvStream()
+	closeC := sub.CloseSignal()
+	ticker := time.NewTicker(WAIT_EXPIRY_CHECK_INTERVAL)
+	defer ticker.Stop()
+
+out:
+	for {
+		select {
+		case <-doneC:
+			err = errors.New("canceled")
+			break out
+		case err = <-closeC:
+			if err == nil {
+				err = errors.New("signature subscription closed")
+			}
+			break out
+		case d := <-streamC:
+			x, ok := d.(*sgows.SignatureResult)
+			if !ok {
+				err = errors.New("unknown result")
+				break out
+			}
+			ans.Slot = x.Context.Slot
+			if x.Value.Err != nil {
+				ans.Status = WAIT_FAILED
+				ans.TxErr = x.Value.Err
+			} else {
+				ans.Status = WAIT_LANDED
+			}
+			break out
+		case <-ticker.C:
+			var valid *sgorpc.IsValidBlockhashResult
+			valid, err = rpcClient.IsBlockhashValid(ctx, blockhash, commitment)
+			if err != nil {
+				log.Debugf("failed to check blockhash for signature=%s: %s", signature.String(), err.Error())
+				err = nil
+				continue
+			}
+			if valid.Value {
+				continue
+			}
+			// the notification may have raced with the expiry
+			var isDone bool
+			ans, isDone, err = signatureStatus(ctx, rpcClient, signature, commitment)
+			if err != nil || isDone {
+				break out
+			}
+		}
+	}
+	return
+}
+
+// Look up a transaction whose blockhash has expired.  isDone is false if the
+// transaction landed, but has yet to reach commitment.
+func signatureStatus(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	signature sgo.Signature,
+	commitment sgorpc.CommitmentType,
+) (ans WaitResult, isDone bool, err error) {
+	r, err := rpcClient.GetSignatureStatuses(ctx, true, signature)
+	if err != nil {
+		return
+	}
+	if len(r.Value) == 0 || r.Value[0] == nil {
+		ans.Status = WAIT_EXPIRED
+		isDone = true
+		return
+	}
+	if !reached(r.Value[0].ConfirmationStatus, commitment) {
+		return
+	}
+	isDone = true
+	ans.Slot = r.Value[0].Slot
+	if r.Value[0].Err != nil {
+		ans.Status = WAIT_FAILED
+		ans.TxErr = r.Value[0].Err
+	} else {
+		ans.Status = WAIT_LANDED
+	}
+	return
+}
+
+func reached(status sgorpc.ConfirmationStatusType, commitment sgorpc.CommitmentType) bool {
+	switch commitment {
+	case sgorpc.CommitmentProcessed:
+		return true
+	case sgorpc.CommitmentConfirmed:
+		return status == sgorpc.ConfirmationStatusConfirmed || status == sgorpc.ConfirmationStatusFinalized
+	default:
+		return status == sgorpc.ConfirmationStatusFinalized
+	}
+}
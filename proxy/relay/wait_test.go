@@ -0,0 +1,152 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	"github.com/solpipe/solpipe-tool/signer"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+func TestSignatureStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var status interface{}
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		if req.Method != "getSignatureStatuses" {
+			t.Errorf("unexpected %s", req.Method)
+		}
+		result := map[string]interface{}{"context": map[string]interface{}{"slot": 20}, "value": []interface{}{status}}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+	defer node.Close()
+	rpcClient := sgorpc.New(node.URL)
+	sig := sgo.Signature{}
+
+	for _, x := range []struct {
+		confirmation string
+		commitment   sgorpc.CommitmentType
+		isDone       bool
+	}{
+		{"processed", sgorpc.CommitmentFinalized, false},
+		{"confirmed", sgorpc.CommitmentFinalized, false},
+		{"processed", sgorpc.CommitmentConfirmed, false},
+		{"confirmed", sgorpc.CommitmentConfirmed, true},
+		{"finalized", sgorpc.CommitmentFinalized, true},
+		{"processed", sgorpc.CommitmentProcessed, true},
+	} {
+		status = map[string]interface{}{"slot": 10, "confirmations": nil, "err": nil, "confirmationStatus": x.confirmation}
+		ans, isDone, err := signatureStatus(ctx, rpcClient, sig, x.commitment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isDone != x.isDone {
+			t.Fatalf("%s at %s: done=%v", x.confirmation, x.commitment, isDone)
+		}
+		if isDone && (ans.Status != WAIT_LANDED || ans.Slot != 10) {
+			t.Fatalf("%s at %s: %+v", x.confirmation, x.commitment, ans)
+		}
+	}
+
+	status = map[string]interface{}{"slot": 10, "confirmations": nil, "err": map[string]interface{}{"InstructionError": []interface{}{0, "InvalidArgument"}}, "confirmationStatus": "finalized"}
+	ans, isDone, err := signatureStatus(ctx, rpcClient, sig, sgorpc.CommitmentFinalized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isDone || ans.Status != WAIT_FAILED || ans.TxErr == nil {
+		t.Fatalf("failed transaction: %+v", ans)
+	}
+
+	status = nil
+	ans, isDone, err = signatureStatus(ctx, rpcClient, sig, sgorpc.CommitmentFinalized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isDone || ans.Status != WAIT_EXPIRED {
+		t.Fatalf("missing transaction: %+v", ans)
+	}
+}
+
+func TestWaitForSignature(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	f, err := cluster.CreateFunded(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination := sgo.NewWallet().PublicKey()
+	send := func(lamports uint64, skipPreflight bool) (sgo.Signature, sgo.Hash) {
+		rh, err := f.RpcClient.GetLatestBlockhash(ctx, sgorpc.CommitmentFinalized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := sgo.NewTransaction(
+			[]sgo.Instruction{sgosys.NewTransferInstruction(lamports, f.Payer.PublicKey(), destination).Build()},
+			rh.Value.Blockhash,
+			sgo.TransactionPayer(f.Payer.PublicKey()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = signer.SignTx(tx, f.Payer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := f.RpcClient.SendTransactionWithOpts(ctx, tx, sgorpc.TransactionOpts{SkipPreflight: skipPreflight})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig, rh.Value.Blockhash
+	}
+	wait := func(sig sgo.Signature, blockhash sgo.Hash) WaitResult {
+		ans, err := WaitForSignature(ctx, f.RpcClient, f.WsClient, sig, blockhash, sgorpc.CommitmentFinalized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ans
+	}
+
+	slot, err := f.Cluster.Slot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans := wait(send(sgo.LAMPORTS_PER_SOL, false)); ans.Status != WAIT_LANDED || ans.Slot != slot {
+		t.Fatalf("landed: %+v", ans)
+	}
+
+	// the fee is paid, but the transfer is more than the payer holds
+	if ans := wait(send(2*cluster.FUNDED_LAMPORTS, true)); ans.Status != WAIT_FAILED || ans.TxErr == nil {
+		t.Fatalf("failed: %+v", ans)
+	}
+
+	// dropped, then the blockhash expires
+	err = f.Cluster.Drop(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, blockhash := send(sgo.LAMPORTS_PER_SOL/2, false)
+	for i := uint64(0); i <= cluster.MAX_BLOCKHASH_AGE; i++ {
+		err = f.Cluster.Tick()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ans := wait(sig, blockhash); ans.Status != WAIT_EXPIRED {
+		t.Fatalf("expired: %+v", ans)
+	}
+}
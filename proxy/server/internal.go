@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 
+	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/meter"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 	sgo "github.com/SolmateDev/solana-go"
 )
@@ -16,6 +18,9 @@ type internal struct {
 	deleteReceiptC   chan<- string
 	updateStream     map[string]*streamInfo // sender -> stream
 	deleteStreamC    chan<- string
+	store            meter.Store
+	payoutM          map[string]struct{} // payouts with open recorders
+	deletePayoutC    chan<- sgo.PublicKey
 }
 
 type streamInfo struct {
@@ -35,17 +40,24 @@ func loopInternal(
 	cancel context.CancelFunc,
 	internalC <-chan func(*internal),
 	router rtr.Router,
+	store meter.Store,
 ) {
 	defer cancel()
 	doneC := ctx.Done()
 	errorC := make(chan error, 1)
 	deleteReceiptC := make(chan string, 1)
 	deleteStreamC := make(chan string, 1)
+	deletePayoutC := make(chan sgo.PublicKey, 1)
 	in := new(internal)
 	in.ctx = ctx
 	in.closeSignalCList = make([]chan<- error, 0)
 	in.deleteReceiptC = deleteReceiptC
 	in.deleteStreamC = deleteStreamC
+	in.errorC = errorC
+	in.router = router
+	in.store = store
+	in.payoutM = make(map[string]struct{})
+	in.deletePayoutC = deletePayoutC
 	var err error
 out:
 	for {
@@ -54,6 +66,12 @@ out:
 			delete(in.updateReceipt, id)
 		case id := <-deleteStreamC:
 			delete(in.updateStream, id)
+		case id := <-deletePayoutC:
+			delete(in.payoutM, id.String())
+			err = in.store.ClosePeriod(id)
+			if err != nil {
+				break out
+			}
 		case <-doneC:
 			break out
 		case err = <-errorC:
@@ -64,6 +82,32 @@ out:
 	}
 	in.finish(err)
 }
+func (in *internal) watch_payout(payout sgo.PublicKey) {
+	_, present := in.payoutM[payout.String()]
+	if present {
+		return
+	}
+	pwd, err := in.router.PayoutById(payout)
+	if err != nil {
+		log.Debugf("cannot watch payout=%s for closure: %s", payout.String(), err.Error())
+		return
+	}
+	in.payoutM[payout.String()] = struct{}{}
+	go loopClosePayout(in.ctx, in.deletePayoutC, payout, pwd.Payout.OnClose())
+}
+
+func loopClosePayout(ctx context.Context, deleteC chan<- sgo.PublicKey, payout sgo.PublicKey, closeC <-chan struct{}) {
+	doneC := ctx.Done()
+	select {
+	case <-doneC:
+	case <-closeC:
+		select {
+		case <-doneC:
+		case deleteC <- payout:
+		}
+	}
+}
+
 func (in *internal) finish(err error) {
 	for i := 0; i < len(in.closeSignalCList); i++ {
 		in.closeSignalCList[i] <- err
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gorilla/websocket"
+	"github.com/mr-tron/base58"
+	log "github.com/sirupsen/logrus"
+	pbr "github.com/solpipe/solpipe-tool/proto/rpcproxy"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/util"
+	"google.golang.org/grpc"
+)
+
+const METHOD_SEND_TRANSACTION = "sendTransaction"
+
+// genesis hashes used to figure out which cluster the rpc node belongs to
+const (
+	GENESIS_MAINNET = "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d"
+	GENESIS_TESTNET = "4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY"
+	GENESIS_DEVNET  = "EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG"
+)
+
+type jsonRpcExternal struct {
+	pbr.UnimplementedJsonRpcServer
+	ctx        context.Context
+	rpcUrl     string
+	wsUrl      string
+	headers    http.Header
+	httpClient *http.Client
+	relay      relay.Relay
+	cluster    pbr.Cluster
+}
+
+// Forward Solana JSON RPC requests from bidders to the rpc node of this pipeline.
+// Calls in util.READ_ONLY_METHODS are passed through.  Transactions sent with sendTransaction go
+// through the relay so that they count against the allocation of the bidder.
+// Attach to the same grpc servers used by Attach so that bidders are authenticated
+// by the mutual TLS certificate.
+func AttachJsonRpc(
+	ctx context.Context,
+	sList []*grpc.Server,
+	config relay.Configuration,
+	relay relay.Relay,
+) error {
+	cluster, err := ClusterFromRpc(ctx, config.Rpc())
+	if err != nil {
+		return err
+	}
+	log.Debugf("serving json rpc for cluster=%s", cluster.String())
+	e1 := jsonRpcExternal{
+		ctx:        ctx,
+		rpcUrl:     config.RpcUrl(),
+		wsUrl:      config.WsUrl(),
+		headers:    config.Headers(),
+		httpClient: &http.Client{},
+		relay:      relay,
+		cluster:    cluster,
+	}
+	for i := 0; i < len(sList); i++ {
+		pbr.RegisterJsonRpcServer(sList[i], e1)
+	}
+	return nil
+}
+
+// Figure out the cluster from the genesis hash.  Anything unrecognized is LOCAL.
+func ClusterFromRpc(ctx context.Context, rpcClient *sgorpc.Client) (pbr.Cluster, error) {
+	genesis, err := rpcClient.GetGenesisHash(ctx)
+	if err != nil {
+		return pbr.Cluster_LOCAL, err
+	}
+	switch genesis.String() {
+	case GENESIS_MAINNET:
+		return pbr.Cluster_MAINNET, nil
+	case GENESIS_TESTNET:
+		return pbr.Cluster_TESTNET, nil
+	case GENESIS_DEVNET:
+		return pbr.Cluster_DEVNET, nil
+	default:
+		return pbr.Cluster_LOCAL, nil
+	}
+}
+
+type jsonRpcRequest struct {
+	JsonRpc string            `json:"jsonrpc"`
+	Id      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type jsonRpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonRpcResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonRpcError   `json:"error,omitempty"`
+}
+
+func (e1 jsonRpcExternal) Rpc(ctx context.Context, req *pbr.Request) (*pbr.Body, error) {
+	body := req.GetBody()
+	if body == nil {
+		return nil, errors.New("no body")
+	}
+	payload := bytes.TrimSpace(body.Payload)
+	if len(payload) == 0 {
+		return nil, errors.New("blank payload")
+	}
+	if payload[0] == '[' {
+		return e1.batch(ctx, payload)
+	}
+	r := new(jsonRpcRequest)
+	err := json.Unmarshal(payload, r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Method == METHOD_SEND_TRANSACTION {
+		return e1.send_tx(ctx, r)
+	}
+	if !util.READ_ONLY_METHODS[r.Method] {
+		return marshalResponse(notAllowed(r))
+	}
+	return e1.forward(ctx, payload)
+}
+
+func notAllowed(r *jsonRpcRequest) jsonRpcResponse {
+	return jsonRpcResponse{JsonRpc: "2.0", Id: r.Id, Error: &jsonRpcError{
+		Code:    -32601,
+		Message: fmt.Sprintf("method %s is not allowed", r.Method),
+	}}
+}
+
+// Split a batch: sendTransaction entries go through the relay, disallowed
+// methods get an error entry and the rest are forwarded as one batch.  The
+// responses come back in one array; JSON RPC clients match them by id.
+func (e1 jsonRpcExternal) batch(ctx context.Context, payload []byte) (*pbr.Body, error) {
+	rawList := make([]json.RawMessage, 0)
+	err := json.Unmarshal(payload, &rawList)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawList) == 0 {
+		return e1.forward(ctx, payload)
+	}
+	ansList := make([]json.RawMessage, 0, len(rawList))
+	forwardList := make([]json.RawMessage, 0, len(rawList))
+	for _, raw := range rawList {
+		r := new(jsonRpcRequest)
+		err = json.Unmarshal(raw, r)
+		if err != nil {
+			return nil, err
+		}
+		var body *pbr.Body
+		switch {
+		case r.Method == METHOD_SEND_TRANSACTION:
+			body, err = e1.send_tx(ctx, r)
+		case !util.READ_ONLY_METHODS[r.Method]:
+			body, err = marshalResponse(notAllowed(r))
+		default:
+			forwardList = append(forwardList, raw)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ansList = append(ansList, body.Payload)
+	}
+	if 0 < len(forwardList) {
+		data, err := json.Marshal(forwardList)
+		if err != nil {
+			return nil, err
+		}
+		body, err := e1.forward(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]json.RawMessage, 0, len(forwardList))
+		err = json.Unmarshal(body.Payload, &list)
+		if err != nil {
+			// the node answered the whole batch with one error
+			list = []json.RawMessage{body.Payload}
+		}
+		ansList = append(ansList, list...)
+	}
+	data, err := json.Marshal(ansList)
+	if err != nil {
+		return nil, err
+	}
+	return &pbr.Body{Payload: data}, nil
+}
+
+// pass a read-only request through to the rpc node
+func (e1 jsonRpcExternal) forward(ctx context.Context, payload []byte) (*pbr.Body, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e1.rpcUrl, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range e1.headers {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := e1.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &pbr.Body{Payload: data}, nil
+}
+
+// send the transaction via the relay on behalf of the authenticated bidder
+func (e1 jsonRpcExternal) send_tx(ctx context.Context, r *jsonRpcRequest) (*pbr.Body, error) {
+	sender, err := relay.GetPeerPubkey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ans := jsonRpcResponse{JsonRpc: "2.0", Id: r.Id}
+	tx, err := parseSendTransaction(r.Params)
+	if err != nil {
+		ans.Error = &jsonRpcError{Code: -32602, Message: err.Error()}
+		return marshalResponse(ans)
+	}
+	sig, err := e1.relay.Submit(ctx, sender, tx)
+	if err != nil {
+		ans.Error = &jsonRpcError{Code: -32002, Message: err.Error()}
+		return marshalResponse(ans)
+	}
+	ans.Result = sig.String()
+	return marshalResponse(ans)
+}
+
+func marshalResponse(ans jsonRpcResponse) (*pbr.Body, error) {
+	data, err := json.Marshal(ans)
+	if err != nil {
+		return nil, err
+	}
+	return &pbr.Body{Payload: data}, nil
+}
+
+// params are [encoded_tx, {"encoding": "base58"|"base64"}]; base58 is the default
+func parseSendTransaction(params []json.RawMessage) (*sgo.Transaction, error) {
+	if len(params) == 0 {
+		return nil, errors.New("no transaction")
+	}
+	var encodedTx string
+	err := json.Unmarshal(params[0], &encodedTx)
+	if err != nil {
+		return nil, err
+	}
+	encoding := "base58"
+	if 1 < len(params) {
+		opts := struct {
+			Encoding string `json:"encoding"`
+		}{}
+		err = json.Unmarshal(params[1], &opts)
+		if err != nil {
+			return nil, err
+		}
+		if 0 < len(opts.Encoding) {
+			encoding = opts.Encoding
+		}
+	}
+	var data []byte
+	switch encoding {
+	case "base58":
+		data, err = base58.Decode(encodedTx)
+	case "base64":
+		data, err = base64.StdEncoding.DecodeString(encodedTx)
+	default:
+		err = fmt.Errorf("unknown encoding %s", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sgo.TransactionFromDecoder(bin.NewBinDecoder(data))
+}
+
+// The first message must name the cluster.  Every message after that is a
+// JSON RPC payload that is written to the websocket of the rpc node.
+func (e1 jsonRpcExternal) Ws(stream pbr.JsonRpc_WsServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	_, ok := first.GetData().(*pbr.Request_Cluster)
+	if !ok {
+		return errors.New("first message must set the cluster")
+	}
+	if first.GetCluster() != e1.cluster {
+		return fmt.Errorf("pipeline serves cluster %s, not %s", e1.cluster.String(), first.GetCluster().String())
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, e1.wsUrl, e1.headers)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	errorC := make(chan error, 2)
+	go loopWsFromBidder(stream, conn, errorC)
+	go loopWsFromNode(stream, conn, errorC)
+
+	select {
+	case <-ctx.Done():
+	case <-e1.ctx.Done():
+	case err = <-errorC:
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+func loopWsFromBidder(stream pbr.JsonRpc_WsServer, conn *websocket.Conn, errorC chan<- error) {
+	var err error
+out:
+	for {
+		var req *pbr.Request
+		req, err = stream.Recv()
+		if err != nil {
+			break out
+		}
+		body := req.GetBody()
+		if body == nil {
+			continue
+		}
+		r := new(jsonRpcRequest)
+		err = json.Unmarshal(body.Payload, r)
+		if err != nil {
+			break out
+		}
+		if !util.SUBSCRIPTION_METHODS[r.Method] {
+			err = fmt.Errorf("method %s is not allowed over websocket", r.Method)
+			break out
+		}
+		err = conn.WriteMessage(websocket.TextMessage, body.Payload)
+		if err != nil {
+			break out
+		}
+	}
+	errorC <- err
+}
+
+func loopWsFromNode(stream pbr.JsonRpc_WsServer, conn *websocket.Conn, errorC chan<- error) {
+	var err error
+out:
+	for {
+		var data []byte
+		_, data, err = conn.ReadMessage()
+		if err != nil {
+			break out
+		}
+		err = stream.Send(&pbr.Body{Payload: data})
+		if err != nil {
+			break out
+		}
+	}
+	errorC <- err
+}
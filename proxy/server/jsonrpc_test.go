@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	pbr "github.com/solpipe/solpipe-tool/proto/rpcproxy"
+	"github.com/solpipe/solpipe-tool/proxy"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRpcAllowList(t *testing.T) {
+	forwarded := make([]string, 0)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		forwarded = append(forwarded, string(data))
+		if data[0] == '[' {
+			w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":5}]`))
+		} else {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":5}`))
+		}
+	}))
+	t.Cleanup(node.Close)
+	e1 := jsonRpcExternal{ctx: context.Background(), rpcUrl: node.URL, httpClient: node.Client()}
+	rpc := func(payload string) (*pbr.Body, error) {
+		return e1.Rpc(context.Background(), &pbr.Request{
+			Data: &pbr.Request_Body{Body: &pbr.Body{Payload: []byte(payload)}},
+		})
+	}
+
+	body, err := rpc(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body.Payload) != `{"jsonrpc":"2.0","id":1,"result":5}` {
+		t.Fatalf("response %s", string(body.Payload))
+	}
+
+	body, err = rpc(`{"jsonrpc":"2.0","id":2,"method":"requestAirdrop","params":["x",1]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ans := new(jsonRpcResponse)
+	err = json.Unmarshal(body.Payload, ans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans.Error == nil || ans.Error.Code != -32601 || string(ans.Id) != "2" {
+		t.Fatalf("response %s", string(body.Payload))
+	}
+
+	// only the allowed part of a batch is forwarded
+	body, err = rpc(`[{"jsonrpc":"2.0","id":1,"method":"getSlot"},{"jsonrpc":"2.0","id":2,"method":"getProgramAccounts"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := batchResponse(t, body)
+	if len(m) != 2 || m["1"].Error != nil || m["2"].Error == nil || m["2"].Error.Code != -32601 {
+		t.Fatalf("response %s", string(body.Payload))
+	}
+	if len(forwarded) != 2 || forwarded[1] != `[{"jsonrpc":"2.0","id":1,"method":"getSlot"}]` {
+		t.Fatalf("forwarded %+v", forwarded)
+	}
+
+	// sendTransaction in a batch goes to the relay on behalf of the peer
+	bidder, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := proxy.NewSelfSignedTlsCertificateChainServer(bidder, []string{"localhost"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certList := make([]*x509.Certificate, len(cert.Certificate))
+	for i, data := range cert.Certificate {
+		certList[i], err = x509.ParseCertificate(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: certList}},
+	})
+	r := &testRelay{sig: sgo.Signature{7}}
+	e1.relay = r
+	tx, err := sgo.NewTransaction(
+		[]sgo.Instruction{sgosys.NewTransferInstruction(1, bidder.PublicKey(), bidder.PublicKey()).Build()},
+		sgo.Hash{1},
+		sgo.TransactionPayer(bidder.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txData, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = e1.Rpc(ctx, &pbr.Request{Data: &pbr.Request_Body{Body: &pbr.Body{Payload: []byte(
+		`[{"jsonrpc":"2.0","id":3,"method":"sendTransaction","params":["` + base64.StdEncoding.EncodeToString(txData) + `",{"encoding":"base64"}]},` +
+			`{"jsonrpc":"2.0","id":1,"method":"getSlot"}]`,
+	)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = batchResponse(t, body)
+	if len(m) != 2 || m["1"].Error != nil || m["3"].Error != nil || m["3"].Result != r.sig.String() {
+		t.Fatalf("response %s", string(body.Payload))
+	}
+	if !r.sender.Equals(bidder.PublicKey()) {
+		t.Fatalf("submitted for %s", r.sender.String())
+	}
+}
+
+type testRelay struct {
+	sig    sgo.Signature
+	sender sgo.PublicKey
+}
+
+func (r *testRelay) Submit(ctx context.Context, sender sgo.PublicKey, tx *sgo.Transaction) (sgo.Signature, error) {
+	r.sender = sender
+	return r.sig, nil
+}
+
+func (r *testRelay) AdjustRate(ctx context.Context, sender sgo.PublicKey, newRate float64) error {
+	return nil
+}
+
+func (r *testRelay) Wait(ctx context.Context, signature sgo.Signature) (relay.WaitResult, error) {
+	return relay.WaitResult{}, nil
+}
+
+// index the responses of a batch by id
+func batchResponse(t *testing.T, body *pbr.Body) map[string]jsonRpcResponse {
+	list := make([]jsonRpcResponse, 0)
+	err := json.Unmarshal(body.Payload, &list)
+	if err != nil {
+		t.Fatalf("response %s: %s", string(body.Payload), err.Error())
+	}
+	m := make(map[string]jsonRpcResponse)
+	for _, x := range list {
+		m[string(x.Id)] = x
+	}
+	return m
+}
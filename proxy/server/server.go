@@ -4,10 +4,11 @@ import (
 	"context"
 	"errors"
 
-	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/meter"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
 	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/signer"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 	"google.golang.org/grpc"
 )
@@ -17,9 +18,10 @@ type external struct {
 	ctx           context.Context
 	internalC     chan<- func(*internal)
 	router        rtr.Router
-	admin         sgo.PrivateKey
+	admin         signer.Signer
 	returnUpdateC chan<- UpdateRequest
 	relay         relay.Relay
+	store         meter.Store
 }
 
 // Listen for connections sending transactions and receipt updates.
@@ -28,21 +30,23 @@ func Attach(
 	ctx context.Context,
 	sList []*grpc.Server,
 	router rtr.Router,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	relay relay.Relay,
 	clearNetConfig *relay.ClearNetListenConfig,
+	store meter.Store,
 ) error {
 	ctx2, cancel := context.WithCancel(ctx)
 	log.Debugf("creating tor server with key=%s", admin.PublicKey().String())
 	internalC := make(chan func(*internal), 10)
 
-	go loopInternal(ctx2, cancel, internalC, router)
+	go loopInternal(ctx2, cancel, internalC, router, store)
 	e1 := external{
 		ctx:       ctx2,
 		internalC: internalC,
 		router:    router,
 		admin:     admin,
 		relay:     relay,
+		store:     store,
 	}
 	for i := 0; i < len(sList); i++ {
 		s := sList[i]
@@ -3,16 +3,22 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 
+	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/meter"
 	pbj "github.com/solpipe/solpipe-tool/proto/job"
+	"github.com/solpipe/solpipe-tool/proxy/relay"
+	"github.com/solpipe/solpipe-tool/tracing"
 	"github.com/solpipe/solpipe-tool/util"
 	sgo "github.com/SolmateDev/solana-go"
 	bin "github.com/gagliardetto/binary"
 )
 
 // sender has sent a transaction and the receiver has received it
-func (e1 external) Submit(req *pbj.Request, stream pbj.Transaction_SubmitServer) error {
-	ctx := stream.Context()
+func (e1 external) Submit(req *pbj.Request, stream pbj.Transaction_SubmitServer) (err error) {
+	ctx := tracing.Extract(stream.Context())
 	doneC := ctx.Done()
 	if req == nil {
 		return errors.New("blank request")
@@ -22,7 +28,7 @@ func (e1 external) Submit(req *pbj.Request, stream pbj.Transaction_SubmitServer)
 		return errors.New("blank transaction")
 	}
 
-	err := stream.Send(&pbj.Response{
+	err = stream.Send(&pbj.Response{
 		Status: pbj.Status_NEW,
 	})
 	if err != nil {
@@ -37,11 +43,19 @@ func (e1 external) Submit(req *pbj.Request, stream pbj.Transaction_SubmitServer)
 	if err != nil {
 		return err
 	}
+	ctx, span := tracing.StartTx(ctx, "server.submit", txHash)
+	defer func() { span.End(err) }()
+
 	// authorize the connection
 	r, err := e1.get_receipt(ctx, txHash)
 	if err != nil {
 		return err
 	}
+	span.SetAttribute("sender", r.sender.String())
+	err = e1.record(r, txHash)
+	if err != nil {
+		return err
+	}
 	select {
 	case <-doneC:
 		err = errors.New("canceled")
@@ -51,16 +65,39 @@ func (e1 external) Submit(req *pbj.Request, stream pbj.Transaction_SubmitServer)
 		return err
 	}
 
-	sig, err := e1.relay.Submit(ctx, r.sender, r.tx)
+	relayCtx, relaySpan := tracing.Start(ctx, "server.forward")
+	sig, err := e1.relay.Submit(relayCtx, r.sender, r.tx)
+	relaySpan.End(err)
 	if err != nil {
+		// the sender is not told, but the trace still shows the failure
+		span.End(err)
 		return nil
 	}
-	err = e1.relay.Wait(ctx, sig)
+	span.SetAttribute("signature", sig.String())
+	err = stream.Send(&pbj.Response{
+		Status: pbj.Status_STARTED,
+	})
+	if err != nil {
+		return err
+	}
+	_, confirmSpan := tracing.Start(ctx, "server.confirm")
+	result, err := e1.relay.Wait(ctx, sig)
 	if err != nil {
+		confirmSpan.End(err)
+		span.End(err)
 		return nil
 	}
-
-	return nil
+	confirmSpan.SetAttribute("status", result.Status.String())
+	confirmSpan.SetAttribute("slot", strconv.FormatUint(result.Slot, 10))
+	confirmSpan.End(nil)
+	status := pbj.Status_FINISHED
+	if result.Status != relay.WAIT_LANDED {
+		log.Debugf("signature=%s %s at slot=%d: %+v", sig.String(), result.Status.String(), result.Slot, result.TxErr)
+		status = pbj.Status_FAILED
+	}
+	return stream.Send(&pbj.Response{
+		Status: status,
+	})
 }
 
 // sender is sending a transaction, so look up to see if it has been authenticated
@@ -99,3 +136,34 @@ func (e1 external) get_receipt(ctx context.Context, txHash sgo.Hash) (a *receipt
 
 	return
 }
+
+// record the transaction so the count and merkle root match the receipt, even
+// after a restart.  A receipt whose count does not follow on from what has
+// been recorded is rejected, and the transaction is not recorded.
+func (e1 external) record(r *receiptWithArgs, txHash sgo.Hash) error {
+	recorder, err := e1.store.Open(r.sender, r.pair.Payout)
+	if err != nil {
+		return err
+	}
+	err = e1.watch_payout(r.pair.Payout)
+	if err != nil {
+		return err
+	}
+	if r.pair.TxCount == 0 {
+		return fmt.Errorf("sender=%s receipt count is 0", r.sender.String())
+	}
+	// checked and appended in one step so that racing submissions from the
+	// same sender cannot both be recorded
+	summary, err := recorder.AppendIfCount(r.pair.TxCount-1, []meter.SingleRecord{{Signature: txHash}})
+	if errors.Is(err, meter.ErrCountMismatch) {
+		return fmt.Errorf("sender=%s receipt count %d does not match expected count %d", r.sender.String(), r.pair.TxCount, summary.MsgCounter+1)
+	}
+	return err
+}
+
+// Evict the recorders for payout from the store once the payout closes.
+func (e1 external) watch_payout(payout sgo.PublicKey) error {
+	return e1.send_cb(e1.ctx, func(in *internal) {
+		in.watch_payout(payout)
+	})
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/meter/lite"
+)
+
+func TestRecordConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	store, err := lite.Create(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	// watch_payout needs a router, so drop the callbacks
+	internalC := make(chan func(*internal), 10)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-internalC:
+			}
+		}
+	}()
+	e1 := external{ctx: ctx, internalC: internalC, store: store}
+	sender := sgo.NewWallet().PublicKey()
+	payout := sgo.NewWallet().PublicKey()
+
+	// every submission carries the same count; only one may be recorded
+	n := 20
+	errorC := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errorC <- e1.record(&receiptWithArgs{
+				sender: sender,
+				pair:   &ReceiptPair{TxCount: 1, Payout: payout},
+			}, sgo.Hash{byte(i + 1)})
+		}(i)
+	}
+	wg.Wait()
+	close(errorC)
+	ok := 0
+	for err := range errorC {
+		if err == nil {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("%d submissions recorded", ok)
+	}
+	recorder, err := store.Open(sender, payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := recorder.AllRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 record, have %d", len(list))
+	}
+
+	err = e1.record(&receiptWithArgs{
+		sender: sender,
+		pair:   &ReceiptPair{TxCount: 3, Payout: payout},
+	}, sgo.Hash{99})
+	if err == nil {
+		t.Fatal("skipped count was recorded")
+	}
+	if recorder.Summary().MsgCounter != 1 {
+		t.Fatalf("mismatched count was appended; have %d", recorder.Summary().MsgCounter)
+	}
+}
@@ -142,6 +142,12 @@ out:
 					break out
 				}
 				pair = pairFromBidReceiptArgs(args)
+				impl, ok := instruction.Impl.(*cba.UpdateBidReceipt)
+				if !ok || impl.GetPayoutAccount() == nil {
+					err = errors.New("no payout account")
+					break out
+				}
+				pair.Payout = impl.GetPayoutAccount().PublicKey
 			case cba.Instruction_UpdateReceipt:
 				// pipeline
 				args := new(cba.UpdateReceipt)
@@ -158,6 +164,12 @@ out:
 					break out
 				}
 				pair = pairFromPipelineReceiptArgs(args)
+				impl, ok := instruction.Impl.(*cba.UpdateReceipt)
+				if !ok || impl.GetPayoutAccount() == nil {
+					err = errors.New("no payout account")
+					break out
+				}
+				pair.Payout = impl.GetPayoutAccount().PublicKey
 			}
 			err = e1.set_receipt(writer.ctx, sender, tx, pair, sendReceiptUpdateToSenderC)
 			if err != nil {
@@ -202,6 +214,7 @@ type UpdateRequest struct {
 type ReceiptPair struct {
 	LastTx  sgo.Hash
 	TxCount uint32
+	Payout  sgo.PublicKey
 }
 
 func pairFromPipelineReceiptArgs(args *cba.UpdateReceipt) ReceiptPair {
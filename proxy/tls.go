@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/tls"
@@ -15,10 +16,12 @@ import (
 
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/signer"
 )
 
-func generateCa(key sgo.PrivateKey) ([]byte, *ed25519.PrivateKey, error) {
-	caPrivKey := ed25519.PrivateKey(key)
+// the admin key signs the CA, so a remote signer only signs at start up
+func generateCa(key signer.Signer) ([]byte, crypto.Signer, error) {
+	caPrivKey := signer.Crypto(key)
 	ca := &x509.Certificate{
 		SerialNumber: big.NewInt(2019),
 		Subject: pkix.Name{
@@ -41,12 +44,12 @@ func generateCa(key sgo.PrivateKey) ([]byte, *ed25519.PrivateKey, error) {
 		return nil, nil, err
 	}
 
-	return caBytes, &caPrivKey, nil
+	return caBytes, caPrivKey, nil
 }
 
 func generateEphemeralCert(
 	caBytes []byte,
-	caPrivKey ed25519.PrivateKey,
+	caPrivKey crypto.Signer,
 ) ([]byte, *ed25519.PrivateKey, error) {
 	ca, err := x509.ParseCertificate(caBytes)
 	if err != nil {
@@ -99,7 +102,7 @@ func generateEphemeralCert(
 }
 
 func NewSelfSignedTlsCertificateChainServer(
-	key sgo.PrivateKey,
+	key signer.Signer,
 	dnsAddr []string,
 	expire time.Time,
 ) (*tls.Certificate, error) {
@@ -108,7 +111,7 @@ func NewSelfSignedTlsCertificateChainServer(
 	if err != nil {
 		return nil, err
 	}
-	cert, priv, err := generateEphemeralCert(ca, *capriv)
+	cert, priv, err := generateEphemeralCert(ca, capriv)
 	if err != nil {
 		return nil, err
 	}
@@ -46,9 +46,10 @@ func Schedule(
 	)
 
 	return external{
-		ctx:    ctxC,
-		cancel: cancel,
-		reqC:   trackHome.ReqC,
+		ctx:       ctxC,
+		cancel:    cancel,
+		internalC: internalC,
+		reqC:      trackHome.ReqC,
 	}
 }
 
@@ -73,6 +74,30 @@ type TriggerStaker struct {
 	Payout  pyt.Payout
 }
 
+// payload of TRIGGER_STAKER_ADD
+func ReadTrigger(event sch.Event) (*TriggerStaker, error) {
+	if event.Payload == nil {
+		return nil, errors.New("no payload")
+	}
+	d, ok := event.Payload.(*TriggerStaker)
+	if !ok {
+		return nil, errors.New("bad format for payload")
+	}
+	return d, nil
+}
+
+// payload of TRIGGER_STAKER_WITHDRAW
+func ReadTriggerReceipt(event sch.Event) (*TriggerReceipt, error) {
+	if event.Payload == nil {
+		return nil, errors.New("no payload")
+	}
+	d, ok := event.Payload.(*TriggerReceipt)
+	if !ok {
+		return nil, errors.New("bad format for payload")
+	}
+	return d, nil
+}
+
 func (e1 external) History() ([]sch.Event, error) {
 	doneC := e1.ctx.Done()
 	ansC := make(chan []sch.Event, 1)
@@ -6,6 +6,7 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
@@ -16,7 +17,7 @@ func (e1 *Script) CloseBids(
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
 	payout pyt.Payout,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 ) error {
 	if e1.txBuilder == nil {
 		return errors.New("blank tx builder")
@@ -49,7 +50,7 @@ func (e1 *Script) Bid(
 	bid cba.Bid,
 	pipeline pipe.Pipeline,
 	payout pyt.Payout,
-	bidAdmin sgo.PrivateKey,
+	bidAdmin signer.Signer,
 	bidFund sgo.PublicKey,
 ) error {
 	if e1.txBuilder == nil {
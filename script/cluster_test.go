@@ -0,0 +1,29 @@
+package script_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+// A cluster with a funded payer for the tests that land transactions.
+func newFunded(t *testing.T) (context.Context, cluster.Funded) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	t.Cleanup(cancel)
+	f, err := cluster.CreateFunded(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx, f
+}
+
+func newKey(t *testing.T) sgo.PrivateKey {
+	key, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
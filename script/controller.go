@@ -4,13 +4,14 @@ import (
 	"errors"
 
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/state"
 	vrs "github.com/solpipe/solpipe-tool/state/version"
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 )
 
-func (e1 *Script) CreateController(adminKey sgo.PrivateKey, crankAuthority sgo.PrivateKey, mint sgo.PublicKey, feeRate *state.Rate) error {
+func (e1 *Script) CreateController(adminKey signer.Signer, crankAuthority signer.Signer, mint sgo.PublicKey, feeRate *state.Rate) error {
 	if e1.txBuilder == nil {
 		return errors.New("tx builder is nil")
 	}
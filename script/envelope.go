@@ -0,0 +1,394 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	sgotkn "github.com/SolmateDev/solana-go/programs/token"
+	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
+)
+
+const ENVELOPE_VERSION = 1
+
+// A partially signed transaction passed around the people who hold its keys.
+// The message is fixed when the envelope is created; each signer reads the
+// Summary and adds a signature, and the envelope is submitted once Missing is
+// empty.
+type Envelope struct {
+	Version     int                 `json:"version"`
+	Note        string              `json:"note,omitempty"`
+	Transaction []byte              `json:"transaction"`
+	History     []EnvelopeSignature `json:"history"` // oldest first
+}
+
+type EnvelopeSignature struct {
+	PublicKey string    `json:"public_key"`
+	Time      time.Time `json:"time"`
+}
+
+func CreateEnvelope(tx *sgo.Transaction, note string) (*Envelope, error) {
+	env := &Envelope{Version: ENVELOPE_VERSION, Note: note, History: make([]EnvelopeSignature, 0)}
+	err := env.set(tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Like ExportTx, but put the transaction in an envelope.  Keys not held here
+// should be given to the script as signer.Offline.
+func (e1 *Script) Envelope(note string) (*Envelope, error) {
+	data, err := e1.ExportTx(true)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ParseTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return CreateEnvelope(tx, note)
+}
+
+// Decode an envelope and check the signatures it already holds.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	env := new(Envelope)
+	err := json.Unmarshal(data, env)
+	if err != nil {
+		return nil, err
+	}
+	if env.Version != ENVELOPE_VERSION {
+		return nil, fmt.Errorf("unknown envelope version %d", env.Version)
+	}
+	_, err = env.Tx()
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func (env *Envelope) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// The transaction, with every signature present checked against the message.
+func (env *Envelope) Tx() (*sgo.Transaction, error) {
+	tx, err := ParseTransaction(env.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	n := int(tx.Message.Header.NumRequiredSignatures)
+	if len(tx.Message.AccountKeys) < n || len(tx.Signatures) != n {
+		return nil, errors.New("envelope transaction has the wrong number of signatures")
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		if tx.Signatures[i].IsZero() {
+			continue
+		}
+		if !tx.Signatures[i].Verify(tx.Message.AccountKeys[i], message) {
+			return nil, fmt.Errorf("bad signature from %s; the transaction changed after it was signed", tx.Message.AccountKeys[i].String())
+		}
+	}
+	return tx, nil
+}
+
+func (env *Envelope) set(tx *sgo.Transaction, added []sgo.PublicKey) error {
+	n := int(tx.Message.Header.NumRequiredSignatures)
+	for len(tx.Signatures) < n {
+		tx.Signatures = append(tx.Signatures, sgo.Signature{})
+	}
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	env.Transaction = data
+	now := time.Now()
+	for _, key := range added {
+		env.History = append(env.History, EnvelopeSignature{PublicKey: key.String(), Time: now})
+	}
+	return nil
+}
+
+// Add signatures from the keys in list that the transaction requires and
+// return the keys that signed.
+func (env *Envelope) Sign(list ...signer.Signer) ([]sgo.PublicKey, error) {
+	tx, err := env.Tx()
+	if err != nil {
+		return nil, err
+	}
+	before := make([]bool, len(tx.Signatures))
+	for i, sig := range tx.Signatures {
+		before[i] = !sig.IsZero()
+	}
+	err = signer.PartialSignTx(tx, list...)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]sgo.PublicKey, 0)
+	for i, sig := range tx.Signatures {
+		if !before[i] && !sig.IsZero() {
+			added = append(added, tx.Message.AccountKeys[i])
+		}
+	}
+	err = env.set(tx, added)
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// the keys that have yet to sign
+func (env *Envelope) Missing() ([]sgo.PublicKey, error) {
+	tx, err := env.Tx()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]sgo.PublicKey, 0)
+	for i, sig := range tx.Signatures {
+		if sig.IsZero() {
+			list = append(list, tx.Message.AccountKeys[i])
+		}
+	}
+	return list, nil
+}
+
+// A description of what the transaction does, for people to read before they
+// sign it.
+func (env *Envelope) Summary() (string, error) {
+	tx, err := env.Tx()
+	if err != nil {
+		return "", err
+	}
+	b := new(strings.Builder)
+	if 0 < len(env.Note) {
+		fmt.Fprintf(b, "note: %s\n", env.Note)
+	}
+	b.WriteString(SummarizeTx(tx))
+	return b.String(), nil
+}
+
+// Describe the fee payer, blockhash, each instruction and who has signed.
+func SummarizeTx(tx *sgo.Transaction) string {
+	b := new(strings.Builder)
+	if 0 < len(tx.Message.AccountKeys) {
+		fmt.Fprintf(b, "fee payer: %s\n", tx.Message.AccountKeys[0].String())
+	}
+	if nonce, ok := DurableNonce(tx); ok {
+		fmt.Fprintf(b, "blockhash: %s (durable nonce %s)\n", tx.Message.RecentBlockhash.String(), nonce.String())
+	} else {
+		fmt.Fprintf(b, "blockhash: %s (expires about a minute after the transaction was built)\n", tx.Message.RecentBlockhash.String())
+	}
+	b.WriteString("instructions:\n")
+	for i, ci := range tx.Message.Instructions {
+		fmt.Fprintf(b, "  %d. %s\n", i, summarizeInstruction(tx, ci))
+	}
+	b.WriteString("signatures:\n")
+	for i := 0; i < int(tx.Message.Header.NumRequiredSignatures) && i < len(tx.Message.AccountKeys); i++ {
+		status := "missing"
+		if i < len(tx.Signatures) && !tx.Signatures[i].IsZero() {
+			status = "signed"
+		}
+		fmt.Fprintf(b, "  %s %s\n", tx.Message.AccountKeys[i].String(), status)
+	}
+	return b.String()
+}
+
+func summarizeInstruction(tx *sgo.Transaction, ci sgo.CompiledInstruction) string {
+	programId, err := tx.ResolveProgramIDIndex(ci.ProgramIDIndex)
+	if err != nil {
+		return fmt.Sprintf("unknown program: %s", err.Error())
+	}
+	accountList := ci.ResolveInstructionAccounts(&tx.Message)
+	switch {
+	case programId.Equals(sgo.SystemProgramID):
+		return summarizeSystem(accountList, ci.Data)
+	case programId.Equals(sgo.ComputeBudget):
+		return summarizeComputeBudget(ci.Data)
+	case programId.Equals(cba.ProgramID):
+		return summarizeCba(accountList, ci.Data)
+	case programId.Equals(sgo.TokenProgramID):
+		return summarizeToken(accountList, ci.Data)
+	case programId.Equals(sgo.SPLAssociatedTokenAccountProgramID):
+		return fmt.Sprintf(
+			"create associated token account %s for %s with mint %s, paid by %s",
+			at(accountList, 1), at(accountList, 2), at(accountList, 3), at(accountList, 0),
+		)
+	default:
+		return fmt.Sprintf("program %s with %d accounts and %d bytes of data", programId.String(), len(accountList), len(ci.Data))
+	}
+}
+
+func summarizeSystem(accountList []*sgo.AccountMeta, data []byte) string {
+	inst, err := sgosys.DecodeInstruction(accountList, data)
+	if err != nil {
+		return fmt.Sprintf("system: undecodable instruction: %s", err.Error())
+	}
+	switch impl := inst.Impl.(type) {
+	case *sgosys.CreateAccount:
+		return fmt.Sprintf(
+			"system: create account %s with %s lamports and %s bytes owned by %s, paid by %s",
+			meta(impl.GetNewAccount()), num(impl.Lamports), num(impl.Space), num(impl.Owner), meta(impl.GetFundingAccount()),
+		)
+	case *sgosys.Transfer:
+		return fmt.Sprintf(
+			"system: transfer %s lamports from %s to %s",
+			num(impl.Lamports), meta(impl.GetFundingAccount()), meta(impl.GetRecipientAccount()),
+		)
+	case *sgosys.AdvanceNonceAccount:
+		return fmt.Sprintf("system: advance nonce %s (authority %s)", meta(impl.GetNonceAccount()), meta(impl.GetNonceAuthorityAccount()))
+	case *sgosys.InitializeNonceAccount:
+		return fmt.Sprintf("system: initialize nonce %s (authority %s)", meta(impl.GetNonceAccount()), num(impl.Authorized))
+	default:
+		return fmt.Sprintf("system: %s", typeName(inst.Impl))
+	}
+}
+
+// Accounts are read by index as the token getters panic on a short list.
+func summarizeToken(accountList []*sgo.AccountMeta, data []byte) string {
+	inst, err := sgotkn.DecodeInstruction(accountList, data)
+	if err != nil {
+		return fmt.Sprintf("token: undecodable instruction: %s", err.Error())
+	}
+	switch impl := inst.Impl.(type) {
+	case *sgotkn.Transfer:
+		return fmt.Sprintf(
+			"token: transfer %s from %s to %s, authorized by %s",
+			num(impl.Amount), at(impl.Accounts, 0), at(impl.Accounts, 1), at(impl.Accounts, 2),
+		)
+	case *sgotkn.TransferChecked:
+		return fmt.Sprintf(
+			"token: transfer %s (%s decimals) of mint %s from %s to %s, authorized by %s",
+			num(impl.Amount), num(impl.Decimals), at(impl.Accounts, 1), at(impl.Accounts, 0), at(impl.Accounts, 2), at(impl.Accounts, 3),
+		)
+	case *sgotkn.MintTo:
+		return fmt.Sprintf(
+			"token: mint %s of %s to %s, authorized by %s",
+			num(impl.Amount), at(impl.Accounts, 0), at(impl.Accounts, 1), at(impl.Accounts, 2),
+		)
+	case *sgotkn.MintToChecked:
+		return fmt.Sprintf(
+			"token: mint %s (%s decimals) of %s to %s, authorized by %s",
+			num(impl.Amount), num(impl.Decimals), at(impl.Accounts, 0), at(impl.Accounts, 1), at(impl.Accounts, 2),
+		)
+	case *sgotkn.Burn:
+		return fmt.Sprintf(
+			"token: burn %s of mint %s from %s, authorized by %s",
+			num(impl.Amount), at(impl.Accounts, 1), at(impl.Accounts, 0), at(impl.Accounts, 2),
+		)
+	case *sgotkn.Approve:
+		return fmt.Sprintf(
+			"token: let %s spend %s from %s, authorized by %s",
+			at(impl.Accounts, 1), num(impl.Amount), at(impl.Accounts, 0), at(impl.Accounts, 2),
+		)
+	case *sgotkn.CloseAccount:
+		return fmt.Sprintf(
+			"token: close %s and send the rent to %s, authorized by %s",
+			at(impl.Accounts, 0), at(impl.Accounts, 1), at(impl.Accounts, 2),
+		)
+	default:
+		return fmt.Sprintf("token: %s", typeName(inst.Impl))
+	}
+}
+
+func summarizeComputeBudget(data []byte) string {
+	switch {
+	case len(data) == 5 && data[0] == COMPUTE_BUDGET_INSTRUCTION_LIMIT:
+		return fmt.Sprintf("compute budget: limit of %d units", binary.LittleEndian.Uint32(data[1:5]))
+	case len(data) == 9 && data[0] == COMPUTE_BUDGET_INSTRUCTION_PRICE:
+		return fmt.Sprintf("compute budget: price of %d micro-lamports per unit", binary.LittleEndian.Uint64(data[1:9]))
+	default:
+		return "compute budget"
+	}
+}
+
+// The admin actions get every setting spelled out; the rest just get a name.
+func summarizeCba(accountList []*sgo.AccountMeta, data []byte) string {
+	inst, err := cba.DecodeInstruction(accountList, data)
+	if err != nil {
+		return fmt.Sprintf("cba: undecodable instruction: %s", err.Error())
+	}
+	switch inst.TypeID {
+	case cba.Instruction_Create:
+		if impl, ok := inst.Impl.(*cba.Create); ok {
+			return fmt.Sprintf(
+				"cba: create controller %s with admin %s, crank authority %s, mint %s and controller fee %s",
+				meta(impl.GetControllerAccount()), meta(impl.GetAdminAccount()), meta(impl.GetCrankAuthorityAccount()),
+				meta(impl.GetPcMintAccount()), rate(impl.ControllerFeeNum, impl.ControllerFeeDen),
+			)
+		}
+	case cba.Instruction_AddPipeline:
+		if impl, ok := inst.Impl.(*cba.AddPipeline); ok {
+			return fmt.Sprintf(
+				"cba: add pipeline %s with admin %s, crank fee %s, validator payout share %s, allotment %s, tick size %s, refund space %s",
+				meta(impl.GetPipelineAccount()), meta(impl.GetAdminAccount()),
+				rate(impl.CrankFeeRateNum, impl.CrankFeeRateDen), rate(impl.ValidatorPayoutShareNum, impl.ValidatorPayoutShareDen),
+				num(impl.Allotment), num(impl.TickSize), num(impl.RefundSpace),
+			)
+		}
+	case cba.Instruction_UpdatePipeline:
+		if impl, ok := inst.Impl.(*cba.UpdatePipeline); ok {
+			return fmt.Sprintf(
+				"cba: update pipeline %s; admin %s hands over to %s; crank fee %s, validator payout share %s, allotment %s, tick size %s",
+				meta(impl.GetPipelineAccount()), meta(impl.GetAdminAccount()), meta(impl.GetNewAdminAccount()),
+				rate(impl.CrankFeeRateNum, impl.CrankFeeRateDen), rate(impl.ValidatorPayoutShareNum, impl.ValidatorPayoutShareDen),
+				num(impl.Allotment), num(impl.TickSize),
+			)
+		}
+	case cba.Instruction_ValidatorSetPayout:
+		if impl, ok := inst.Impl.(*cba.ValidatorSetPayout); ok {
+			return fmt.Sprintf(
+				"cba: validator %s (admin %s) joins payout %s of pipeline %s with receipt %s",
+				meta(impl.GetValidatorManagerAccount()), meta(impl.GetValidatorAdminAccount()),
+				meta(impl.GetPayoutAccount()), meta(impl.GetPipelineAccount()), meta(impl.GetReceiptAccount()),
+			)
+		}
+	case cba.Instruction_ClaimRefund:
+		if impl, ok := inst.Impl.(*cba.ClaimRefund); ok {
+			return fmt.Sprintf(
+				"cba: pay refund from pipeline %s to %s",
+				meta(impl.GetPipelineAccount()), meta(impl.GetUserFundAccount()),
+			)
+		}
+	}
+	return fmt.Sprintf("cba: %s", typeName(inst.Impl))
+}
+
+func meta(m *sgo.AccountMeta) string {
+	if m == nil {
+		return "?"
+	}
+	return m.PublicKey.String()
+}
+
+func at(list []*sgo.AccountMeta, i int) string {
+	if len(list) <= i {
+		return "?"
+	}
+	return meta(list[i])
+}
+
+func num[T any](x *T) string {
+	if x == nil {
+		return "?"
+	}
+	return fmt.Sprintf("%v", *x)
+}
+
+func rate(n *uint64, d *uint64) string {
+	return num(n) + "/" + num(d)
+}
+
+func typeName(impl interface{}) string {
+	name := fmt.Sprintf("%T", impl)
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,73 @@
+package script_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+)
+
+// Signers in TestEnvelopeSign each hold one key; here the signed envelope
+// has to land on a cluster.
+func TestEnvelope(t *testing.T) {
+	ctx, f := newFunded(t)
+	c, rpcClient, wsClient, payer, s := f.Cluster, f.RpcClient, f.WsClient, f.Payer, f.Script
+	source, authority, nonce, destination := newKey(t), newKey(t), newKey(t), newKey(t)
+	err := script.Airdrop(ctx, rpcClient, wsClient, source.PublicKey(), sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateNonce(payer, nonce, authority.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Tick()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetNonceTx(signer.Offline(payer.PublicKey()), nonce.PublicKey(), signer.Offline(authority.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Transfer(signer.Offline(source.PublicKey()), destination.PublicKey(), sgo.LAMPORTS_PER_SOL/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := s.Envelope("move funds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := env.Summary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(summary, "advance nonce "+nonce.PublicKey().String()) {
+		t.Fatalf("summary lacks the nonce:\n%s", summary)
+	}
+	for _, key := range []sgo.PrivateKey{source, authority, payer} {
+		_, err = env.Sign(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx, err := env.Tx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := script.Send(ctx, rpcClient, wsClient, tx, script.SendOpts{Interval: 50 * time.Millisecond})
+	if o.Status != script.SEND_LANDED {
+		t.Fatalf("unexpected outcome %+v", o)
+	}
+}
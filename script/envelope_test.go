@@ -0,0 +1,247 @@
+package script
+
+import (
+	"strings"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	sgotkn "github.com/SolmateDev/solana-go/programs/token"
+	cba "github.com/solpipe/cba"
+)
+
+// a transfer from source, paid by payer, using a durable nonce held by authority
+func testEnvelope(t *testing.T) (env *Envelope, payer, source, authority sgo.PrivateKey, nonce, destination sgo.PublicKey) {
+	payer, source, authority = sgo.NewWallet().PrivateKey, sgo.NewWallet().PrivateKey, sgo.NewWallet().PrivateKey
+	nonce, destination = sgo.NewWallet().PublicKey(), sgo.NewWallet().PublicKey()
+	tx, err := sgo.NewTransaction(
+		[]sgo.Instruction{
+			sgosys.NewAdvanceNonceAccountInstruction(nonce, sgo.SysVarRecentBlockHashesPubkey, authority.PublicKey()).Build(),
+			computeUnitPrice(10),
+			sgosys.NewTransferInstruction(sgo.LAMPORTS_PER_SOL/2, source.PublicKey(), destination).Build(),
+		},
+		sgo.Hash{1},
+		sgo.TransactionPayer(payer.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err = CreateEnvelope(tx, "move funds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestEnvelopeSummary(t *testing.T) {
+	env, payer, source, authority, nonce, destination := testEnvelope(t)
+	summary, err := env.Summary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []string{
+		"note: move funds",
+		"fee payer: " + payer.PublicKey().String(),
+		"(durable nonce " + nonce.String() + ")",
+		"0. system: advance nonce " + nonce.String() + " (authority " + authority.PublicKey().String() + ")",
+		"1. compute budget: price of 10 micro-lamports per unit",
+		"2. system: transfer 500000000 lamports from " + source.PublicKey().String() + " to " + destination.String(),
+		source.PublicKey().String() + " missing",
+	} {
+		if !strings.Contains(summary, x) {
+			t.Fatalf("summary lacks %q:\n%s", x, summary)
+		}
+	}
+}
+
+func TestEnvelopeSign(t *testing.T) {
+	env, payer, source, authority, _, _ := testEnvelope(t)
+	missing, err := env.Missing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 3 {
+		t.Fatalf("missing %d signatures", len(missing))
+	}
+	// the envelope goes from one person to the next
+	for _, key := range []sgo.PrivateKey{source, authority, payer} {
+		data, err := env.MarshalIndent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err = ParseEnvelope(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		added, err := env.Sign(key, sgo.NewWallet().PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(added) != 1 || !added[0].Equals(key.PublicKey()) {
+			t.Fatalf("signed by %+v", added)
+		}
+		// nobody can change the transaction once someone has signed
+		tampered := *env
+		tampered.Transaction = append([]byte{}, env.Transaction...)
+		tampered.Transaction[len(tampered.Transaction)-1]++
+		_, err = tampered.Tx()
+		if err == nil {
+			t.Fatal("tampered envelope passed")
+		}
+	}
+	if len(env.History) != 3 {
+		t.Fatalf("history %+v", env.History)
+	}
+	missing, err = env.Missing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("still missing %+v", missing)
+	}
+	tx, err := env.Tx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.VerifySignatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSummarizeToken(t *testing.T) {
+	source, destination, owner, mint := sgo.NewWallet().PublicKey(), sgo.NewWallet().PublicKey(), sgo.NewWallet().PublicKey(), sgo.NewWallet().PublicKey()
+	for _, x := range []struct {
+		inst    sgo.Instruction
+		summary string
+	}{
+		{
+			sgotkn.NewTransferInstruction(700, source, destination, owner, nil).Build(),
+			"token: transfer 700 from " + source.String() + " to " + destination.String() + ", authorized by " + owner.String(),
+		},
+		{
+			sgotkn.NewTransferCheckedInstruction(700, 2, source, mint, destination, owner, nil).Build(),
+			"token: transfer 700 (2 decimals) of mint " + mint.String() + " from " + source.String() + " to " + destination.String() + ", authorized by " + owner.String(),
+		},
+		{
+			sgotkn.NewMintToInstruction(5, mint, destination, owner, nil).Build(),
+			"token: mint 5 of " + mint.String() + " to " + destination.String() + ", authorized by " + owner.String(),
+		},
+		{
+			sgotkn.NewCloseAccountInstruction(source, destination, owner, nil).Build(),
+			"token: close " + source.String() + " and send the rent to " + destination.String() + ", authorized by " + owner.String(),
+		},
+	} {
+		data, err := x.inst.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary := summarizeToken(x.inst.Accounts(), data)
+		if summary != x.summary {
+			t.Errorf("summary %q != %q", summary, x.summary)
+		}
+	}
+
+	// too few accounts must not panic
+	data, err := sgotkn.NewTransferInstruction(700, source, destination, owner, nil).Build().Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := summarizeToken([]*sgo.AccountMeta{sgo.Meta(source)}, data)
+	if summary != "token: transfer 700 from "+source.String()+" to ?, authorized by ?" {
+		t.Errorf("summary %q", summary)
+	}
+}
+
+func TestSummarizeCba(t *testing.T) {
+	if cba.ProgramID.IsZero() {
+		cba.SetProgramID(sgo.NewWallet().PublicKey())
+	}
+	k := make([]sgo.PublicKey, 6)
+	for i := range k {
+		k[i] = sgo.NewWallet().PublicKey()
+	}
+
+	create := cba.NewCreateInstructionBuilder()
+	create.SetControllerAccount(k[0])
+	create.SetAdminAccount(k[1])
+	create.SetCrankAuthorityAccount(k[2])
+	create.SetPcMintAccount(k[3])
+	create.SetControllerFeeNum(uint64(1))
+	create.SetControllerFeeDen(uint64(100))
+
+	add := cba.NewAddPipelineInstructionBuilder()
+	add.SetPipelineAccount(k[0])
+	add.SetAdminAccount(k[1])
+	add.SetCrankFeeRateNum(uint64(1))
+	add.SetCrankFeeRateDen(uint64(50))
+	add.SetValidatorPayoutShareNum(uint64(9))
+	add.SetValidatorPayoutShareDen(uint64(10))
+	add.SetAllotment(uint16(20))
+	add.SetTickSize(uint16(1))
+	add.SetRefundSpace(uint16(30))
+
+	update := cba.NewUpdatePipelineInstructionBuilder()
+	update.SetPipelineAccount(k[0])
+	update.SetAdminAccount(k[1])
+	update.SetNewAdminAccount(k[2])
+	update.SetCrankFeeRateNum(uint64(2))
+	update.SetCrankFeeRateDen(uint64(50))
+	update.SetValidatorPayoutShareNum(uint64(8))
+	update.SetValidatorPayoutShareDen(uint64(10))
+	update.SetAllotment(uint16(25))
+	update.SetTickSize(uint16(2))
+
+	setPayout := cba.NewValidatorSetPayoutInstructionBuilder()
+	setPayout.SetValidatorManagerAccount(k[0])
+	setPayout.SetValidatorAdminAccount(k[1])
+	setPayout.SetPayoutAccount(k[2])
+	setPayout.SetPipelineAccount(k[3])
+	setPayout.SetReceiptAccount(k[4])
+
+	refund := cba.NewClaimRefundInstructionBuilder()
+	refund.SetPipelineAccount(k[0])
+	refund.SetUserFundAccount(k[5])
+
+	for _, x := range []struct {
+		inst    *cba.Instruction
+		summary string
+	}{
+		{
+			create.Build(),
+			"cba: create controller " + k[0].String() + " with admin " + k[1].String() + ", crank authority " + k[2].String() +
+				", mint " + k[3].String() + " and controller fee 1/100",
+		},
+		{
+			add.Build(),
+			"cba: add pipeline " + k[0].String() + " with admin " + k[1].String() +
+				", crank fee 1/50, validator payout share 9/10, allotment 20, tick size 1, refund space 30",
+		},
+		{
+			update.Build(),
+			"cba: update pipeline " + k[0].String() + "; admin " + k[1].String() + " hands over to " + k[2].String() +
+				"; crank fee 2/50, validator payout share 8/10, allotment 25, tick size 2",
+		},
+		{
+			setPayout.Build(),
+			"cba: validator " + k[0].String() + " (admin " + k[1].String() + ") joins payout " + k[2].String() +
+				" of pipeline " + k[3].String() + " with receipt " + k[4].String(),
+		},
+		{
+			refund.Build(),
+			"cba: pay refund from pipeline " + k[0].String() + " to " + k[5].String(),
+		},
+	} {
+		data, err := x.inst.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) == 0 {
+			t.Skip("cba bindings do not encode instructions")
+		}
+		summary := summarizeCba(x.inst.Accounts(), data)
+		if summary != x.summary {
+			t.Errorf("summary %q != %q", summary, x.summary)
+		}
+	}
+}
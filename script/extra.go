@@ -11,9 +11,10 @@ import (
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
 	bin "github.com/gagliardetto/binary"
+	"github.com/solpipe/solpipe-tool/signer"
 )
 
-func (e1 *Script) CreateAccountDirect(size uint64, account sgo.PrivateKey, owner sgo.PublicKey, payer sgo.PrivateKey) (sgo.PublicKey, error) {
+func (e1 *Script) CreateAccountDirect(size uint64, account sgo.PrivateKey, owner sgo.PublicKey, payer signer.Signer) (sgo.PublicKey, error) {
 	if e1.txBuilder == nil {
 		return sgo.PublicKey{}, errors.New("tx builder is blank")
 	}
@@ -35,7 +36,7 @@ func (e1 *Script) CreateAccountDirect(size uint64, account sgo.PrivateKey, owner
 }
 
 // provide space=x bytes to create an empty account
-func (e1 *Script) CreateAccount(size uint64, owner sgo.PublicKey, payer sgo.PrivateKey) (sgo.PublicKey, error) {
+func (e1 *Script) CreateAccount(size uint64, owner sgo.PublicKey, payer signer.Signer) (sgo.PublicKey, error) {
 	if e1.txBuilder == nil {
 		return sgo.PublicKey{}, errors.New("tx builder is blank")
 	}
@@ -109,7 +110,7 @@ func GetTokenAccount(ctx context.Context, rpcClient *sgorpc.Client, owner sgo.Pu
 }
 
 func (e1 *Script) token_account(
-	payer sgo.PrivateKey,
+	payer signer.Signer,
 	owner sgo.PublicKey,
 	mint sgo.PublicKey,
 ) (accountId sgo.PublicKey, err error) {
@@ -0,0 +1,193 @@
+package script
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	COMPUTE_BUDGET_INSTRUCTION_LIMIT = 2
+	COMPUTE_BUDGET_INSTRUCTION_PRICE = 3
+	MAX_COMPUTE_UNITS                = 1_400_000
+	COMPUTE_UNIT_MARGIN_PERCENT      = 10
+)
+
+// How CBA transactions bid for inclusion under congestion.  The price is in
+// micro-lamports per compute unit.
+type FeePolicy struct {
+	MicroLamports    uint64 `json:"micro_lamports"`     // fixed price; the floor when Percentile is set
+	Percentile       uint8  `json:"percentile"`         // 1-100; price at this percentile of recent fees paid on the writable accounts
+	MaxMicroLamports uint64 `json:"max_micro_lamports"` // cap on the price; 0 means no cap
+	SimulateLimit    bool   `json:"simulate_limit"`     // set the compute unit limit from a simulation instead of the maximum
+}
+
+func (fp *FeePolicy) Check() error {
+	if 100 < fp.Percentile {
+		return errors.New("percentile must be at most 100")
+	}
+	if 0 < fp.MaxMicroLamports && fp.MaxMicroLamports < fp.MicroLamports {
+		return errors.New("max micro lamports is below the fixed price")
+	}
+	return nil
+}
+
+// A ComputeBudget instruction whose data is filled in just before the
+// transaction is signed.
+type computeBudget struct {
+	data []byte
+}
+
+func computeUnitLimit(units uint32) *computeBudget {
+	cb := &computeBudget{data: make([]byte, 5)}
+	cb.data[0] = COMPUTE_BUDGET_INSTRUCTION_LIMIT
+	cb.setLimit(units)
+	return cb
+}
+
+func computeUnitPrice(microLamports uint64) *computeBudget {
+	cb := &computeBudget{data: make([]byte, 9)}
+	cb.data[0] = COMPUTE_BUDGET_INSTRUCTION_PRICE
+	cb.setPrice(microLamports)
+	return cb
+}
+
+func (cb *computeBudget) setLimit(units uint32) {
+	binary.LittleEndian.PutUint32(cb.data[1:5], units)
+}
+
+func (cb *computeBudget) setPrice(microLamports uint64) {
+	binary.LittleEndian.PutUint64(cb.data[1:9], microLamports)
+}
+
+func (cb *computeBudget) ProgramID() sgo.PublicKey {
+	return sgo.ComputeBudget
+}
+
+func (cb *computeBudget) Accounts() []*sgo.AccountMeta {
+	return []*sgo.AccountMeta{}
+}
+
+func (cb *computeBudget) Data() ([]byte, error) {
+	return cb.data, nil
+}
+
+// ComputeBudget instructions go first so they apply to the whole transaction.
+func (e1 *Script) startFee() {
+	e1.limit = nil
+	e1.price = nil
+	fp := e1.config.Fee
+	if fp == nil {
+		return
+	}
+	e1.limit = computeUnitLimit(MAX_COMPUTE_UNITS)
+	e1.price = computeUnitPrice(fp.MicroLamports)
+	e1.txBuilder.AddInstruction(e1.limit)
+	e1.txBuilder.AddInstruction(e1.price)
+}
+
+// Set the price and limit once every other instruction is in.  Failing to
+// fetch fees or simulate leaves the fixed price and maximum limit in place.
+func (e1 *Script) setFee() error {
+	fp := e1.config.Fee
+	if fp == nil || e1.price == nil {
+		return nil
+	}
+	tx, err := e1.txBuilder.Build()
+	if err != nil {
+		return err
+	}
+	price := fp.MicroLamports
+	if 0 < fp.Percentile {
+		recent, err := e1.recentFee(tx, fp.Percentile)
+		if err != nil {
+			log.Debugf("failed to get recent prioritization fees: %s", err.Error())
+		} else if price < recent {
+			price = recent
+		}
+	}
+	if 0 < fp.MaxMicroLamports && fp.MaxMicroLamports < price {
+		price = fp.MaxMicroLamports
+	}
+	e1.price.setPrice(price)
+	if fp.SimulateLimit {
+		units, err := e1.simulateUnits(tx)
+		if err != nil {
+			log.Debugf("failed to simulate compute units: %s", err.Error())
+		} else {
+			e1.limit.setLimit(units)
+		}
+	}
+	return nil
+}
+
+type prioritizationFee struct {
+	Slot              uint64 `json:"slot"`
+	PrioritizationFee uint64 `json:"prioritizationFee"`
+}
+
+func (e1 *Script) recentFee(tx *sgo.Transaction, percentile uint8) (uint64, error) {
+	accountList := make([]string, 0)
+	for i, key := range tx.Message.AccountKeys {
+		if tx.Message.IsWritable(key) && i != 0 {
+			accountList = append(accountList, key.String())
+		}
+	}
+	var list []prioritizationFee
+	err := e1.rpc.RPCCallForInto(e1.ctx, &list, "getRecentPrioritizationFees", []interface{}{accountList})
+	if err != nil {
+		return 0, err
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	feeList := make([]uint64, len(list))
+	for i, x := range list {
+		feeList[i] = x.PrioritizationFee
+	}
+	return feePercentile(feeList, percentile), nil
+}
+
+// the smallest fee at or above the given percentile of feeList; feeList is sorted in place
+func feePercentile(feeList []uint64, percentile uint8) uint64 {
+	if len(feeList) == 0 {
+		return 0
+	}
+	sort.Slice(feeList, func(i, j int) bool { return feeList[i] < feeList[j] })
+	i := (len(feeList)*int(percentile) + 99) / 100
+	if 0 < i {
+		i--
+	}
+	return feeList[i]
+}
+
+// Simulated with the maximum limit, plus a margin as account state can change
+// before the transaction lands.
+func (e1 *Script) simulateUnits(tx *sgo.Transaction) (uint32, error) {
+	// the simulation does not verify signatures, but they must be there
+	tx.Signatures = make([]sgo.Signature, tx.Message.Header.NumRequiredSignatures)
+	resp, err := e1.rpc.SimulateTransactionWithOpts(e1.ctx, tx, &sgorpc.SimulateTransactionOpts{
+		Commitment: sgorpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.Value == nil {
+		return 0, errors.New("blank simulation result")
+	}
+	if resp.Value.Err != nil {
+		return 0, errors.New("simulation failed")
+	}
+	if resp.Value.UnitsConsumed == nil {
+		return 0, errors.New("simulation did not report compute units")
+	}
+	units := *resp.Value.UnitsConsumed * (100 + COMPUTE_UNIT_MARGIN_PERCENT) / 100
+	if MAX_COMPUTE_UNITS < units {
+		units = MAX_COMPUTE_UNITS
+	}
+	return uint32(units), nil
+}
@@ -0,0 +1,93 @@
+package script_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	"github.com/solpipe/solpipe-tool/script"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+func TestPriorityFee(t *testing.T) {
+	ctx, f := newFunded(t)
+	c, rpcClient, wsClient, payer := f.Cluster, f.RpcClient, f.WsClient, f.Payer
+	destination := newKey(t)
+
+	transfer := func(fp *script.FeePolicy) {
+		s, err := script.Create(ctx, &script.Configuration{Version: vrs.VERSION_1, Fee: fp}, rpcClient, wsClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.SetTx(payer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Transfer(payer, destination.PublicKey(), sgo.LAMPORTS_PER_SOL/10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.FinishTx(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.Tick()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	transfer(&script.FeePolicy{MicroLamports: 100})
+	// the 100th percentile is capped
+	transfer(&script.FeePolicy{MicroLamports: 1, Percentile: 100, MaxMicroLamports: 50, SimulateLimit: true})
+
+	list, err := rpcClient.GetSignaturesForAddress(ctx, destination.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("%d transactions", len(list))
+	}
+	priceM := make(map[uint64]uint32)
+	for _, x := range list {
+		var version uint64
+		result, err := rpcClient.GetTransaction(ctx, x.Signature, &sgorpc.GetTransactionOpts{
+			Encoding:                       sgo.EncodingBase64,
+			MaxSupportedTransactionVersion: &version,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := script.ParseTransaction(result.Transaction.GetBinary())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var limit uint32
+		var price uint64
+		for _, ci := range tx.Message.Instructions {
+			programId, err := tx.ResolveProgramIDIndex(ci.ProgramIDIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !programId.Equals(sgo.ComputeBudget) {
+				continue
+			}
+			switch ci.Data[0] {
+			case script.COMPUTE_BUDGET_INSTRUCTION_LIMIT:
+				limit = binary.LittleEndian.Uint32(ci.Data[1:5])
+			case script.COMPUTE_BUDGET_INSTRUCTION_PRICE:
+				price = binary.LittleEndian.Uint64(ci.Data[1:9])
+			}
+		}
+		priceM[price] = limit
+	}
+	if priceM[100] != script.MAX_COMPUTE_UNITS {
+		t.Fatalf("fixed price limit %d", priceM[100])
+	}
+	// two compute budget instructions and the transfer, plus the margin
+	expected := uint32(3 * cluster.COMPUTE_UNITS_PER_INSTRUCTION * (100 + script.COMPUTE_UNIT_MARGIN_PERCENT) / 100)
+	if priceM[50] != expected {
+		t.Fatalf("simulated limit %d != %d (prices %+v)", priceM[50], expected, priceM)
+	}
+}
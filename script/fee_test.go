@@ -0,0 +1,63 @@
+package script
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestFeePercentile(t *testing.T) {
+	feeList := []uint64{50, 10, 40, 20, 30, 0, 90, 60, 80, 70}
+	for _, x := range []struct {
+		percentile uint8
+		fee        uint64
+	}{
+		{1, 0},
+		{10, 0},
+		{11, 10},
+		{50, 40},
+		{75, 70},
+		{100, 90},
+	} {
+		fee := feePercentile(append([]uint64{}, feeList...), x.percentile)
+		if fee != x.fee {
+			t.Errorf("percentile %d: %d != %d", x.percentile, fee, x.fee)
+		}
+	}
+	if feePercentile(nil, 50) != 0 {
+		t.Error("fee without any recent fees")
+	}
+}
+
+func TestFeePolicyCheck(t *testing.T) {
+	for _, fp := range []FeePolicy{
+		{Percentile: 101},
+		{MicroLamports: 10, MaxMicroLamports: 5},
+	} {
+		if fp.Check() == nil {
+			t.Errorf("accepted %+v", fp)
+		}
+	}
+	fp := FeePolicy{MicroLamports: 5, Percentile: 100, MaxMicroLamports: 10}
+	if err := fp.Check(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestComputeBudget(t *testing.T) {
+	data, err := computeUnitLimit(200_000).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 5 || data[0] != COMPUTE_BUDGET_INSTRUCTION_LIMIT || binary.LittleEndian.Uint32(data[1:]) != 200_000 {
+		t.Fatalf("limit %x", data)
+	}
+	price := computeUnitPrice(1)
+	price.setPrice(7)
+	data, err = price.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 9 || data[0] != COMPUTE_BUDGET_INSTRUCTION_PRICE || binary.LittleEndian.Uint64(data[1:]) != 7 {
+		t.Fatalf("price %x", data)
+	}
+}
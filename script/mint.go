@@ -11,6 +11,7 @@ import (
 	sgosys "github.com/SolmateDev/solana-go/programs/system"
 	sgotkn "github.com/SolmateDev/solana-go/programs/token"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	"github.com/solpipe/solpipe-tool/signer"
 )
 
 const MINT_SIZE = 4 + 40 + 8 + 1 + 1 + 4 + 40
@@ -53,7 +54,7 @@ func LoadMint(ctx context.Context, mintId sgo.PublicKey, authority sgo.PrivateKe
 	return mr, nil
 }
 
-func (e1 *Script) CreateMint(payer sgo.PrivateKey, authority sgo.PrivateKey, decimals uint8) (*MintResult, error) {
+func (e1 *Script) CreateMint(payer signer.Signer, authority sgo.PrivateKey, decimals uint8) (*MintResult, error) {
 	mint, err := sgo.NewRandomPrivateKey()
 	if err != nil {
 		return nil, err
@@ -61,7 +62,7 @@ func (e1 *Script) CreateMint(payer sgo.PrivateKey, authority sgo.PrivateKey, dec
 	return e1.CreateMintDirect(mint, payer, authority, decimals)
 }
 
-func (e1 *Script) CreateMintDirect(mint sgo.PrivateKey, payer sgo.PrivateKey, authority sgo.PrivateKey, decimals uint8) (*MintResult, error) {
+func (e1 *Script) CreateMintDirect(mint sgo.PrivateKey, payer signer.Signer, authority sgo.PrivateKey, decimals uint8) (*MintResult, error) {
 	if e1.txBuilder == nil {
 		return nil, errors.New("tx builder is blank")
 	}
@@ -88,7 +89,7 @@ func (e1 *Script) CreateMintDirect(mint sgo.PrivateKey, payer sgo.PrivateKey, au
 
 }
 
-func (e1 *Script) CreateTokenAccount(payer sgo.PrivateKey, owner sgo.PublicKey, mint sgo.PublicKey) error {
+func (e1 *Script) CreateTokenAccount(payer signer.Signer, owner sgo.PublicKey, mint sgo.PublicKey) error {
 	if e1.txBuilder == nil {
 		return errors.New("tx builder is blank")
 	}
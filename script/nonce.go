@@ -0,0 +1,122 @@
+package script
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	bin "github.com/gagliardetto/binary"
+	"github.com/solpipe/solpipe-tool/signer"
+)
+
+const (
+	NONCE_ACCOUNT_SIZE      = 80
+	NONCE_STATE_INITIALIZED = 1
+	NONCE_ACCOUNT_INDEX     = 0 // the nonce account is the first account of AdvanceNonceAccount
+)
+
+// Create a durable nonce account.  Transactions that use the nonce must be
+// signed by authority.
+func (e1 *Script) CreateNonce(payer signer.Signer, nonce signer.Signer, authority sgo.PublicKey) error {
+	if e1.txBuilder == nil {
+		return errors.New("tx builder is blank")
+	}
+	lamports, err := e1.rpc.GetMinimumBalanceForRentExemption(e1.ctx, NONCE_ACCOUNT_SIZE, sgorpc.CommitmentFinalized)
+	if err != nil {
+		return err
+	}
+	b := sgosys.NewCreateAccountInstructionBuilder()
+	b.SetFundingAccount(payer.PublicKey())
+	e1.AppendKey(payer)
+	b.SetLamports(lamports)
+	b.SetNewAccount(nonce.PublicKey())
+	e1.AppendKey(nonce)
+	b.SetOwner(sgo.SystemProgramID)
+	b.SetSpace(NONCE_ACCOUNT_SIZE)
+	e1.txBuilder.AddInstruction(b.Build())
+
+	e1.txBuilder.AddInstruction(sgosys.NewInitializeNonceAccountInstruction(
+		authority,
+		nonce.PublicKey(),
+		sgo.SysVarRecentBlockHashesPubkey,
+		sgo.SysVarRentPubkey,
+	).Build())
+	return nil
+}
+
+// Like SetTx, but the transaction takes its blockhash from a durable nonce
+// account, so it stays valid until the nonce is advanced rather than expiring
+// after a minute or so.  Use this to sign on another machine.
+func (e1 *Script) SetNonceTx(payer signer.Signer, nonce sgo.PublicKey, authority signer.Signer) error {
+	if e1.txBuilder != nil {
+		return errors.New("tx builder already started")
+	}
+	e1.txBuilder = sgo.NewTransactionBuilder()
+	e1.txBuilder.SetFeePayer(payer.PublicKey())
+	e1.keyMap = make(map[string]signer.Signer)
+	e1.AppendKey(payer)
+	// the runtime only treats the transaction as durable if this goes first
+	e1.txBuilder.AddInstruction(sgosys.NewAdvanceNonceAccountInstruction(
+		nonce,
+		sgo.SysVarRecentBlockHashesPubkey,
+		authority.PublicKey(),
+	).Build())
+	e1.AppendKey(authority)
+	e1.nonce = &nonce
+	e1.startFee()
+	return nil
+}
+
+// Fetch and decode a durable nonce account.  The Nonce field is the blockhash
+// that transactions using the account must carry.
+func GetNonce(ctx context.Context, rpcClient *sgorpc.Client, nonce sgo.PublicKey) (*sgosys.NonceAccount, error) {
+	resp, err := rpcClient.GetAccountInfo(ctx, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Value == nil {
+		return nil, errors.New("nonce account does not exist")
+	}
+	if !resp.Value.Owner.Equals(sgo.SystemProgramID) {
+		return nil, errors.New("nonce account is not owned by the system program")
+	}
+	return ParseNonce(resp.Value.Data.GetBinary())
+}
+
+func ParseNonce(data []byte) (*sgosys.NonceAccount, error) {
+	if len(data) != NONCE_ACCOUNT_SIZE {
+		return nil, errors.New("wrong size for a nonce account")
+	}
+	na := new(sgosys.NonceAccount)
+	err := bin.NewBinDecoder(data).Decode(na)
+	if err != nil {
+		return nil, err
+	}
+	if na.State != NONCE_STATE_INITIALIZED {
+		return nil, errors.New("nonce account is not initialized")
+	}
+	return na, nil
+}
+
+// Return the nonce account if tx uses a durable nonce instead of a recent
+// blockhash.
+func DurableNonce(tx *sgo.Transaction) (nonce sgo.PublicKey, ok bool) {
+	if len(tx.Message.Instructions) == 0 {
+		return
+	}
+	ci := tx.Message.Instructions[0]
+	programId, err := tx.ResolveProgramIDIndex(ci.ProgramIDIndex)
+	if err != nil || !programId.Equals(sgo.SystemProgramID) {
+		return
+	}
+	if len(ci.Data) < 4 || binary.LittleEndian.Uint32(ci.Data[0:4]) != sgosys.Instruction_AdvanceNonceAccount {
+		return
+	}
+	if len(ci.Accounts) <= NONCE_ACCOUNT_INDEX || len(tx.Message.AccountKeys) <= int(ci.Accounts[NONCE_ACCOUNT_INDEX]) {
+		return
+	}
+	return tx.Message.AccountKeys[ci.Accounts[NONCE_ACCOUNT_INDEX]], true
+}
@@ -0,0 +1,81 @@
+package script_test
+
+import (
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+func TestDurableNonceSend(t *testing.T) {
+	ctx, f := newFunded(t)
+	c, rpcClient, wsClient, payer, s := f.Cluster, f.RpcClient, f.WsClient, f.Payer, f.Script
+	authority, nonce, destination := newKey(t), newKey(t), newKey(t)
+	err := s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateNonce(payer, nonce, authority.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Tick()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the authority signs elsewhere, after the blockhash would have expired
+	export := func(amount uint64) []byte {
+		err := s.SetNonceTx(payer, nonce.PublicKey(), signer.Offline(authority.PublicKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Transfer(payer, destination.PublicKey(), amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := s.ExportTx(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	dataList := [][]byte{export(1_000_000), export(2_000_000)}
+	for i := uint64(0); i <= cluster.MAX_BLOCKHASH_AGE; i++ {
+		err = c.Tick()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	txList := make([]*sgo.Transaction, len(dataList))
+	for i, data := range dataList {
+		txList[i], err = script.ParseTransaction(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx := txList[i]; tx.VerifySignatures() == nil {
+			t.Fatal("verified without the authority signature")
+		}
+		err = signer.SignTx(txList[i], authority)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o := script.Send(ctx, rpcClient, wsClient, txList[0], script.SendOpts{Interval: 50 * time.Millisecond})
+	if o.Status != script.SEND_LANDED {
+		t.Fatalf("unexpected outcome %+v", o)
+	}
+	// the first transaction advanced the nonce
+	o = script.Send(ctx, rpcClient, wsClient, txList[1], script.SendOpts{Interval: 50 * time.Millisecond})
+	if o.Status == script.SEND_LANDED {
+		t.Fatal("transaction with a used nonce landed")
+	}
+}
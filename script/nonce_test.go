@@ -0,0 +1,61 @@
+package script
+
+import (
+	"encoding/binary"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+)
+
+func TestParseNonce(t *testing.T) {
+	authority := sgo.NewWallet().PublicKey()
+	blockhash := sgo.NewWallet().PublicKey()
+	data := make([]byte, NONCE_ACCOUNT_SIZE)
+	binary.LittleEndian.PutUint32(data[4:8], NONCE_STATE_INITIALIZED)
+	copy(data[8:40], authority.Bytes())
+	copy(data[40:72], blockhash.Bytes())
+
+	na, err := ParseNonce(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !na.AuthorizedPubkey.Equals(authority) || !na.Nonce.Equals(blockhash) {
+		t.Fatalf("nonce %+v", na)
+	}
+	_, err = ParseNonce(data[:NONCE_ACCOUNT_SIZE-1])
+	if err == nil {
+		t.Fatal("parsed a short account")
+	}
+	_, err = ParseNonce(make([]byte, NONCE_ACCOUNT_SIZE))
+	if err == nil {
+		t.Fatal("parsed an uninitialized account")
+	}
+}
+
+func TestDurableNonce(t *testing.T) {
+	payer := sgo.NewWallet().PublicKey()
+	nonce := sgo.NewWallet().PublicKey()
+	authority := sgo.NewWallet().PublicKey()
+	transfer := sgosys.NewTransferInstruction(1, payer, authority).Build()
+	advance := sgosys.NewAdvanceNonceAccountInstruction(nonce, sgo.SysVarRecentBlockHashesPubkey, authority).Build()
+
+	tx, err := sgo.NewTransaction([]sgo.Instruction{advance, transfer}, sgo.Hash{1}, sgo.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, ok := DurableNonce(tx)
+	if !ok || !id.Equals(nonce) {
+		t.Fatalf("nonce %s %t", id.String(), ok)
+	}
+
+	// the advance must come first
+	tx, err = sgo.NewTransaction([]sgo.Instruction{transfer, advance}, sgo.Hash{1}, sgo.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok = DurableNonce(tx)
+	if ok {
+		t.Fatal("nonce found after the first instruction")
+	}
+}
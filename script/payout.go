@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 	"github.com/solpipe/solpipe-tool/state/pipeline"
@@ -16,7 +17,7 @@ func (e1 *Script) ClosePayout(
 	controller ctr.Controller,
 	pipeline pipeline.Pipeline,
 	payout pyt.Payout,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 ) (err error) {
 	if e1.txBuilder == nil {
 		err = errors.New("no tx builder")
@@ -6,6 +6,7 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 )
@@ -14,7 +15,7 @@ import (
 func (e1 *Script) AppendPeriod(
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	start uint64,
 	length uint64,
 	withhold uint16,
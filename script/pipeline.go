@@ -6,6 +6,7 @@ import (
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	"github.com/solpipe/solpipe-tool/state"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	vrs "github.com/solpipe/solpipe-tool/state/version"
@@ -14,8 +15,8 @@ import (
 
 func (e1 *Script) AddPipeline(
 	controller ctr.Controller,
-	payer sgo.PrivateKey,
-	adminKey sgo.PrivateKey,
+	payer signer.Signer,
+	adminKey signer.Signer,
 	crankFee state.Rate,
 	allotment uint16,
 	validatorPayoutShare state.Rate,
@@ -45,8 +46,8 @@ func (e1 *Script) AddPipeline(
 func (e1 *Script) AddPipelineDirect(
 	pipelineKeypair sgo.PrivateKey,
 	controller ctr.Controller,
-	payer sgo.PrivateKey,
-	adminKey sgo.PrivateKey,
+	payer signer.Signer,
+	adminKey signer.Signer,
 	crankFee state.Rate,
 	allotment uint16,
 	validatorPayoutShare state.Rate,
@@ -122,7 +123,7 @@ func (e1 *Script) refundSize(refundSpace uint16) uint64 {
 func (e1 *Script) UpdatePipeline(
 	controller sgo.PublicKey,
 	pipelineId sgo.PublicKey,
-	adminKey sgo.PrivateKey,
+	adminKey signer.Signer,
 	crankFee state.Rate,
 	allotment uint16,
 	validatorPayoutShare state.Rate,
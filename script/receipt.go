@@ -3,13 +3,14 @@ package script
 import (
 	sgo "github.com/SolmateDev/solana-go"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 )
 
 type BidReceiptSettings struct {
 	Receipt       sgo.PublicKey
 	Controller    sgo.PublicKey
 	Pipeline      sgo.PublicKey
-	PipelineAdmin sgo.PrivateKey
+	PipelineAdmin signer.Signer
 	Payout        sgo.PublicKey
 }
 
@@ -37,7 +38,7 @@ type ReceiptSettings struct {
 	Receipt         sgo.PublicKey
 	Controller      sgo.PublicKey
 	Pipeline        sgo.PublicKey
-	PipelineAdmin   sgo.PrivateKey
+	PipelineAdmin   signer.Signer
 	Payout          sgo.PublicKey
 	ValidatorMember sgo.PublicKey
 }
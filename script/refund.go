@@ -5,6 +5,7 @@ import (
 
 	sgo "github.com/SolmateDev/solana-go"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 )
@@ -13,7 +14,7 @@ import (
 func (e1 *Script) MultipleClaimRefund(
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
-	payer sgo.PrivateKey,
+	payer signer.Signer,
 ) error {
 	list, err := pipeline.AllClaim()
 	if err != nil {
@@ -32,7 +33,7 @@ func (e1 *Script) ClaimRefund(
 	controller ctr.Controller,
 	pipeline pipe.Pipeline,
 	claim cba.Claim,
-	payer sgo.PrivateKey,
+	payer signer.Signer,
 ) error {
 	if e1.txBuilder == nil {
 		return errors.New("blank tx builder")
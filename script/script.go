@@ -5,14 +5,17 @@ import (
 	"errors"
 
 	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
 	bin "github.com/gagliardetto/binary"
+	"github.com/solpipe/solpipe-tool/signer"
 	vrs "github.com/solpipe/solpipe-tool/state/version"
 )
 
 type Configuration struct {
 	Version vrs.CbaVersion `json:"version"`
+	Fee     *FeePolicy     `json:"fee,omitempty"` // nil sends no ComputeBudget instructions
 }
 
 type Script struct {
@@ -21,14 +24,23 @@ type Script struct {
 	ws  *sgows.Client
 	//Controller ctr.Controller
 	txBuilder *sgo.TransactionBuilder
-	keyMap    map[string]sgo.PrivateKey
+	keyMap    map[string]signer.Signer
 	config    *Configuration
+	limit     *computeBudget
+	price     *computeBudget
+	nonce     *sgo.PublicKey // set by SetNonceTx
 }
 
 func Create(ctx context.Context, config *Configuration, rpcClient *sgorpc.Client, wsClient *sgows.Client) (*Script, error) {
 	if config == nil {
 		return nil, errors.New("no config")
 	}
+	if config.Fee != nil {
+		err := config.Fee.Check()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	e1 := &Script{
 		ctx: ctx, rpc: rpcClient, ws: wsClient,
@@ -38,10 +50,19 @@ func Create(ctx context.Context, config *Configuration, rpcClient *sgorpc.Client
 	return e1, nil
 }
 
-func (e1 *Script) AppendKey(key sgo.PrivateKey) {
+// key signs the transaction once it is finished
+func (e1 *Script) AppendKey(key signer.Signer) {
 	e1.keyMap[key.PublicKey().String()] = key
 }
 
+func (e1 *Script) signers() []signer.Signer {
+	list := make([]signer.Signer, 0, len(e1.keyMap))
+	for _, s := range e1.keyMap {
+		list = append(list, s)
+	}
+	return list
+}
+
 func (e1 *Script) AppendInstruction(instruction sgo.Instruction) error {
 	if e1.txBuilder == nil {
 		return errors.New("blank tx builder")
@@ -50,14 +71,16 @@ func (e1 *Script) AppendInstruction(instruction sgo.Instruction) error {
 	return nil
 }
 
-func (e1 *Script) SetTx(payer sgo.PrivateKey) error {
+func (e1 *Script) SetTx(payer signer.Signer) error {
 	if e1.txBuilder != nil {
 		return errors.New("tx builder already started")
 	}
 	e1.txBuilder = sgo.NewTransactionBuilder()
 	e1.txBuilder.SetFeePayer(payer.PublicKey())
-	e1.keyMap = make(map[string]sgo.PrivateKey)
+	e1.keyMap = make(map[string]signer.Signer)
 	e1.AppendKey(payer)
+	e1.nonce = nil
+	e1.startFee()
 
 	return nil
 }
@@ -131,54 +154,58 @@ func ParseTransaction(data []byte) (tx *sgo.Transaction, err error) {
 	return
 }
 
-func (e1 *Script) sendTxDirect(tx *sgo.Transaction, simulate bool) error {
-	return SendTx(e1.ctx, e1.rpc, e1.ws, tx, simulate)
-}
-
+// Send tx and wait for it to be finalized, rebroadcasting until its blockhash
+// expires.  Use Send to re-sign on expiry or to see what happened to tx.
 func SendTx(ctx context.Context, rpcClient *sgorpc.Client, wsClient *sgows.Client, tx *sgo.Transaction, simulate bool) error {
-	var err error
-	var sig sgo.Signature
-
-	sig, err = rpcClient.SendTransactionWithOpts(ctx, tx, sgorpc.TransactionOpts{SkipPreflight: !simulate})
-	if err != nil {
-		return err
-	}
-	err = WaitSig(ctx, wsClient, sig)
-	if err != nil {
-		return err
-	}
-	return nil
+	return Send(ctx, rpcClient, wsClient, tx, SendOpts{Simulate: simulate}).Error()
 }
 
-// Instead of sending the transaction via RPC, just export the transaction as a binary
+// Instead of sending the transaction via RPC, just export the transaction as a
+// binary.  With ignoreSigError, keys that sign elsewhere leave their signatures
+// blank.  Use SetNonceTx if the transaction is to be sent after its blockhash
+// would otherwise expire.
 func (e1 *Script) ExportTx(ignoreSigError bool) ([]byte, error) {
 	if e1.txBuilder == nil {
 		return nil, errors.New("no tx builder")
 	}
-	var err error
-	var rh *sgorpc.GetLatestBlockhashResult
-	e1.txBuilder.SetRecentBlockHash(rh.Value.Blockhash)
+	err := e1.SetBlockHash()
+	if err != nil {
+		return nil, err
+	}
+	err = e1.setFee()
+	if err != nil {
+		return nil, err
+	}
 	var tx *sgo.Transaction
 	tx, err = e1.txBuilder.Build()
 	if err != nil {
 		return nil, err
 	}
-	_, err = tx.Sign(func(p sgo.PublicKey) *sgo.PrivateKey {
-		x, present := e1.keyMap[p.String()]
-		if present {
-			return &x
-		}
-		return nil
-	})
-	if !ignoreSigError && err != nil {
+	if ignoreSigError {
+		err = signer.PartialSignTx(tx, e1.signers()...)
+	} else {
+		err = signer.SignTx(tx, e1.signers()...)
+	}
+	if err != nil {
 		return nil, err
-	} else if err != nil {
-		err = nil
 	}
+	e1.txBuilder = nil
+	e1.keyMap = nil
+	e1.nonce = nil
 	return tx.MarshalBinary()
 }
 
+// Use the latest blockhash, or the one stored in the nonce account.
 func (e1 *Script) SetBlockHash() (err error) {
+	if e1.nonce != nil {
+		var na *sgosys.NonceAccount
+		na, err = GetNonce(e1.ctx, e1.rpc, *e1.nonce)
+		if err != nil {
+			return
+		}
+		e1.txBuilder.SetRecentBlockHash(sgo.Hash(na.Nonce))
+		return
+	}
 	var rh *sgorpc.GetLatestBlockhashResult
 	rh, err = e1.rpc.GetLatestBlockhash(e1.ctx, sgorpc.CommitmentFinalized)
 	if err != nil {
@@ -189,38 +216,60 @@ func (e1 *Script) SetBlockHash() (err error) {
 }
 
 // compile the transaction and send it to a validator
-func (e1 *Script) FinishTx(simulate bool) (err error) {
+func (e1 *Script) FinishTx(simulate bool) error {
+	return e1.Finish(simulate).Error()
+}
+
+// Like FinishTx, but say what happened to the transaction.  The transaction is
+// re-signed with a fresh blockhash if the first one expires before it lands,
+// unless it uses a durable nonce.
+func (e1 *Script) Finish(simulate bool) (o Outcome) {
+	o.Status = SEND_REJECTED
 	if e1.txBuilder == nil {
-		err = errors.New("no tx builder")
+		o.Err = errors.New("no tx builder")
 		return
 	}
 
-	err = e1.SetBlockHash()
-	if err != nil {
+	o.Err = e1.SetBlockHash()
+	if o.Err != nil {
+		return
+	}
+	o.Err = e1.setFee()
+	if o.Err != nil {
 		return
 	}
 
+	builder := e1.txBuilder
+	list := e1.signers()
 	var tx *sgo.Transaction
-	tx, err = e1.txBuilder.Build()
-	if err != nil {
+	tx, o.Err = builder.Build()
+	if o.Err != nil {
 		return
 	}
-	_, err = tx.Sign(func(p sgo.PublicKey) *sgo.PrivateKey {
-		x, present := e1.keyMap[p.String()]
-		if present {
-			return &x
-		}
-		return nil
-	})
-	if err != nil {
+	o.Err = signer.SignTx(tx, list...)
+	if o.Err != nil {
 		return
 	}
+	durable := e1.nonce != nil
 	e1.txBuilder = nil
 	e1.keyMap = nil
-	err = e1.sendTxDirect(tx, simulate)
-	if err != nil {
-		return
+	e1.nonce = nil
+	if durable {
+		return Send(e1.ctx, e1.rpc, e1.ws, tx, SendOpts{Simulate: simulate})
 	}
-
-	return
+	return Send(e1.ctx, e1.rpc, e1.ws, tx, SendOpts{
+		Simulate: simulate,
+		Resign: func(blockhash sgo.Hash) (*sgo.Transaction, error) {
+			builder.SetRecentBlockHash(blockhash)
+			tx, err := builder.Build()
+			if err != nil {
+				return nil, err
+			}
+			err = signer.SignTx(tx, list...)
+			if err != nil {
+				return nil, err
+			}
+			return tx, nil
+		},
+	})
 }
@@ -0,0 +1,335 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RESEND_INTERVAL      = 2 * time.Second
+	MAX_BLOCKHASH_RESIGN = 3
+)
+
+type SendStatus int
+
+const (
+	SEND_REJECTED SendStatus = iota // the rpc node refused the transaction
+	SEND_LANDED                     // landed without error
+	SEND_FAILED                     // landed, but an instruction failed
+	SEND_EXPIRED                    // the blockhash expired, or the nonce advanced, before the transaction landed
+	SEND_CANCELED
+)
+
+func (s SendStatus) String() string {
+	switch s {
+	case SEND_REJECTED:
+		return "rejected"
+	case SEND_LANDED:
+		return "landed"
+	case SEND_FAILED:
+		return "failed"
+	case SEND_EXPIRED:
+		return "expired"
+	case SEND_CANCELED:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// What happened to a transaction given to Send.
+type Outcome struct {
+	Status      SendStatus
+	Signature   sgo.Signature // the transaction that landed, or the last one broadcast
+	Slot        uint64        // set if the transaction landed
+	Broadcasts  int
+	Blockhashes int // 1 plus the number of times the transaction was re-signed
+	Err         error
+}
+
+// nil if the transaction landed without error
+func (o Outcome) Error() error {
+	if o.Status == SEND_LANDED {
+		return nil
+	}
+	return &SendError{Outcome: o}
+}
+
+type SendError struct {
+	Outcome Outcome
+}
+
+func (e *SendError) Error() string {
+	if e.Outcome.Err == nil {
+		return fmt.Sprintf("transaction %s %s", e.Outcome.Signature.String(), e.Outcome.Status.String())
+	}
+	return fmt.Sprintf("transaction %s %s: %s", e.Outcome.Signature.String(), e.Outcome.Status.String(), e.Outcome.Err.Error())
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Outcome.Err
+}
+
+// Sign the same instructions again with a fresh blockhash.
+type Resign func(blockhash sgo.Hash) (*sgo.Transaction, error)
+
+type SendOpts struct {
+	Simulate   bool                  // preflight the first broadcast of each blockhash
+	Commitment sgorpc.CommitmentType // blank means finalized
+	Interval   time.Duration         // between broadcasts; 0 means RESEND_INTERVAL
+	Resign     Resign                // nil means give up once the blockhash expires
+	MaxResign  int                   // 0 means MAX_BLOCKHASH_RESIGN
+}
+
+type landResult struct {
+	sig  sgo.Signature
+	slot uint64
+	err  interface{}
+}
+
+// Broadcast tx every opts.Interval until it lands at opts.Commitment.  The
+// transaction is only re-signed once the finalized block height has passed
+// the last valid block height of its blockhash without the signature being
+// seen.  Every fork builds on the finalized block, so no fork can still
+// include the old transaction.  A transaction using a durable nonce is
+// broadcast until the nonce advances.
+// wsClient may be nil, in which case only polling tells when the transaction
+// lands.
+func Send(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
+	tx *sgo.Transaction,
+	opts SendOpts,
+) (o Outcome) {
+	if len(opts.Commitment) == 0 {
+		opts.Commitment = sgorpc.CommitmentFinalized
+	}
+	if opts.Interval == 0 {
+		opts.Interval = RESEND_INTERVAL
+	}
+	if opts.MaxResign == 0 {
+		opts.MaxResign = MAX_BLOCKHASH_RESIGN
+	}
+	ctxWatch, cancel := context.WithCancel(ctx)
+	defer cancel()
+	landC := make(chan landResult, 1+opts.MaxResign)
+	sigList := make([]sgo.Signature, 0, 1+opts.MaxResign)
+
+	o.Blockhashes = 1
+	// unknown for the transaction we were given
+	var lastValid uint64
+	sig, err := rpcClient.SendTransactionWithOpts(ctx, tx, sgorpc.TransactionOpts{SkipPreflight: !opts.Simulate})
+	o.Broadcasts++
+	if err != nil {
+		o.Status = SEND_REJECTED
+		o.Err = err
+		return
+	}
+	o.Signature = sig
+	sigList = append(sigList, sig)
+	if wsClient != nil {
+		go loopWatchSig(ctxWatch, wsClient, sig, opts.Commitment, landC)
+	}
+
+	doneC := ctx.Done()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-doneC:
+			o.Status = SEND_CANCELED
+			o.Err = errors.New("canceled")
+			return
+		case r := <-landC:
+			return o.land(r)
+		case <-ticker.C:
+		}
+
+		// subscriptions can drop notifications, so poll as well
+		r, seen, err := signatureStatus(ctx, rpcClient, opts.Commitment, sigList)
+		if err != nil {
+			log.Debugf("failed to get signature status: %s", err.Error())
+			continue
+		}
+		if r != nil {
+			return o.land(*r)
+		}
+		if seen {
+			// landed, but not yet at the commitment we want
+			continue
+		}
+		valid, err := blockhashValid(ctx, rpcClient, tx, lastValid)
+		if err != nil {
+			log.Debugf("failed to check blockhash: %s", err.Error())
+			continue
+		}
+		if valid {
+			_, err = rpcClient.SendTransactionWithOpts(ctx, tx, sgorpc.TransactionOpts{SkipPreflight: true})
+			o.Broadcasts++
+			if err != nil {
+				log.Debugf("failed to rebroadcast %s: %s", o.Signature.String(), err.Error())
+			}
+			continue
+		}
+
+		// the transaction may have landed just before the blockhash expired
+		r, seen, err = signatureStatus(ctx, rpcClient, opts.Commitment, sigList)
+		if err != nil {
+			log.Debugf("failed to get signature status: %s", err.Error())
+			continue
+		}
+		if r != nil {
+			return o.land(*r)
+		}
+		if seen {
+			continue
+		}
+		if _, durable := DurableNonce(tx); durable {
+			// re-signing cannot help, as the blockhash comes from the nonce
+			o.Status = SEND_EXPIRED
+			o.Err = errors.New("nonce advanced")
+			return
+		}
+		if opts.Resign == nil || opts.MaxResign < o.Blockhashes {
+			o.Status = SEND_EXPIRED
+			o.Err = errors.New("blockhash expired")
+			return
+		}
+		rh, err := rpcClient.GetLatestBlockhash(ctx, sgorpc.CommitmentFinalized)
+		if err != nil {
+			log.Debugf("failed to get blockhash: %s", err.Error())
+			continue
+		}
+		tx, err = opts.Resign(rh.Value.Blockhash)
+		if err != nil {
+			o.Status = SEND_EXPIRED
+			o.Err = fmt.Errorf("failed to re-sign: %w", err)
+			return
+		}
+		lastValid = rh.Value.LastValidBlockHeight
+		o.Blockhashes++
+		log.Debugf("blockhash expired for %s; re-signed (blockhashes=%d)", o.Signature.String(), o.Blockhashes)
+		sig, err = rpcClient.SendTransactionWithOpts(ctx, tx, sgorpc.TransactionOpts{SkipPreflight: !opts.Simulate})
+		o.Broadcasts++
+		if err != nil {
+			o.Status = SEND_REJECTED
+			o.Err = err
+			return
+		}
+		o.Signature = sig
+		sigList = append(sigList, sig)
+		if wsClient != nil {
+			go loopWatchSig(ctxWatch, wsClient, sig, opts.Commitment, landC)
+		}
+	}
+}
+
+// A transaction using a durable nonce stays valid until something advances the
+// nonce.  Otherwise the blockhash counts as valid until the finalized block
+// height passes lastValid; with lastValid=0, until the finalized bank no longer
+// knows the blockhash.
+func blockhashValid(ctx context.Context, rpcClient *sgorpc.Client, tx *sgo.Transaction, lastValid uint64) (bool, error) {
+	nonce, durable := DurableNonce(tx)
+	if durable {
+		na, err := GetNonce(ctx, rpcClient, nonce)
+		if err != nil {
+			return false, err
+		}
+		return sgo.Hash(na.Nonce).Equals(tx.Message.RecentBlockhash), nil
+	}
+	if 0 < lastValid {
+		height, err := rpcClient.GetBlockHeight(ctx, sgorpc.CommitmentFinalized)
+		if err != nil {
+			return false, err
+		}
+		return height <= lastValid, nil
+	}
+	valid, err := rpcClient.IsBlockhashValid(ctx, tx.Message.RecentBlockhash, sgorpc.CommitmentFinalized)
+	if err != nil {
+		return false, err
+	}
+	return valid.Value, nil
+}
+
+func (o Outcome) land(r landResult) Outcome {
+	o.Signature = r.sig
+	o.Slot = r.slot
+	if r.err != nil {
+		o.Status = SEND_FAILED
+		o.Err = fmt.Errorf("%+v", r.err)
+	} else {
+		o.Status = SEND_LANDED
+	}
+	return o
+}
+
+// Return the first signature in sigList that has reached commitment.  seen is
+// true if any has landed at all.
+func signatureStatus(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	commitment sgorpc.CommitmentType,
+	sigList []sgo.Signature,
+) (r *landResult, seen bool, err error) {
+	resp, err := rpcClient.GetSignatureStatuses(ctx, false, sigList...)
+	if err != nil {
+		return nil, false, err
+	}
+	for i, x := range resp.Value {
+		if x == nil || len(sigList) <= i {
+			continue
+		}
+		seen = true
+		if reached(x.ConfirmationStatus, commitment) {
+			return &landResult{sig: sigList[i], slot: x.Slot, err: x.Err}, true, nil
+		}
+	}
+	return nil, seen, nil
+}
+
+func reached(status sgorpc.ConfirmationStatusType, commitment sgorpc.CommitmentType) bool {
+	switch commitment {
+	case sgorpc.CommitmentProcessed:
+		return true
+	case sgorpc.CommitmentConfirmed:
+		return status == sgorpc.ConfirmationStatusConfirmed || status == sgorpc.ConfirmationStatusFinalized
+	default:
+		return status == sgorpc.ConfirmationStatusFinalized
+	}
+}
+
+func loopWatchSig(
+	ctx context.Context,
+	wsClient *sgows.Client,
+	sig sgo.Signature,
+	commitment sgorpc.CommitmentType,
+	landC chan<- landResult,
+) {
+	sub, err := wsClient.SignatureSubscribe(sig, commitment)
+	if err != nil {
+		log.Debugf("failed to subscribe to %s: %s", sig.String(), err.Error())
+		return
+	}
+	defer sub.Unsubscribe()
+	select {
+	case <-ctx.Done():
+	case <-sub.CloseSignal():
+	case d := <-sub.RecvStream():
+		x, ok := d.(*sgows.SignatureResult)
+		if !ok {
+			return
+		}
+		select {
+		case landC <- landResult{sig: sig, slot: x.Context.Slot, err: x.Value.Err}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package script_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/signer"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+func TestSend(t *testing.T) {
+	ctx, f := newFunded(t)
+	c, rpcClient, wsClient, payer, s := f.Cluster, f.RpcClient, f.WsClient, f.Payer, f.Script
+	destination := newKey(t)
+	// vary the amount so no two transactions share a signature
+	amount := uint64(sgo.LAMPORTS_PER_SOL / 10)
+	transfer := func() script.Outcome {
+		err := s.SetTx(payer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		amount++
+		err = s.Transfer(payer, destination.PublicKey(), amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Finish(true)
+	}
+	// expire the blockhash of everything sent so far
+	expire := func() {
+		time.Sleep(100 * time.Millisecond)
+		for i := uint64(0); i <= cluster.MAX_BLOCKHASH_AGE; i++ {
+			err := c.Tick()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+
+	// the first broadcast is dropped
+	err := c.Drop(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := transfer()
+	if o.Status != script.SEND_LANDED || o.Broadcasts != 2 || o.Blockhashes != 1 {
+		t.Fatalf("unexpected outcome %+v", o)
+	}
+
+	// everything is dropped until the blockhash expires
+	err = c.Drop(math.MaxInt32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		expire()
+		c.Drop(0)
+	}()
+	o = transfer()
+	if o.Status != script.SEND_LANDED || o.Blockhashes != 2 {
+		t.Fatalf("unexpected outcome %+v", o)
+	}
+
+	// without a way to re-sign, the transaction expires
+	rh, err := rpcClient.GetLatestBlockhash(ctx, sgorpc.CommitmentFinalized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := sgo.NewTransaction(
+		[]sgo.Instruction{sgosys.NewTransferInstruction(1, payer.PublicKey(), destination.PublicKey()).Build()},
+		rh.Value.Blockhash,
+		sgo.TransactionPayer(payer.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signer.SignTx(tx, payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Drop(math.MaxInt32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go expire()
+	o = script.Send(ctx, rpcClient, wsClient, tx, script.SendOpts{Interval: 50 * time.Millisecond})
+	if o.Status != script.SEND_EXPIRED {
+		t.Fatalf("unexpected outcome %+v", o)
+	}
+	var sendErr *script.SendError
+	if !errors.As(o.Error(), &sendErr) {
+		t.Fatalf("error %v", o.Error())
+	}
+}
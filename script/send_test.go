@@ -0,0 +1,48 @@
+package script
+
+import (
+	"errors"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+)
+
+func TestReached(t *testing.T) {
+	for _, x := range []struct {
+		status     sgorpc.ConfirmationStatusType
+		commitment sgorpc.CommitmentType
+		reached    bool
+	}{
+		{sgorpc.ConfirmationStatusProcessed, sgorpc.CommitmentProcessed, true},
+		{sgorpc.ConfirmationStatusProcessed, sgorpc.CommitmentConfirmed, false},
+		{sgorpc.ConfirmationStatusConfirmed, sgorpc.CommitmentConfirmed, true},
+		{sgorpc.ConfirmationStatusConfirmed, sgorpc.CommitmentFinalized, false},
+		{sgorpc.ConfirmationStatusFinalized, sgorpc.CommitmentConfirmed, true},
+		{sgorpc.ConfirmationStatusFinalized, sgorpc.CommitmentFinalized, true},
+	} {
+		if reached(x.status, x.commitment) != x.reached {
+			t.Errorf("%s at %s: expected %t", x.status, x.commitment, x.reached)
+		}
+	}
+}
+
+func TestOutcomeError(t *testing.T) {
+	o := Outcome{Status: SEND_LANDED}
+	if o.Error() != nil {
+		t.Fatal(o.Error())
+	}
+	cause := errors.New("blockhash expired")
+	o = Outcome{Status: SEND_EXPIRED, Signature: sgo.Signature{1}, Err: cause}
+	var sendErr *SendError
+	if !errors.As(o.Error(), &sendErr) || sendErr.Outcome.Status != SEND_EXPIRED {
+		t.Fatalf("error %v", o.Error())
+	}
+	if !errors.Is(o.Error(), cause) {
+		t.Fatal("error does not unwrap to the cause")
+	}
+	o = o.land(landResult{sig: sgo.Signature{2}, slot: 5, err: "InstructionError"})
+	if o.Status != SEND_FAILED || o.Slot != 5 || !o.Signature.Equals(sgo.Signature{2}) {
+		t.Fatalf("outcome %+v", o)
+	}
+}
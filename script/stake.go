@@ -0,0 +1,164 @@
+package script
+
+import (
+	"encoding/binary"
+	"errors"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	"github.com/solpipe/solpipe-tool/signer"
+)
+
+// size of StakeStateV2
+const STAKE_ACCOUNT_SIZE uint64 = 200
+
+// stake program instruction discriminants
+const (
+	STAKE_INSTRUCTION_INITIALIZE uint32 = 0
+	STAKE_INSTRUCTION_DELEGATE   uint32 = 2
+	STAKE_INSTRUCTION_SPLIT      uint32 = 3
+	STAKE_INSTRUCTION_WITHDRAW   uint32 = 4
+	STAKE_INSTRUCTION_DEACTIVATE uint32 = 5
+)
+
+var StakeConfigId = sgo.MustPublicKeyFromBase58("StakeConfig11111111111111111111111111111111")
+
+// Create a stake account holding lamports (which must cover rent) with admin as both stake and withdraw authority.
+func (e1 *Script) CreateStake(
+	payer signer.Signer,
+	stake sgo.PrivateKey,
+	admin sgo.PublicKey,
+	lamports uint64,
+) error {
+	if e1.txBuilder == nil {
+		return errors.New("tx builder is blank")
+	}
+	e1.AppendKey(payer)
+	e1.AppendKey(stake)
+	e1.txBuilder.AddInstruction(sgosys.NewCreateAccountInstructionBuilder().SetSpace(STAKE_ACCOUNT_SIZE).SetLamports(lamports).SetOwner(sgo.StakeProgramID).SetFundingAccount(payer.PublicKey()).SetNewAccount(stake.PublicKey()).Build())
+
+	// Authorized {staker, withdrawer} followed by a blank Lockup {unix_timestamp, epoch, custodian}
+	data := make([]byte, 4+32+32+8+8+32)
+	binary.LittleEndian.PutUint32(data[0:4], STAKE_INSTRUCTION_INITIALIZE)
+	copy(data[4:36], admin.Bytes())
+	copy(data[36:68], admin.Bytes())
+	e1.txBuilder.AddInstruction(sgo.NewInstruction(
+		sgo.StakeProgramID,
+		sgo.AccountMetaSlice{
+			sgo.Meta(stake.PublicKey()).WRITE(),
+			sgo.Meta(sgo.SysVarRentPubkey),
+		},
+		data,
+	))
+	return nil
+}
+
+func (e1 *Script) DelegateStake(
+	stake sgo.PublicKey,
+	admin signer.Signer,
+	vote sgo.PublicKey,
+) error {
+	if e1.txBuilder == nil {
+		return errors.New("tx builder is blank")
+	}
+	e1.AppendKey(admin)
+	e1.txBuilder.AddInstruction(sgo.NewInstruction(
+		sgo.StakeProgramID,
+		sgo.AccountMetaSlice{
+			sgo.Meta(stake).WRITE(),
+			sgo.Meta(vote),
+			sgo.Meta(sgo.SysVarClockPubkey),
+			sgo.Meta(sgo.SysVarStakeHistoryPubkey),
+			sgo.Meta(StakeConfigId),
+			sgo.Meta(admin.PublicKey()).SIGNER(),
+		},
+		stakeInstructionData(STAKE_INSTRUCTION_DELEGATE, nil),
+	))
+	return nil
+}
+
+// Start the cool down; the lamports can be withdrawn once the stake is inactive.
+func (e1 *Script) DeactivateStake(
+	stake sgo.PublicKey,
+	admin signer.Signer,
+) error {
+	if e1.txBuilder == nil {
+		return errors.New("tx builder is blank")
+	}
+	e1.AppendKey(admin)
+	e1.txBuilder.AddInstruction(sgo.NewInstruction(
+		sgo.StakeProgramID,
+		sgo.AccountMetaSlice{
+			sgo.Meta(stake).WRITE(),
+			sgo.Meta(sgo.SysVarClockPubkey),
+			sgo.Meta(admin.PublicKey()).SIGNER(),
+		},
+		stakeInstructionData(STAKE_INSTRUCTION_DEACTIVATE, nil),
+	))
+	return nil
+}
+
+func (e1 *Script) WithdrawStake(
+	stake sgo.PublicKey,
+	admin signer.Signer,
+	destination sgo.PublicKey,
+	lamports uint64,
+) error {
+	if e1.txBuilder == nil {
+		return errors.New("tx builder is blank")
+	}
+	e1.AppendKey(admin)
+	e1.txBuilder.AddInstruction(sgo.NewInstruction(
+		sgo.StakeProgramID,
+		sgo.AccountMetaSlice{
+			sgo.Meta(stake).WRITE(),
+			sgo.Meta(destination).WRITE(),
+			sgo.Meta(sgo.SysVarClockPubkey),
+			sgo.Meta(sgo.SysVarStakeHistoryPubkey),
+			sgo.Meta(admin.PublicKey()).SIGNER(),
+		},
+		stakeInstructionData(STAKE_INSTRUCTION_WITHDRAW, &lamports),
+	))
+	return nil
+}
+
+// Move lamports from stake into the new stake account newStake, which keeps the same authorities and delegation.
+// The payer funds the rent of newStake.
+func (e1 *Script) SplitStake(
+	payer signer.Signer,
+	stake sgo.PublicKey,
+	admin signer.Signer,
+	newStake sgo.PrivateKey,
+	lamports uint64,
+) error {
+	if e1.txBuilder == nil {
+		return errors.New("tx builder is blank")
+	}
+	_, err := e1.CreateAccountDirect(STAKE_ACCOUNT_SIZE, newStake, sgo.StakeProgramID, payer)
+	if err != nil {
+		return err
+	}
+	e1.AppendKey(admin)
+	e1.txBuilder.AddInstruction(sgo.NewInstruction(
+		sgo.StakeProgramID,
+		sgo.AccountMetaSlice{
+			sgo.Meta(stake).WRITE(),
+			sgo.Meta(newStake.PublicKey()).WRITE(),
+			sgo.Meta(admin.PublicKey()).SIGNER(),
+		},
+		stakeInstructionData(STAKE_INSTRUCTION_SPLIT, &lamports),
+	))
+	return nil
+}
+
+func stakeInstructionData(instruction uint32, lamports *uint64) []byte {
+	if lamports == nil {
+		data := make([]byte, 4)
+		binary.LittleEndian.PutUint32(data, instruction)
+		return data
+	}
+	data := make([]byte, 12)
+	binary.LittleEndian.PutUint32(data[0:4], instruction)
+	binary.LittleEndian.PutUint64(data[4:12], *lamports)
+	return data
+}
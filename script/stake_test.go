@@ -0,0 +1,114 @@
+package script_test
+
+import (
+	"context"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	"github.com/solpipe/solpipe-tool/script"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+)
+
+func TestStake(t *testing.T) {
+	ctx, f := newFunded(t)
+	rpcClient, payer, s := f.RpcClient, f.Payer, f.Script
+	admin, stake, split, vote := newKey(t), newKey(t), newKey(t), newKey(t)
+	err := script.Airdrop(ctx, rpcClient, f.WsClient, admin.PublicKey(), sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rentLamports, err := rpcClient.GetMinimumBalanceForRentExemption(ctx, script.STAKE_ACCOUNT_SIZE, sgorpc.CommitmentFinalized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateStake(payer, stake, admin.PublicKey(), rentLamports+5*sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DelegateStake(stake.PublicKey(), admin, vote.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := delegation(ctx, t, rpcClient, stake.PublicKey())
+	if !d.Vote.Equals(vote.PublicKey()) || d.Stake != 5*sgo.LAMPORTS_PER_SOL {
+		t.Fatalf("unexpected delegation %+v", d)
+	}
+
+	// the split keeps the delegation
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SplitStake(payer, stake.PublicKey(), admin, split, 2*sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = delegation(ctx, t, rpcClient, split.PublicKey())
+	if !d.Vote.Equals(vote.PublicKey()) || d.Stake != 2*sgo.LAMPORTS_PER_SOL {
+		t.Fatalf("unexpected split delegation %+v", d)
+	}
+
+	// active stake cannot be withdrawn
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.WithdrawStake(stake.PublicKey(), admin, payer.PublicKey(), sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(false)
+	if err == nil {
+		t.Fatal("withdrew active stake")
+	}
+
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DeactivateStake(stake.PublicKey(), admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.WithdrawStake(stake.PublicKey(), admin, payer.PublicKey(), rentLamports+3*sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai, err := rpcClient.GetAccountInfo(ctx, stake.PublicKey())
+	if err == nil && ai.Value != nil {
+		t.Fatalf("stake account still has %d lamports", ai.Value.Lamports)
+	}
+}
+
+func delegation(ctx context.Context, t *testing.T, rpcClient *sgorpc.Client, id sgo.PublicKey) skr.Delegation {
+	ai, err := rpcClient.GetAccountInfo(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ai.Value.Owner.Equals(sgo.StakeProgramID) {
+		t.Fatalf("owner %s", ai.Value.Owner.String())
+	}
+	d, err := skr.ParseDelegation(ai.Value.Data.GetBinary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
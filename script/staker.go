@@ -5,13 +5,14 @@ import (
 
 	sgo "github.com/SolmateDev/solana-go"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	skr "github.com/solpipe/solpipe-tool/state/staker"
 )
 
 func (e1 *Script) AddStaker(
 	controllerId sgo.PublicKey,
-	stake sgo.PrivateKey,
-	admin sgo.PrivateKey,
+	stake signer.Signer,
+	admin signer.Signer,
 ) error {
 	var err error
 	if e1.txBuilder == nil {
@@ -41,7 +42,7 @@ func (e1 *Script) AddStaker(
 func (e1 *Script) AddStakerToReceipt(
 	controllerId sgo.PublicKey,
 	stake sgo.PublicKey,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 	payoutId sgo.PublicKey,
 	receiptId sgo.PublicKey,
 	validatorManagerId sgo.PublicKey,
@@ -56,7 +57,7 @@ func (e1 *Script) AddStakerToReceipt(
 		return err
 	}
 	var stakerReceiptId sgo.PublicKey
-	stakerReceiptId, _, err = skr.StakerReceiptId(managerId, stakerReceiptId)
+	stakerReceiptId, _, err = skr.StakerReceiptId(managerId, receiptId)
 	if err != nil {
 		return err
 	}
@@ -5,9 +5,10 @@ import (
 
 	sgo "github.com/SolmateDev/solana-go"
 	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	"github.com/solpipe/solpipe-tool/signer"
 )
 
-func (e1 *Script) Transfer(source sgo.PrivateKey, destination sgo.PublicKey, amount uint64) error {
+func (e1 *Script) Transfer(source signer.Signer, destination sgo.PublicKey, amount uint64) error {
 	if e1.txBuilder == nil {
 		return errors.New("tx builder is blank")
 	}
@@ -8,6 +8,7 @@ import (
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
 	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/signer"
 	ctr "github.com/solpipe/solpipe-tool/state/controller"
 	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
 	val "github.com/solpipe/solpipe-tool/state/validator"
@@ -15,9 +16,9 @@ import (
 
 func (e1 *Script) AddValidator(
 	controller sgo.PublicKey,
-	vote sgo.PrivateKey,
+	vote signer.Signer,
 	stake sgo.PublicKey,
-	admin sgo.PrivateKey,
+	admin signer.Signer,
 ) (member sgo.PublicKey, err error) {
 	if e1.txBuilder == nil {
 		err = errors.New("no tx builder")
@@ -54,7 +55,7 @@ func (e1 *Script) ValidatorSetPipeline(
 	payoutId sgo.PublicKey,
 	pipelineId sgo.PublicKey,
 	validatorId sgo.PublicKey,
-	validatorAdmin sgo.PrivateKey,
+	validatorAdmin signer.Signer,
 ) (receiptId sgo.PublicKey, err error) {
 	if e1.txBuilder == nil {
 		err = errors.New("no tx builder")
@@ -91,7 +92,7 @@ func (e1 *Script) ValidatorWithdrawReceipt(
 	pipeline pipe.Pipeline,
 	validator val.Validator,
 	receiptId sgo.PublicKey,
-	payer sgo.PrivateKey,
+	payer signer.Signer,
 ) (err error) {
 	if e1.txBuilder == nil {
 		err = errors.New("no tx builder")
@@ -141,7 +142,7 @@ func (e1 *Script) Deprecate_CreateValidatorReceipt(
 	controllerId sgo.PublicKey,
 	pipelineId sgo.PublicKey,
 	vote sgo.PublicKey,
-	pipelineAdmin sgo.PrivateKey,
+	pipelineAdmin signer.Signer,
 	validatorAdmin sgo.PublicKey,
 	start uint64,
 ) (tx sgo.Transaction, err error) {
@@ -161,7 +162,7 @@ func SignValidatorReceipt(
 	wsClient *sgows.Client,
 	pipelineAdmin sgo.PublicKey,
 	txData []byte,
-	validatorAdmin sgo.PrivateKey,
+	validatorAdmin signer.Signer,
 ) (err error) {
 
 	var tx *sgo.Transaction
@@ -174,18 +175,12 @@ func SignValidatorReceipt(
 		err = errors.New("no pipeline admin signature")
 		return
 	}
-	preLength := len(tx.Signatures)
-
-	tx.Sign(func(pubkey sgo.PublicKey) *sgo.PrivateKey {
-		if pubkey.Equals(validatorAdmin.PublicKey()) {
-			return &validatorAdmin
-		}
-		return nil
-	})
-	postLength := len(tx.Signatures)
-
-	if postLength <= preLength {
-		err = errors.New("no length")
+	if !tx.Message.IsSigner(validatorAdmin.PublicKey()) {
+		err = errors.New("no validator admin signer")
+		return
+	}
+	err = signer.SignTx(tx, validatorAdmin)
+	if err != nil {
 		return
 	}
 
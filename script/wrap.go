@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // ignore the ctx in script
@@ -135,6 +137,14 @@ out:
 		if err == nil {
 			break out
 		}
+		var sendErr *SendError
+		if errors.As(err, &sendErr) {
+			o := sendErr.Outcome
+			log.Debugf("try %d: transaction %s %s (broadcasts=%d; blockhashes=%d)", i, o.Signature.String(), o.Status.String(), o.Broadcasts, o.Blockhashes)
+			if o.Status == SEND_CANCELED {
+				break out
+			}
+		}
 		select {
 		case <-doneC:
 			return errors.New("canceled")
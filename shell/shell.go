@@ -1,3 +1,5 @@
+// Package shell runs the solana CLI.  It is an optional fallback; the script
+// package builds the same stake program transactions without the CLI.
 package shell
 
 import (
@@ -15,6 +17,7 @@ type Solana struct {
 	rpcUrl string
 }
 
+// Fails if the solana CLI is not on the PATH.
 func CreateSolana(rpcUrl string) (s Solana, err error) {
 	path, err := exec.LookPath("solana")
 	if err != nil {
@@ -0,0 +1,208 @@
+package signer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KEYSTORE_VERSION = 1
+	KDF_SCRYPT       = "scrypt"
+	KDF_ARGON2ID     = "argon2id"
+	CIPHER_AES_GCM   = "aes-256-gcm"
+)
+
+// cost parameters for new keystores
+const (
+	SCRYPT_N          = 1 << 17
+	SCRYPT_R          = 8
+	SCRYPT_P          = 1
+	ARGON2_TIME       = 3
+	ARGON2_MEMORY     = 64 * 1024 // KiB
+	ARGON2_THREADS    = 4
+	KEYSTORE_KEY_SIZE = 32
+	KEYSTORE_SALT     = 32
+)
+
+// Cost parameters read from a keystore file are bounded so that a tampered
+// file cannot make decryption take unbounded memory or time.
+const (
+	SCRYPT_N_MAX      = 1 << 20
+	SCRYPT_R_MAX      = 32
+	SCRYPT_P_MAX      = 16
+	ARGON2_TIME_MAX   = 100
+	ARGON2_MEMORY_MAX = 1024 * 1024 // KiB
+)
+
+// A private key encrypted with a passphrase.  The public key is left in the
+// clear so that operators can tell keystores apart without decrypting them.
+type Keystore struct {
+	Version    int       `json:"version"`
+	PublicKey  string    `json:"public_key"`
+	Kdf        string    `json:"kdf"`
+	KdfParams  KdfParams `json:"kdf_params"`
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// N, R and P are for scrypt; Time, Memory and Threads for argon2id.
+type KdfParams struct {
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+	Salt    []byte `json:"salt"`
+}
+
+// Encrypt key with a key derived from passphrase by kdf (KDF_SCRYPT or KDF_ARGON2ID).
+func EncryptKeystore(key sgo.PrivateKey, passphrase []byte, kdf string) (*Keystore, error) {
+	ks := &Keystore{
+		Version:   KEYSTORE_VERSION,
+		PublicKey: key.PublicKey().String(),
+		Kdf:       kdf,
+		KdfParams: KdfParams{Salt: make([]byte, KEYSTORE_SALT)},
+		Cipher:    CIPHER_AES_GCM,
+	}
+	switch kdf {
+	case KDF_SCRYPT:
+		ks.KdfParams.N = SCRYPT_N
+		ks.KdfParams.R = SCRYPT_R
+		ks.KdfParams.P = SCRYPT_P
+	case KDF_ARGON2ID:
+		ks.KdfParams.Time = ARGON2_TIME
+		ks.KdfParams.Memory = ARGON2_MEMORY
+		ks.KdfParams.Threads = ARGON2_THREADS
+	default:
+		return nil, fmt.Errorf("unknown kdf %s", kdf)
+	}
+	_, err := rand.Read(ks.KdfParams.Salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	ks.Nonce = make([]byte, aead.NonceSize())
+	_, err = rand.Read(ks.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	// the public key is authenticated so it cannot be swapped out
+	ks.Ciphertext = aead.Seal(nil, ks.Nonce, key, []byte(ks.PublicKey))
+	return ks, nil
+}
+
+func (ks *Keystore) Decrypt(passphrase []byte) (sgo.PrivateKey, error) {
+	if ks.Version != KEYSTORE_VERSION {
+		return nil, fmt.Errorf("unknown keystore version %d", ks.Version)
+	}
+	if ks.Cipher != CIPHER_AES_GCM {
+		return nil, fmt.Errorf("unknown cipher %s", ks.Cipher)
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(ks.Nonce) != aead.NonceSize() {
+		return nil, errors.New("bad nonce")
+	}
+	plain, err := aead.Open(nil, ks.Nonce, ks.Ciphertext, []byte(ks.PublicKey))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted keystore")
+	}
+	key := sgo.PrivateKey(plain)
+	if key.PublicKey().String() != ks.PublicKey {
+		return nil, errors.New("keystore public key does not match the private key")
+	}
+	return key, nil
+}
+
+func (ks *Keystore) aead(passphrase []byte) (cipher.AEAD, error) {
+	p := ks.KdfParams
+	var secret []byte
+	var err error
+	switch ks.Kdf {
+	case KDF_SCRYPT:
+		if p.N <= 1 || p.N&(p.N-1) != 0 || p.R <= 0 || p.P <= 0 {
+			return nil, errors.New("bad scrypt parameters")
+		}
+		if SCRYPT_N_MAX < p.N || SCRYPT_R_MAX < p.R || SCRYPT_P_MAX < p.P {
+			return nil, errors.New("scrypt parameters are too large")
+		}
+		secret, err = scrypt.Key(passphrase, p.Salt, p.N, p.R, p.P, KEYSTORE_KEY_SIZE)
+		if err != nil {
+			return nil, err
+		}
+	case KDF_ARGON2ID:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return nil, errors.New("bad argon2id parameters")
+		}
+		if ARGON2_TIME_MAX < p.Time || ARGON2_MEMORY_MAX < p.Memory {
+			return nil, errors.New("argon2id parameters are too large")
+		}
+		secret = argon2.IDKey(passphrase, p.Salt, p.Time, p.Memory, p.Threads, KEYSTORE_KEY_SIZE)
+	default:
+		return nil, fmt.Errorf("unknown kdf %s", ks.Kdf)
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Parse data as a keystore; solana-keygen files and other JSON fail.
+func ParseKeystore(data []byte) (*Keystore, error) {
+	ks := new(Keystore)
+	err := json.Unmarshal(data, ks)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Version == 0 || len(ks.Ciphertext) == 0 {
+		return nil, errors.New("not a keystore")
+	}
+	return ks, nil
+}
+
+func ReadKeystore(fp string, passphrase []byte) (sgo.PrivateKey, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	ks, err := ParseKeystore(data)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Decrypt(passphrase)
+}
+
+// The file is only readable by the owner.  An existing file is never overwritten.
+func WriteKeystore(fp string, ks *Keystore) error {
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
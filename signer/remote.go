@@ -0,0 +1,405 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	bin "github.com/gagliardetto/binary"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	SCHEME_REMOTE           = "grpc"
+	SCHEME_REMOTE_TLS       = "grpcs"
+	METHOD_SIGN             = "/signer.Signer/Sign"
+	METHOD_SIGN_CERTIFICATE = "/signer.Signer/SignCertificate"
+	HEADER_PUBLIC_KEY       = "signer-public-key"
+	HEADER_TOKEN            = "signer-token"
+	REMOTE_TIMEOUT          = 30 * time.Second
+	// SignCertificate requests must start with this so that a request meant
+	// for one method can never be mistaken for the other.
+	CERTIFICATE_PREFIX = "solpipe signer certificate v1\x00"
+)
+
+// Signs with a key held by a signing service, such as the one run by Serve.
+type remote struct {
+	ctx   context.Context
+	conn  *grpc.ClientConn
+	key   sgo.PublicKey
+	token string
+}
+
+// Connect to the signing service at target and sign with key.  Without
+// tlsConfig the token and messages travel in plaintext, so target must then be
+// on this host.
+func Dial(ctx context.Context, target string, key sgo.PublicKey, token string, tlsConfig *tls.Config) (Signer, error) {
+	var creds credentials.TransportCredentials
+	if tlsConfig == nil {
+		host, _, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, err
+		}
+		if !isLoopbackHost(host) {
+			return nil, fmt.Errorf("refusing to dial %s without tls; use %s://", target, SCHEME_REMOTE_TLS)
+		}
+		creds = insecure.NewCredentials()
+	} else {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.DialContext(ctx, target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	go loopCloseConnection(ctx, conn)
+	return remote{ctx: ctx, conn: conn, key: key, token: token}, nil
+}
+
+// Dial a signer given as grpcs://[token@]host:port/<public key>[?ca=<pem file>].
+// The service certificate is checked against the system roots unless ca is
+// set.  grpc:// skips tls and only reaches services on this host.
+func DialUrl(ctx context.Context, signerUrl string) (Signer, error) {
+	u, err := url.Parse(signerUrl)
+	if err != nil {
+		return nil, err
+	}
+	var tlsConfig *tls.Config
+	switch u.Scheme {
+	case SCHEME_REMOTE:
+	case SCHEME_REMOTE_TLS:
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if ca := u.Query().Get("ca"); 0 < len(ca) {
+			tlsConfig.RootCAs, err = readCertPool(ca)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("signer url must start with %s:// or %s://", SCHEME_REMOTE_TLS, SCHEME_REMOTE)
+	}
+	key, err := sgo.PublicKeyFromBase58(strings.TrimPrefix(u.Path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("signer url must end with the public key: %w", err)
+	}
+	token := ""
+	if u.User != nil {
+		token = u.User.Username()
+	}
+	return Dial(ctx, u.Host, key, token, tlsConfig)
+}
+
+func IsRemoteUrl(input string) bool {
+	return strings.HasPrefix(input, SCHEME_REMOTE+"://") || strings.HasPrefix(input, SCHEME_REMOTE_TLS+"://")
+}
+
+func readCertPool(fp string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", fp)
+	}
+	return pool, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func isLoopbackAddr(addr net.Addr) bool {
+	switch x := addr.(type) {
+	case *net.TCPAddr:
+		return x.IP.IsLoopback()
+	case *net.UnixAddr:
+		return true
+	default:
+		return false
+	}
+}
+
+func loopCloseConnection(ctx context.Context, conn *grpc.ClientConn) {
+	<-ctx.Done()
+	conn.Close()
+}
+
+func (r remote) PublicKey() sgo.PublicKey {
+	return r.key
+}
+
+func (r remote) Sign(message []byte) (sgo.Signature, error) {
+	return r.invoke(METHOD_SIGN, message, message)
+}
+
+// Sign the DER encoded TBSCertificate of an Ed25519 x509 certificate.
+func (r remote) SignCertificate(tbs []byte) (sgo.Signature, error) {
+	req := append([]byte(CERTIFICATE_PREFIX), tbs...)
+	return r.invoke(METHOD_SIGN_CERTIFICATE, req, tbs)
+}
+
+func (r remote) invoke(method string, req []byte, message []byte) (sig sgo.Signature, err error) {
+	ctx, cancel := context.WithTimeout(r.ctx, REMOTE_TIMEOUT)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, HEADER_PUBLIC_KEY, r.key.String(), HEADER_TOKEN, r.token)
+	resp := new(wrapperspb.BytesValue)
+	err = r.conn.Invoke(ctx, method, wrapperspb.Bytes(req), resp)
+	if err != nil {
+		return
+	}
+	if len(resp.Value) != sgo.SignatureLength {
+		err = errors.New("bad signature length")
+		return
+	}
+	// do not trust the service to have used the right key
+	if !ed25519.Verify(ed25519.PublicKey(r.key.Bytes()), message, resp.Value) {
+		err = errors.New("invalid signature from signing service")
+		return
+	}
+	sig = sgo.SignatureFromBytes(resp.Value)
+	return
+}
+
+type signServer interface {
+	Sign(context.Context, *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error)
+	SignCertificate(context.Context, *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "signer.Signer",
+	HandlerType: (*signServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Sign",
+			Handler: unaryHandler(METHOD_SIGN, func(srv signServer) func(context.Context, *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+				return srv.Sign
+			}),
+		},
+		{
+			MethodName: "SignCertificate",
+			Handler: unaryHandler(METHOD_SIGN_CERTIFICATE, func(srv signServer) func(context.Context, *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+				return srv.SignCertificate
+			}),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func unaryHandler(
+	method string,
+	pick func(signServer) func(context.Context, *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error),
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(wrapperspb.BytesValue)
+		err := dec(in)
+		if err != nil {
+			return nil, err
+		}
+		f := pick(srv.(signServer))
+		if interceptor == nil {
+			return f(ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: method,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return f(ctx, req.(*wrapperspb.BytesValue))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+type server struct {
+	token   string
+	signerM map[string]Signer
+}
+
+// Run the reference signing service on listener until ctx is done.  Sign only
+// signs transaction messages that require a signature from the key, and
+// SignCertificate only signs Ed25519 x509 certificates.  A blank token lets
+// anyone who can reach the listener ask for signatures.  Without tlsConfig the
+// listener must be on a loopback address, as the token would otherwise cross
+// the network in plaintext.
+func Serve(ctx context.Context, listener net.Listener, tlsConfig *tls.Config, token string, list ...Signer) <-chan error {
+	errorC := make(chan error, 1)
+	opts := []grpc.ServerOption{}
+	if tlsConfig == nil {
+		if !isLoopbackAddr(listener.Addr()) {
+			errorC <- fmt.Errorf("refusing to listen on %s without tls", listener.Addr().String())
+			return errorC
+		}
+	} else {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	srv := server{token: token, signerM: make(map[string]Signer)}
+	for _, s := range list {
+		srv.signerM[s.PublicKey().String()] = s
+	}
+	s := grpc.NewServer(opts...)
+	s.RegisterService(&serviceDesc, srv)
+	go loopServe(ctx, s, listener, errorC)
+	return errorC
+}
+
+func loopServe(ctx context.Context, s *grpc.Server, listener net.Listener, errorC chan<- error) {
+	serveErrorC := make(chan error, 1)
+	go func() {
+		serveErrorC <- s.Serve(listener)
+	}()
+	select {
+	case <-ctx.Done():
+		s.GracefulStop()
+		errorC <- nil
+	case err := <-serveErrorC:
+		errorC <- err
+	}
+}
+
+// Find the signer named in the request metadata after checking the token.
+func (srv server) signer(ctx context.Context) (Signer, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, errors.New("no metadata")
+	}
+	if 0 < len(srv.token) {
+		list := md.Get(HEADER_TOKEN)
+		if len(list) != 1 || subtle.ConstantTimeCompare([]byte(list[0]), []byte(srv.token)) != 1 {
+			return nil, errors.New("bad token")
+		}
+	}
+	list := md.Get(HEADER_PUBLIC_KEY)
+	if len(list) != 1 {
+		return nil, errors.New("no public key")
+	}
+	s, present := srv.signerM[list[0]]
+	if !present {
+		return nil, fmt.Errorf("no key %s", list[0])
+	}
+	return s, nil
+}
+
+func (srv server) Sign(ctx context.Context, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	s, err := srv.signer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := parseMessage(req.Value)
+	if err != nil {
+		return nil, fmt.Errorf("not a transaction message: %w", err)
+	}
+	if !msg.IsSigner(s.PublicKey()) {
+		return nil, errors.New("message does not need a signature from this key")
+	}
+	log.Debugf("signing message with key=%s (instructions=%d)", s.PublicKey().String(), len(msg.Instructions))
+	sig, err := s.Sign(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return wrapperspb.Bytes(sig[:]), nil
+}
+
+func (srv server) SignCertificate(ctx context.Context, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	s, err := srv.signer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(req.Value, []byte(CERTIFICATE_PREFIX)) {
+		return nil, errors.New("missing certificate prefix")
+	}
+	tbs := req.Value[len(CERTIFICATE_PREFIX):]
+	if !isCertificate(tbs) {
+		return nil, errors.New("not an ed25519 certificate")
+	}
+	// the signature is over the bare certificate, so it must not double as
+	// a transaction signature
+	if _, err = parseMessage(tbs); err == nil {
+		return nil, errors.New("certificate is also a transaction message")
+	}
+	log.Debugf("signing certificate with key=%s", s.PublicKey().String())
+	sig, err := s.Sign(tbs)
+	if err != nil {
+		return nil, err
+	}
+	return wrapperspb.Bytes(sig[:]), nil
+}
+
+// Decode a transaction message, leaving no bytes over.
+func parseMessage(data []byte) (*sgo.Message, error) {
+	msg := new(sgo.Message)
+	dec := bin.NewBinDecoder(data)
+	err := msg.UnmarshalWithDecoder(dec)
+	if err != nil {
+		return nil, err
+	}
+	if dec.HasRemaining() {
+		return nil, errors.New("trailing bytes after message")
+	}
+	return msg, nil
+}
+
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// Agents identify themselves over TLS with a CA certificate signed by the
+// admin key.  Check that data is a complete TBSCertificate using Ed25519.
+func isCertificate(data []byte) bool {
+	input := cryptobyte.String(data)
+	var tbs cryptobyte.String
+	if !input.ReadASN1(&tbs, cbasn1.SEQUENCE) || !input.Empty() {
+		return false
+	}
+	if !tbs.SkipOptionalASN1(cbasn1.Tag(0).Constructed().ContextSpecific()) {
+		return false
+	}
+	if !tbs.SkipASN1(cbasn1.INTEGER) {
+		return false
+	}
+	var algorithm cryptobyte.String
+	if !tbs.ReadASN1(&algorithm, cbasn1.SEQUENCE) {
+		return false
+	}
+	var oid asn1.ObjectIdentifier
+	if !algorithm.ReadASN1ObjectIdentifier(&oid) || !algorithm.Empty() || !oid.Equal(oidEd25519) {
+		return false
+	}
+	// issuer, validity, subject and subjectPublicKeyInfo
+	for i := 0; i < 4; i++ {
+		if !tbs.SkipASN1(cbasn1.SEQUENCE) {
+			return false
+		}
+	}
+	// issuerUniqueID, subjectUniqueID and extensions
+	for _, tag := range []cbasn1.Tag{
+		cbasn1.Tag(1).ContextSpecific(),
+		cbasn1.Tag(2).ContextSpecific(),
+		cbasn1.Tag(3).Constructed().ContextSpecific(),
+	} {
+		if !tbs.SkipOptionalASN1(tag) {
+			return false
+		}
+	}
+	return tbs.Empty()
+}
@@ -0,0 +1,140 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+
+	sgo "github.com/SolmateDev/solana-go"
+)
+
+// Signs transactions on behalf of one key.  sgo.PrivateKey is a Signer; the
+// remote signer keeps the key off this host altogether.
+type Signer interface {
+	PublicKey() sgo.PublicKey
+	Sign(message []byte) (sgo.Signature, error)
+}
+
+// Return the key material if s holds the key in this process.  Tor onion
+// services need the key itself rather than signatures.
+func Local(s Signer) (key sgo.PrivateKey, ok bool) {
+	switch x := s.(type) {
+	case sgo.PrivateKey:
+		return x, true
+	case *sgo.PrivateKey:
+		if x == nil {
+			return nil, false
+		}
+		return *x, true
+	default:
+		return nil, false
+	}
+}
+
+// Stands in for a key that signs on another machine.  PartialSignTx skips it,
+// leaving the signature blank.
+func Offline(key sgo.PublicKey) Signer {
+	return offline{key: key}
+}
+
+type offline struct {
+	key sgo.PublicKey
+}
+
+func (o offline) PublicKey() sgo.PublicKey {
+	return o.key
+}
+
+func (o offline) Sign(message []byte) (sgo.Signature, error) {
+	return sgo.Signature{}, fmt.Errorf("%s signs offline", o.key.String())
+}
+
+// Sign tx with every signer in list that the message requires.  All required
+// signatures must be present afterwards.
+func SignTx(tx *sgo.Transaction, list ...Signer) error {
+	err := PartialSignTx(tx, list...)
+	if err != nil {
+		return err
+	}
+	n := int(tx.Message.Header.NumRequiredSignatures)
+	for i := 0; i < n; i++ {
+		if tx.Signatures[i].IsZero() {
+			return fmt.Errorf("missing signature from %s", tx.Message.AccountKeys[i].String())
+		}
+	}
+	return nil
+}
+
+// Like SignTx, but signatures from keys not in list may stay blank so that
+// others can add theirs later.
+func PartialSignTx(tx *sgo.Transaction, list ...Signer) error {
+	n := int(tx.Message.Header.NumRequiredSignatures)
+	if len(tx.Message.AccountKeys) < n {
+		return errors.New("message has fewer accounts than required signatures")
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	signerM := make(map[string]Signer)
+	for _, s := range list {
+		if _, ok := s.(offline); ok {
+			continue
+		}
+		signerM[s.PublicKey().String()] = s
+	}
+	// signatures are positional, so pad before filling in our own
+	for len(tx.Signatures) < n {
+		tx.Signatures = append(tx.Signatures, sgo.Signature{})
+	}
+	for i := 0; i < n; i++ {
+		s, present := signerM[tx.Message.AccountKeys[i].String()]
+		if !present {
+			continue
+		}
+		tx.Signatures[i], err = s.Sign(message)
+		if err != nil {
+			return fmt.Errorf("failed to sign with %s: %w", s.PublicKey().String(), err)
+		}
+	}
+	return nil
+}
+
+// Implemented by signers that sign x509 certificates apart from transaction
+// messages, as the remote signer does.
+type CertificateSigner interface {
+	SignCertificate(tbs []byte) (sgo.Signature, error)
+}
+
+// Adapt s to crypto.Signer so it can sign x509 certificates.
+func Crypto(s Signer) crypto.Signer {
+	return cryptoSigner{s: s}
+}
+
+type cryptoSigner struct {
+	s Signer
+}
+
+func (cs cryptoSigner) Public() crypto.PublicKey {
+	return ed25519.PublicKey(cs.s.PublicKey().Bytes())
+}
+
+// ed25519 signs the whole message, so digest is the message itself.
+func (cs cryptoSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("ed25519 does not sign prehashed messages")
+	}
+	var sig sgo.Signature
+	var err error
+	if x, ok := cs.s.(CertificateSigner); ok {
+		sig, err = x.SignCertificate(digest)
+	} else {
+		sig, err = cs.s.Sign(digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sig[:], nil
+}
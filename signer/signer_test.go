@@ -0,0 +1,287 @@
+package signer_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	"github.com/solpipe/solpipe-tool/signer"
+)
+
+func transferTx(t *testing.T, payer sgo.PublicKey, source sgo.PublicKey) *sgo.Transaction {
+	tx, err := sgo.NewTransaction(
+		[]sgo.Instruction{
+			sgosys.NewTransferInstruction(1, source, payer).Build(),
+		},
+		sgo.Hash{1},
+		sgo.TransactionPayer(payer),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestSignTx(t *testing.T) {
+	payer, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transferTx(t, payer.PublicKey(), source.PublicKey())
+
+	err = signer.SignTx(tx, payer, signer.Offline(source.PublicKey()))
+	if err == nil {
+		t.Fatal("signed without the source key")
+	}
+	// the source signs later, in another place
+	err = signer.PartialSignTx(tx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.VerifySignatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeystore(t *testing.T) {
+	key, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, kdf := range []string{signer.KDF_SCRYPT, signer.KDF_ARGON2ID} {
+		ks, err := signer.EncryptKeystore(key, []byte("correct horse"), kdf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp := filepath.Join(dir, kdf+".json")
+		err = signer.WriteKeystore(fp, ks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = signer.WriteKeystore(fp, ks)
+		if err == nil {
+			t.Fatal("overwrote a keystore")
+		}
+		_, err = signer.ReadKeystore(fp, []byte("battery staple"))
+		if err == nil {
+			t.Fatal("decrypted with the wrong passphrase")
+		}
+		x, err := signer.ReadKeystore(fp, []byte("correct horse"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !x.PublicKey().Equals(key.PublicKey()) {
+			t.Fatal("wrong key")
+		}
+
+		// a tampered file must not set the cost of decryption
+		tamperM := map[string][]func(*signer.KdfParams){
+			signer.KDF_SCRYPT: {
+				func(p *signer.KdfParams) { p.N *= 1 << 10 },
+				func(p *signer.KdfParams) { p.N += 1 },
+				func(p *signer.KdfParams) { p.P = 1 << 20 },
+				func(p *signer.KdfParams) { p.P = 0 },
+			},
+			signer.KDF_ARGON2ID: {
+				func(p *signer.KdfParams) { p.Memory *= 1 << 10 },
+				func(p *signer.KdfParams) { p.Time *= 1 << 10 },
+			},
+		}
+		for _, tamper := range tamperM[kdf] {
+			bad := *ks
+			tamper(&bad.KdfParams)
+			_, err = bad.Decrypt([]byte("correct horse"))
+			if err == nil {
+				t.Fatalf("%s decrypted with kdf parameters %+v", kdf, bad.KdfParams)
+			}
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	payer, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorC := signer.Serve(ctx, listener, nil, "secret", payer, source)
+
+	s, err := signer.DialUrl(ctx, fmt.Sprintf("grpc://secret@%s/%s", listener.Addr().String(), payer.PublicKey().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transferTx(t, payer.PublicKey(), source.PublicKey())
+	err = signer.PartialSignTx(tx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signer.SignTx(tx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.VerifySignatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the CA certificate agents use for TLS
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	crypto := signer.Crypto(s)
+	ca, err := x509.CreateCertificate(rand.Reader, template, template, crypto.Public(), crypto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cert.CheckSignatureFrom(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing else gets signed, and certificates only through SignCertificate
+	_, err = s.Sign([]byte("hello"))
+	if err == nil {
+		t.Fatal("signed an arbitrary message")
+	}
+	_, err = s.Sign(cert.RawTBSCertificate)
+	if err == nil {
+		t.Fatal("signed a certificate as a message")
+	}
+	msg, err := tx.Message.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.(signer.CertificateSigner).SignCertificate(msg)
+	if err == nil {
+		t.Fatal("signed a message as a certificate")
+	}
+	bad, err := signer.DialUrl(ctx, fmt.Sprintf("grpc://wrong@%s/%s", listener.Addr().String(), payer.PublicKey().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bad.Sign(msg)
+	if err == nil {
+		t.Fatal("signed with the wrong token")
+	}
+
+	cancel()
+	err = <-errorC
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteTls(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	payer, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// plaintext is refused off the loopback interface
+	open, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-signer.Serve(ctx, open, nil, "secret", payer)
+	if err == nil {
+		t.Fatal("served without tls on a public address")
+	}
+	open.Close()
+	_, err = signer.DialUrl(ctx, fmt.Sprintf("grpc://secret@10.0.0.1:50051/%s", payer.PublicKey().String()))
+	if err == nil {
+		t.Fatal("dialed a public address without tls")
+	}
+
+	_, serverKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, serverKey.Public(), serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: serverKey}},
+	}
+	errorC := signer.Serve(ctx, listener, tlsConfig, "secret", payer)
+
+	s, err := signer.DialUrl(ctx, fmt.Sprintf("grpcs://secret@%s/%s?ca=%s", listener.Addr().String(), payer.PublicKey().String(), caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transferTx(t, payer.PublicKey(), source.PublicKey())
+	err = signer.SignTx(tx, s, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.VerifySignatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	err = <-errorC
+	if err != nil {
+		t.Fatal(err)
+	}
+}
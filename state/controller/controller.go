@@ -89,11 +89,31 @@ func CreateController(ctx context.Context, rpcClient *sgorpc.Client, wsClient *s
 	}
 	home := sub2.CreateSubHome[cba.Controller]()
 	reqC := home.ReqC
-	go loopController(ctx, internalC, controllerId, *data, sub, home)
+	go loopController(ctx, internalC, rpcClient, wsClient, controllerId, *data, sub, nil, home)
 
 	return Controller{ctx: ctx, subSlot: subSlot, id: controllerId, internalC: internalC, updateControllerC: reqC, rpc: rpcClient, ws: wsClient, Version: version}, nil
 }
 
+// Create a controller that takes updates from dataC (see state/replay) instead
+// of a websocket subscription.  rpcClient is only used by Print.
+func CreateControllerFromReplay(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	subSlot slt.SlotHome,
+	data cba.Controller,
+	dataC <-chan cba.Controller,
+	version vrs.CbaVersion,
+) (Controller, error) {
+	controllerId, _, err := vrs.ControllerId(version)
+	if err != nil {
+		return Controller{}, err
+	}
+	internalC := make(chan func(*controllerInternal), 10)
+	home := sub2.CreateSubHome[cba.Controller]()
+	go loopController(ctx, internalC, rpcClient, nil, controllerId, data, nil, dataC, home)
+	return Controller{ctx: ctx, subSlot: subSlot, id: controllerId, internalC: internalC, updateControllerC: home.ReqC, rpc: rpcClient, Version: version}, nil
+}
+
 func (e1 Controller) Id() sgo.PublicKey {
 	return e1.id
 }
@@ -135,14 +155,19 @@ type controllerInternal struct {
 	id               sgo.PublicKey
 	data             *cba.Controller
 	home             *sub2.SubHome[cba.Controller]
+	rpc              *sgorpc.Client
+	ws               *sgows.Client
 }
 
 func loopController(
 	ctx context.Context,
 	internalC <-chan func(*controllerInternal),
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
 	id sgo.PublicKey,
 	data cba.Controller,
 	sub *sgows.AccountSubscription,
+	replayC <-chan cba.Controller,
 	home *sub2.SubHome[cba.Controller],
 ) {
 	doneC := ctx.Done()
@@ -154,9 +179,16 @@ func loopController(
 	in.id = id
 	in.data = &data
 	in.home = home
+	in.rpc = rpcClient
+	in.ws = wsClient
 
-	streamC := sub.RecvStream()
-	closeC := sub.RecvErr()
+	// when replaying, sub is nil and these channels block forever
+	var streamC <-chan sgows.Result
+	var closeC <-chan error
+	if sub != nil {
+		streamC = sub.RecvStream()
+		closeC = sub.RecvErr()
+	}
 
 	var err error
 out:
@@ -176,8 +208,21 @@ out:
 				break out
 			}
 			in.on_data(data)
+		case x, ok := <-replayC:
+			if !ok {
+				err = errors.New("replay finished")
+				break out
+			}
+			in.on_data(&x)
 		case err = <-closeC:
-			break out
+			log.Debugf("controller subscription dropped: %+v", err)
+			sub.Unsubscribe()
+			sub, err = in.resubscribe()
+			if err != nil {
+				break out
+			}
+			streamC = sub.RecvStream()
+			closeC = sub.RecvErr()
 		case <-doneC:
 			break out
 		case err = <-errorC:
@@ -195,3 +240,32 @@ out:
 func (in *controllerInternal) on_data(data *cba.Controller) {
 	in.data = data
 }
+
+// subscribe on a fresh connection and then refetch the controller in case it
+// changed while the connection was down
+func (in *controllerInternal) resubscribe() (*sgows.AccountSubscription, error) {
+	var err error
+	in.ws, err = sub.Reconnect(in.ctx, in.ws)
+	if err != nil {
+		return nil, err
+	}
+	s, err := in.ws.AccountSubscribe(in.id, sgorpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	r, err := in.rpc.GetAccountInfoWithOpts(in.ctx, in.id, &sgorpc.GetAccountInfoOpts{
+		Commitment: sgorpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		s.Unsubscribe()
+		return nil, err
+	}
+	data := new(cba.Controller)
+	err = bin.UnmarshalBorsh(data, r.Value.Data.GetBinary())
+	if err != nil {
+		s.Unsubscribe()
+		return nil, err
+	}
+	in.on_data(data)
+	return s, nil
+}
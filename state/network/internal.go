@@ -8,6 +8,7 @@ import (
 
 	"github.com/solpipe/solpipe-tool/ds/sub"
 	"github.com/solpipe/solpipe-tool/state/slot"
+	sub2 "github.com/solpipe/solpipe-tool/state/sub"
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
 	log "github.com/sirupsen/logrus"
@@ -31,11 +32,16 @@ type BlockTransactionCount struct {
 
 const DECIFER_GOROUTINE_COUNT = 5
 
+// Blocks missed while the block subscription was down are fetched over rpc,
+// up to this many slots back from the current slot.
+const BACKFILL_SLOT_MAX = 1000
+
 func loopInternal(
 	ctx context.Context,
 	cancel context.CancelFunc,
 	internalC <-chan func(*internal),
 	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
 	sub *sgows.BlockSubscription,
 	slotHome slot.SlotHome,
 	rawC chan<- *sgows.BlockResult,
@@ -50,13 +56,21 @@ func loopInternal(
 	in.rpc = rpcClient
 	in.slot = 0
 
-	defer sub.Unsubscribe()
-	streamBlockC := sub.RecvStream()
-	errorBlockC := sub.CloseSignal()
+	// when replaying, sub is nil and these channels block forever
+	var streamBlockC <-chan sgows.Result
+	var errorBlockC <-chan error
+	if sub != nil {
+		defer func() {
+			sub.Unsubscribe()
+		}()
+		streamBlockC = sub.RecvStream()
+		errorBlockC = sub.CloseSignal()
+	}
 
 	slotSub := slotHome.OnSlot()
 	defer slotSub.Unsubscribe()
 	voteDelay := 5 * time.Second
+	lastBlock := uint64(0)
 out:
 	for {
 		select {
@@ -66,7 +80,24 @@ out:
 		case err = <-slotSub.ErrorC:
 			break out
 		case err = <-errorBlockC:
-			break out
+			log.Debugf("block subscription dropped after slot=%d: %+v", lastBlock, err)
+			sub.Unsubscribe()
+			wsClient, err = sub2.Reconnect(ctx, wsClient)
+			if err != nil {
+				break out
+			}
+			sub, err = blockSubscribe(wsClient)
+			if err != nil {
+				break out
+			}
+			streamBlockC = sub.RecvStream()
+			errorBlockC = sub.CloseSignal()
+			// subscribe first so that no block falls between the
+			// backfill and the new subscription
+			lastBlock, err = in.backfill(lastBlock, rawC)
+			if err != nil {
+				break out
+			}
 		case d := <-streamBlockC:
 			b, ok := d.(*sgows.BlockResult)
 			if !ok {
@@ -77,6 +108,11 @@ out:
 				err = fmt.Errorf("%+v", b.Value.Err)
 				break out
 			}
+			if b.Value.Slot <= lastBlock {
+				// already backfilled
+				continue
+			}
+			lastBlock = b.Value.Slot
 			rawC <- b
 		case in.slot = <-slotSub.StreamC:
 		case <-doneC:
@@ -89,6 +125,55 @@ out:
 	in.finish(err)
 }
 
+// Fetch the finalized blocks after lastBlock up to the current slot and
+// send them to rawC.  The last slot sent is returned.
+func (in *internal) backfill(lastBlock uint64, rawC chan<- *sgows.BlockResult) (uint64, error) {
+	if lastBlock == 0 {
+		return lastBlock, nil
+	}
+	end, err := in.rpc.GetSlot(in.ctx, sgorpc.CommitmentFinalized)
+	if err != nil {
+		return lastBlock, err
+	}
+	if end <= lastBlock {
+		return lastBlock, nil
+	}
+	start := lastBlock + 1
+	if BACKFILL_SLOT_MAX < end-lastBlock {
+		log.Debugf("skipping slots %d to %d", start, end-BACKFILL_SLOT_MAX)
+		start = end - BACKFILL_SLOT_MAX + 1
+	}
+	slotList, err := in.rpc.GetBlocks(in.ctx, start, &end, sgorpc.CommitmentFinalized)
+	if err != nil {
+		return lastBlock, err
+	}
+	log.Debugf("backfilling %d blocks from slot=%d to slot=%d", len(slotList), start, end)
+	rewards := false
+	version := uint64(0)
+	for _, s := range slotList {
+		block, err := in.rpc.GetBlockWithOpts(in.ctx, s, &sgorpc.GetBlockOpts{
+			TransactionDetails:             sgorpc.TransactionDetailsNone,
+			Rewards:                        &rewards,
+			Commitment:                     sgorpc.CommitmentFinalized,
+			MaxSupportedTransactionVersion: &version,
+		})
+		if err != nil {
+			return lastBlock, err
+		}
+		b := new(sgows.BlockResult)
+		b.Context.Slot = s
+		b.Value.Slot = s
+		b.Value.Block = block
+		select {
+		case <-in.ctx.Done():
+			return lastBlock, in.ctx.Err()
+		case rawC <- b:
+		}
+		lastBlock = s
+	}
+	return end, nil
+}
+
 func (in *internal) finish(err error) {
 	log.Debug(err)
 	for i := 0; i < len(in.closeSignalCList); i++ {
@@ -96,6 +181,32 @@ func (in *internal) finish(err error) {
 	}
 }
 
+func loopReplayBlock(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	blockC <-chan BlockTransactionCount,
+	blockCountHome *sub.SubHome[BlockTransactionCount],
+) {
+	doneC := ctx.Done()
+	defer cancel()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case id := <-blockCountHome.DeleteC:
+			blockCountHome.Delete(id)
+		case r := <-blockCountHome.ReqC:
+			blockCountHome.Receive(r)
+		case x, ok := <-blockC:
+			if !ok {
+				break out
+			}
+			blockCountHome.Broadcast(x)
+		}
+	}
+}
+
 func loopCountBlock(
 	ctx context.Context,
 	cancel context.CancelFunc,
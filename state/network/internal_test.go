@@ -0,0 +1,69 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+)
+
+func TestBackfill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Id     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		var result interface{}
+		switch req.Method {
+		case "getSlot":
+			result = 20
+		case "getBlocks":
+			// slot 13 was skipped
+			result = []uint64{11, 12, 14, 15}
+		case "getBlock":
+			hash := "11111111111111111111111111111111"
+			result = map[string]interface{}{"blockhash": hash, "previousBlockhash": hash, "parentSlot": 0}
+		default:
+			t.Errorf("unexpected %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+	defer node.Close()
+
+	in := new(internal)
+	in.ctx = ctx
+	in.rpc = sgorpc.New(node.URL)
+	rawC := make(chan *sgows.BlockResult, 10)
+	lastBlock, err := in.backfill(10, rawC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastBlock != 20 {
+		t.Fatalf("last block %d", lastBlock)
+	}
+	close(rawC)
+	slotList := make([]uint64, 0)
+	for b := range rawC {
+		slotList = append(slotList, b.Value.Slot)
+	}
+	if len(slotList) != 4 || slotList[0] != 11 || slotList[3] != 15 {
+		t.Fatalf("backfilled %+v", slotList)
+	}
+
+	// nothing has been seen yet
+	lastBlock, err = in.backfill(0, rawC)
+	if err != nil || lastBlock != 0 {
+		t.Fatalf("%d %+v", lastBlock, err)
+	}
+}
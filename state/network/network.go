@@ -24,10 +24,7 @@ func Create(ctx context.Context, controller ctr.Controller, rpcClient *sgorpc.Cl
 	internalC := make(chan func(*internal), 10)
 
 	var sub *sgows.BlockSubscription
-	sub, err = wsClient.BlockSubscribe(sgows.NewBlockSubscribeFilterAll(), &sgows.BlockSubscribeOpts{
-		Commitment:         sgorpc.CommitmentFinalized,
-		TransactionDetails: sgorpc.TransactionDetailsNone,
-	})
+	sub, err = blockSubscribe(wsClient)
 	if err != nil {
 		return
 	}
@@ -48,12 +45,50 @@ func Create(ctx context.Context, controller ctr.Controller, rpcClient *sgorpc.Cl
 	ctxIn, cancelIn := context.WithCancel(ctx)
 	go loopVoteUpdate(ctxIn, cancelIn, rpcClient, voteHome)
 	go loopCountBlock(ctxIn, cancelIn, rawC, blockCountHome)
-	go loopInternal(ctxIn, cancelIn, internalC, rpcClient, sub, slotSub, rawC)
+	go loopInternal(ctxIn, cancelIn, internalC, rpcClient, wsClient, sub, slotSub, rawC)
 	// this one is last since we need the OnBlock subscription
 	go loopTps(ctxIn, cancelIn, networkStatsHome, n.OnBlock())
 	return
 }
 
+// Create a network that counts the blocks read from blockC (see state/replay)
+// instead of subscribing over a websocket.  Recordings do not hold vote
+// stake, so OnVoteStake and OnTotalStake receive nothing.
+func CreateFromReplay(
+	ctx context.Context,
+	controller ctr.Controller,
+	rpcClient *sgorpc.Client,
+	blockC <-chan BlockTransactionCount,
+) Network {
+	internalC := make(chan func(*internal), 10)
+	slotSub := controller.SlotHome()
+	blockCountHome := sub2.CreateSubHome[BlockTransactionCount]()
+	networkStatsHome := sub2.CreateSubHome[NetworkStatus]()
+	voteHome := sub2.CreateSubHome[VoteStake]()
+	n := Network{
+		ctx:         ctx,
+		Controller:  controller,
+		internalC:   internalC,
+		blockReqC:   blockCountHome.ReqC,
+		networkReqC: networkStatsHome.ReqC,
+		voteReqC:    voteHome.ReqC,
+	}
+
+	ctxIn, cancelIn := context.WithCancel(ctx)
+	go loopVoteUpdate(ctxIn, cancelIn, nil, voteHome)
+	go loopReplayBlock(ctxIn, cancelIn, blockC, blockCountHome)
+	go loopInternal(ctxIn, cancelIn, internalC, rpcClient, nil, nil, slotSub, nil)
+	go loopTps(ctxIn, cancelIn, networkStatsHome, n.OnBlock())
+	return n
+}
+
+func blockSubscribe(wsClient *sgows.Client) (*sgows.BlockSubscription, error) {
+	return wsClient.BlockSubscribe(sgows.NewBlockSubscribeFilterAll(), &sgows.BlockSubscribeOpts{
+		Commitment:         sgorpc.CommitmentFinalized,
+		TransactionDetails: sgorpc.TransactionDetailsNone,
+	})
+}
+
 func (e1 Network) OnTotalStake() sub2.Subscription[VoteStake] {
 	return e1.OnVoteStake(ZeroPub())
 }
@@ -30,6 +30,11 @@ func loopVoteUpdate(
 	delay := 5 * time.Second
 
 	zero := ZeroPub()
+	// without rpcClient (ie when replaying) vote stake is never polled
+	var pollC <-chan time.Time
+	if rpcClient != nil {
+		pollC = time.After(delay)
+	}
 out:
 	for {
 		select {
@@ -39,8 +44,9 @@ out:
 			voteHome.Delete(id)
 		case r := <-voteHome.ReqC:
 			voteHome.Receive(r)
-		case <-time.After(delay):
+		case <-pollC:
 			delay = 5 * time.Minute
+			pollC = time.After(delay)
 			r, err := rpcClient.GetVoteAccounts(ctx, &sgorpc.GetVoteAccountsOpts{
 				Commitment: sgorpc.CommitmentFinalized,
 			})
@@ -1,11 +1,13 @@
 package pricing
 
 import (
+	sgo "github.com/SolmateDev/solana-go"
 	cba "github.com/solpipe/cba"
 	pyt "github.com/solpipe/solpipe-tool/state/payout"
 )
 
 type periodInfo struct {
+	id           sgo.PublicKey
 	period       cba.Period
 	bs           pyt.BidStatus
 	pipelineInfo *pipelineInfo
@@ -91,6 +91,7 @@ out:
 		case err = <-slotSub.ErrorC:
 			break out
 		case in.slot = <-slotSub.StreamC:
+			in.period_prune()
 			if slotMax <= in.slot+1000 {
 				slotMax, err = in.handle.TimeAppend(10000)
 				if err != nil {
@@ -118,6 +119,10 @@ out:
 			if err != nil {
 				break out
 			}
+			pi, present := in.pipelineM[relStake.pipelineId.String()]
+			if present {
+				pi.stake = relStake.relative
+			}
 		case err = <-pipelineSub.ErrorC:
 			break out
 		case p := <-pipelineSub.StreamC:
@@ -158,6 +163,10 @@ out:
 			if err != nil {
 				break out
 			}
+			y, present := in.payoutM[update.payoutId.String()]
+			if present {
+				y.bs = update.bs
+			}
 		}
 	}
 	in.finish(err)
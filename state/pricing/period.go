@@ -20,8 +20,18 @@ func (in *internal) on_period(update periodUpdate) {
 	}
 
 	in.payoutM[update.payout.Id.String()] = &periodInfo{
+		id:           update.payout.Id,
 		period:       update.data.Period,
 		bs:           bs,
 		pipelineInfo: pi,
 	}
 }
+
+// forget periods that have finished; the time series keeps their history
+func (in *internal) period_prune() {
+	for id, y := range in.payoutM {
+		if y.period.Start+y.period.Length < in.slot {
+			delete(in.payoutM, id)
+		}
+	}
+}
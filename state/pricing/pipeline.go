@@ -189,6 +189,7 @@ type pipelineInfo struct {
 	bidder sgo.PublicKey
 	p      pipe.Pipeline
 	data   cba.Pipeline
+	stake  sub.StakeUpdate
 	cancel context.CancelFunc
 }
 
@@ -0,0 +1,193 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+// The price of the bandwidth of a pipeline over one period.
+type Quote struct {
+	PipelineId    sgo.PublicKey
+	PayoutId      sgo.PublicKey
+	Period        cba.Period
+	Capacity      float64 // tps of the pipeline (network tps * stake share)
+	TotalDeposits uint64
+}
+
+// Deposits paid (in pc_mint) for each TPS of capacity over the period.  The
+// price is 0 if the pipeline has no capacity.
+func (q Quote) Price() float64 {
+	if q.Capacity <= 0 {
+		return 0
+	}
+	return float64(q.TotalDeposits) / q.Capacity
+}
+
+// capacity of a pipeline in tps
+func capacity(ns ntk.NetworkStatus, stake sub.StakeUpdate) float64 {
+	if stake.ActivatedStake == nil || stake.TotalStake == nil || stake.TotalStake.Sign() <= 0 {
+		return 0
+	}
+	share, _ := new(big.Rat).SetFrac(stake.ActivatedStake, stake.TotalStake).Float64()
+	return ns.AverageTransactionsPerSecond * share
+}
+
+func (in *internal) quote(y *periodInfo) Quote {
+	return Quote{
+		PipelineId:    y.pipelineInfo.Id(),
+		PayoutId:      y.id,
+		Period:        y.period,
+		Capacity:      capacity(in.ns, y.pipelineInfo.stake),
+		TotalDeposits: y.bs.TotalDeposits,
+	}
+}
+
+func (e1 Pricing) quotes(filter func(slot uint64, period cba.Period) bool) ([]Quote, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan []Quote, 1)
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ans := make([]Quote, 0)
+		for _, y := range in.payoutM {
+			if filter(in.slot, y.period) {
+				ans = append(ans, in.quote(y))
+			}
+		}
+		ansC <- ans
+	}:
+	}
+	var ans []Quote
+	select {
+	case <-doneC:
+		return nil, errors.New("canceled")
+	case ans = <-ansC:
+	}
+	sort.SliceStable(ans, func(i, j int) bool {
+		return ans[i].Period.Start < ans[j].Period.Start
+	})
+	return ans, nil
+}
+
+// Quotes for the periods that are running right now (at most one per pipeline).
+func (e1 Pricing) Current() ([]Quote, error) {
+	return e1.quotes(func(slot uint64, period cba.Period) bool {
+		return period.Start <= slot && slot < period.Start+period.Length
+	})
+}
+
+// Quotes for periods that have not started yet, sorted by start slot.  The
+// capacity is forecasted from the current network tps and stake share.
+func (e1 Pricing) Forecast() ([]Quote, error) {
+	return e1.quotes(func(slot uint64, period cba.Period) bool {
+		return slot < period.Start
+	})
+}
+
+// the latest slot seen by the pricing engine
+func (e1 Pricing) Slot() (uint64, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan uint64, 1)
+	select {
+	case <-doneC:
+		return 0, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ansC <- in.slot
+	}:
+	}
+	select {
+	case <-doneC:
+		return 0, errors.New("canceled")
+	case slot := <-ansC:
+		return slot, nil
+	}
+}
+
+// The bid book of a payout.  Finished periods are read from the time series.
+func (e1 Pricing) BidBook(payoutId sgo.PublicKey) (pyt.BidStatus, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan pyt.BidStatus, 1)
+	presentC := make(chan bool, 1)
+	select {
+	case <-doneC:
+		return pyt.BidStatus{}, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		y, present := in.payoutM[payoutId.String()]
+		if present {
+			ansC <- y.bs
+		}
+		presentC <- present
+	}:
+	}
+	var present bool
+	select {
+	case <-doneC:
+		return pyt.BidStatus{}, errors.New("canceled")
+	case present = <-presentC:
+	}
+	if present {
+		return <-ansC, nil
+	}
+	list, err := e1.handler.BidSeries(payoutId, ts.Query{Step: math.MaxInt64})
+	if err != nil {
+		return pyt.BidStatus{}, err
+	}
+	if len(list) == 0 {
+		return pyt.BidStatus{}, errors.New("unknown payout")
+	}
+	bs := list[len(list)-1].Status
+	bs.IsFinal = true
+	return bs, nil
+}
+
+// Quotes for the periods of a pipeline that start inside the window.  Deposits
+// and capacity are taken from the last points recorded before each period
+// started.
+func (e1 Pricing) History(pipelineId sgo.PublicKey, q ts.Query) ([]Quote, error) {
+	periodList, err := e1.handler.PeriodSeries(pipelineId, q)
+	if err != nil {
+		return nil, err
+	}
+	ans := make([]Quote, len(periodList))
+	for i, p := range periodList {
+		// one bucket holding everything up to the start of the period
+		before := ts.Query{Finish: p.Period.Start, Step: math.MaxInt64}
+		ans[i] = Quote{
+			PipelineId: pipelineId,
+			PayoutId:   p.PayoutId,
+			Period:     p.Period,
+		}
+		bidList, err := e1.handler.BidSeries(p.PayoutId, before)
+		if err != nil {
+			return nil, err
+		}
+		if 0 < len(bidList) {
+			ans[i].TotalDeposits = bidList[len(bidList)-1].Status.TotalDeposits
+		}
+		networkList, err := e1.handler.NetworkSeries(before)
+		if err != nil {
+			return nil, err
+		}
+		stakeList, err := e1.handler.StakeSeries(pipelineId, before)
+		if err != nil {
+			return nil, err
+		}
+		if 0 < len(networkList) && 0 < len(stakeList) {
+			ans[i].Capacity = capacity(
+				networkList[len(networkList)-1].Status,
+				stakeList[len(stakeList)-1].Stake,
+			)
+		}
+	}
+	return ans, nil
+}
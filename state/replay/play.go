@@ -0,0 +1,319 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	bin "github.com/gagliardetto/binary"
+	log "github.com/sirupsen/logrus"
+	cba "github.com/solpipe/cba"
+	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+	slt "github.com/solpipe/solpipe-tool/state/slot"
+	"github.com/solpipe/solpipe-tool/state/sub"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+// Subscribers in ds/sub are dropped when their buffer fills up, so entries are
+// never played back faster than this.
+const MIN_PLAY_INTERVAL = 1 * time.Millisecond
+
+// A router, network and slot home driven by a recording.  Once the recording
+// runs out, they keep their last state until ctx is canceled.
+type Replay struct {
+	ctx        context.Context
+	internalC  chan<- func(*internal)
+	Header     Header
+	Router     rtr.Router
+	Network    ntk.Network
+	Controller ctr.Controller
+	Rpc        *sgorpc.Client // answers account reads from the replayed accounts
+}
+
+type internal struct {
+	ctx              context.Context
+	closeSignalCList []chan<- error
+	controllerId     sgo.PublicKey
+	account          map[string][]byte
+	slot             uint64
+}
+
+// Play the recording at filePath.  speed scales the time between entries
+// (ie 10 plays back ten times faster than recorded).
+func Play(ctx context.Context, filePath string, speed float64) (Replay, error) {
+	if speed <= 0 {
+		return Replay{}, errors.New("speed must be positive")
+	}
+	r, err := openReader(filePath)
+	if err != nil {
+		return Replay{}, err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			r.Close()
+		}
+	}()
+
+	s := &sub.Snapshot{
+		Version:   sub.SNAPSHOT_VERSION,
+		ProgramId: r.Header.ProgramId.String(),
+		Slot:      r.Header.Slot,
+		Account:   make(map[string][]byte),
+	}
+	var next Entry
+	for {
+		next, err = r.Read()
+		if err != nil || next.Kind != RECORD_SNAPSHOT {
+			break
+		}
+		s.Account[next.Id.String()] = next.Data
+	}
+	isFinished := false
+	if err == io.EOF {
+		isFinished = true
+	} else if err != nil {
+		return Replay{}, err
+	}
+	all, err := s.Result(r.Header.Version)
+	if err != nil {
+		return Replay{}, err
+	}
+	if all.Controller == nil {
+		return Replay{}, errors.New("recording has no controller")
+	}
+	controllerId, _, err := vrs.ControllerId(r.Header.Version)
+	if err != nil {
+		return Replay{}, err
+	}
+
+	internalC := make(chan func(*internal), 10)
+	rpcClient := sgorpc.NewWithCustomRPCClient(replayRpc{ctx: ctx, internalC: internalC})
+	accountC := make(chan sub.AccountUpdate)
+	slotC := make(chan uint64)
+	blockC := make(chan ntk.BlockTransactionCount)
+	controllerC := make(chan cba.Controller)
+
+	// the player has to run before the router starts since creating pipelines
+	// reads accounts over rpcClient
+	startC := make(chan struct{})
+	go loopPlay(
+		ctx,
+		internalC,
+		r,
+		next,
+		isFinished,
+		speed,
+		controllerId,
+		s,
+		startC,
+		accountC,
+		slotC,
+		blockC,
+		controllerC,
+	)
+	ok = true
+
+	slotHome, err := slt.SubscribeReplay(ctx, slotC)
+	if err != nil {
+		return Replay{}, err
+	}
+	controller, err := ctr.CreateControllerFromReplay(ctx, rpcClient, slotHome, *all.Controller, controllerC, r.Header.Version)
+	if err != nil {
+		return Replay{}, err
+	}
+	network := ntk.CreateFromReplay(ctx, controller, rpcClient, blockC)
+	router, err := rtr.CreateRouterFromReplay(ctx, network, rpcClient, accountC, all)
+	if err != nil {
+		return Replay{}, err
+	}
+	close(startC)
+
+	return Replay{
+		ctx:        ctx,
+		internalC:  internalC,
+		Header:     r.Header,
+		Router:     router,
+		Network:    network,
+		Controller: controller,
+		Rpc:        rpcClient,
+	}, nil
+}
+
+// fires when the recording runs out (nil) or cannot be read
+func (e1 Replay) CloseSignal() <-chan error {
+	signalC := make(chan error, 1)
+	select {
+	case <-e1.ctx.Done():
+		signalC <- errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		in.closeSignalCList = append(in.closeSignalCList, signalC)
+	}:
+	}
+	return signalC
+}
+
+// the slot of the last entry played back
+func (e1 Replay) Slot() (uint64, error) {
+	doneC := e1.ctx.Done()
+	ansC := make(chan uint64, 1)
+	select {
+	case <-doneC:
+		return 0, errors.New("canceled")
+	case e1.internalC <- func(in *internal) {
+		ansC <- in.slot
+	}:
+	}
+	select {
+	case <-doneC:
+		return 0, errors.New("canceled")
+	case slot := <-ansC:
+		return slot, nil
+	}
+}
+
+func loopPlay(
+	ctx context.Context,
+	internalC <-chan func(*internal),
+	r *reader,
+	next Entry,
+	isFinished bool,
+	speed float64,
+	controllerId sgo.PublicKey,
+	s *sub.Snapshot,
+	startC <-chan struct{},
+	accountC chan<- sub.AccountUpdate,
+	slotC chan<- uint64,
+	blockC chan<- ntk.BlockTransactionCount,
+	controllerC chan<- cba.Controller,
+) {
+	defer r.Close()
+	doneC := ctx.Done()
+	in := new(internal)
+	in.ctx = ctx
+	in.closeSignalCList = make([]chan<- error, 0)
+	in.controllerId = controllerId
+	in.account = s.Account
+	in.slot = s.Slot
+
+	var err error
+	var nextC <-chan time.Time
+	lastTime := next.Time
+	wait := func() {
+		d := time.Duration(float64(next.Time-lastTime) / speed)
+		if d < MIN_PLAY_INTERVAL {
+			d = MIN_PLAY_INTERVAL
+		}
+		nextC = time.After(d)
+	}
+
+	// answer rpc calls, but hold back entries until the router is listening
+wait_start:
+	for {
+		select {
+		case <-doneC:
+			return
+		case req := <-internalC:
+			req(in)
+		case <-startC:
+			break wait_start
+		}
+	}
+	if isFinished {
+		in.finish(nil)
+	} else {
+		wait()
+	}
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case req := <-internalC:
+			req(in)
+		case <-nextC:
+			if !in.play(internalC, next, accountC, slotC, blockC, controllerC) {
+				break out
+			}
+			lastTime = next.Time
+			next, err = r.Read()
+			if err == nil {
+				wait()
+			} else {
+				nextC = nil
+				if err == io.EOF {
+					err = nil
+				}
+				log.Debugf("replay finished at slot=%d", in.slot)
+				in.finish(err)
+			}
+		}
+	}
+}
+
+func (in *internal) finish(err error) {
+	for i := 0; i < len(in.closeSignalCList); i++ {
+		in.closeSignalCList[i] <- err
+	}
+	in.closeSignalCList = make([]chan<- error, 0)
+}
+
+// returns false if ctx was canceled
+func (in *internal) play(
+	internalC <-chan func(*internal),
+	x Entry,
+	accountC chan<- sub.AccountUpdate,
+	slotC chan<- uint64,
+	blockC chan<- ntk.BlockTransactionCount,
+	controllerC chan<- cba.Controller,
+) bool {
+	if in.slot < x.Slot {
+		in.slot = x.Slot
+	}
+	switch x.Kind {
+	case RECORD_ACCOUNT:
+		if x.Data == nil {
+			delete(in.account, x.Id.String())
+		} else {
+			in.account[x.Id.String()] = x.Data
+			if x.Id.Equals(in.controllerId) {
+				c := new(cba.Controller)
+				err := bin.UnmarshalBorsh(c, x.Data)
+				if err != nil {
+					log.Debugf("failed to decode controller: %s", err.Error())
+				} else if !send(in, internalC, controllerC, *c) {
+					return false
+				}
+			}
+		}
+		return send(in, internalC, accountC, x.account())
+	case RECORD_SLOT:
+		return send(in, internalC, slotC, x.Slot)
+	case RECORD_BLOCK:
+		return send(in, internalC, blockC, x.Block)
+	default:
+		return true
+	}
+}
+
+// keep answering rpc calls while waiting on a consumer, since the consumer may
+// itself be waiting on an rpc call
+func send[T any](in *internal, internalC <-chan func(*internal), c chan<- T, x T) bool {
+	doneC := in.ctx.Done()
+	for {
+		select {
+		case <-doneC:
+			return false
+		case req := <-internalC:
+			req(in)
+		case c <- x:
+			return true
+		}
+	}
+}
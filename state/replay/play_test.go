@@ -0,0 +1,123 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	bin "github.com/gagliardetto/binary"
+	cba "github.com/solpipe/cba"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+func TestPlay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	controllerId, controllerBump, err := vrs.ControllerId(vrs.VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cba.Controller{ControllerBump: controllerBump, Admin: sgo.NewWallet().PublicKey()}
+	data, err := bin.MarshalBorsh(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, cba.ControllerDiscriminator[:]) {
+		t.Skip("cba accounts are encoded without a discriminator")
+	}
+	c.Admin = sgo.NewWallet().PublicKey()
+	data2, err := bin.MarshalBorsh(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// entries after the first wait long enough for the subscriptions below
+	second := time.Second.Nanoseconds()
+	fp := filepath.Join(t.TempDir(), "recording.gz")
+	w, err := createWriter(fp, Header{Format: FORMAT_VERSION, ProgramId: cba.ProgramID, Version: vrs.VERSION_1, Slot: 10, Start: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []Entry{
+		{Kind: RECORD_SNAPSHOT, Time: 0, Slot: 10, Id: controllerId, Data: data},
+		{Kind: RECORD_SLOT, Time: 0, Slot: 11},
+		{Kind: RECORD_SLOT, Time: second, Slot: 12},
+		{Kind: RECORD_BLOCK, Time: second, Slot: 12, Block: ntk.BlockTransactionCount{Slot: 12, TransactionCount: 5}},
+		{Kind: RECORD_ACCOUNT, Time: second, Slot: 12, Id: controllerId, Data: data2},
+	} {
+		err = w.Write(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Play(ctx, fp, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeC := r.CloseSignal()
+	slotSub := r.Controller.SlotHome().OnSlot()
+	defer slotSub.Unsubscribe()
+	blockSub := r.Network.OnBlock()
+	defer blockSub.Unsubscribe()
+	accountSub := r.Router.OnAccount()
+	defer accountSub.Unsubscribe()
+
+	// slot 11 may have been played before the subscription
+	for slot := uint64(0); slot != 12; {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		case slot = <-slotSub.StreamC:
+			if slot != 11 && slot != 12 {
+				t.Fatalf("slot %d", slot)
+			}
+		}
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	case b := <-blockSub.StreamC:
+		if b.Slot != 12 || b.TransactionCount != 5 {
+			t.Fatalf("block %+v", b)
+		}
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	case u := <-accountSub.StreamC:
+		if !u.Id.Equals(controllerId) || u.Slot != 12 || !bytes.Equal(u.Data, data2) {
+			t.Fatalf("account %+v", u)
+		}
+	}
+	x, err := r.Controller.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !x.Admin.Equals(c.Admin) {
+		t.Fatalf("controller admin %s", x.Admin.String())
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	case err = <-closeC:
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	slot, err := r.Slot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot != 12 {
+		t.Fatalf("replay slot %d", slot)
+	}
+}
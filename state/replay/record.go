@@ -0,0 +1,100 @@
+package replay
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	cba "github.com/solpipe/cba"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+// how often buffered entries are written to disk
+const RECORD_FLUSH_INTERVAL = 5 * time.Second
+
+// Record the account updates, slots and blocks seen by the router to filePath
+// until ctx is canceled.  The recording starts with every program account,
+// fetched with rpcClient.  Returns nil once ctx is canceled.
+func Record(
+	ctx context.Context,
+	filePath string,
+	rpcClient *sgorpc.Client,
+	router rtr.Router,
+) error {
+	// subscribe before fetching the snapshot so that no update falls in between
+	accountSub := router.OnAccount()
+	defer accountSub.Unsubscribe()
+	slotSub := router.Controller.SlotHome().OnSlot()
+	defer slotSub.Unsubscribe()
+	blockSub := router.Network.OnBlock()
+	defer blockSub.Unsubscribe()
+
+	s, err := sub.FetchSnapshot(ctx, rpcClient)
+	if err != nil {
+		return err
+	}
+	w, err := createWriter(filePath, Header{
+		Format:    FORMAT_VERSION,
+		ProgramId: cba.ProgramID,
+		Version:   router.Controller.Version,
+		Slot:      s.Slot,
+		Start:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	keyList := make([]string, 0, len(s.Account))
+	for k := range s.Account {
+		keyList = append(keyList, k)
+	}
+	sort.Strings(keyList)
+	now := time.Now().UnixNano()
+	for _, k := range keyList {
+		id, err := sgo.PublicKeyFromBase58(k)
+		if err != nil {
+			return err
+		}
+		err = w.Write(Entry{Kind: RECORD_SNAPSHOT, Time: now, Slot: s.Slot, Id: id, Data: s.Account[k]})
+		if err != nil {
+			return err
+		}
+	}
+
+	doneC := ctx.Done()
+	ticker := time.NewTicker(RECORD_FLUSH_INTERVAL)
+	defer ticker.Stop()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-accountSub.ErrorC:
+			break out
+		case err = <-slotSub.ErrorC:
+			break out
+		case err = <-blockSub.ErrorC:
+			break out
+		case u := <-accountSub.StreamC:
+			if u.Slot < s.Slot {
+				// already in the snapshot
+				continue
+			}
+			err = w.Write(Entry{Kind: RECORD_ACCOUNT, Time: time.Now().UnixNano(), Slot: u.Slot, Id: u.Id, Data: u.Data})
+		case slot := <-slotSub.StreamC:
+			err = w.Write(Entry{Kind: RECORD_SLOT, Time: time.Now().UnixNano(), Slot: slot})
+		case b := <-blockSub.StreamC:
+			err = w.Write(Entry{Kind: RECORD_BLOCK, Time: time.Now().UnixNano(), Slot: b.Slot, Block: b})
+		case <-ticker.C:
+			err = w.Flush()
+		}
+		if err != nil {
+			break out
+		}
+	}
+	return err
+}
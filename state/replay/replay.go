@@ -0,0 +1,142 @@
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	"github.com/solpipe/solpipe-tool/state/sub"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+// A recording is a gzip compressed stream of gob encoded values: a Header, one
+// RECORD_SNAPSHOT entry per program account as of the start of the recording,
+// and then the other entries in the order in which they arrived.
+const FORMAT_VERSION uint32 = 1
+
+type RecordKind uint8
+
+const (
+	RECORD_SNAPSHOT RecordKind = 0 // an account as of the start of the recording
+	RECORD_ACCOUNT  RecordKind = 1 // an account update; Data is nil if the account was closed
+	RECORD_SLOT     RecordKind = 2
+	RECORD_BLOCK    RecordKind = 3
+)
+
+type Header struct {
+	Format    uint32
+	ProgramId sgo.PublicKey
+	Version   vrs.CbaVersion
+	Slot      uint64 // slot as of which the snapshot entries were fetched
+	Start     time.Time
+}
+
+type Entry struct {
+	Kind  RecordKind
+	Time  int64 // unix nanoseconds at which the entry was recorded
+	Slot  uint64
+	Id    sgo.PublicKey
+	Data  []byte
+	Block ntk.BlockTransactionCount
+}
+
+func (r Entry) account() sub.AccountUpdate {
+	return sub.AccountUpdate{Id: r.Id, Slot: r.Slot, Data: r.Data}
+}
+
+type writer struct {
+	f   *os.File
+	z   *gzip.Writer
+	enc *gob.Encoder
+}
+
+func createWriter(filePath string, header Header) (*writer, error) {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	w := &writer{f: f, z: gzip.NewWriter(f)}
+	w.enc = gob.NewEncoder(w.z)
+	err = w.enc.Encode(header)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *writer) Write(r Entry) error {
+	return w.enc.Encode(r)
+}
+
+// write out buffered entries so that a crash loses little of the recording
+func (w *writer) Flush() error {
+	return w.z.Flush()
+}
+
+func (w *writer) Close() error {
+	err := w.z.Close()
+	err2 := w.f.Close()
+	if err != nil {
+		return err
+	}
+	return err2
+}
+
+type reader struct {
+	f      *os.File
+	z      *gzip.Reader
+	dec    *gob.Decoder
+	Header Header
+}
+
+func openReader(filePath string) (*reader, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{f: f}
+	r.z, err = gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.dec = gob.NewDecoder(r.z)
+	err = r.dec.Decode(&r.Header)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	if r.Header.Format != FORMAT_VERSION {
+		r.Close()
+		return nil, fmt.Errorf("recording has format %d, expected %d", r.Header.Format, FORMAT_VERSION)
+	}
+	if !r.Header.ProgramId.Equals(cba.ProgramID) {
+		r.Close()
+		return nil, fmt.Errorf("recording is for program %s", r.Header.ProgramId.String())
+	}
+	return r, nil
+}
+
+// returns io.EOF at the end of the recording; a recording cut short by a crash
+// ends with io.ErrUnexpectedEOF, which is treated the same way
+func (r *reader) Read() (Entry, error) {
+	var x Entry
+	err := r.dec.Decode(&x)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return x, err
+}
+
+func (r *reader) Close() error {
+	r.z.Close()
+	return r.f.Close()
+}
@@ -0,0 +1,85 @@
+package replay
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+func TestRecording(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "recording.gz")
+	a := sgo.NewWallet().PublicKey()
+	list := []Entry{
+		{Kind: RECORD_SNAPSHOT, Time: 1, Slot: 10, Id: a, Data: []byte{1, 2}},
+		{Kind: RECORD_SLOT, Time: 2, Slot: 11},
+		{Kind: RECORD_ACCOUNT, Time: 3, Slot: 11, Id: a},
+		{Kind: RECORD_BLOCK, Time: 4, Slot: 11, Block: ntk.BlockTransactionCount{Slot: 11, TransactionCount: 5}},
+	}
+
+	w, err := createWriter(fp, Header{Format: FORMAT_VERSION, ProgramId: cba.ProgramID, Version: vrs.VERSION_1, Slot: 10, Start: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range list {
+		err = w.Write(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a crash leaves a flushed but unterminated file behind
+	err = w.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(r *reader) {
+		defer r.Close()
+		if r.Header.Slot != 10 || r.Header.Version != vrs.VERSION_1 {
+			t.Fatalf("bad header: %+v", r.Header)
+		}
+		for i, x := range list {
+			y, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if y.Kind != x.Kind || y.Slot != x.Slot || y.Time != x.Time || !y.Id.Equals(x.Id) || len(y.Data) != len(x.Data) || y.Block.TransactionCount != x.Block.TransactionCount {
+				t.Fatalf("entry %d changed: %+v", i, y)
+			}
+		}
+		_, err := r.Read()
+		if err != io.EOF {
+			t.Fatalf("expected end of recording, got %v", err)
+		}
+	}
+
+	r, err := openReader(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(r)
+
+	err = os.WriteFile(fp, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = openReader(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(r)
+}
@@ -0,0 +1,123 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	cba "github.com/solpipe/cba"
+)
+
+// Answers the account reads made by state objects (ie pipelines fetching
+// their period ring) from the accounts replayed so far.  Other methods fail
+// since the recording has nothing to answer them with.
+type replayRpc struct {
+	ctx       context.Context
+	internalC chan<- func(*internal)
+}
+
+func (rr replayRpc) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	doneC := rr.ctx.Done()
+	errorC := make(chan error, 1)
+	ansC := make(chan []byte, 1)
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case <-ctx.Done():
+		return ctx.Err()
+	case rr.internalC <- func(in *internal) {
+		data, err := in.call(method, params)
+		errorC <- err
+		ansC <- data
+	}:
+	}
+	var err error
+	select {
+	case <-doneC:
+		return errors.New("canceled")
+	case err = <-errorC:
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(<-ansC, out)
+}
+
+func (rr replayRpc) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return fmt.Errorf("%s is not available when replaying", method)
+}
+
+func (in *internal) call(method string, params []interface{}) ([]byte, error) {
+	ctx := sgorpc.RPCContext{Context: sgorpc.Context{Slot: in.slot}}
+	switch method {
+	case "getSlot":
+		return json.Marshal(in.slot)
+	case "getAccountInfo":
+		id, err := paramPublicKey(params)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(sgorpc.GetAccountInfoResult{RPCContext: ctx, Value: in.rpc_account(id)})
+	case "getMultipleAccounts":
+		if len(params) == 0 {
+			return nil, errors.New("no accounts")
+		}
+		idList, ok := params[0].([]sgo.PublicKey)
+		if !ok {
+			return nil, errors.New("bad account list")
+		}
+		ans := sgorpc.GetMultipleAccountsResult{RPCContext: ctx, Value: make([]*sgorpc.Account, len(idList))}
+		for i, id := range idList {
+			ans.Value[i] = in.rpc_account(id)
+		}
+		return json.Marshal(ans)
+	case "getProgramAccounts":
+		programId, err := paramPublicKey(params)
+		if err != nil {
+			return nil, err
+		}
+		ans := make(sgorpc.GetProgramAccountsResult, 0, len(in.account))
+		if programId.Equals(cba.ProgramID) {
+			// filters are ignored; callers skip accounts they do not want
+			for k := range in.account {
+				id, err := sgo.PublicKeyFromBase58(k)
+				if err != nil {
+					return nil, err
+				}
+				ans = append(ans, &sgorpc.KeyedAccount{Pubkey: id, Account: in.rpc_account(id)})
+			}
+		}
+		return json.Marshal(ans)
+	default:
+		return nil, fmt.Errorf("%s is not available when replaying", method)
+	}
+}
+
+func paramPublicKey(params []interface{}) (sgo.PublicKey, error) {
+	if len(params) == 0 {
+		return sgo.PublicKey{}, errors.New("no account")
+	}
+	id, ok := params[0].(sgo.PublicKey)
+	if !ok {
+		return sgo.PublicKey{}, errors.New("bad account")
+	}
+	return id, nil
+}
+
+// nil if the account does not exist
+func (in *internal) rpc_account(id sgo.PublicKey) *sgorpc.Account {
+	data, present := in.account[id.String()]
+	if !present {
+		return nil
+	}
+	return &sgorpc.Account{
+		// the recording does not hold balances; any open account has some
+		Lamports: 1,
+		Owner:    cba.ProgramID,
+		Data:     sgorpc.DataBytesOrJSONFromBytes(data),
+	}
+}
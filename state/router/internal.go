@@ -94,6 +94,7 @@ func loopInternal(
 	if err != nil {
 		return
 	}
+	in.update_metrics()
 
 out:
 	for {
@@ -139,7 +140,7 @@ out:
 		case err = <-payoutG.ErrorC:
 			break out
 		case d := <-payoutG.StreamC:
-			log.Debugf("received payout update____; id=%s; is open=%f", d.Id.String(), d.IsOpen)
+			log.Debugf("received payout update____; id=%s; is open=%t", d.Id.String(), d.IsOpen)
 			in.on_payout(d)
 		case err = <-receiptG.ErrorC:
 			break out
@@ -222,6 +223,7 @@ out:
 		case r := <-in.oa.validator.ReqC:
 			in.oa.validator.Receive(r)
 		}
+		in.update_metrics()
 	}
 	in.finish(err)
 
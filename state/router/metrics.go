@@ -0,0 +1,14 @@
+package router
+
+import "github.com/solpipe/solpipe-tool/metrics"
+
+var metricObjects = metrics.NewGaugeVec("solpipe_router_objects", "program accounts tracked by the router", "type")
+
+// cheap enough to run after every event
+func (in *internal) update_metrics() {
+	metricObjects.With("pipeline").Set(float64(len(in.l_pipeline.byId)))
+	metricObjects.With("payout").Set(float64(len(in.l_payout.byId)))
+	metricObjects.With("validator").Set(float64(len(in.l_validator.byId)))
+	metricObjects.With("receipt").Set(float64(len(in.l_receipt.byId)))
+	metricObjects.With("staker").Set(float64(len(in.l_staker.byId)))
+}
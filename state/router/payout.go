@@ -108,7 +108,7 @@ func (in *internal) on_payout(pwd sub.PayoutWithData) error {
 
 	if newlyCreated {
 		in.oa.payout.Broadcast(p)
-		go loopDelete(in.ctx, p.OnClose(), in.reqClose.payoutCloseC, p.Id, in.ws)
+		go loopDelete(in.ctx, p.OnClose(), in.reqClose.payoutCloseC, p.Id, in.rpc, in.ws)
 	}
 	return nil
 }
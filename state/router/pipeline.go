@@ -74,7 +74,7 @@ func (in *internal) on_pipeline(obj sub.PipelineGroup, slotHome slot.SlotHome) e
 		// new pipeline, so send a broadcast out
 		in.oa.pipeline.Broadcast(pipeline)
 
-		go loopDelete(in.ctx, pipeline.OnClose(), in.reqClose.pipelineCloseC, pipeline.Id, in.ws)
+		go loopDelete(in.ctx, pipeline.OnClose(), in.reqClose.pipelineCloseC, pipeline.Id, in.rpc, in.ws)
 		//in.oa.controllerCloseC
 	}
 
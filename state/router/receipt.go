@@ -60,7 +60,7 @@ func (in *internal) on_receipt(d sub.ReceiptGroup) error {
 
 	if newlyCreated {
 		in.oa.receipt.Broadcast(r)
-		go loopDelete(in.ctx, r.OnClose(), in.reqClose.receiptCloseC, r.Id, in.ws)
+		go loopDelete(in.ctx, r.OnClose(), in.reqClose.receiptCloseC, r.Id, in.rpc, in.ws)
 
 		pwd, present := in.l_payout.byId[data.Payout.String()]
 		if present {
@@ -103,6 +103,7 @@ func (e1 Router) ReceiptById(id sgo.PublicKey) (rpt.Receipt, error) {
 		r, present := in.l_receipt.byId[id.String()]
 		if !present {
 			errorC <- errors.New("no receipt")
+			return
 		}
 		errorC <- nil
 		ansC <- r
@@ -41,11 +41,21 @@ func CreateRouter(
 	wsClient *sgows.Client,
 	all *sub.ProgramAllResult,
 	version vrs.CbaVersion,
+) (Router, error) {
+	return createRouter(ctx, network, rpcClient, wsClient, all, version, "")
+}
+
+func createRouter(
+	ctx context.Context,
+	network ntk.Network,
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
+	all *sub.ProgramAllResult,
+	version vrs.CbaVersion,
+	snapshotFilePath string,
 ) (Router, error) {
 	var err error
 
-	// TODO: add ctx,cancel call here
-	startErrorC := make(chan error, 1)
 	subErrorC := make(chan error, 1)
 
 	subAll, err := sub.SubscribeProgramAll(ctx, rpcClient, wsClient, subErrorC)
@@ -53,12 +63,49 @@ func CreateRouter(
 		return Router{}, err
 	}
 
-	if all == nil {
+	if all == nil && 0 < len(snapshotFilePath) {
+		all, err = warmStart(ctx, rpcClient, subAll, snapshotFilePath, version)
+		if err != nil {
+			return Router{}, err
+		}
+	} else if all == nil {
 		all, err = sub.FetchProgramAll(ctx, rpcClient, version)
 		if err != nil {
 			return Router{}, err
 		}
 	}
+	return startRouter(ctx, network, rpcClient, wsClient, subAll, subErrorC, all)
+}
+
+// Create a router that takes account updates from accountC (see state/replay)
+// instead of a websocket subscription.  all is the state of the program when
+// the first update in accountC was made.  Accounts are read with rpcClient
+// when a pipeline is created.
+func CreateRouterFromReplay(
+	ctx context.Context,
+	network ntk.Network,
+	rpcClient *sgorpc.Client,
+	accountC <-chan sub.AccountUpdate,
+	all *sub.ProgramAllResult,
+) (Router, error) {
+	subErrorC := make(chan error, 1)
+	subAll := sub.ReplayProgramAll(ctx, accountC, subErrorC)
+	return startRouter(ctx, network, rpcClient, nil, subAll, subErrorC, all)
+}
+
+func startRouter(
+	ctx context.Context,
+	network ntk.Network,
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
+	subAll *sub.SubscriptionProgramGroup,
+	subErrorC chan error,
+	all *sub.ProgramAllResult,
+) (Router, error) {
+	var err error
+
+	// TODO: add ctx,cancel call here
+	startErrorC := make(chan error, 1)
 	controller := network.Controller
 
 	validatorG := dssub.SubscriptionRequest(subAll.ValidatorC, func(data sub.ValidatorGroup) bool {
@@ -158,14 +205,28 @@ func loopDelete(
 	closeC <-chan struct{},
 	deleteC chan<- sgo.PublicKey,
 	id sgo.PublicKey,
+	rpcClient *sgorpc.Client,
 	wsClient *sgows.Client,
 ) {
 	doneC := ctx.Done()
+	if wsClient == nil {
+		// replaying; closed accounts arrive through the program subscription
+		select {
+		case <-doneC:
+		case <-closeC:
+		}
+		return
+	}
+	// the router may have been created before the connection last dropped
+	wsClient = sub.Current(wsClient)
 	endSub, err := wsClient.AccountSubscribe(id, sgorpc.CommitmentFinalized)
 	if err != nil {
 		log.Error(err)
 		return
 	}
+	defer func() {
+		endSub.Unsubscribe()
+	}()
 	sendDelete := false
 out:
 	for {
@@ -173,9 +234,29 @@ out:
 		case <-doneC:
 			break out
 		case err = <-endSub.RecvErr():
-			log.Error(err)
-			sendDelete = false
-			break out
+			log.Debugf("account subscription for id=%s dropped: %+v", id.String(), err)
+			endSub.Unsubscribe()
+			wsClient, err = sub.Reconnect(ctx, wsClient)
+			if err != nil {
+				log.Error(err)
+				break out
+			}
+			newSub, err := wsClient.AccountSubscribe(id, sgorpc.CommitmentFinalized)
+			if err != nil {
+				log.Error(err)
+				break out
+			}
+			endSub = newSub
+			// the account may have been closed while the connection was down
+			sendDelete, err = isClosed(ctx, rpcClient, id)
+			if err != nil {
+				log.Error(err)
+				break out
+			}
+			if sendDelete {
+				log.Debugf("account id=%s closed while disconnected", id.String())
+				break out
+			}
 		case d := <-endSub.RecvStream():
 			x, ok := d.(*sgows.AccountResult)
 			if !ok {
@@ -202,6 +283,18 @@ out:
 
 }
 
+func isClosed(ctx context.Context, rpcClient *sgorpc.Client, id sgo.PublicKey) (bool, error) {
+	r, err := rpcClient.GetAccountInfoWithOpts(ctx, id, &sgorpc.GetAccountInfoOpts{
+		Commitment: sgorpc.CommitmentFinalized,
+	})
+	if errors.Is(err, sgorpc.ErrNotFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return r.Value == nil || r.Value.Lamports == 0, nil
+}
+
 func createObjSub() (*objSub, objReq) {
 	oa := new(objSub)
 	controllerCloseC := make(chan sgo.PublicKey, 1)
@@ -317,6 +410,13 @@ func (e1 Router) OnPayout() dssub.Subscription[sub.PayoutWithData] {
 	})
 }
 
+// every account update, undecoded; the buffer is large enough to record from
+func (e1 Router) OnAccount() dssub.Subscription[sub.AccountUpdate] {
+	return dssub.SubscriptionRequestWithBufferSize(e1.allGroup.AccountC, SNAPSHOT_BUFFER_SIZE, func(u sub.AccountUpdate) bool {
+		return true
+	})
+}
+
 func (e1 Router) AddPipeline(id sgo.PublicKey, data cba.Pipeline) error {
 	errorC := make(chan error, 1)
 	e1.internalC <- func(in *internal) {
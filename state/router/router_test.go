@@ -0,0 +1,135 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/state"
+	ctr "github.com/solpipe/solpipe-tool/state/controller"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	pipe "github.com/solpipe/solpipe-tool/state/pipeline"
+	rtr "github.com/solpipe/solpipe-tool/state/router"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+// The router picks up a pipeline and a payout added by scripts after it
+// started.
+func TestRouter(t *testing.T) {
+	if !cluster.CbaEnabled() {
+		t.Skip("cba bindings do not encode instructions")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	f, err := cluster.CreateFunded(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, payer := f.Script, f.Payer
+	admin, crankAuthority, mintAuthority := sgo.NewWallet().PrivateKey, sgo.NewWallet().PrivateKey, sgo.NewWallet().PrivateKey
+	err = script.Airdrop(ctx, f.RpcClient, f.WsClient, admin.PublicKey(), sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish := func() {
+		err := s.FinishTx(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr, err := s.CreateMint(payer, mintAuthority, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish()
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateController(admin, crankAuthority, *mr.Id, &state.Rate{N: 1, D: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish()
+
+	controller, err := ctr.CreateController(ctx, f.RpcClient, f.WsClient, vrs.VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := ntk.Create(ctx, controller, f.RpcClient, f.WsClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := rtr.CreateRouter(ctx, network, f.RpcClient, f.WsClient, nil, vrs.VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipelineSub := router.ObjectOnPipeline()
+	defer pipelineSub.Unsubscribe()
+	payoutSub := router.ObjectOnPayout()
+	defer payoutSub.Unsubscribe()
+
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipelineKey, err := s.AddPipeline(controller, payer, admin, state.Rate{N: 1, D: 100}, 10, state.Rate{N: 1, D: 2}, script.TICKSIZE_DEFAULT, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish()
+	var p pipe.Pipeline
+	for !p.Id.Equals(pipelineKey.PublicKey()) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the pipeline")
+		case err = <-pipelineSub.ErrorC:
+			t.Fatal(err)
+		case p = <-pipelineSub.StreamC:
+		}
+	}
+	_, err = router.PipelineById(p.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slot, err := f.Cluster.Slot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payoutId, err := s.AppendPeriod(controller, p, admin, slot+10, 20, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish()
+	var payout pyt.Payout
+	for !payout.Id.Equals(payoutId) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the payout")
+		case err = <-payoutSub.ErrorC:
+			t.Fatal(err)
+		case payout = <-payoutSub.StreamC:
+		}
+	}
+	pwd, err := router.PayoutById(payoutId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pwd.Data.Pipeline.Equals(p.Id) || pwd.Data.Period.Start != slot+10 || pwd.Data.Period.Length != 20 {
+		t.Fatalf("payout %+v", pwd.Data)
+	}
+}
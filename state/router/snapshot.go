@@ -0,0 +1,169 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	log "github.com/sirupsen/logrus"
+	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	ntk "github.com/solpipe/solpipe-tool/state/network"
+	"github.com/solpipe/solpipe-tool/state/sub"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+// how often the snapshot is written to disk if accounts have changed
+const SNAPSHOT_WRITE_INTERVAL = 1 * time.Minute
+
+// account updates arrive in bursts at the start of each period
+const SNAPSHOT_BUFFER_SIZE uint16 = 10000
+
+// wait this long before trying again to catch up after updates were dropped
+const SNAPSHOT_RESUBSCRIBE_DELAY = 10 * time.Second
+
+// Create a router that starts from the snapshot at filePath instead of
+// fetching every program account.  Only the accounts that changed since the
+// snapshot slot are fetched.  The snapshot is kept up to date and written back
+// every SNAPSHOT_WRITE_INTERVAL and when ctx is canceled.  If the file is
+// missing, corrupt or too old, all program accounts are fetched as usual.
+func CreateRouterWithSnapshot(
+	ctx context.Context,
+	network ntk.Network,
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
+	filePath string,
+	version vrs.CbaVersion,
+) (Router, error) {
+	return createRouter(ctx, network, rpcClient, wsClient, nil, version, filePath)
+}
+
+func warmStart(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	subAll *sub.SubscriptionProgramGroup,
+	filePath string,
+	version vrs.CbaVersion,
+) (*sub.ProgramAllResult, error) {
+	// subscribe before reading the snapshot so that no update falls in between
+	accountSub := dssub.SubscriptionRequestWithBufferSize(
+		subAll.AccountC,
+		SNAPSHOT_BUFFER_SIZE,
+		func(u sub.AccountUpdate) bool { return true },
+	)
+	s, err := sub.ReadSnapshot(filePath)
+	if err == nil {
+		err = s.Reconcile(ctx, rpcClient)
+		if err != nil {
+			log.Debugf("failed to reconcile snapshot %s: %s", filePath, err.Error())
+		}
+	} else {
+		log.Debugf("failed to read snapshot %s: %s", filePath, err.Error())
+	}
+	if err != nil {
+		s, err = sub.FetchSnapshot(ctx, rpcClient)
+		if err != nil {
+			accountSub.Unsubscribe()
+			return nil, err
+		}
+	}
+	all, err := s.Result(version)
+	if err != nil {
+		accountSub.Unsubscribe()
+		return nil, err
+	}
+	go loopSnapshot(ctx, rpcClient, subAll, filePath, s, accountSub)
+	return all, nil
+}
+
+func loopSnapshot(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	subAll *sub.SubscriptionProgramGroup,
+	filePath string,
+	s *sub.Snapshot,
+	accountSub dssub.Subscription[sub.AccountUpdate],
+) {
+	defer func() { accountSub.Unsubscribe() }()
+	doneC := ctx.Done()
+	ticker := time.NewTicker(SNAPSHOT_WRITE_INTERVAL)
+	defer ticker.Stop()
+	// the snapshot may have been reconciled, so write it out on the first tick
+	isDirty := true
+	var err error
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case err = <-accountSub.ErrorC:
+			if err == nil {
+				// the program subscription has shut down
+				break out
+			}
+			// the buffer overflowed and updates were dropped
+			log.Errorf("snapshot %s fell behind: %s", filePath, err.Error())
+			accountSub, err = resubscribeSnapshot(ctx, rpcClient, subAll, s)
+			if err != nil {
+				break out
+			}
+			isDirty = true
+		case u := <-accountSub.StreamC:
+			s.Update(u)
+			isDirty = true
+		case <-ticker.C:
+			if isDirty {
+				err = s.Write(filePath)
+				if err != nil {
+					log.Debugf("failed to write snapshot %s: %s", filePath, err.Error())
+				} else {
+					isDirty = false
+				}
+			}
+		}
+	}
+	if isDirty {
+		err = s.Write(filePath)
+		if err != nil {
+			log.Debugf("failed to write snapshot %s: %s", filePath, err.Error())
+		}
+	}
+}
+
+// Subscribe again and catch up from the snapshot slot.  Dropped updates are
+// no older than the snapshot slot, so reconciling picks them up; if that fails,
+// fetch every account.  Keeps trying until ctx is canceled.
+func resubscribeSnapshot(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	subAll *sub.SubscriptionProgramGroup,
+	s *sub.Snapshot,
+) (dssub.Subscription[sub.AccountUpdate], error) {
+	doneC := ctx.Done()
+	for {
+		// subscribe before catching up so that no update falls in between
+		accountSub := dssub.SubscriptionRequestWithBufferSize(
+			subAll.AccountC,
+			SNAPSHOT_BUFFER_SIZE,
+			func(u sub.AccountUpdate) bool { return true },
+		)
+		err := s.Reconcile(ctx, rpcClient)
+		if err == nil {
+			return accountSub, nil
+		}
+		log.Debugf("failed to reconcile snapshot: %s", err.Error())
+		fresh, err := sub.FetchSnapshot(ctx, rpcClient)
+		if err == nil {
+			*s = *fresh
+			return accountSub, nil
+		}
+		accountSub.Unsubscribe()
+		log.Errorf("failed to fetch program accounts for the snapshot: %s", err.Error())
+		select {
+		case <-doneC:
+			return accountSub, errors.New("canceled")
+		case <-time.After(SNAPSHOT_RESUBSCRIBE_DELAY):
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	"github.com/solpipe/solpipe-tool/state/sub"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+// After the account buffer overflows, the snapshot subscribes again and keeps
+// taking updates.
+func TestSnapshotResubscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c, err := cluster.Create(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stand in for the program subscription
+	home := dssub.CreateSubHome[sub.AccountUpdate]()
+	broadcastC := make(chan sub.AccountUpdate)
+	countC := make(chan chan<- int)
+	pendingC := make(chan chan<- int)
+	go func() {
+		var streamC chan<- sub.AccountUpdate
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case r := <-home.ReqC:
+				streamC = home.Receive(r)
+			case respC := <-pendingC:
+				respC <- len(streamC)
+			case id := <-home.DeleteC:
+				home.Delete(id)
+			case u := <-broadcastC:
+				home.Broadcast(u)
+			case respC := <-countC:
+				respC <- home.SubscriberCount()
+			}
+		}
+	}()
+	ask := func(reqC chan<- chan<- int) int {
+		respC := make(chan int, 1)
+		reqC <- respC
+		return <-respC
+	}
+
+	a, b := sgo.NewWallet().PublicKey(), sgo.NewWallet().PublicKey()
+	accountSub := dssub.SubscriptionRequestWithBufferSize(home.ReqC, 1, func(u sub.AccountUpdate) bool { return true })
+	broadcastC <- sub.AccountUpdate{Id: a, Slot: 1, Data: []byte{1}}
+	broadcastC <- sub.AccountUpdate{Id: a, Slot: 2, Data: []byte{2}}
+	if ask(countC) != 0 {
+		t.Fatal("subscription did not overflow")
+	}
+
+	s := &sub.Snapshot{Version: sub.SNAPSHOT_VERSION, ProgramId: cba.ProgramID.String(), Account: make(map[string][]byte)}
+	fp := filepath.Join(t.TempDir(), "snapshot.json")
+	ctxC, cancelC := context.WithCancel(ctx)
+	defer cancelC()
+	doneC := make(chan struct{})
+	go func() {
+		loopSnapshot(ctxC, c.Rpc(), &sub.SubscriptionProgramGroup{AccountC: home.ReqC}, fp, s, accountSub)
+		close(doneC)
+	}()
+	for ask(countC) != 1 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting to resubscribe")
+		case <-doneC:
+			t.Fatal("snapshot loop exited")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	broadcastC <- sub.AccountUpdate{Id: b, Slot: 3, Data: []byte{3}}
+	// the loop applies an update before it can see the cancel
+	for ask(pendingC) != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancelC()
+	<-doneC
+
+	x, err := sub.ReadSnapshot(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(x.Account[b.String()], []byte{3}) || x.Slot != 3 {
+		t.Fatalf("snapshot %+v", x)
+	}
+}
@@ -75,7 +75,7 @@ func (in *internal) on_stake(obj sub.StakeGroup) error {
 
 	if newlyCreated {
 		in.oa.staker.Broadcast(ref.s)
-		go loopDelete(in.ctx, ref.s.OnClose(), in.reqClose.stakerCloseC, ref.s.Id, in.ws)
+		go loopDelete(in.ctx, ref.s.OnClose(), in.reqClose.stakerCloseC, ref.s.Id, in.rpc, in.ws)
 	}
 
 	return nil
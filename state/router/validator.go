@@ -116,7 +116,7 @@ func (in *internal) on_validator(obj sub.ValidatorGroup) error {
 			V:    ref.v,
 			Data: validatorData,
 		})
-		go loopDelete(in.ctx, ref.v.OnClose(), in.reqClose.validatorCloseC, ref.v.Id, in.ws)
+		go loopDelete(in.ctx, ref.v.OnClose(), in.reqClose.validatorCloseC, ref.v.Id, in.rpc, in.ws)
 	}
 
 	return nil
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	dssub "github.com/solpipe/solpipe-tool/ds/sub"
+	sub2 "github.com/solpipe/solpipe-tool/state/sub"
 )
 
 type SlotHome struct {
@@ -52,6 +53,23 @@ func SubscribeSlot(
 	}, nil
 }
 
+// Broadcast the slots read from slotC (see state/replay) instead of
+// subscribing over a websocket.  The home closes when slotC is closed.
+func SubscribeReplay(ctxOutside context.Context, slotC <-chan uint64) (SlotHome, error) {
+	ctx, cancel := context.WithCancel(ctxOutside)
+	home := dssub.CreateSubHome[uint64]()
+	id, err := uuid.NewRandom()
+	if err != nil {
+		cancel()
+		return SlotHome{}, err
+	}
+	singleReqC := make(chan chan<- uint64)
+	go loopReplay(ctx, home, slotC, cancel, singleReqC)
+	return SlotHome{
+		reqC: home.ReqC, ctx: ctx, id: id, singleReqC: singleReqC,
+	}, nil
+}
+
 func (sh SlotHome) Time() (uint64, error) {
 	err := sh.ctx.Err()
 	if err != nil {
@@ -111,7 +129,9 @@ func loopInternal(
 	}
 	streamC := sub.RecvStream()
 	streamErrorC := sub.CloseSignal()
-	defer sub.Unsubscribe()
+	defer func() {
+		sub.Unsubscribe()
+	}()
 
 	log.Debug("preparing slot stream")
 	time.Sleep(5 * time.Second)
@@ -148,7 +168,19 @@ out:
 		case err = <-streamErrorC:
 			if err != nil {
 				log.Debugf("slot stream error: %s", err.Error())
-				break out
+				sub.Unsubscribe()
+				wsClient, err = sub2.Reconnect(ctx, wsClient)
+				if err != nil {
+					break out
+				}
+				sub, err = wsClient.SlotSubscribe()
+				if err != nil {
+					break out
+				}
+				streamC = sub.RecvStream()
+				streamErrorC = sub.CloseSignal()
+				// poll right away to cover the slots missed while disconnected
+				nextC = time.After(0)
 			}
 		case <-doneC:
 			break out
@@ -177,5 +209,39 @@ func sendBroadcat(
 	}
 	//log.Debugf("slot____=%d; sub count=%d", *slot, home.SubscriberCount())
 	*lastSlot = *slot
+	sub2.ObserveSlot(*slot)
 	home.Broadcast(*slot)
 }
+
+func loopReplay(
+	ctx context.Context,
+	home *dssub.SubHome[uint64],
+	slotC <-chan uint64,
+	cancel context.CancelFunc,
+	singleReqC <-chan chan<- uint64,
+) {
+	defer cancel()
+	defer home.Close()
+	doneC := ctx.Done()
+	lastSlot := uint64(0)
+	slot := uint64(0)
+	ok := true
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case slot, ok = <-slotC:
+			if !ok {
+				break out
+			}
+			sendBroadcat(home, &lastSlot, &slot)
+		case respC := <-singleReqC:
+			respC <- lastSlot
+		case r := <-home.ReqC:
+			home.Receive(r)
+		case id := <-home.DeleteC:
+			home.Delete(id)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package staker
+
+import (
+	"encoding/binary"
+	"errors"
+
+	sgo "github.com/SolmateDev/solana-go"
+)
+
+// StakeStateV2 discriminants used by the native stake program
+const (
+	STAKE_STATE_UNINITIALIZED uint32 = 0
+	STAKE_STATE_INITIALIZED   uint32 = 1
+	STAKE_STATE_STAKE         uint32 = 2
+	STAKE_STATE_REWARDS_POOL  uint32 = 3
+)
+
+// 4 byte discriminant + Meta (rent reserve, authorized staker/withdrawer, lockup)
+const stakeDelegationOffset = 4 + 8 + 32 + 32 + 8 + 8 + 32
+
+// Delegation of a native stake account.
+type Delegation struct {
+	Vote              sgo.PublicKey
+	Stake             uint64
+	ActivationEpoch   uint64
+	DeactivationEpoch uint64 // math.MaxUint64 while the stake is active
+}
+
+// Read the delegation out of the data of a stake program account.
+func ParseDelegation(data []byte) (d Delegation, err error) {
+	if len(data) < 4 {
+		err = errors.New("stake account too short")
+		return
+	}
+	state := binary.LittleEndian.Uint32(data[0:4])
+	if state != STAKE_STATE_STAKE {
+		err = errors.New("stake account is not delegated")
+		return
+	}
+	if len(data) < stakeDelegationOffset+32+8+8+8 {
+		err = errors.New("stake account too short")
+		return
+	}
+	i := stakeDelegationOffset
+	d.Vote = sgo.PublicKeyFromBytes(data[i : i+32])
+	i += 32
+	d.Stake = binary.LittleEndian.Uint64(data[i : i+8])
+	i += 8
+	d.ActivationEpoch = binary.LittleEndian.Uint64(data[i : i+8])
+	i += 8
+	d.DeactivationEpoch = binary.LittleEndian.Uint64(data[i : i+8])
+	return
+}
@@ -0,0 +1,37 @@
+package staker_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+)
+
+func TestParseDelegation(t *testing.T) {
+	vote, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// StakeStateV2::Stake as laid out by the stake program (200 bytes)
+	data := make([]byte, 200)
+	binary.LittleEndian.PutUint32(data[0:4], skr.STAKE_STATE_STAKE)
+	copy(data[124:156], vote.PublicKey().Bytes())
+	binary.LittleEndian.PutUint64(data[156:164], 5*sgo.LAMPORTS_PER_SOL)
+	binary.LittleEndian.PutUint64(data[164:172], 10)
+	binary.LittleEndian.PutUint64(data[172:180], ^uint64(0))
+
+	d, err := skr.ParseDelegation(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Vote.Equals(vote.PublicKey()) || d.Stake != 5*sgo.LAMPORTS_PER_SOL || d.ActivationEpoch != 10 || d.DeactivationEpoch != ^uint64(0) {
+		t.Fatalf("unexpected delegation %+v", d)
+	}
+
+	binary.LittleEndian.PutUint32(data[0:4], skr.STAKE_STATE_INITIALIZED)
+	_, err = skr.ParseDelegation(data)
+	if err == nil {
+		t.Fatal("parsed an undelegated stake account")
+	}
+}
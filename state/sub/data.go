@@ -75,6 +75,14 @@ type ReceiptGroup struct {
 	IsOpen bool
 }
 
+// The raw data of an account owned by the program.  Data is nil if the account
+// has been closed.
+type AccountUpdate struct {
+	Id   sgo.PublicKey
+	Slot uint64
+	Data []byte
+}
+
 type PayoutWithData struct {
 	Id     sgo.PublicKey
 	Data   cba.Payout
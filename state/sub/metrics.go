@@ -0,0 +1,40 @@
+package sub
+
+import (
+	"sync/atomic"
+
+	"github.com/solpipe/solpipe-tool/metrics"
+)
+
+var (
+	latestSlot      uint64 // set by ObserveSlot; read with atomic
+	metricSlot      = metrics.NewGauge("solpipe_slot", "latest slot from the slot subscription")
+	metricLag       = metrics.NewGauge("solpipe_subscription_lag_slots", "slots between the latest slot and the slot of a program account update when the update arrived")
+	metricUpdates   = metrics.NewCounter("solpipe_subscription_updates_total", "program account updates received over the websocket")
+	metricReconnect = metrics.NewCounter("solpipe_subscription_reconnect_total", "times the program subscription was dropped and resubscribed")
+)
+
+// Record the latest slot so that the lag of program account updates can be
+// measured.  state/slot calls this for every slot it broadcasts.
+func ObserveSlot(slot uint64) {
+	for {
+		old := atomic.LoadUint64(&latestSlot)
+		if slot <= old {
+			return
+		}
+		if atomic.CompareAndSwapUint64(&latestSlot, old, slot) {
+			metricSlot.Set(float64(slot))
+			return
+		}
+	}
+}
+
+func observeUpdate(slot uint64) {
+	metricUpdates.Inc()
+	latest := atomic.LoadUint64(&latestSlot)
+	if slot < latest {
+		metricLag.Set(float64(latest - slot))
+	} else {
+		metricLag.Set(0)
+	}
+}
@@ -0,0 +1,256 @@
+package sub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	bin "github.com/gagliardetto/binary"
+	cba "github.com/solpipe/cba"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+// Commitment of the program subscription and of every fetch of program
+// accounts, signatures and slots.  Snapshot.Slot advances with the updates
+// from the subscription and Reconcile reads signatures from that slot on, so
+// both must use the same commitment or Reconcile could miss transactions.
+const PROGRAM_COMMITMENT = sgorpc.CommitmentFinalized
+
+// version of the on-disk snapshot format; snapshots in any other format are ignored
+const SNAPSHOT_VERSION uint32 = 1
+
+// Reconciling fetches every program transaction after the snapshot slot.  Past
+// this many transactions, a full getProgramAccounts scan is cheaper.
+const SNAPSHOT_MAX_RECONCILE_TX int = 2000
+
+// getMultipleAccounts takes at most this many accounts
+const MAX_MULTIPLE_ACCOUNTS int = 100
+
+var ErrSnapshotStale = errors.New("snapshot is too old to reconcile")
+
+// The raw data of every account owned by the program as of Slot.  Decode it
+// with Result.
+type Snapshot struct {
+	Version   uint32            `json:"version"`
+	ProgramId string            `json:"program_id"`
+	Slot      uint64            `json:"slot"`
+	Account   map[string][]byte `json:"account"` // account id -> data
+}
+
+func createSnapshot(slot uint64) *Snapshot {
+	return &Snapshot{
+		Version:   SNAPSHOT_VERSION,
+		ProgramId: cba.ProgramID.String(),
+		Slot:      slot,
+		Account:   make(map[string][]byte),
+	}
+}
+
+// Fetch every account owned by the program.  This is the slow path used when
+// there is no usable snapshot on disk.
+func FetchSnapshot(ctx context.Context, rpcClient *sgorpc.Client) (*Snapshot, error) {
+	// take the slot first so that reconciling from it never misses an update
+	slot, err := rpcClient.GetSlot(ctx, PROGRAM_COMMITMENT)
+	if err != nil {
+		return nil, err
+	}
+	r, err := rpcClient.GetProgramAccountsWithOpts(ctx, cba.ProgramID, &sgorpc.GetProgramAccountsOpts{
+		Commitment: PROGRAM_COMMITMENT,
+		Encoding:   sgo.EncodingBase64,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := createSnapshot(slot)
+	for i := 0; i < len(r); i++ {
+		if 0 < r[i].Account.Lamports {
+			s.Account[r[i].Pubkey.String()] = r[i].Account.Data.GetBinary()
+		}
+	}
+	return s, nil
+}
+
+func ReadSnapshot(filePath string) (*Snapshot, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Snapshot)
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Version != SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("snapshot has version %d, expected %d", s.Version, SNAPSHOT_VERSION)
+	}
+	if s.ProgramId != cba.ProgramID.String() {
+		return nil, fmt.Errorf("snapshot is for program %s", s.ProgramId)
+	}
+	if s.Account == nil {
+		s.Account = make(map[string][]byte)
+	}
+	return s, nil
+}
+
+// Write the snapshot to a temporary file and then move it into place so that a
+// crash never leaves a half written snapshot behind.
+func (s *Snapshot) Write(filePath string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmpPath := filePath + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// Apply an update from the program subscription.  Updates from before the
+// snapshot slot are older than what the snapshot holds, so they are ignored.
+func (s *Snapshot) Update(u AccountUpdate) {
+	if u.Slot < s.Slot {
+		return
+	}
+	if u.Data == nil {
+		delete(s.Account, u.Id.String())
+	} else {
+		s.Account[u.Id.String()] = u.Data
+	}
+	s.Slot = u.Slot
+}
+
+// Bring the snapshot up to date by refetching every account touched by a
+// program transaction since the snapshot slot.  Returns ErrSnapshotStale if
+// there are too many transactions to go through.
+func (s *Snapshot) Reconcile(ctx context.Context, rpcClient *sgorpc.Client) error {
+	list, slot, err := fetchChanged(ctx, rpcClient, s.Slot)
+	if err != nil {
+		return err
+	}
+	for _, u := range list {
+		s.Update(u)
+	}
+	if s.Slot < slot {
+		s.Slot = slot
+	}
+	return nil
+}
+
+// Fetch every account touched by a program transaction at or after slot.  Only
+// static account keys are considered; the program does not use address lookup
+// tables.  Data is nil for accounts that have been closed or are no longer
+// owned by the program.  The returned slot is the slot as of which the
+// accounts were fetched.  Returns ErrSnapshotStale if there are more than
+// SNAPSHOT_MAX_RECONCILE_TX transactions to go through.
+func fetchChanged(ctx context.Context, rpcClient *sgorpc.Client, slot uint64) ([]AccountUpdate, uint64, error) {
+	idM := make(map[string]sgo.PublicKey)
+	limit := 1000
+	maxVersion := uint64(0)
+	count := 0
+	var before sgo.Signature
+out:
+	for {
+		list, err := rpcClient.GetSignaturesForAddressWithOpts(ctx, cba.ProgramID, &sgorpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Before:     before,
+			Commitment: PROGRAM_COMMITMENT,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, x := range list {
+			if x.Slot < slot {
+				break out
+			}
+			count++
+			if SNAPSHOT_MAX_RECONCILE_TX < count {
+				return nil, 0, ErrSnapshotStale
+			}
+			if x.Err != nil {
+				// failed transactions do not change program accounts
+				continue
+			}
+			r, err := rpcClient.GetTransaction(ctx, x.Signature, &sgorpc.GetTransactionOpts{
+				Encoding:                       sgo.EncodingBase64,
+				Commitment:                     PROGRAM_COMMITMENT,
+				MaxSupportedTransactionVersion: &maxVersion,
+			})
+			if err != nil {
+				return nil, 0, err
+			}
+			if r.Transaction == nil {
+				continue
+			}
+			tx, err := sgo.TransactionFromDecoder(bin.NewBinDecoder(r.Transaction.GetBinary()))
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, id := range tx.Message.AccountKeys {
+				idM[id.String()] = id
+			}
+		}
+		if len(list) < limit {
+			break out
+		}
+		before = list[len(list)-1].Signature
+	}
+
+	idList := make([]sgo.PublicKey, 0, len(idM))
+	for _, id := range idM {
+		idList = append(idList, id)
+	}
+	ans := make([]AccountUpdate, 0, len(idList))
+	for i := 0; i < len(idList); i += MAX_MULTIPLE_ACCOUNTS {
+		j := i + MAX_MULTIPLE_ACCOUNTS
+		if len(idList) < j {
+			j = len(idList)
+		}
+		r, err := rpcClient.GetMultipleAccountsWithOpts(ctx, idList[i:j], &sgorpc.GetMultipleAccountsOpts{
+			Encoding:   sgo.EncodingBase64,
+			Commitment: PROGRAM_COMMITMENT,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if slot < r.Context.Slot {
+			slot = r.Context.Slot
+		}
+		for k, a := range r.Value {
+			u := AccountUpdate{Id: idList[i+k], Slot: r.Context.Slot}
+			if a != nil && 0 < a.Lamports && a.Owner.Equals(cba.ProgramID) {
+				u.Data = a.Data.GetBinary()
+			}
+			ans = append(ans, u)
+		}
+	}
+	return ans, slot, nil
+}
+
+// Decode the snapshot for the controller of the given version.
+func (s *Snapshot) Result(version vrs.CbaVersion) (*ProgramAllResult, error) {
+	controllerId, controllerBump, err := vrs.ControllerId(version)
+	if err != nil {
+		return nil, err
+	}
+	keyList := make([]string, 0, len(s.Account))
+	for k := range s.Account {
+		keyList = append(keyList, k)
+	}
+	sort.Strings(keyList)
+	list := make([]AccountUpdate, len(keyList))
+	for i, k := range keyList {
+		list[i].Id, err = sgo.PublicKeyFromBase58(k)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Data = s.Account[k]
+	}
+	return programAllFromAccounts(controllerId, controllerBump, list)
+}
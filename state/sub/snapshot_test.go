@@ -0,0 +1,142 @@
+package sub_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+func TestSnapshot(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "snapshot.json")
+	a := sgo.NewWallet().PublicKey()
+	b := sgo.NewWallet().PublicKey()
+
+	s := &sub.Snapshot{
+		Version:   sub.SNAPSHOT_VERSION,
+		ProgramId: cba.ProgramID.String(),
+		Slot:      10,
+		Account:   map[string][]byte{a.String(): {1, 2, 3}},
+	}
+	_, err := sub.ReadSnapshot(fp)
+	if err == nil {
+		t.Fatal("read a missing snapshot")
+	}
+
+	s.Update(sub.AccountUpdate{Id: b, Slot: 9, Data: []byte{4}})
+	if _, present := s.Account[b.String()]; present {
+		t.Fatal("applied an update from before the snapshot slot")
+	}
+	s.Update(sub.AccountUpdate{Id: b, Slot: 11, Data: []byte{4}})
+	s.Update(sub.AccountUpdate{Id: a, Slot: 12})
+	if _, present := s.Account[a.String()]; present {
+		t.Fatal("closed account still in snapshot")
+	}
+	if s.Slot != 12 {
+		t.Fatalf("slot %d, expected 12", s.Slot)
+	}
+
+	err = s.Write(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := sub.ReadSnapshot(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.Slot != s.Slot || len(s2.Account) != 1 || s2.Account[b.String()][0] != 4 {
+		t.Fatalf("snapshot changed on disk: %+v", s2)
+	}
+
+	s2.ProgramId = b.String()
+	err = s2.Write(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sub.ReadSnapshot(fp)
+	if err == nil {
+		t.Fatal("read a snapshot for another program")
+	}
+}
+
+func TestSnapshotCommitment(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := sgo.NewWallet().PublicKey()
+	tx := &sgo.Transaction{
+		Signatures: []sgo.Signature{{}},
+		Message:    sgo.Message{AccountKeys: []sgo.PublicKey{a, cba.ProgramID}},
+	}
+	txData, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitmentM := make(map[string]string)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Id     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		opts := struct {
+			Commitment string `json:"commitment"`
+		}{}
+		if 0 < len(req.Params) {
+			json.Unmarshal(req.Params[len(req.Params)-1], &opts)
+		}
+		commitmentM[req.Method] = opts.Commitment
+		var result interface{}
+		switch req.Method {
+		case "getSlot":
+			result = 100
+		case "getProgramAccounts":
+			result = []interface{}{}
+		case "getSignaturesForAddress":
+			result = []interface{}{map[string]interface{}{"signature": sgo.Signature{}.String(), "slot": 110, "err": nil}}
+		case "getTransaction":
+			result = map[string]interface{}{"slot": 110, "transaction": []string{base64.StdEncoding.EncodeToString(txData), "base64"}}
+		case "getMultipleAccounts":
+			result = map[string]interface{}{"context": map[string]interface{}{"slot": 120}, "value": []interface{}{nil, nil}}
+		default:
+			t.Errorf("unexpected %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+	defer node.Close()
+	rpcClient := sgorpc.New(node.URL)
+
+	s, err := sub.FetchSnapshot(ctx, rpcClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Slot != 100 {
+		t.Fatalf("slot %d, expected 100", s.Slot)
+	}
+	err = s.Reconcile(ctx, rpcClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Slot != 120 {
+		t.Fatalf("slot %d, expected 120", s.Slot)
+	}
+	if len(commitmentM) != 5 {
+		t.Fatalf("calls %+v", commitmentM)
+	}
+	for method, commitment := range commitmentM {
+		if commitment != string(sub.PROGRAM_COMMITMENT) {
+			t.Errorf("%s at %s", method, commitment)
+		}
+	}
+}
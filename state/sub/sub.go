@@ -29,6 +29,7 @@ type SubscriptionProgramGroup struct {
 	StakerReceiptC chan<- dssub.ResponseChannel[StakerReceiptGroup]
 	ReceiptC       chan<- dssub.ResponseChannel[ReceiptGroup]
 	PayoutC        chan<- dssub.ResponseChannel[PayoutWithData]
+	AccountC       chan<- dssub.ResponseChannel[AccountUpdate] // every update, undecoded
 }
 
 type internalSubscriptionProgramGroup struct {
@@ -43,6 +44,13 @@ type internalSubscriptionProgramGroup struct {
 	stakeReceipt *dssub.SubHome[StakerReceiptGroup]
 	receipt      *dssub.SubHome[ReceiptGroup]
 	payout       *dssub.SubHome[PayoutWithData]
+	account      *dssub.SubHome[AccountUpdate]
+	ctx          context.Context
+	rpc          *sgorpc.Client
+	ws           *sgows.Client
+	tracking     map[string]DATA_TYPE
+	accountSlot  map[string]uint64 // slot of the last update per account
+	slot         uint64            // slot of the last update
 }
 
 type ProgramAllResult struct {
@@ -78,16 +86,33 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 		return nil, err
 	}
 	r, err := rpcClient.GetProgramAccountsWithOpts(ctx, cba.ProgramID, &sgorpc.GetProgramAccountsOpts{
-		Commitment: sgorpc.CommitmentFinalized,
+		Commitment: PROGRAM_COMMITMENT,
 		Encoding:   solana.EncodingBase64,
 	})
 	if err != nil {
 		return nil, err
 	}
-	ans := createProgramAllResult()
+	list := make([]AccountUpdate, 0, len(r))
 	for i := 0; i < len(r); i++ {
 		if 0 < r[i].Account.Lamports {
-			data := r[i].Account.Data.GetBinary()
+			list = append(list, AccountUpdate{Id: r[i].Pubkey, Data: r[i].Account.Data.GetBinary()})
+		}
+	}
+	return programAllFromAccounts(controllerId, controllerBump, list)
+}
+
+// Decode the accounts owned by the program.  Accounts belonging to other
+// controllers are skipped.
+func programAllFromAccounts(
+	controllerId solana.PublicKey,
+	controllerBump uint8,
+	r []AccountUpdate,
+) (*ProgramAllResult, error) {
+	var err error
+	ans := createProgramAllResult()
+	for i := 0; i < len(r); i++ {
+		if r[i].Data != nil {
+			data := r[i].Data
 			if len(data) < 8 {
 				return nil, errors.New("account is the wrong size")
 			}
@@ -102,7 +127,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					x := new(cba.Controller)
 					err = c.Decode(x)
 					if err == nil {
-						if x.ControllerBump == controllerBump && controllerId.Equals(r[i].Pubkey) {
+						if x.ControllerBump == controllerBump && controllerId.Equals(r[i].Id) {
 							ans.Controller = x
 						}
 					}
@@ -112,7 +137,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					if err == nil {
 						if x.Controller.Equals(controllerId) {
 							ans.Validator.Append(&ValidatorGroup{
-								Id:     r[i].Pubkey,
+								Id:     r[i].Id,
 								Data:   *x,
 								IsOpen: true,
 							})
@@ -124,7 +149,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					if err == nil {
 						if x.Controller.Equals(controllerId) {
 							ans.Pipeline.Append(&PipelineGroup{
-								Id:     r[i].Pubkey,
+								Id:     r[i].Id,
 								Data:   *x,
 								IsOpen: true,
 							})
@@ -136,7 +161,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					err = c.Decode(x)
 					if err == nil {
 						ans.PeriodRing[x.Pipeline.String()] = &PeriodGroup{
-							Id:     r[i].Pubkey,
+							Id:     r[i].Id,
 							Data:   *x,
 							IsOpen: true,
 						}
@@ -152,7 +177,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					err = c.Decode(x)
 					if err == nil {
 						ans.BidList[x.Payout.String()] = &BidGroup{
-							Id:     r[i].Pubkey,
+							Id:     r[i].Id,
 							Data:   *x,
 							IsOpen: true,
 						}
@@ -162,7 +187,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					err = c.Decode(x)
 					if err == nil {
 						ans.Stake.Append(&StakeGroup{
-							Id:     r[i].Pubkey,
+							Id:     r[i].Id,
 							Data:   *x,
 							IsOpen: true,
 						})
@@ -172,7 +197,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					err = c.Decode(x)
 					if err == nil {
 						ans.StakeReceipt.Append(&StakerReceiptGroup{
-							Id:     r[i].Pubkey,
+							Id:     r[i].Id,
 							Data:   *x,
 							IsOpen: true,
 						})
@@ -183,7 +208,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					if err == nil {
 						log.Debugf("1____+++++++receipt=%+v", x)
 						ans.Receipt.Append(&ReceiptGroup{
-							Id:     r[i].Pubkey,
+							Id:     r[i].Id,
 							Data:   *x,
 							IsOpen: true,
 						})
@@ -193,7 +218,7 @@ func FetchProgramAll(ctx context.Context, rpcClient *sgorpc.Client, version vrs.
 					err = c.Decode(x)
 					if err == nil {
 						ans.Payout.Append(&PayoutWithData{
-							Id:     r[i].Pubkey,
+							Id:     r[i].Id,
 							Data:   *x,
 							IsOpen: true,
 						})
@@ -236,11 +261,37 @@ func SubscribeProgramAll(
 	errorC chan<- error,
 ) (*SubscriptionProgramGroup, error) {
 
-	sub, err := wsClient.ProgramSubscribe(cba.ProgramID, sgorpc.CommitmentFinalized)
+	// a backfill after a dropped connection starts from here until the first update arrives
+	slot, err := rpcClient.GetSlot(ctx, PROGRAM_COMMITMENT)
 	if err != nil {
 		return nil, err
 	}
+	sub, err := wsClient.ProgramSubscribe(cba.ProgramID, PROGRAM_COMMITMENT)
+	if err != nil {
+		return nil, err
+	}
+
+	ans, in := createProgramGroup()
+	in.slot = slot
+
+	go loopSubscribePipeline(ctx, rpcClient, wsClient, sub, nil, in, errorC)
 
+	return ans, nil
+}
+
+// Decode account updates read from replayC (see state/replay) instead of a
+// websocket subscription.  The group ends when replayC is closed.
+func ReplayProgramAll(
+	ctx context.Context,
+	replayC <-chan AccountUpdate,
+	errorC chan<- error,
+) *SubscriptionProgramGroup {
+	ans, in := createProgramGroup()
+	go loopSubscribePipeline(ctx, nil, nil, nil, replayC, in, errorC)
+	return ans
+}
+
+func createProgramGroup() (*SubscriptionProgramGroup, *internalSubscriptionProgramGroup) {
 	ans := new(SubscriptionProgramGroup)
 	in := new(internalSubscriptionProgramGroup)
 	in.controller = dssub.CreateSubHome[cba.Controller]()
@@ -266,10 +317,9 @@ func SubscribeProgramAll(
 	ans.PayoutC = in.payout.ReqC
 	in.refund = dssub.CreateSubHome[cba.Refunds]()
 	ans.RefundC = in.refund.ReqC
-
-	go loopSubscribePipeline(ctx, sub, in, errorC)
-
-	return ans, nil
+	in.account = dssub.CreateSubHome[AccountUpdate]()
+	ans.AccountC = in.account.ReqC
+	return ans, in
 }
 
 type DATA_TYPE int
@@ -289,29 +339,30 @@ const (
 
 func loopSubscribePipeline(
 	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	wsClient *sgows.Client,
 	sub *sgows.ProgramSubscription,
+	replayC <-chan AccountUpdate,
 	in *internalSubscriptionProgramGroup,
 	errorC chan<- error,
 ) {
 
 	doneC := ctx.Done()
-	streamC := sub.RecvStream()
-	streamErrorC := sub.CloseSignal()
+	// when replaying, sub is nil and these channels block forever
+	var streamC <-chan sgows.Result
+	var streamErrorC <-chan error
+	if sub != nil {
+		streamC = sub.RecvStream()
+		streamErrorC = sub.CloseSignal()
+	}
 
 	var err error
 
-	D_controller := binary.BigEndian.Uint64(cba.ControllerDiscriminator[:])
-	D_validator := binary.BigEndian.Uint64(cba.ValidatorManagerDiscriminator[:])
-	D_pipeline := binary.BigEndian.Uint64(cba.PipelineDiscriminator[:])
-	D_bidlist := binary.BigEndian.Uint64(cba.BidListDiscriminator[:])
-	D_periodring := binary.BigEndian.Uint64(cba.PeriodRingDiscriminator[:])
-	D_stake := binary.BigEndian.Uint64(cba.StakerManagerDiscriminator[:])
-	D_stakeReceipt := binary.BigEndian.Uint64(cba.StakerReceiptDiscriminator[:])
-	D_receipt := binary.BigEndian.Uint64(cba.ReceiptDiscriminator[:])
-	D_payout := binary.BigEndian.Uint64(cba.PayoutDiscriminator[:])
-	D_refund := binary.BigEndian.Uint64(cba.RefundsDiscriminator[:])
-
-	trackingAccounts := make(map[string]DATA_TYPE)
+	in.ctx = ctx
+	in.rpc = rpcClient
+	in.ws = wsClient
+	in.tracking = make(map[string]DATA_TYPE)
+	in.accountSlot = make(map[string]uint64)
 
 out:
 	for {
@@ -363,185 +414,326 @@ out:
 			in.refund.Delete(id)
 		case r := <-in.refund.ReqC:
 			in.refund.Receive(r)
+		case id := <-in.account.DeleteC:
+			in.account.Delete(id)
+		case r := <-in.account.ReqC:
+			in.account.Receive(r)
 		case err = <-streamErrorC: // error
-			break out
+			log.Debugf("program subscription dropped at slot=%d: %+v", in.slot, err)
+			metricReconnect.Inc()
+			sub.Unsubscribe()
+			sub, err = in.resubscribe()
+			if err != nil {
+				break out
+			}
+			streamC = sub.RecvStream()
+			streamErrorC = sub.CloseSignal()
 		case d := <-streamC:
 			x, ok := d.(*sgows.ProgramResult)
 			if !ok {
 				err = errors.New("bad program result")
 				break out
 			}
+			u := AccountUpdate{Id: x.Value.Pubkey, Slot: x.Context.Slot}
+			observeUpdate(x.Context.Slot)
+			if 0 < x.Value.Account.Lamports {
+				u.Data = x.Value.Account.Data.GetBinary()
+			}
+			err = in.on_update(u)
+			if err != nil {
+				break out
+			}
+		case u, ok := <-replayC:
+			if !ok {
+				err = errors.New("replay finished")
+				break out
+			}
+			err = in.on_update(u)
+			if err != nil {
+				break out
+			}
+		}
+	}
+	if sub != nil {
+		sub.Unsubscribe()
+	}
 
-			data := x.Value.Account.Data.GetBinary()
+	errorC <- err
+}
 
-			if 8 <= len(data) && 0 < x.Value.Account.Lamports {
+// Apply an update from the stream or from a backfill.  Updates older than the
+// last one seen for the account are dropped so that a backfill racing the
+// stream never rolls an account back.
+func (in *internalSubscriptionProgramGroup) on_update(u AccountUpdate) error {
+	last, present := in.accountSlot[u.Id.String()]
+	if present && u.Slot < last {
+		return nil
+	}
+	in.accountSlot[u.Id.String()] = u.Slot
+	if in.slot < u.Slot {
+		in.slot = u.Slot
+	}
+	in.account.Broadcast(u)
+	return in.on_account(u)
+}
 
-				switch binary.BigEndian.Uint64(data[0:8]) {
-				case D_controller:
-					y := new(cba.Controller)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.controller.Broadcast(*y)
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_CONTROLLER
-				case D_validator:
-					y := new(cba.ValidatorManager)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					log.Debugf("-----+++validator=%+v", y)
-					in.validator.Broadcast(ValidatorGroup{
-						Id:     x.Value.Pubkey,
-						Data:   *y,
-						IsOpen: true,
-					})
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_VALIDATOR
-				case D_pipeline:
-					y := new(cba.Pipeline)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.pipeline.Broadcast(PipelineGroup{
-						Id:     x.Value.Pubkey,
-						Data:   *y,
-						IsOpen: true,
-					})
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_PIPELINE
-				case D_bidlist:
-					y := new(cba.BidList)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.bidSummary.Broadcast(BidSummary{
-						Payout:        y.Payout,
-						TotalDeposits: y.TotalDeposits,
-					})
-					in.bidList.Broadcast(*y)
-				case D_periodring:
-					y := new(cba.PeriodRing)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.periodRing.Broadcast(*y)
-				case D_stake:
-					y := new(cba.StakerManager)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.stakeManager.Broadcast(StakeGroup{
-						Id:     x.Value.Pubkey,
-						Data:   *y,
-						IsOpen: true,
-					})
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_STAKER
-				case D_stakeReceipt:
-					// TODO
-					y := new(cba.StakerReceipt)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.stakeReceipt.Broadcast(StakerReceiptGroup{
-						Id:     x.Value.Pubkey,
-						Data:   *y,
-						IsOpen: true,
-					})
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_STAKER_RECEIPT
-				case D_receipt:
-					y := new(cba.Receipt)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.receipt.Broadcast(ReceiptGroup{
-						Id:     x.Value.Pubkey,
-						Data:   *y,
-						IsOpen: true,
-					})
-					log.Debugf("2____+++++++receipt=%+v", y)
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_RECEIPT
-				case D_payout:
-
-					y := new(cba.Payout)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					log.Debugf("update on payout with id=%s; data=%+v", x.Value.Pubkey.String(), y)
-					in.payout.Broadcast(PayoutWithData{
-						Id:     x.Value.Pubkey,
-						Data:   *y,
-						IsOpen: true,
-					})
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_PAYOUT
-				case D_refund:
-					log.Debugf("update on refund with id=%s", x.Value.Pubkey.String())
-					y := new(cba.Refunds)
-					err = bin.UnmarshalBorsh(y, data)
-					if err != nil {
-						break out
-					}
-					in.refund.Broadcast(*y)
-					trackingAccounts[x.Value.Pubkey.String()] = TYPE_REFUND
-				default:
-				}
-			} else if 8 <= len(data) {
-				log.Debugf("account=%s has data, but lamports=0", x.Value.Pubkey.String())
-			} else {
-				// account being deleted
-				log.Debugf("delete with id=%s", x.Value.Pubkey.String())
-				id := x.Value.Pubkey
-				dataType, present := trackingAccounts[id.String()]
-				if present {
-					switch dataType {
-					case TYPE_CONTROLLER:
-						// the controller never dies
-					case TYPE_VALIDATOR:
-						in.validator.Broadcast(ValidatorGroup{
-							Id:     id,
-							IsOpen: false,
-						})
-					case TYPE_PIPELINE:
-						in.pipeline.Broadcast(PipelineGroup{
-							Id:     id,
-							IsOpen: false,
-						})
-					case TYPE_BIDS:
-						// bids are taken care of by the Payout account
-					case TYPE_PERIODS:
-						// periods are taken care of by the Pipeline account
-					case TYPE_REFUND:
-						// refunds are taken care of by the Pipeline account
-					case TYPE_STAKER:
-						in.stakeManager.Broadcast(StakeGroup{
-							Id:     id,
-							IsOpen: false,
-						})
-					case TYPE_PAYOUT:
-						log.Debug("delete payout")
-						in.payout.Broadcast(PayoutWithData{
-							Id:     id,
-							IsOpen: false,
-						})
-					case TYPE_RECEIPT:
-						in.receipt.Broadcast(ReceiptGroup{
-							Id:     id,
-							IsOpen: false,
-						})
-					}
-				}
-			}
+// Subscribe again on a fresh connection and then refetch every account that
+// changed while the connection was down.  The subscription comes first so
+// that nothing falls between the backfill and the stream.
+func (in *internalSubscriptionProgramGroup) resubscribe() (*sgows.ProgramSubscription, error) {
+	var err error
+	in.ws, err = Reconnect(in.ctx, in.ws)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := in.ws.ProgramSubscribe(cba.ProgramID, PROGRAM_COMMITMENT)
+	if err != nil {
+		return nil, err
+	}
+	err = in.backfill()
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+	return sub, nil
+}
 
+// Closing an account wipes its data, so a closed account is only broadcast as
+// closed if its type is known from an earlier update.  The router watches each
+// account it holds separately (see loopDelete).
+func (in *internalSubscriptionProgramGroup) backfill() error {
+	list, slot, err := fetchChanged(in.ctx, in.rpc, in.slot)
+	if errors.Is(err, ErrSnapshotStale) {
+		log.Debugf("too many changes since slot=%d, fetching all accounts", in.slot)
+		list, slot, err = fetchAll(in.ctx, in.rpc, in.tracking)
+	}
+	if err != nil {
+		return err
+	}
+	log.Debugf("backfilled %d accounts from slot=%d to slot=%d", len(list), in.slot, slot)
+	for _, u := range list {
+		err = in.on_update(u)
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	errorC <- err
+// Fetch every account owned by the program.  Tracked accounts that no longer
+// exist are returned as closed.
+func fetchAll(
+	ctx context.Context,
+	rpcClient *sgorpc.Client,
+	tracking map[string]DATA_TYPE,
+) ([]AccountUpdate, uint64, error) {
+	s, err := FetchSnapshot(ctx, rpcClient)
+	if err != nil {
+		return nil, 0, err
+	}
+	ans := make([]AccountUpdate, 0, len(s.Account))
+	for k, data := range s.Account {
+		id, err := solana.PublicKeyFromBase58(k)
+		if err != nil {
+			return nil, 0, err
+		}
+		ans = append(ans, AccountUpdate{Id: id, Slot: s.Slot, Data: data})
+	}
+	for k := range tracking {
+		_, present := s.Account[k]
+		if !present {
+			id, err := solana.PublicKeyFromBase58(k)
+			if err != nil {
+				return nil, 0, err
+			}
+			ans = append(ans, AccountUpdate{Id: id, Slot: s.Slot})
+		}
+	}
+	return ans, s.Slot, nil
+}
+
+// Decode an account and broadcast it to the subscribers of its type.  Data is
+// nil if the account has been closed.
+func (in *internalSubscriptionProgramGroup) on_account(u AccountUpdate) error {
+	var err error
+	data := u.Data
+
+	D_controller := binary.BigEndian.Uint64(cba.ControllerDiscriminator[:])
+	D_validator := binary.BigEndian.Uint64(cba.ValidatorManagerDiscriminator[:])
+	D_pipeline := binary.BigEndian.Uint64(cba.PipelineDiscriminator[:])
+	D_bidlist := binary.BigEndian.Uint64(cba.BidListDiscriminator[:])
+	D_periodring := binary.BigEndian.Uint64(cba.PeriodRingDiscriminator[:])
+	D_stake := binary.BigEndian.Uint64(cba.StakerManagerDiscriminator[:])
+	D_stakeReceipt := binary.BigEndian.Uint64(cba.StakerReceiptDiscriminator[:])
+	D_receipt := binary.BigEndian.Uint64(cba.ReceiptDiscriminator[:])
+	D_payout := binary.BigEndian.Uint64(cba.PayoutDiscriminator[:])
+	D_refund := binary.BigEndian.Uint64(cba.RefundsDiscriminator[:])
+
+	if 8 <= len(data) {
+
+		switch binary.BigEndian.Uint64(data[0:8]) {
+		case D_controller:
+			y := new(cba.Controller)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.controller.Broadcast(*y)
+			in.tracking[u.Id.String()] = TYPE_CONTROLLER
+		case D_validator:
+			y := new(cba.ValidatorManager)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			log.Debugf("-----+++validator=%+v", y)
+			in.validator.Broadcast(ValidatorGroup{
+				Id:     u.Id,
+				Data:   *y,
+				IsOpen: true,
+			})
+			in.tracking[u.Id.String()] = TYPE_VALIDATOR
+		case D_pipeline:
+			y := new(cba.Pipeline)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.pipeline.Broadcast(PipelineGroup{
+				Id:     u.Id,
+				Data:   *y,
+				IsOpen: true,
+			})
+			in.tracking[u.Id.String()] = TYPE_PIPELINE
+		case D_bidlist:
+			y := new(cba.BidList)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.bidSummary.Broadcast(BidSummary{
+				Payout:        y.Payout,
+				TotalDeposits: y.TotalDeposits,
+			})
+			in.bidList.Broadcast(*y)
+		case D_periodring:
+			y := new(cba.PeriodRing)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.periodRing.Broadcast(*y)
+		case D_stake:
+			y := new(cba.StakerManager)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.stakeManager.Broadcast(StakeGroup{
+				Id:     u.Id,
+				Data:   *y,
+				IsOpen: true,
+			})
+			in.tracking[u.Id.String()] = TYPE_STAKER
+		case D_stakeReceipt:
+			// TODO
+			y := new(cba.StakerReceipt)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.stakeReceipt.Broadcast(StakerReceiptGroup{
+				Id:     u.Id,
+				Data:   *y,
+				IsOpen: true,
+			})
+			in.tracking[u.Id.String()] = TYPE_STAKER_RECEIPT
+		case D_receipt:
+			y := new(cba.Receipt)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.receipt.Broadcast(ReceiptGroup{
+				Id:     u.Id,
+				Data:   *y,
+				IsOpen: true,
+			})
+			log.Debugf("2____+++++++receipt=%+v", y)
+			in.tracking[u.Id.String()] = TYPE_RECEIPT
+		case D_payout:
+
+			y := new(cba.Payout)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			log.Debugf("update on payout with id=%s; data=%+v", u.Id.String(), y)
+			in.payout.Broadcast(PayoutWithData{
+				Id:     u.Id,
+				Data:   *y,
+				IsOpen: true,
+			})
+			in.tracking[u.Id.String()] = TYPE_PAYOUT
+		case D_refund:
+			log.Debugf("update on refund with id=%s", u.Id.String())
+			y := new(cba.Refunds)
+			err = bin.UnmarshalBorsh(y, data)
+			if err != nil {
+				return err
+			}
+			in.refund.Broadcast(*y)
+			in.tracking[u.Id.String()] = TYPE_REFUND
+		default:
+		}
+	} else if data != nil {
+		log.Debugf("account=%s is too small", u.Id.String())
+	} else {
+		// account being deleted
+		log.Debugf("delete with id=%s", u.Id.String())
+		id := u.Id
+		dataType, present := in.tracking[id.String()]
+		if present {
+			switch dataType {
+			case TYPE_CONTROLLER:
+				// the controller never dies
+			case TYPE_VALIDATOR:
+				in.validator.Broadcast(ValidatorGroup{
+					Id:     id,
+					IsOpen: false,
+				})
+			case TYPE_PIPELINE:
+				in.pipeline.Broadcast(PipelineGroup{
+					Id:     id,
+					IsOpen: false,
+				})
+			case TYPE_BIDS:
+				// bids are taken care of by the Payout account
+			case TYPE_PERIODS:
+				// periods are taken care of by the Pipeline account
+			case TYPE_REFUND:
+				// refunds are taken care of by the Pipeline account
+			case TYPE_STAKER:
+				in.stakeManager.Broadcast(StakeGroup{
+					Id:     id,
+					IsOpen: false,
+				})
+			case TYPE_PAYOUT:
+				log.Debug("delete payout")
+				in.payout.Broadcast(PayoutWithData{
+					Id:     id,
+					IsOpen: false,
+				})
+			case TYPE_RECEIPT:
+				in.receipt.Broadcast(ReceiptGroup{
+					Id:     id,
+					IsOpen: false,
+				})
+			}
+			delete(in.tracking, id.String())
+		}
+	}
+	return nil
 }
 
 func SubscribeBidList(wsClient *sgows.Client) (*sgows.ProgramSubscription, error) {
@@ -0,0 +1,146 @@
+package sub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RECONNECT_DELAY_START = 1 * time.Second
+	RECONNECT_DELAY_MAX   = 30 * time.Second
+)
+
+var ErrNoReconnect = errors.New("websocket client was not created with Dial")
+
+// Every subscription loop holds on to the client it subscribed with.  When the
+// connection drops, all of them call Reconnect with that client and get the
+// same replacement; only the first caller dials.
+type wsConnection struct {
+	lock       sync.Mutex
+	url        string
+	headers    http.Header
+	client     *sgows.Client
+	clientList []*sgows.Client
+	closed     bool
+}
+
+var wsConnectionLock sync.Mutex
+var wsConnectionM = make(map[*sgows.Client]*wsConnection)
+
+// Connect a websocket client that subscription loops can replace with
+// Reconnect when the connection drops.  The headers are copied.  The client
+// is closed when ctx is done.
+func Dial(ctx context.Context, wsUrl string, headers http.Header) (*sgows.Client, error) {
+	wc := &wsConnection{url: wsUrl, headers: headers.Clone()}
+	client, err := wc.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wc.client = client
+	wc.clientList = []*sgows.Client{client}
+	wsConnectionLock.Lock()
+	wsConnectionM[client] = wc
+	wsConnectionLock.Unlock()
+	go func() {
+		<-ctx.Done()
+		Close(client)
+	}()
+	return client, nil
+}
+
+// Close the latest client replacing the given one and forget every client
+// of the connection.  Clients not created with Dial are closed as is.
+func Close(client *sgows.Client) {
+	wc, present := lookupConnection(client)
+	if !present {
+		client.Close()
+		return
+	}
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+	if wc.closed {
+		return
+	}
+	wc.closed = true
+	wc.client.Close()
+	wsConnectionLock.Lock()
+	for _, c := range wc.clientList {
+		delete(wsConnectionM, c)
+	}
+	wsConnectionLock.Unlock()
+	wc.clientList = nil
+}
+
+func (wc *wsConnection) dial(ctx context.Context) (*sgows.Client, error) {
+	return sgows.ConnectWithOptions(ctx, wc.url, &sgows.Options{HttpHeader: wc.headers.Clone()})
+}
+
+func lookupConnection(client *sgows.Client) (*wsConnection, bool) {
+	wsConnectionLock.Lock()
+	defer wsConnectionLock.Unlock()
+	wc, present := wsConnectionM[client]
+	return wc, present
+}
+
+// The latest client replacing the given one.  Clients not created with Dial
+// are returned as is.
+func Current(client *sgows.Client) *sgows.Client {
+	wc, present := lookupConnection(client)
+	if !present {
+		return client
+	}
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+	return wc.client
+}
+
+// Replace a client whose connection dropped.  If another subscription loop
+// already replaced it, that replacement is returned.  Otherwise, dial with an
+// exponential backoff until ctx is canceled.
+func Reconnect(ctx context.Context, old *sgows.Client) (*sgows.Client, error) {
+	wc, present := lookupConnection(old)
+	if !present {
+		return nil, ErrNoReconnect
+	}
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+	if wc.closed {
+		return nil, ErrNoReconnect
+	}
+	if wc.client != old {
+		return wc.client, nil
+	}
+	old.Close()
+
+	doneC := ctx.Done()
+	delay := RECONNECT_DELAY_START
+	for {
+		client, err := wc.dial(ctx)
+		if err == nil {
+			wc.client = client
+			// keep old clients so that late callers find the replacement;
+			// Close deletes them all
+			wc.clientList = append(wc.clientList, client)
+			wsConnectionLock.Lock()
+			wsConnectionM[client] = wc
+			wsConnectionLock.Unlock()
+			return client, nil
+		}
+		log.Debugf("failed to reconnect websocket, retrying in %s: %s", delay, err.Error())
+		select {
+		case <-doneC:
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if RECONNECT_DELAY_MAX < delay {
+			delay = RECONNECT_DELAY_MAX
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package sub_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+func TestReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, _, err = conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	old, err := sub.Dial(ctx, wsUrl, http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := sub.Reconnect(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client == old {
+		t.Fatal("reconnect returned the dropped client")
+	}
+	// a second loop holding the old client gets the same replacement
+	again, err := sub.Reconnect(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != client {
+		t.Fatal("reconnect dialed twice")
+	}
+	if sub.Current(old) != client {
+		t.Fatal("current client is stale")
+	}
+
+	sub.Close(old)
+	if sub.Current(client) != client || sub.Current(old) != old {
+		t.Fatal("closed clients are still tracked")
+	}
+	_, err = sub.Reconnect(ctx, client)
+	if err != sub.ErrNoReconnect {
+		t.Fatalf("reconnect after close: %+v", err)
+	}
+}
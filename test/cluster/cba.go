@@ -0,0 +1,1035 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	sgo "github.com/SolmateDev/solana-go"
+	bin "github.com/gagliardetto/binary"
+	cba "github.com/solpipe/cba"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+	val "github.com/solpipe/solpipe-tool/state/validator"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+	"github.com/solpipe/solpipe-tool/util"
+)
+
+const (
+	// slots after the end of a period before the payout can be closed
+	CLOSE_PAYOUT_DELAY uint64 = 100
+	// number of receipts a validator manager remembers
+	VALIDATOR_RING_SIZE int = 10
+)
+
+// Execute a CBA instruction.  The checks are the ones the tests rely on;
+// this is not a line by line copy of the on-chain program.
+func (tc *txContext) run_cba(accountList []*sgo.AccountMeta, data []byte) error {
+	inst, err := cba.DecodeInstruction(accountList, data)
+	if err != nil {
+		return err
+	}
+	switch inst.TypeID {
+	case cba.Instruction_Create:
+		return decoded(inst, tc.cba_create)
+	case cba.Instruction_AddPipeline:
+		return decoded(inst, tc.cba_add_pipeline)
+	case cba.Instruction_UpdatePipeline:
+		return decoded(inst, tc.cba_update_pipeline)
+	case cba.Instruction_AppendPeriod:
+		return decoded(inst, tc.cba_append_period)
+	case cba.Instruction_InsertBid:
+		return decoded(inst, tc.cba_insert_bid)
+	case cba.Instruction_CloseBids:
+		return decoded(inst, tc.cba_close_bids)
+	case cba.Instruction_Crank:
+		return decoded(inst, tc.cba_crank)
+	case cba.Instruction_ClosePayout:
+		return decoded(inst, tc.cba_close_payout)
+	case cba.Instruction_ClaimRefund:
+		return decoded(inst, tc.cba_claim_refund)
+	case cba.Instruction_AddValidator:
+		return decoded(inst, tc.cba_add_validator)
+	case cba.Instruction_ValidatorSetPayout:
+		return decoded(inst, tc.cba_validator_set_payout)
+	case cba.Instruction_ValidatorWithdrawReceipt:
+		return decoded(inst, tc.cba_validator_withdraw_receipt)
+	case cba.Instruction_UpdateReceipt:
+		return decoded(inst, tc.cba_update_receipt)
+	case cba.Instruction_UpdateBidReceipt:
+		return decoded(inst, tc.cba_update_bid_receipt)
+	case cba.Instruction_AddStaker:
+		return decoded(inst, tc.cba_add_staker)
+	case cba.Instruction_AddStakerToReceipt:
+		return decoded(inst, tc.cba_add_staker_to_receipt)
+	default:
+		return fmt.Errorf("cba instruction %T is not supported", inst.Impl)
+	}
+}
+
+// run the handler if the instruction decoded to the type it expects
+func decoded[T any](inst *cba.Instruction, run func(*T) error) error {
+	impl, ok := inst.Impl.(*T)
+	if !ok {
+		return fmt.Errorf("cba instruction %T is not %T", inst.Impl, impl)
+	}
+	return run(impl)
+}
+
+func (tc *txContext) cba_create(impl *cba.Create) error {
+	admin, err := signer(impl.GetAdminAccount(), "admin")
+	if err != nil {
+		return err
+	}
+	controllerId, err := account(impl.GetControllerAccount(), "controller")
+	if err != nil {
+		return err
+	}
+	mintId, err := account(impl.GetPcMintAccount(), "pc mint")
+	if err != nil {
+		return err
+	}
+	vaultId, err := account(impl.GetPcVaultAccount(), "pc vault")
+	if err != nil {
+		return err
+	}
+	feeN, err := arg(impl.ControllerFeeNum, "controller fee numerator")
+	if err != nil {
+		return err
+	}
+	feeD, err := arg(impl.ControllerFeeDen, "controller fee denominator")
+	if err != nil {
+		return err
+	}
+	if feeD == 0 || feeD < feeN {
+		return errors.New("bad controller fee")
+	}
+	expected, bump, err := vrs.ControllerId(vrs.VERSION_1)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(controllerId) {
+		return fmt.Errorf("controller must be %s", expected.String())
+	}
+	expected, _, err = vrs.PcVaultId(vrs.VERSION_1)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(vaultId) {
+		return fmt.Errorf("pc vault must be %s", expected.String())
+	}
+	err = tc.init_token_account(admin, vaultId, mintId, controllerId)
+	if err != nil {
+		return err
+	}
+	err = initCba(tc, admin, controllerId, &cba.Controller{
+		ControllerBump: bump,
+		Admin:          admin,
+		PcMint:         mintId,
+		PcVault:        vaultId,
+	}, util.STRUCT_SIZE_CONTROLLER)
+	if err != nil {
+		return err
+	}
+	tc.onCommit = append(tc.onCommit, func(in *internal) {
+		in.controllerFee = [2]uint64{feeN, feeD}
+	})
+	return nil
+}
+
+func (tc *txContext) cba_add_pipeline(impl *cba.AddPipeline) error {
+	admin, err := signer(impl.GetAdminAccount(), "admin")
+	if err != nil {
+		return err
+	}
+	pipelineId, err := signer(impl.GetPipelineAccount(), "pipeline")
+	if err != nil {
+		return err
+	}
+	controllerId, err := account(impl.GetControllerAccount(), "controller")
+	if err != nil {
+		return err
+	}
+	vaultId, err := account(impl.GetPipelineVaultAccount(), "pipeline vault")
+	if err != nil {
+		return err
+	}
+	refundsId, err := account(impl.GetRefundsAccount(), "refunds")
+	if err != nil {
+		return err
+	}
+	periodsId, err := account(impl.GetPeriodsAccount(), "periods")
+	if err != nil {
+		return err
+	}
+	crankFee, err := rateArg(impl.CrankFeeRateNum, impl.CrankFeeRateDen, "crank fee")
+	if err != nil {
+		return err
+	}
+	share, err := rateArg(impl.ValidatorPayoutShareNum, impl.ValidatorPayoutShareDen, "validator payout share")
+	if err != nil {
+		return err
+	}
+	tickSize, err := arg(impl.TickSize, "tick size")
+	if err != nil {
+		return err
+	}
+	controller, err := load[cba.Controller](tc, controllerId)
+	if err != nil {
+		return err
+	}
+	expected, _, err := vrs.PipelineVaultId(vrs.VERSION_1, pipelineId)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(vaultId) {
+		return fmt.Errorf("pipeline vault must be %s", expected.String())
+	}
+	err = tc.init_token_account(admin, vaultId, controller.PcMint, controllerId)
+	if err != nil {
+		return err
+	}
+
+	ring := &cba.PeriodRing{Pipeline: pipelineId, Ring: []cba.PeriodWithPayout{}}
+	n, err := capacity(tc, periodsId, ring, cba.PeriodWithPayout{Period: cba.Period{IsBlank: true}})
+	if err != nil {
+		return err
+	}
+	ring.Ring = make([]cba.PeriodWithPayout, n)
+	for i := 0; i < n; i++ {
+		ring.Ring[i].Period.IsBlank = true
+	}
+	err = store(tc, periodsId, ring)
+	if err != nil {
+		return err
+	}
+
+	refunds := &cba.Refunds{Pipeline: pipelineId, Refunds: []cba.Claim{}}
+	n, err = capacity(tc, refundsId, refunds, cba.Claim{})
+	if err != nil {
+		return err
+	}
+	refunds.Refunds = make([]cba.Claim, n)
+	err = store(tc, refundsId, refunds)
+	if err != nil {
+		return err
+	}
+
+	err = initCba(tc, admin, pipelineId, &cba.Pipeline{
+		ValidatorPayoutShare: share,
+		CrankFeeRate:         crankFee,
+		Controller:           controllerId,
+		Admin:                admin,
+		PcVault:              vaultId,
+		Periods:              periodsId,
+		Refunds:              refundsId,
+	}, util.STRUCT_SIZE_PIPELINE)
+	if err != nil {
+		return err
+	}
+	tc.onCommit = append(tc.onCommit, func(in *internal) {
+		in.tickSize[pipelineId.String()] = tickSize
+	})
+	return nil
+}
+
+func (tc *txContext) cba_update_pipeline(impl *cba.UpdatePipeline) error {
+	pipelineId, err := account(impl.GetPipelineAccount(), "pipeline")
+	if err != nil {
+		return err
+	}
+	pipeline, err := tc.pipeline_admin(pipelineId, impl.GetAdminAccount())
+	if err != nil {
+		return err
+	}
+	newAdmin, err := signer(impl.GetNewAdminAccount(), "new admin")
+	if err != nil {
+		return err
+	}
+	crankFee, err := rateArg(impl.CrankFeeRateNum, impl.CrankFeeRateDen, "crank fee")
+	if err != nil {
+		return err
+	}
+	share, err := rateArg(impl.ValidatorPayoutShareNum, impl.ValidatorPayoutShareDen, "validator payout share")
+	if err != nil {
+		return err
+	}
+	tickSize, err := arg(impl.TickSize, "tick size")
+	if err != nil {
+		return err
+	}
+	pipeline.Admin = newAdmin
+	pipeline.CrankFeeRate = crankFee
+	pipeline.ValidatorPayoutShare = share
+	err = store(tc, pipelineId, pipeline)
+	if err != nil {
+		return err
+	}
+	tc.onCommit = append(tc.onCommit, func(in *internal) {
+		in.tickSize[pipelineId.String()] = tickSize
+	})
+	return nil
+}
+
+func (tc *txContext) cba_append_period(impl *cba.AppendPeriod) error {
+	pipelineId, err := account(impl.GetPipelineAccount(), "pipeline")
+	if err != nil {
+		return err
+	}
+	pipeline, err := tc.pipeline_admin(pipelineId, impl.GetAdminAccount())
+	if err != nil {
+		return err
+	}
+	payoutId, err := signer(impl.GetPayoutAccount(), "payout")
+	if err != nil {
+		return err
+	}
+	bidsId, err := account(impl.GetBidsAccount(), "bids")
+	if err != nil {
+		return err
+	}
+	periodsId, err := account(impl.GetPeriodsAccount(), "periods")
+	if err != nil {
+		return err
+	}
+	if !periodsId.Equals(pipeline.Periods) {
+		return errors.New("periods do not belong to the pipeline")
+	}
+	start, err := arg(impl.Start, "start")
+	if err != nil {
+		return err
+	}
+	length, err := arg(impl.Length, "length")
+	if err != nil {
+		return err
+	}
+	if length == 0 {
+		return errors.New("period has no length")
+	}
+
+	ring, err := load[cba.PeriodRing](tc, periodsId)
+	if err != nil {
+		return err
+	}
+	size := uint16(len(ring.Ring))
+	if size == 0 {
+		return errors.New("period ring has no space")
+	}
+	if 0 < ring.Length {
+		last := ring.Ring[(ring.Start+ring.Length-1)%size].Period
+		if start < last.Start+last.Length {
+			return fmt.Errorf("period starting at %d overlaps the last period", start)
+		}
+	}
+	if ring.Length == size {
+		// forget the oldest period
+		ring.Start = (ring.Start + 1) % size
+		ring.Length--
+	}
+	period := cba.Period{IsBlank: false, Start: start, Length: length}
+	ring.Ring[(ring.Start+ring.Length)%size] = cba.PeriodWithPayout{Period: period, Payout: payoutId}
+	ring.Length++
+	err = store(tc, periodsId, ring)
+	if err != nil {
+		return err
+	}
+
+	bids := &cba.BidList{Payout: payoutId, Book: []cba.Bid{}}
+	n, err := capacity(tc, bidsId, bids, cba.Bid{IsBlank: true})
+	if err != nil {
+		return err
+	}
+	bids.Book = make([]cba.Bid, n)
+	for i := 0; i < n; i++ {
+		bids.Book[i].IsBlank = true
+	}
+	err = store(tc, bidsId, bids)
+	if err != nil {
+		return err
+	}
+
+	return initCba(tc, pipeline.Admin, payoutId, &cba.Payout{
+		TickSize:      tc.in.tickSize[pipelineId.String()],
+		CrankFee:      pipeline.CrankFeeRate,
+		ControllerFee: tc.in.controllerFee,
+		Pipeline:      pipelineId,
+		Period:        period,
+		Bids:          bidsId,
+	}, 0)
+}
+
+// The deposit is added to any existing bid from the same user.
+func (tc *txContext) cba_insert_bid(impl *cba.InsertBid) error {
+	user, err := signer(impl.GetUserAccount(), "user")
+	if err != nil {
+		return err
+	}
+	fundId, err := account(impl.GetUserFundAccount(), "user fund")
+	if err != nil {
+		return err
+	}
+	vaultId, err := account(impl.GetPcVaultAccount(), "pc vault")
+	if err != nil {
+		return err
+	}
+	bid, err := arg(impl.Bid, "bid")
+	if err != nil {
+		return err
+	}
+	if !bid.User.Equals(user) {
+		return errors.New("bid user did not sign")
+	}
+	payout, pipeline, bids, err := tc.payout(impl.GetPayoutAccount(), impl.GetPipelineAccount(), impl.GetBidsAccount())
+	if err != nil {
+		return err
+	}
+	if !vaultId.Equals(pipeline.PcVault) {
+		return errors.New("pc vault does not belong to the pipeline")
+	}
+	if bids.BiddingFinished || payout.Period.Start <= tc.slot {
+		return errors.New("bidding has finished")
+	}
+	fund, err := tc.token_account(fundId)
+	if err != nil {
+		return err
+	}
+	if !fund.Owner.Equals(user) {
+		return fmt.Errorf("%s does not own %s", user.String(), fundId.String())
+	}
+	err = tc.token_transfer(fundId, vaultId, bid.Deposit)
+	if err != nil {
+		return err
+	}
+	k := -1
+	for i := 0; i < len(bids.Book); i++ {
+		if !bids.Book[i].IsBlank && bids.Book[i].User.Equals(user) {
+			k = i
+			break
+		}
+		if k < 0 && bids.Book[i].IsBlank {
+			k = i
+		}
+	}
+	if k < 0 {
+		return errors.New("bid list is full")
+	}
+	if bids.Book[k].IsBlank {
+		bids.Book[k] = cba.Bid{User: user, IsBlank: false}
+	}
+	bids.Book[k].Deposit += bid.Deposit
+	bids.TotalDeposits += bid.Deposit
+	return store(tc, payout.Bids, bids)
+}
+
+func (tc *txContext) cba_close_bids(impl *cba.CloseBids) error {
+	payout, _, bids, err := tc.payout(impl.GetPayoutAccount(), impl.GetPipelineAccount(), impl.GetBidsAccount())
+	if err != nil {
+		return err
+	}
+	_, err = tc.pipeline_admin(payout.Pipeline, impl.GetPipelineAdminAccount())
+	if err != nil {
+		return err
+	}
+	if tc.slot < payout.Period.Start {
+		return errors.New("period has not started")
+	}
+	if bids.BiddingFinished {
+		return nil
+	}
+	bids.BiddingFinished = true
+	return store(tc, payout.Bids, bids)
+}
+
+// The first crank after the period starts closes bidding and earns the crank fee.
+func (tc *txContext) cba_crank(impl *cba.Crank) error {
+	_, err := signer(impl.GetCrankerAccount(), "cranker")
+	if err != nil {
+		return err
+	}
+	fundId, err := account(impl.GetCrankerFundAccount(), "cranker fund")
+	if err != nil {
+		return err
+	}
+	payout, pipeline, bids, err := tc.payout(impl.GetPayoutAccount(), impl.GetPipelineAccount(), impl.GetBidsAccount())
+	if err != nil {
+		return err
+	}
+	if tc.slot < payout.Period.Start {
+		return errors.New("period has not started")
+	}
+	if bids.BiddingFinished {
+		return errors.New("bidding has already finished")
+	}
+	bids.BiddingFinished = true
+	err = store(tc, payout.Bids, bids)
+	if err != nil {
+		return err
+	}
+	return tc.token_transfer(pipeline.PcVault, fundId, fraction(bids.TotalDeposits, payout.CrankFee))
+}
+
+func (tc *txContext) cba_close_payout(impl *cba.ClosePayout) error {
+	payoutId, err := account(impl.GetPayoutAccount(), "payout")
+	if err != nil {
+		return err
+	}
+	payout, err := load[cba.Payout](tc, payoutId)
+	if err != nil {
+		return err
+	}
+	pipeline, err := tc.pipeline_admin(payout.Pipeline, impl.GetPipelineAdminAccount())
+	if err != nil {
+		return err
+	}
+	controller, err := load[cba.Controller](tc, pipeline.Controller)
+	if err != nil {
+		return err
+	}
+	if tc.slot < payout.Period.Start+payout.Period.Length+CLOSE_PAYOUT_DELAY {
+		return errors.New("payout cannot be closed yet")
+	}
+	// the bid list is not passed in, but the fake has no need to check ownership
+	bids, err := load[cba.BidList](tc, payout.Bids)
+	if err != nil {
+		return err
+	}
+	vault, err := tc.token_account(pipeline.PcVault)
+	if err != nil {
+		return err
+	}
+	fee := fraction(bids.TotalDeposits, payout.ControllerFee)
+	if vault.Amount < fee {
+		fee = vault.Amount
+	}
+	err = tc.token_transfer(pipeline.PcVault, controller.PcVault, fee)
+	if err != nil {
+		return err
+	}
+	err = tc.close(payout.Bids, pipeline.Admin)
+	if err != nil {
+		return err
+	}
+	return tc.close(payoutId, pipeline.Admin)
+}
+
+func (tc *txContext) cba_claim_refund(impl *cba.ClaimRefund) error {
+	pipelineId, err := account(impl.GetPipelineAccount(), "pipeline")
+	if err != nil {
+		return err
+	}
+	fundId, err := account(impl.GetUserFundAccount(), "user fund")
+	if err != nil {
+		return err
+	}
+	pipeline, err := load[cba.Pipeline](tc, pipelineId)
+	if err != nil {
+		return err
+	}
+	fund, err := tc.token_account(fundId)
+	if err != nil {
+		return err
+	}
+	refunds, err := load[cba.Refunds](tc, pipeline.Refunds)
+	if err != nil {
+		return err
+	}
+	var amount uint64
+	for i := 0; i < len(refunds.Refunds); i++ {
+		if refunds.Refunds[i].User.Equals(fund.Owner) {
+			amount += refunds.Refunds[i].Balance
+			refunds.Refunds[i] = cba.Claim{}
+		}
+	}
+	if amount == 0 {
+		return errors.New("nothing to refund")
+	}
+	err = store(tc, pipeline.Refunds, refunds)
+	if err != nil {
+		return err
+	}
+	return tc.token_transfer(pipeline.PcVault, fundId, amount)
+}
+
+func (tc *txContext) cba_add_validator(impl *cba.AddValidator) error {
+	controllerId, err := account(impl.GetControllerAccount(), "controller")
+	if err != nil {
+		return err
+	}
+	managerId, err := account(impl.GetValidatorManagerAccount(), "validator manager")
+	if err != nil {
+		return err
+	}
+	vote, err := signer(impl.GetVoteAdminAccount(), "vote admin")
+	if err != nil {
+		return err
+	}
+	admin, err := signer(impl.GetValidatorAdminAccount(), "validator admin")
+	if err != nil {
+		return err
+	}
+	_, err = load[cba.Controller](tc, controllerId)
+	if err != nil {
+		return err
+	}
+	expected, _, err := val.ValidatorManagerId(controllerId, vote)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(managerId) {
+		return fmt.Errorf("validator manager must be %s", expected.String())
+	}
+	return initCba(tc, admin, managerId, &cba.ValidatorManager{
+		Ring:       make([]cba.ValidatorRingEntry, VALIDATOR_RING_SIZE),
+		Admin:      admin,
+		Controller: controllerId,
+		Vote:       vote,
+	}, 0)
+}
+
+func (tc *txContext) cba_validator_set_payout(impl *cba.ValidatorSetPayout) error {
+	managerId, err := account(impl.GetValidatorManagerAccount(), "validator manager")
+	if err != nil {
+		return err
+	}
+	manager, err := tc.validator_admin(managerId, impl.GetValidatorAdminAccount(), true)
+	if err != nil {
+		return err
+	}
+	receiptId, err := signer(impl.GetReceiptAccount(), "receipt")
+	if err != nil {
+		return err
+	}
+	payoutId, err := account(impl.GetPayoutAccount(), "payout")
+	if err != nil {
+		return err
+	}
+	payout, err := load[cba.Payout](tc, payoutId)
+	if err != nil {
+		return err
+	}
+	if payout.Period.Start+payout.Period.Length <= tc.slot {
+		return errors.New("period has finished")
+	}
+	k := 0
+	for i := 0; i < len(manager.Ring); i++ {
+		if manager.Ring[i].Receipt.IsZero() {
+			k = i
+			break
+		}
+		if manager.Ring[i].Start < manager.Ring[k].Start {
+			k = i
+		}
+	}
+	manager.Ring[k] = cba.ValidatorRingEntry{Start: payout.Period.Start, Receipt: receiptId}
+	err = store(tc, managerId, manager)
+	if err != nil {
+		return err
+	}
+	payout.ValidatorCount++
+	err = store(tc, payoutId, payout)
+	if err != nil {
+		return err
+	}
+	return initCba(tc, manager.Admin, receiptId, &cba.Receipt{Validator: managerId, Payout: payoutId}, 0)
+}
+
+// Each validator that set the payout gets an equal part of the validator share.
+func (tc *txContext) cba_validator_withdraw_receipt(impl *cba.ValidatorWithdrawReceipt) error {
+	managerId, err := account(impl.GetValidatorManagerAccount(), "validator manager")
+	if err != nil {
+		return err
+	}
+	manager, err := tc.validator_admin(managerId, impl.GetValidatorAdminAccount(), false)
+	if err != nil {
+		return err
+	}
+	receiptId, err := account(impl.GetReceiptAccount(), "receipt")
+	if err != nil {
+		return err
+	}
+	fundId, err := account(impl.GetValidatorFundAccount(), "validator fund")
+	if err != nil {
+		return err
+	}
+	receipt, err := load[cba.Receipt](tc, receiptId)
+	if err != nil {
+		return err
+	}
+	if !receipt.Validator.Equals(managerId) {
+		return errors.New("receipt belongs to another validator")
+	}
+	payout, err := load[cba.Payout](tc, receipt.Payout)
+	if err != nil {
+		return err
+	}
+	if tc.slot < payout.Period.Start+payout.Period.Length {
+		return errors.New("period has not finished")
+	}
+	pipeline, err := load[cba.Pipeline](tc, payout.Pipeline)
+	if err != nil {
+		return err
+	}
+	bids, err := load[cba.BidList](tc, payout.Bids)
+	if err != nil {
+		return err
+	}
+	k := -1
+	for i := 0; i < len(manager.Ring); i++ {
+		if manager.Ring[i].Receipt.Equals(receiptId) {
+			k = i
+			break
+		}
+	}
+	if k < 0 {
+		return errors.New("receipt not found")
+	}
+	if manager.Ring[k].HasValidatorWithdrawn {
+		return errors.New("receipt already withdrawn")
+	}
+	manager.Ring[k].HasValidatorWithdrawn = true
+	err = store(tc, managerId, manager)
+	if err != nil {
+		return err
+	}
+	amount := fraction(bids.TotalDeposits, pipeline.ValidatorPayoutShare)
+	if 0 < payout.ValidatorCount {
+		amount = amount / payout.ValidatorCount
+	}
+	return tc.token_transfer(pipeline.PcVault, fundId, amount)
+}
+
+func (tc *txContext) cba_update_receipt(impl *cba.UpdateReceipt) error {
+	payoutId, err := account(impl.GetPayoutAccount(), "payout")
+	if err != nil {
+		return err
+	}
+	managerId, err := account(impl.GetValidatorManagerAccount(), "validator manager")
+	if err != nil {
+		return err
+	}
+	txSent, err := arg(impl.TxSent, "tx sent")
+	if err != nil {
+		return err
+	}
+	lastTx, err := arg(impl.LastTx, "last tx")
+	if err != nil {
+		return err
+	}
+	payout, err := load[cba.Payout](tc, payoutId)
+	if err != nil {
+		return err
+	}
+	_, err = tc.pipeline_admin(payout.Pipeline, impl.GetPipelineAdminAccount())
+	if err != nil {
+		return err
+	}
+	manager, err := load[cba.ValidatorManager](tc, managerId)
+	if err != nil {
+		return err
+	}
+	for _, entry := range manager.Ring {
+		if entry.Receipt.IsZero() {
+			continue
+		}
+		receipt, err := load[cba.Receipt](tc, entry.Receipt)
+		if err != nil || !receipt.Payout.Equals(payoutId) {
+			continue
+		}
+		receipt.TxSent += txSent
+		receipt.MsgCounter++
+		receipt.TxSentMerkleroot = lastTx
+		return store(tc, entry.Receipt, receipt)
+	}
+	return errors.New("validator has no receipt for the payout")
+}
+
+// The bidder receipt is created on the first update.  Each update counts
+// one transaction.
+func (tc *txContext) cba_update_bid_receipt(impl *cba.UpdateBidReceipt) error {
+	payoutId, err := account(impl.GetPayoutAccount(), "payout")
+	if err != nil {
+		return err
+	}
+	receiptId, err := account(impl.GetBidReceiptAccount(), "bid receipt")
+	if err != nil {
+		return err
+	}
+	lastTx, err := arg(impl.LastTx, "last tx")
+	if err != nil {
+		return err
+	}
+	payout, err := load[cba.Payout](tc, payoutId)
+	if err != nil {
+		return err
+	}
+	_, err = tc.pipeline_admin(payout.Pipeline, impl.GetPipelineAdminAccount())
+	if err != nil {
+		return err
+	}
+	if tc.v.get(receiptId) == nil {
+		err = initCba(tc, impl.GetPipelineAdminAccount().PublicKey, receiptId, &cba.Receipt{Payout: payoutId}, 0)
+		if err != nil {
+			return err
+		}
+	}
+	receipt, err := load[cba.Receipt](tc, receiptId)
+	if err != nil {
+		return err
+	}
+	if !receipt.Payout.Equals(payoutId) {
+		return errors.New("bid receipt belongs to another payout")
+	}
+	receipt.TxSent++
+	receipt.MsgCounter++
+	receipt.TxSentMerkleroot = lastTx
+	return store(tc, receiptId, receipt)
+}
+
+func (tc *txContext) cba_add_staker(impl *cba.AddStaker) error {
+	controllerId, err := account(impl.GetControllerAccount(), "controller")
+	if err != nil {
+		return err
+	}
+	stake, err := signer(impl.GetStakerAccount(), "staker")
+	if err != nil {
+		return err
+	}
+	admin, err := signer(impl.GetStakerSignerAccount(), "staker signer")
+	if err != nil {
+		return err
+	}
+	managerId, err := account(impl.GetStakerManagerAccount(), "staker manager")
+	if err != nil {
+		return err
+	}
+	expected, _, err := skr.StakerManagerId(controllerId, stake)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(managerId) {
+		return fmt.Errorf("staker manager must be %s", expected.String())
+	}
+	return initCba(tc, admin, managerId, &cba.StakerManager{Admin: admin, Stake: stake}, 0)
+}
+
+func (tc *txContext) cba_add_staker_to_receipt(impl *cba.AddStakerToReceipt) error {
+	admin, err := signer(impl.GetStakeAdminAccount(), "stake admin")
+	if err != nil {
+		return err
+	}
+	stake, err := account(impl.GetStakerAccount(), "staker")
+	if err != nil {
+		return err
+	}
+	managerId, err := account(impl.GetStakerManagerAccount(), "staker manager")
+	if err != nil {
+		return err
+	}
+	receiptId, err := account(impl.GetReceiptAccount(), "receipt")
+	if err != nil {
+		return err
+	}
+	stakerReceiptId, err := account(impl.GetStakerReceiptAccount(), "staker receipt")
+	if err != nil {
+		return err
+	}
+	manager, err := load[cba.StakerManager](tc, managerId)
+	if err != nil {
+		return err
+	}
+	if !manager.Admin.Equals(admin) || !manager.Stake.Equals(stake) {
+		return errors.New("staker manager does not match")
+	}
+	expected, _, err := skr.StakerReceiptId(managerId, receiptId)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(stakerReceiptId) {
+		return fmt.Errorf("staker receipt must be %s", expected.String())
+	}
+	receipt, err := load[cba.Receipt](tc, receiptId)
+	if err != nil {
+		return err
+	}
+	payout, err := load[cba.Payout](tc, receipt.Payout)
+	if err != nil {
+		return err
+	}
+	var delegated uint64
+	if a := tc.v.get(stake); a != nil {
+		delegated = a.Lamports
+	}
+	receipt.StakerCounter++
+	err = store(tc, receiptId, receipt)
+	if err != nil {
+		return err
+	}
+	payout.StakerCount++
+	err = store(tc, receipt.Payout, payout)
+	if err != nil {
+		return err
+	}
+	return initCba(tc, admin, stakerReceiptId, &cba.StakerReceipt{
+		Manager:        managerId,
+		DelegatedStake: delegated,
+		Receipt:        receiptId,
+	}, 0)
+}
+
+// load the pipeline and check that m is its admin and signed
+func (tc *txContext) pipeline_admin(pipelineId sgo.PublicKey, m *sgo.AccountMeta) (*cba.Pipeline, error) {
+	admin, err := signer(m, "pipeline admin")
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := load[cba.Pipeline](tc, pipelineId)
+	if err != nil {
+		return nil, err
+	}
+	if !pipeline.Admin.Equals(admin) {
+		return nil, fmt.Errorf("%s is not the pipeline admin", admin.String())
+	}
+	return pipeline, nil
+}
+
+func (tc *txContext) validator_admin(managerId sgo.PublicKey, m *sgo.AccountMeta, mustSign bool) (*cba.ValidatorManager, error) {
+	admin, err := account(m, "validator admin")
+	if err != nil {
+		return nil, err
+	}
+	if mustSign && !m.IsSigner {
+		return nil, fmt.Errorf("validator admin %s did not sign", admin.String())
+	}
+	manager, err := load[cba.ValidatorManager](tc, managerId)
+	if err != nil {
+		return nil, err
+	}
+	if !manager.Admin.Equals(admin) {
+		return nil, fmt.Errorf("%s is not the validator admin", admin.String())
+	}
+	return manager, nil
+}
+
+// load a payout together with the pipeline and bid list it refers to
+func (tc *txContext) payout(payoutMeta, pipelineMeta, bidsMeta *sgo.AccountMeta) (*cba.Payout, *cba.Pipeline, *cba.BidList, error) {
+	payoutId, err := account(payoutMeta, "payout")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pipelineId, err := account(pipelineMeta, "pipeline")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bidsId, err := account(bidsMeta, "bids")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	payout, err := load[cba.Payout](tc, payoutId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !payout.Pipeline.Equals(pipelineId) || !payout.Bids.Equals(bidsId) {
+		return nil, nil, nil, errors.New("payout does not match the pipeline or bids")
+	}
+	pipeline, err := load[cba.Pipeline](tc, pipelineId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bids, err := load[cba.BidList](tc, bidsId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return payout, pipeline, bids, nil
+}
+
+func load[T any](tc *txContext, id sgo.PublicKey) (*T, error) {
+	a := tc.v.get(id)
+	if a == nil || !a.Owner.Equals(cba.ProgramID) {
+		return nil, fmt.Errorf("account %s is not owned by the cba program", id.String())
+	}
+	x := new(T)
+	err := bin.UnmarshalBorsh(x, a.Data)
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", id.String(), err)
+	}
+	return x, nil
+}
+
+// write x over the data of an existing program account, zero padding the rest
+func store(tc *txContext, id sgo.PublicKey, x interface{}) error {
+	a := tc.v.get(id)
+	if a == nil || !a.Owner.Equals(cba.ProgramID) {
+		return fmt.Errorf("account %s is not owned by the cba program", id.String())
+	}
+	data, err := bin.MarshalBorsh(x)
+	if err != nil {
+		return err
+	}
+	if len(a.Data) < len(data) {
+		return fmt.Errorf("account %s is too small", id.String())
+	}
+	a.Data = make([]byte, len(a.Data))
+	copy(a.Data, data)
+	tc.v.set(id, a)
+	return nil
+}
+
+// create a program account holding x; size 0 means exactly as big as x
+func initCba(tc *txContext, payer sgo.PublicKey, id sgo.PublicKey, x interface{}, size uint64) error {
+	data, err := bin.MarshalBorsh(x)
+	if err != nil {
+		return err
+	}
+	if size < uint64(len(data)) {
+		size = uint64(len(data))
+	}
+	err = tc.create(payer, id, cba.ProgramID, make([]byte, size))
+	if err != nil {
+		return err
+	}
+	return store(tc, id, x)
+}
+
+// how many more entries fit in the account after header is written
+func capacity(tc *txContext, id sgo.PublicKey, header interface{}, entry interface{}) (int, error) {
+	a := tc.v.get(id)
+	if a == nil || !a.Owner.Equals(cba.ProgramID) {
+		return 0, fmt.Errorf("account %s is not owned by the cba program", id.String())
+	}
+	h, err := bin.MarshalBorsh(header)
+	if err != nil {
+		return 0, err
+	}
+	e, err := bin.MarshalBorsh(entry)
+	if err != nil {
+		return 0, err
+	}
+	if len(a.Data) < len(h) || len(e) == 0 {
+		return 0, fmt.Errorf("account %s is too small", id.String())
+	}
+	return (len(a.Data) - len(h)) / len(e), nil
+}
+
+func rateArg(n *uint64, d *uint64, name string) ([2]uint64, error) {
+	x, err := arg(n, name+" numerator")
+	if err != nil {
+		return [2]uint64{}, err
+	}
+	y, err := arg(d, name+" denominator")
+	if err != nil {
+		return [2]uint64{}, err
+	}
+	if y == 0 || y < x {
+		return [2]uint64{}, fmt.Errorf("bad %s", name)
+	}
+	return [2]uint64{x, y}, nil
+}
+
+// x*N/D without overflow; the rates here never exceed 1
+func fraction(x uint64, rate [2]uint64) uint64 {
+	if rate[1] == 0 || rate[1] < rate[0] {
+		return 0
+	}
+	hi, lo := bits.Mul64(x, rate[0])
+	q, _ := bits.Div64(hi, lo, rate[1])
+	return q
+}
@@ -0,0 +1,510 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	bin "github.com/gagliardetto/binary"
+	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/script"
+	"github.com/solpipe/solpipe-tool/state"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+	"github.com/solpipe/solpipe-tool/util"
+)
+
+// Run every CBA instruction the fake executes through one period: from
+// creating the controller to closing the payout.
+func TestCba(t *testing.T) {
+	if !cluster.CbaEnabled() {
+		t.Skip("cba bindings do not encode instructions")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	t.Cleanup(cancel)
+	f, err := cluster.CreateFunded(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, rpcClient, wsClient, payer, s := f.Cluster, f.RpcClient, f.WsClient, f.Payer, f.Script
+	admin, crankAuthority, mintAuthority := newKey(t), newKey(t), newKey(t)
+	pipelineKey, user, cranker, vote := newKey(t), newKey(t), newKey(t), newKey(t)
+	validatorAdmin, stake, stakeAdmin := newKey(t), newKey(t), newKey(t)
+	for _, k := range []sgo.PrivateKey{admin, validatorAdmin, stakeAdmin} {
+		err := script.Airdrop(ctx, rpcClient, wsClient, k.PublicKey(), 10*sgo.LAMPORTS_PER_SOL)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	finish := func(simulate bool) {
+		err := s.FinishTx(simulate)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	instruction := func(b interface{ Build() *cba.Instruction }, keys ...sgo.PrivateKey) {
+		for _, k := range keys {
+			s.AppendKey(k)
+		}
+		err := s.AppendInstruction(b.Build())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tickTo := func(slot uint64) {
+		for {
+			x, err := c.Slot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slot <= x {
+				return
+			}
+			err = c.Tick()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// the bidder holds the pc mint
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr, err := s.CreateMint(payer, mintAuthority, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	mint := *mr.Id
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, owner := range []sgo.PublicKey{user.PublicKey(), cranker.PublicKey(), validatorAdmin.PublicKey()} {
+		err = s.CreateTokenAccount(payer, owner, mint)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = s.MintIssue(mr, user.PublicKey(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+
+	// Create
+	controllerId, _, err := vrs.ControllerId(vrs.VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateController(admin, crankAuthority, mint, &state.Rate{N: 1, D: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	controller := cbaData[cba.Controller](t, c, controllerId)
+	if !controller.PcMint.Equals(mint) {
+		t.Fatalf("controller mint %s", controller.PcMint.String())
+	}
+
+	// AddPipeline
+	pipelineId := pipelineKey.PublicKey()
+	pipelineVault, _, err := vrs.PipelineVaultId(vrs.VERSION_1, pipelineId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refundsId, err := s.CreateAccount(util.STRUCT_SIZE_REFUND_HEADER+10*util.STRUCT_SIZE_REFUND_CLAIM, cba.ProgramID, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	periodsId, err := s.CreateAccount(util.STRUCT_SIZE_PERIOD_RING, cba.ProgramID, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewAddPipelineInstructionBuilder()
+		b.SetControllerAccount(controllerId)
+		b.SetPcMintAccount(mint)
+		b.SetPipelineAccount(pipelineId)
+		b.SetPipelineVaultAccount(pipelineVault)
+		b.SetRefundsAccount(refundsId)
+		b.SetPeriodsAccount(periodsId)
+		b.SetAdminAccount(admin.PublicKey())
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		b.SetSystemProgramAccount(sgo.SystemProgramID)
+		b.SetRentAccount(sgo.SysVarRentPubkey)
+		b.SetAllotment(uint16(10))
+		b.SetCrankFeeRateNum(uint64(1))
+		b.SetCrankFeeRateDen(uint64(100))
+		b.SetValidatorPayoutShareNum(uint64(1))
+		b.SetValidatorPayoutShareDen(uint64(2))
+		b.SetTickSize(script.TICKSIZE_DEFAULT)
+		b.SetRefundSpace(uint16(10))
+		b.SetReceiptLimit(script.RECEIPT_LIMIT_DEFUALT)
+		instruction(b, pipelineKey, admin)
+	}
+	finish(true)
+
+	// UpdatePipeline
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpdatePipeline(controllerId, pipelineId, admin, state.Rate{N: 1, D: 10}, 10, state.Rate{N: 1, D: 2}, script.TICKSIZE_DEFAULT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	pipeline := cbaData[cba.Pipeline](t, c, pipelineId)
+	if pipeline.CrankFeeRate != [2]uint64{1, 10} {
+		t.Fatalf("crank fee %+v", pipeline.CrankFeeRate)
+	}
+
+	// AppendPeriod
+	slot, err := c.Slot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, length := slot+10, uint64(20)
+	payoutKey := newKey(t)
+	payoutId := payoutKey.PublicKey()
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bidsId, err := s.CreateAccount(util.STRUCT_SIZE_BID_LIST_HEADER+10*util.STRUCT_BID_SINGLE, cba.ProgramID, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewAppendPeriodInstructionBuilder()
+		b.SetAdminAccount(admin.PublicKey())
+		b.SetClockAccount(sgo.SysVarClockPubkey)
+		b.SetControllerAccount(controllerId)
+		b.SetEpochScheduleAccount(sgo.SysVarEpochSchedulePubkey)
+		b.SetPayoutAccount(payoutId)
+		b.SetBidsAccount(bidsId)
+		b.SetPeriodsAccount(periodsId)
+		b.SetPipelineAccount(pipelineId)
+		b.SetRentAccount(sgo.SysVarRentPubkey)
+		b.SetSystemProgramAccount(sgo.SystemProgramID)
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		b.SetStart(start)
+		b.SetLength(length)
+		b.SetWithhold(uint16(0))
+		b.SetBidSpace(uint16(10))
+		instruction(b, payoutKey, admin)
+	}
+	finish(true)
+	ring := cbaData[cba.PeriodRing](t, c, periodsId)
+	if ring.Length != 1 || !ring.Ring[ring.Start].Payout.Equals(payoutId) {
+		t.Fatalf("period ring %+v", ring.Ring[ring.Start])
+	}
+
+	// AddValidator and ValidatorSetPayout
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	managerId, err := s.AddValidator(controllerId, vote, stake.PublicKey(), validatorAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiptId, err := s.ValidatorSetPipeline(controllerId, payoutId, pipelineId, managerId, validatorAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	if n := cbaData[cba.Payout](t, c, payoutId).ValidatorCount; n != 1 {
+		t.Fatalf("%d validators", n)
+	}
+
+	// AddStaker and AddStakerToReceipt
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddStaker(controllerId, stake, stakeAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddStakerToReceipt(controllerId, stake.PublicKey(), stakeAdmin, payoutId, receiptId, managerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(true)
+	if n := cbaData[cba.Receipt](t, c, receiptId).StakerCounter; n != 1 {
+		t.Fatalf("%d stakers", n)
+	}
+
+	// InsertBid
+	userFund, _, err := sgo.FindAssociatedTokenAddress(user.PublicKey(), mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewInsertBidInstructionBuilder()
+		b.SetBidsAccount(bidsId)
+		b.SetControllerAccount(controllerId)
+		b.SetPayoutAccount(payoutId)
+		b.SetPcVaultAccount(pipelineVault)
+		b.SetPeriodsAccount(periodsId)
+		b.SetPipelineAccount(pipelineId)
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		b.SetUserAccount(user.PublicKey())
+		b.SetUserFundAccount(userFund)
+		b.SetBid(cba.Bid{User: user.PublicKey(), Deposit: 800})
+		instruction(b, user)
+	}
+	finish(true)
+	if total := cbaData[cba.BidList](t, c, bidsId).TotalDeposits; total != 800 {
+		t.Fatalf("total deposits %d", total)
+	}
+
+	// UpdateReceipt and UpdateBidReceipt
+	bidReceiptId := sgo.NewWallet().PublicKey()
+	updateReceipt := func(pipelineAdmin sgo.PrivateKey) error {
+		err := s.SetTx(payer)
+		if err != nil {
+			return err
+		}
+		rb, err := script.ReceiptSettings{
+			Controller:      controllerId,
+			Pipeline:        pipelineId,
+			PipelineAdmin:   pipelineAdmin,
+			Payout:          payoutId,
+			ValidatorMember: managerId,
+		}.Update(sgo.Hash{1})
+		if err != nil {
+			return err
+		}
+		instruction(rb, pipelineAdmin)
+		bb, err := script.BidReceiptSettings{
+			Receipt:       bidReceiptId,
+			Controller:    controllerId,
+			Pipeline:      pipelineId,
+			PipelineAdmin: pipelineAdmin,
+			Payout:        payoutId,
+		}.Update(sgo.Hash{2})
+		if err != nil {
+			return err
+		}
+		instruction(bb, pipelineAdmin)
+		return s.FinishTx(true)
+	}
+	if updateReceipt(user) == nil {
+		t.Fatal("receipts updated by someone other than the pipeline admin")
+	}
+	for i := 0; i < 2; i++ {
+		err = updateReceipt(admin)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := cbaData[cba.Receipt](t, c, receiptId); r.MsgCounter != 2 {
+		t.Fatalf("receipt %+v", r)
+	}
+	if r := cbaData[cba.Receipt](t, c, bidReceiptId); r.MsgCounter != 2 || r.TxSent != 2 || !r.Payout.Equals(payoutId) {
+		t.Fatalf("bid receipt %+v", r)
+	}
+
+	// Crank closes bidding; CloseBids afterwards changes nothing
+	tickTo(start)
+	crankerFund, _, err := sgo.FindAssociatedTokenAddress(cranker.PublicKey(), mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewCrankInstructionBuilder()
+		b.SetBidsAccount(bidsId)
+		b.SetClockAccount(sgo.SysVarClockPubkey)
+		b.SetControllerAccount(controllerId)
+		b.SetCrankerAccount(cranker.PublicKey())
+		b.SetCrankerFundAccount(crankerFund)
+		b.SetPayoutAccount(payoutId)
+		b.SetPcVaultAccount(pipelineVault)
+		b.SetPipelineAccount(pipelineId)
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		instruction(b, cranker)
+	}
+	finish(true)
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewCloseBidsInstructionBuilder()
+		b.SetBidsAccount(bidsId)
+		b.SetClockAccount(sgo.SysVarClockPubkey)
+		b.SetControllerAccount(controllerId)
+		b.SetPayoutAccount(payoutId)
+		b.SetPipelineAccount(pipelineId)
+		b.SetPipelineAdminAccount(admin.PublicKey())
+		instruction(b, admin)
+	}
+	finish(true)
+	if !cbaData[cba.BidList](t, c, bidsId).BiddingFinished {
+		t.Fatal("bidding has not finished")
+	}
+	// the payout took the crank fee of 1/10 when the period was appended
+	tokenBalance(ctx, t, rpcClient, cranker.PublicKey(), mint, 80)
+
+	// ValidatorWithdrawReceipt
+	tickTo(start + length)
+	validatorFund, _, err := sgo.FindAssociatedTokenAddress(validatorAdmin.PublicKey(), mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewValidatorWithdrawReceiptInstructionBuilder()
+		b.SetClockAccount(sgo.SysVarClockPubkey)
+		b.SetControllerAccount(controllerId)
+		b.SetPayoutAccount(payoutId)
+		b.SetPcVaultAccount(pipelineVault)
+		b.SetPipelineAccount(pipelineId)
+		b.SetReceiptAccount(receiptId)
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		b.SetValidatorAdminAccount(validatorAdmin.PublicKey())
+		b.SetValidatorFundAccount(validatorFund)
+		b.SetValidatorManagerAccount(managerId)
+		instruction(b)
+	}
+	finish(true)
+	tokenBalance(ctx, t, rpcClient, validatorAdmin.PublicKey(), mint, 400)
+
+	// ClaimRefund; nothing in the fake writes refunds, so seed one
+	a, present, err := c.Account(refundsId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !present {
+		t.Fatal("no refunds account")
+	}
+	refunds := new(cba.Refunds)
+	err = bin.UnmarshalBorsh(refunds, a.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunds.Refunds[0] = cba.Claim{User: user.PublicKey(), Balance: 50}
+	data, err := bin.MarshalBorsh(refunds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(a.Data, data)
+	err = c.SetAccount(refundsId, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	{
+		b := cba.NewClaimRefundInstructionBuilder()
+		b.SetControllerAccount(controllerId)
+		b.SetPipelineAccount(pipelineId)
+		b.SetPipelineVaultAccount(pipelineVault)
+		b.SetRefundsAccount(refundsId)
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		b.SetUserFundAccount(userFund)
+		instruction(b)
+	}
+	finish(true)
+	tokenBalance(ctx, t, rpcClient, user.PublicKey(), mint, 250)
+
+	// ClosePayout
+	closePayout := func() error {
+		err := s.SetTx(payer)
+		if err != nil {
+			return err
+		}
+		b := cba.NewClosePayoutInstructionBuilder()
+		b.SetClockAccount(sgo.SysVarClockPubkey)
+		b.SetControllerAccount(controllerId)
+		b.SetControllerPcVaultAccount(controller.PcVault)
+		b.SetPayoutAccount(payoutId)
+		b.SetPipelineAccount(pipelineId)
+		b.SetPipelineAdminAccount(admin.PublicKey())
+		b.SetPipelinePcVaultAccount(pipelineVault)
+		b.SetTokenProgramAccount(sgo.TokenProgramID)
+		instruction(b, admin)
+		return s.FinishTx(true)
+	}
+	if closePayout() == nil {
+		t.Fatal("payout closed too early")
+	}
+	tickTo(start + length + cluster.CLOSE_PAYOUT_DELAY)
+	err = closePayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []sgo.PublicKey{payoutId, bidsId} {
+		_, present, err = c.Account(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if present {
+			t.Fatalf("account %s was not closed", id.String())
+		}
+	}
+}
+
+func cbaData[T any](t *testing.T, c cluster.Cluster, id sgo.PublicKey) *T {
+	a, present, err := c.Account(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !present {
+		t.Fatalf("account %s does not exist", id.String())
+	}
+	x := new(T)
+	err = bin.UnmarshalBorsh(x, a.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func tokenBalance(ctx context.Context, t *testing.T, rpcClient *sgorpc.Client, owner sgo.PublicKey, mint sgo.PublicKey, expected uint64) {
+	fund, err := script.GetTokenAccount(ctx, rpcClient, owner, mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fund.Amount != expected {
+		t.Fatalf("%s holds %d != %d", owner.String(), fund.Amount, expected)
+	}
+}
@@ -0,0 +1,191 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/state/sub"
+)
+
+// real clusters average about 400ms per slot
+const SLOT_INTERVAL_DEFAULT = 400 * time.Millisecond
+
+// A Solana cluster running in process.  It serves JSON RPC and websocket
+// subscriptions on RpcUrl and WsUrl from an in memory ledger that executes the
+// system, token and CBA programs.  Transactions take effect as soon as they
+// are sent, at every commitment level.
+//
+// The script, router and relay tests run on it; the cranker test still runs
+// against the sandbox validator.
+type Cluster struct {
+	ctx       context.Context
+	internalC chan<- func(*internal)
+	RpcUrl    string
+	WsUrl     string
+}
+
+type Account struct {
+	Lamports   uint64
+	Owner      sgo.PublicKey
+	Data       []byte
+	Executable bool
+}
+
+// Start a cluster listening on a random local port.  Slots advance every
+// slotInterval; with slotInterval=0, slots only advance on Tick.  The cluster
+// shuts down when ctx is canceled.
+func Create(ctx context.Context, slotInterval time.Duration) (Cluster, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return Cluster{}, err
+	}
+	internalC := make(chan func(*internal), 10)
+	e1 := Cluster{
+		ctx:       ctx,
+		internalC: internalC,
+		RpcUrl:    fmt.Sprintf("http://%s", l.Addr().String()),
+		WsUrl:     fmt.Sprintf("ws://%s", l.Addr().String()),
+	}
+	server := &http.Server{
+		Handler: external{ctx: ctx, internalC: internalC},
+	}
+	go loopInternal(ctx, internalC, slotInterval)
+	go loopServe(server, l)
+	go loopClose(ctx, server)
+	return e1, nil
+}
+
+func loopServe(server *http.Server, l net.Listener) {
+	err := server.Serve(l)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Debug(err)
+	}
+}
+
+func loopClose(ctx context.Context, server *http.Server) {
+	<-ctx.Done()
+	server.Close()
+}
+
+func (e1 Cluster) Rpc() *sgorpc.Client {
+	return sgorpc.New(e1.RpcUrl)
+}
+
+// The client reconnects like clients for a real cluster (see sub.Dial).
+func (e1 Cluster) Ws(ctx context.Context) (*sgows.Client, error) {
+	return sub.Dial(ctx, e1.WsUrl, http.Header{})
+}
+
+func (e1 Cluster) send_cb(cb func(*internal)) error {
+	select {
+	case <-e1.ctx.Done():
+		return errors.New("canceled")
+	case e1.internalC <- cb:
+		return nil
+	}
+}
+
+func (e1 Cluster) Slot() (uint64, error) {
+	ansC := make(chan uint64, 1)
+	err := e1.send_cb(func(in *internal) {
+		ansC <- in.slot
+	})
+	if err != nil {
+		return 0, err
+	}
+	return <-ansC, nil
+}
+
+// Finish the current block and start the next slot.
+func (e1 Cluster) Tick() error {
+	doneC := make(chan struct{}, 1)
+	err := e1.send_cb(func(in *internal) {
+		in.tick()
+		doneC <- struct{}{}
+	})
+	if err != nil {
+		return err
+	}
+	<-doneC
+	return nil
+}
+
+// Drop the next n transactions sent, as a congested leader would.  The sender
+// still gets the signature back.
+func (e1 Cluster) Drop(n int) error {
+	doneC := make(chan struct{}, 1)
+	err := e1.send_cb(func(in *internal) {
+		in.drop = n
+		doneC <- struct{}{}
+	})
+	if err != nil {
+		return err
+	}
+	<-doneC
+	return nil
+}
+
+// Write an account directly into the ledger (ie to seed vote accounts or
+// accounts owned by programs the cluster does not execute).  An account with
+// no lamports is deleted.
+func (e1 Cluster) SetAccount(id sgo.PublicKey, a Account) error {
+	doneC := make(chan struct{}, 1)
+	err := e1.send_cb(func(in *internal) {
+		v := in.view()
+		v.set(id, &a)
+		in.commit(v)
+		doneC <- struct{}{}
+	})
+	if err != nil {
+		return err
+	}
+	<-doneC
+	return nil
+}
+
+func (e1 Cluster) Account(id sgo.PublicKey) (a Account, present bool, err error) {
+	ansC := make(chan *Account, 1)
+	err = e1.send_cb(func(in *internal) {
+		x, present := in.account[id.String()]
+		if present {
+			ansC <- x.copy()
+		} else {
+			ansC <- nil
+		}
+	})
+	if err != nil {
+		return
+	}
+	x := <-ansC
+	if x == nil {
+		return
+	}
+	a = *x
+	present = true
+	return
+}
+
+// Report a vote account with the given activated stake in getVoteAccounts.
+func (e1 Cluster) SetVoteStake(vote sgo.PublicKey, node sgo.PublicKey, stake uint64) error {
+	return e1.send_cb(func(in *internal) {
+		in.voteStake[vote.String()] = voteInfo{vote: vote, node: node, stake: stake}
+	})
+}
+
+func (a *Account) copy() *Account {
+	if a == nil {
+		return nil
+	}
+	b := *a
+	b.Data = make([]byte, len(a.Data))
+	copy(b.Data, a.Data)
+	return &b
+}
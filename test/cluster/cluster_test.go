@@ -0,0 +1,186 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgotkn "github.com/SolmateDev/solana-go/programs/token"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	"github.com/solpipe/solpipe-tool/script"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+	"github.com/solpipe/solpipe-tool/test/cluster"
+)
+
+func TestCluster(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+
+	c, err := cluster.Create(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcClient := c.Rpc()
+	wsClient, err := c.Ws(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payer, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authority, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = script.Airdrop(ctx, rpcClient, wsClient, payer.PublicKey(), 10*sgo.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mint, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fundId, _, err := sgo.FindAssociatedTokenAddress(user.PublicKey(), mint.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountSub, err := wsClient.AccountSubscribe(fundId, sgorpc.CommitmentFinalized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer accountSub.Unsubscribe()
+	slotSub, err := wsClient.SlotSubscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slotSub.Unsubscribe()
+
+	// subscriptions are confirmed asynchronously, so tick until the first
+	// slot notification; by then the account subscription is in place too
+	var x *sgows.SlotResult
+	for x == nil {
+		err = c.Tick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		case r := <-slotSub.RecvStream():
+			x = r.(*sgows.SlotResult)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	slot, err := c.Slot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Slot != slot {
+		t.Fatalf("slot %d != %d", x.Slot, slot)
+	}
+
+	s, err := script.Create(ctx, &script.Configuration{Version: vrs.VERSION_1}, rpcClient, wsClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr, err := s.CreateMintDirect(mint, payer, authority, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateTokenAccount(payer, user.PublicKey(), *mr.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.MintIssue(mr, user.PublicKey(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ar := recv(ctx, t, accountSub.RecvStream()).(*sgows.AccountResult)
+	if !ar.Value.Owner.Equals(sgo.TokenProgramID) {
+		t.Fatalf("owner %s", ar.Value.Owner.String())
+	}
+	fund, err := script.GetTokenAccount(ctx, rpcClient, user.PublicKey(), *mr.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fund.Amount != 1000 {
+		t.Fatalf("amount %d != 1000", fund.Amount)
+	}
+
+	list, err := rpcClient.GetProgramAccountsWithOpts(ctx, sgo.TokenProgramID, &sgorpc.GetProgramAccountsOpts{
+		Filters: []sgorpc.RPCFilter{{DataSize: sgotkn.MINT_SIZE}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || !list[0].Pubkey.Equals(*mr.Id) {
+		t.Fatalf("expected only the mint, got %d accounts", len(list))
+	}
+
+	// the user does not have the authority to mint
+	err = s.SetTx(payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.MintIssue(&script.MintResult{Id: mr.Id, Authority: &user}, user.PublicKey(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.FinishTx(false)
+	if err == nil {
+		t.Fatal("minting without the authority succeeded")
+	}
+	fund, err = script.GetTokenAccount(ctx, rpcClient, user.PublicKey(), *mr.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fund.Amount != 1000 {
+		t.Fatalf("amount %d != 1000", fund.Amount)
+	}
+}
+
+func newKey(t *testing.T) sgo.PrivateKey {
+	key, err := sgo.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func recv(ctx context.Context, t *testing.T, streamC <-chan sgows.Result) sgows.Result {
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	case x := <-streamC:
+		return x
+	}
+	return nil
+}
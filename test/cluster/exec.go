@@ -0,0 +1,249 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/script"
+)
+
+// State of a transaction while it executes.  Nothing reaches the ledger
+// unless every instruction succeeds.
+type txContext struct {
+	in       *internal
+	v        *view
+	slot     uint64
+	onCommit []func(*internal) // program state kept outside of accounts
+}
+
+func (in *internal) airdrop(id sgo.PublicKey, lamports uint64) (sgo.Signature, error) {
+	var sig sgo.Signature
+	_, err := rand.Read(sig[:])
+	if err != nil {
+		return sig, err
+	}
+	v := in.view()
+	a := v.get(id)
+	if a == nil {
+		a = &Account{Owner: sgo.SystemProgramID, Data: []byte{}}
+	}
+	a.Lamports += lamports
+	v.set(id, a)
+	in.commit(v)
+	in.record(&txStatus{sig: sig, slot: in.slot, time: time.Now(), accountList: []sgo.PublicKey{id}})
+	return sig, nil
+}
+
+// Check and execute a transaction.  With skipPreflight, a transaction that
+// fails after the fee is paid still lands with an error, as on a real cluster.
+// Otherwise, the failure is returned and nothing changes.
+func (in *internal) process(tx *sgo.Transaction, data []byte, skipPreflight bool) (*txStatus, error) {
+	err := in.check(tx, true)
+	if err != nil {
+		return nil, err
+	}
+	s := &txStatus{
+		sig:         tx.Signatures[0],
+		slot:        in.slot,
+		time:        time.Now(),
+		data:        data,
+		fee:         fee(tx),
+		accountList: tx.Message.AccountKeys,
+		priorityFee: priorityFee(tx),
+	}
+	tc, err := in.execute(tx, true)
+	if err != nil {
+		if !skipPreflight {
+			return nil, err
+		}
+		s.err = err
+		tc, err = in.execute(tx, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	in.commit(tc.v)
+	for _, f := range tc.onCommit {
+		f(in)
+	}
+	in.record(s)
+	return s, nil
+}
+
+// return the compute units consumed
+func (in *internal) simulate(tx *sgo.Transaction, sigVerify bool) (uint64, error) {
+	err := in.check(tx, sigVerify)
+	if err != nil {
+		return 0, err
+	}
+	_, err = in.execute(tx, true)
+	if err != nil {
+		return 0, err
+	}
+	return COMPUTE_UNITS_PER_INSTRUCTION * uint64(len(tx.Message.Instructions)), nil
+}
+
+// checks that fail before a fee is charged
+func (in *internal) check(tx *sgo.Transaction, sigVerify bool) error {
+	if len(tx.Signatures) == 0 {
+		return errors.New("transaction has no signatures")
+	}
+	if sigVerify {
+		err := tx.VerifySignatures()
+		if err != nil {
+			return err
+		}
+		_, present := in.status[tx.Signatures[0]]
+		if present {
+			return errors.New("transaction already processed")
+		}
+	}
+	_, present := in.blockhashM[tx.Message.RecentBlockhash]
+	if !present && !in.durable(tx) {
+		return errors.New("blockhash not found")
+	}
+	return nil
+}
+
+// the price set by a ComputeBudget instruction, if any
+func priorityFee(tx *sgo.Transaction) uint64 {
+	for _, ci := range tx.Message.Instructions {
+		programId, err := tx.ResolveProgramIDIndex(ci.ProgramIDIndex)
+		if err != nil || !programId.Equals(sgo.ComputeBudget) {
+			continue
+		}
+		if len(ci.Data) == 9 && ci.Data[0] == script.COMPUTE_BUDGET_INSTRUCTION_PRICE {
+			return binary.LittleEndian.Uint64(ci.Data[1:9])
+		}
+	}
+	return 0
+}
+
+func fee(tx *sgo.Transaction) uint64 {
+	return LAMPORTS_PER_SIGNATURE * uint64(len(tx.Signatures))
+}
+
+// charge the fee and, if runInstructions, run every instruction
+func (in *internal) execute(tx *sgo.Transaction, runInstructions bool) (*txContext, error) {
+	tc := &txContext{in: in, v: in.view(), slot: in.slot, onCommit: make([]func(*internal), 0)}
+	payer := tx.Message.AccountKeys[0]
+	err := tc.debit(payer, fee(tx))
+	if err != nil {
+		return nil, fmt.Errorf("fee payer: %w", err)
+	}
+	if !runInstructions {
+		return tc, nil
+	}
+	for i := 0; i < len(tx.Message.Instructions); i++ {
+		ci := tx.Message.Instructions[i]
+		programId, err := tx.ResolveProgramIDIndex(ci.ProgramIDIndex)
+		if err != nil {
+			return nil, err
+		}
+		err = tc.run(programId, ci.ResolveInstructionAccounts(&tx.Message), ci.Data)
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+	}
+	return tc, nil
+}
+
+func (tc *txContext) run(programId sgo.PublicKey, accountList []*sgo.AccountMeta, data []byte) (err error) {
+	defer func() {
+		// bad instruction data must not take down the cluster
+		if r := recover(); r != nil {
+			err = fmt.Errorf("program %s panicked: %v", programId.String(), r)
+		}
+	}()
+	switch {
+	case programId.Equals(sgo.SystemProgramID):
+		return tc.run_system(accountList, data)
+	case programId.Equals(sgo.TokenProgramID):
+		return tc.run_token(accountList, data)
+	case programId.Equals(sgo.SPLAssociatedTokenAccountProgramID):
+		return tc.run_associated_token(accountList, data)
+	case programId.Equals(sgo.StakeProgramID):
+		return tc.run_stake(accountList, data)
+	case programId.Equals(sgo.ComputeBudget):
+		return nil
+	case programId.Equals(cba.ProgramID):
+		return tc.run_cba(accountList, data)
+	default:
+		return fmt.Errorf("program %s is not supported", programId.String())
+	}
+}
+
+func (tc *txContext) debit(id sgo.PublicKey, lamports uint64) error {
+	a := tc.v.get(id)
+	if a == nil || a.Lamports < lamports {
+		return fmt.Errorf("insufficient lamports in %s", id.String())
+	}
+	a.Lamports -= lamports
+	tc.v.set(id, a)
+	return nil
+}
+
+func (tc *txContext) credit(id sgo.PublicKey, lamports uint64) {
+	a := tc.v.get(id)
+	if a == nil {
+		a = &Account{Owner: sgo.SystemProgramID, Data: []byte{}}
+	}
+	a.Lamports += lamports
+	tc.v.set(id, a)
+}
+
+// create an account owned by owner, paid for by payer
+func (tc *txContext) create(payer sgo.PublicKey, id sgo.PublicKey, owner sgo.PublicKey, data []byte) error {
+	if tc.v.get(id) != nil {
+		return fmt.Errorf("account %s already exists", id.String())
+	}
+	lamports := rent(uint64(len(data)))
+	err := tc.debit(payer, lamports)
+	if err != nil {
+		return err
+	}
+	tc.v.set(id, &Account{Lamports: lamports, Owner: owner, Data: data})
+	return nil
+}
+
+// move the lamports to destination and delete the account
+func (tc *txContext) close(id sgo.PublicKey, destination sgo.PublicKey) error {
+	a := tc.v.get(id)
+	if a == nil {
+		return fmt.Errorf("account %s does not exist", id.String())
+	}
+	tc.credit(destination, a.Lamports)
+	tc.v.set(id, nil)
+	return nil
+}
+
+func account(m *sgo.AccountMeta, name string) (sgo.PublicKey, error) {
+	if m == nil {
+		return sgo.PublicKey{}, fmt.Errorf("missing %s account", name)
+	}
+	return m.PublicKey, nil
+}
+
+func signer(m *sgo.AccountMeta, name string) (sgo.PublicKey, error) {
+	id, err := account(m, name)
+	if err != nil {
+		return id, err
+	}
+	if !m.IsSigner {
+		return id, fmt.Errorf("%s account %s did not sign", name, id.String())
+	}
+	return id, nil
+}
+
+func arg[T any](x *T, name string) (T, error) {
+	if x == nil {
+		var zero T
+		return zero, fmt.Errorf("missing %s", name)
+	}
+	return *x, nil
+}
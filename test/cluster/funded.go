@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"context"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	cba "github.com/solpipe/cba"
+	"github.com/solpipe/solpipe-tool/script"
+	vrs "github.com/solpipe/solpipe-tool/state/version"
+)
+
+// A cluster with a funded payer and a script that sends to it.
+type Funded struct {
+	Cluster   Cluster
+	RpcClient *sgorpc.Client
+	WsClient  *sgows.Client
+	Payer     sgo.PrivateKey
+	Script    *script.Script
+}
+
+// the payer of CreateFunded starts with this many lamports
+const FUNDED_LAMPORTS = 10 * sgo.LAMPORTS_PER_SOL
+
+// Start a cluster whose slots only advance on Tick and airdrop
+// FUNDED_LAMPORTS to a new payer.
+func CreateFunded(ctx context.Context) (f Funded, err error) {
+	f.Cluster, err = Create(ctx, 0)
+	if err != nil {
+		return
+	}
+	f.RpcClient = f.Cluster.Rpc()
+	f.WsClient, err = f.Cluster.Ws(ctx)
+	if err != nil {
+		return
+	}
+	f.Payer, err = sgo.NewRandomPrivateKey()
+	if err != nil {
+		return
+	}
+	err = script.Airdrop(ctx, f.RpcClient, f.WsClient, f.Payer.PublicKey(), FUNDED_LAMPORTS)
+	if err != nil {
+		return
+	}
+	f.Script, err = script.Create(ctx, &script.Configuration{Version: vrs.VERSION_1}, f.RpcClient, f.WsClient)
+	return
+}
+
+// Give the cba bindings a program id if they lack one, and report whether they
+// encode instructions the cluster can decode.  Tests that run CBA instructions
+// skip when this is false.
+func CbaEnabled() bool {
+	if cba.ProgramID.IsZero() {
+		cba.SetProgramID(sgo.NewWallet().PublicKey())
+	}
+	b := cba.NewCloseBidsInstructionBuilder()
+	b.SetPipelineAdminAccount(sgo.NewWallet().PublicKey())
+	inst := b.Build()
+	data, err := inst.Data()
+	if err != nil {
+		return false
+	}
+	decoded, err := cba.DecodeInstruction(inst.Accounts(), data)
+	return err == nil && decoded.Impl != nil && 0 < len(data)
+}
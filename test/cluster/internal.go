@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	LAMPORTS_PER_SIGNATURE uint64 = 5000
+	// slots for which a blockhash can be used in a transaction
+	MAX_BLOCKHASH_AGE uint64 = 150
+	SLOTS_PER_EPOCH   uint64 = 432000
+	// simulations charge a flat cost per instruction
+	COMPUTE_UNITS_PER_INSTRUCTION uint64 = 5000
+)
+
+type internal struct {
+	ctx           context.Context
+	slot          uint64
+	genesis       sgo.Hash
+	blockhash     sgo.Hash
+	blockhashM    map[sgo.Hash]uint64 // recent blockhash -> slot
+	account       map[string]*Account
+	status        map[sgo.Signature]*txStatus
+	history       map[string][]*txStatus // account -> transactions mentioning it, oldest first
+	block         []*txStatus            // transactions in the current slot
+	voteStake     map[string]voteInfo
+	subM          map[uint64]*subscription
+	nextSubId     uint64
+	controllerFee [2]uint64
+	tickSize      map[string]uint16 // pipeline -> tick size copied into new payouts
+	drop          int               // transactions to drop before processing any
+}
+
+type voteInfo struct {
+	vote  sgo.PublicKey
+	node  sgo.PublicKey
+	stake uint64
+}
+
+type txStatus struct {
+	sig         sgo.Signature
+	slot        uint64
+	time        time.Time
+	data        []byte // nil for airdrops
+	fee         uint64
+	err         error
+	accountList []sgo.PublicKey
+	priorityFee uint64 // micro-lamports per compute unit
+}
+
+func loopInternal(
+	ctx context.Context,
+	internalC <-chan func(*internal),
+	slotInterval time.Duration,
+) {
+	doneC := ctx.Done()
+	in := new(internal)
+	in.ctx = ctx
+	in.slot = 1
+	in.genesis = sha256.Sum256([]byte("genesis"))
+	in.blockhash = in.genesis
+	in.blockhashM = map[sgo.Hash]uint64{in.blockhash: in.slot}
+	in.account = make(map[string]*Account)
+	in.status = make(map[sgo.Signature]*txStatus)
+	in.history = make(map[string][]*txStatus)
+	in.block = make([]*txStatus, 0)
+	in.voteStake = make(map[string]voteInfo)
+	in.subM = make(map[uint64]*subscription)
+	in.nextSubId = 1
+	in.controllerFee = [2]uint64{0, 1}
+	in.tickSize = make(map[string]uint16)
+
+	var tickC <-chan time.Time
+	if 0 < slotInterval {
+		ticker := time.NewTicker(slotInterval)
+		defer ticker.Stop()
+		tickC = ticker.C
+	}
+
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case req := <-internalC:
+			req(in)
+		case <-tickC:
+			in.tick()
+		}
+	}
+	log.Debug("fake cluster exiting")
+}
+
+// finish the block for the current slot and move to the next one
+func (in *internal) tick() {
+	in.notify_block()
+	in.block = make([]*txStatus, 0)
+
+	parent := in.slot
+	in.slot++
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], in.slot)
+	in.blockhash = sha256.Sum256(append(in.blockhash[:], b[:]...))
+	in.blockhashM[in.blockhash] = in.slot
+	for h, slot := range in.blockhashM {
+		if slot+MAX_BLOCKHASH_AGE < in.slot {
+			delete(in.blockhashM, h)
+		}
+	}
+	in.notify_slot(parent)
+}
+
+// Accounts as modified by a transaction that has not been committed.
+type view struct {
+	base    map[string]*Account
+	changeM map[string]*Account // nil means the account was deleted
+	idM     map[string]sgo.PublicKey
+}
+
+func (in *internal) view() *view {
+	return &view{
+		base:    in.account,
+		changeM: make(map[string]*Account),
+		idM:     make(map[string]sgo.PublicKey),
+	}
+}
+
+// a copy of the account; nil if it does not exist
+func (v *view) get(id sgo.PublicKey) *Account {
+	a, present := v.changeM[id.String()]
+	if !present {
+		a = v.base[id.String()]
+	}
+	return a.copy()
+}
+
+// accounts without lamports are deleted
+func (v *view) set(id sgo.PublicKey, a *Account) {
+	v.idM[id.String()] = id
+	if a == nil || a.Lamports == 0 {
+		v.changeM[id.String()] = nil
+	} else {
+		v.changeM[id.String()] = a.copy()
+	}
+}
+
+func (in *internal) commit(v *view) {
+	for k, a := range v.changeM {
+		id := v.idM[k]
+		old := in.account[k]
+		if a == nil {
+			delete(in.account, k)
+		} else {
+			in.account[k] = a
+		}
+		in.notify_account(id, old, a)
+	}
+}
+
+func (in *internal) record(s *txStatus) {
+	in.status[s.sig] = s
+	in.block = append(in.block, s)
+	for _, id := range s.accountList {
+		in.history[id.String()] = append(in.history[id.String()], s)
+	}
+	in.notify_signature(s)
+}
+
+func rent(size uint64) uint64 {
+	return (size + 128) * 3480 * 2
+}
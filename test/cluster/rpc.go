@@ -0,0 +1,508 @@
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gorilla/websocket"
+	"github.com/mr-tron/base58"
+)
+
+const (
+	ERROR_PARSE    = -32700
+	ERROR_METHOD   = -32601
+	ERROR_PARAMS   = -32602
+	ERROR_INTERNAL = -32603
+	// same code a validator uses for failed preflight checks
+	ERROR_TRANSACTION = -32002
+)
+
+type external struct {
+	ctx       context.Context
+	internalC chan<- func(*internal)
+}
+
+type rpcRequest struct {
+	Id     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// carries a JSON RPC error code up to the response
+type callError struct {
+	code int
+	err  error
+}
+
+func (ce callError) Error() string {
+	return ce.err.Error()
+}
+
+func resultResponse(id json.RawMessage, result interface{}) rpcResponse {
+	return rpcResponse{Version: "2.0", Id: id, Result: result}
+}
+
+func errorResponse(id json.RawMessage, code int, err error) rpcResponse {
+	ce := new(callError)
+	if errors.As(err, ce) {
+		code = ce.code
+	}
+	return rpcResponse{Version: "2.0", Id: id, Error: &rpcError{Code: code, Message: err.Error()}}
+}
+
+func (e1 external) send_cb(cb func(*internal)) error {
+	select {
+	case <-e1.ctx.Done():
+		return errors.New("canceled")
+	case e1.internalC <- cb:
+		return nil
+	}
+}
+
+func (e1 external) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		e1.serve_ws(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := new(rpcRequest)
+	var resp rpcResponse
+	err = json.Unmarshal(data, req)
+	if err != nil {
+		resp = errorResponse(nil, ERROR_PARSE, err)
+	} else {
+		ansC := make(chan rpcResponse, 1)
+		err = e1.send_cb(func(in *internal) {
+			result, err := in.rpc_call(req.Method, req.Params)
+			if err != nil {
+				ansC <- errorResponse(req.Id, ERROR_INTERNAL, err)
+			} else {
+				ansC <- resultResponse(req.Id, result)
+			}
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		resp = <-ansC
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (in *internal) rpc_call(method string, params []json.RawMessage) (interface{}, error) {
+	switch method {
+	case "getSlot", "getBlockHeight":
+		return in.slot, nil
+	case "getHealth":
+		return "ok", nil
+	case "getVersion":
+		return map[string]interface{}{"solana-core": "1.14.0", "feature-set": 0}, nil
+	case "getGenesisHash":
+		return in.genesis, nil
+	case "getEpochInfo":
+		return sgorpc.GetEpochInfoResult{
+			AbsoluteSlot: in.slot,
+			BlockHeight:  in.slot,
+			Epoch:        in.slot / SLOTS_PER_EPOCH,
+			SlotIndex:    in.slot % SLOTS_PER_EPOCH,
+			SlotsInEpoch: SLOTS_PER_EPOCH,
+		}, nil
+	case "getLatestBlockhash":
+		return sgorpc.GetLatestBlockhashResult{
+			RPCContext: in.context(),
+			Value: &sgorpc.LatestBlockhashResult{
+				Blockhash:            in.blockhash,
+				LastValidBlockHeight: in.slot + MAX_BLOCKHASH_AGE,
+			},
+		}, nil
+	case "getRecentBlockhash":
+		return map[string]interface{}{
+			"context": in.context().Context,
+			"value": map[string]interface{}{
+				"blockhash":     in.blockhash,
+				"feeCalculator": map[string]interface{}{"lamportsPerSignature": LAMPORTS_PER_SIGNATURE},
+			},
+		}, nil
+	case "isBlockhashValid":
+		var h sgo.Hash
+		err := param(params, 0, &h)
+		if err != nil {
+			return nil, err
+		}
+		_, present := in.blockhashM[h]
+		return sgorpc.IsValidBlockhashResult{RPCContext: in.context(), Value: present}, nil
+	case "getMinimumBalanceForRentExemption":
+		var size uint64
+		err := param(params, 0, &size)
+		if err != nil {
+			return nil, err
+		}
+		return rent(size), nil
+	case "getBalance":
+		id, err := paramKey(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		ans := sgorpc.GetBalanceResult{RPCContext: in.context()}
+		a, present := in.account[id.String()]
+		if present {
+			ans.Value = a.Lamports
+		}
+		return ans, nil
+	case "getAccountInfo":
+		id, err := paramKey(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return sgorpc.GetAccountInfoResult{RPCContext: in.context(), Value: in.rpc_account(id)}, nil
+	case "getMultipleAccounts":
+		var list []sgo.PublicKey
+		err := param(params, 0, &list)
+		if err != nil {
+			return nil, err
+		}
+		ans := sgorpc.GetMultipleAccountsResult{RPCContext: in.context(), Value: make([]*sgorpc.Account, len(list))}
+		for i, id := range list {
+			ans.Value[i] = in.rpc_account(id)
+		}
+		return ans, nil
+	case "getProgramAccounts":
+		return in.rpc_program_accounts(params)
+	case "getVoteAccounts":
+		current := make([]sgorpc.VoteAccountsResult, 0, len(in.voteStake))
+		for _, v := range in.voteStake {
+			current = append(current, sgorpc.VoteAccountsResult{
+				VotePubkey:       v.vote,
+				NodePubkey:       v.node,
+				ActivatedStake:   v.stake,
+				EpochVoteAccount: true,
+				LastVote:         in.slot,
+			})
+		}
+		return sgorpc.GetVoteAccountsResult{Current: current, Delinquent: []sgorpc.VoteAccountsResult{}}, nil
+	case "requestAirdrop":
+		id, err := paramKey(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		var lamports uint64
+		err = param(params, 1, &lamports)
+		if err != nil {
+			return nil, err
+		}
+		return in.airdrop(id, lamports)
+	case "sendTransaction":
+		tx, data, config, err := paramTransaction(params)
+		if err != nil {
+			return nil, err
+		}
+		if 0 < in.drop && 0 < len(tx.Signatures) {
+			in.drop--
+			return tx.Signatures[0], nil
+		}
+		s, err := in.process(tx, data, config.SkipPreflight)
+		if err != nil {
+			return nil, callError{code: ERROR_TRANSACTION, err: err}
+		}
+		return s.sig, nil
+	case "simulateTransaction":
+		tx, _, config, err := paramTransaction(params)
+		if err != nil {
+			return nil, err
+		}
+		var errValue interface{}
+		units, err := in.simulate(tx, config.SigVerify)
+		if err != nil {
+			errValue = err.Error()
+		}
+		return map[string]interface{}{
+			"context": in.context().Context,
+			"value":   map[string]interface{}{"err": errValue, "logs": []string{}, "unitsConsumed": units},
+		}, nil
+	case "getRecentPrioritizationFees":
+		var list []sgo.PublicKey
+		if 0 < len(params) {
+			err := param(params, 0, &list)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return in.recentPrioritizationFees(list), nil
+	case "getSignatureStatuses":
+		var list []sgo.Signature
+		err := param(params, 0, &list)
+		if err != nil {
+			return nil, err
+		}
+		ans := sgorpc.GetSignatureStatusesResult{RPCContext: in.context(), Value: make([]*sgorpc.SignatureStatusesResult, len(list))}
+		for i, sig := range list {
+			s, present := in.status[sig]
+			if present {
+				ans.Value[i] = &sgorpc.SignatureStatusesResult{
+					Slot:               s.slot,
+					Err:                s.errValue(),
+					ConfirmationStatus: sgorpc.ConfirmationStatusFinalized,
+				}
+			}
+		}
+		return ans, nil
+	case "getSignaturesForAddress":
+		return in.rpc_signatures(params)
+	case "getTransaction":
+		sig, err := paramSignature(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		s, present := in.status[sig]
+		if !present || s.data == nil {
+			return nil, nil
+		}
+		blockTime := sgo.UnixTimeSeconds(s.time.Unix())
+		return map[string]interface{}{
+			"slot":        s.slot,
+			"blockTime":   blockTime,
+			"transaction": []string{base64.StdEncoding.EncodeToString(s.data), "base64"},
+			"meta":        s.meta(),
+		}, nil
+	default:
+		return nil, callError{code: ERROR_METHOD, err: fmt.Errorf("method %s is not supported", method)}
+	}
+}
+
+// nil if the account does not exist
+func (in *internal) rpc_account(id sgo.PublicKey) *sgorpc.Account {
+	a, present := in.account[id.String()]
+	if !present {
+		return nil
+	}
+	return rpcAccount(a)
+}
+
+type programAccountsConfig struct {
+	Filters []struct {
+		DataSize *uint64 `json:"dataSize"`
+		Memcmp   *struct {
+			Offset uint64 `json:"offset"`
+			Bytes  string `json:"bytes"`
+		} `json:"memcmp"`
+	} `json:"filters"`
+}
+
+func (in *internal) rpc_program_accounts(params []json.RawMessage) (interface{}, error) {
+	programId, err := paramKey(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	config := new(programAccountsConfig)
+	if 1 < len(params) {
+		err = param(params, 1, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ans := make(sgorpc.GetProgramAccountsResult, 0)
+	for k, a := range in.account {
+		if !a.Owner.Equals(programId) || !config.match(a.Data) {
+			continue
+		}
+		ans = append(ans, &sgorpc.KeyedAccount{Pubkey: sgo.MustPublicKeyFromBase58(k), Account: rpcAccount(a)})
+	}
+	return ans, nil
+}
+
+func (config *programAccountsConfig) match(data []byte) bool {
+	for _, f := range config.Filters {
+		if f.DataSize != nil && uint64(len(data)) != *f.DataSize {
+			return false
+		}
+		if f.Memcmp != nil {
+			b, err := base58.Decode(f.Memcmp.Bytes)
+			if err != nil {
+				return false
+			}
+			end := f.Memcmp.Offset + uint64(len(b))
+			if uint64(len(data)) < end || string(data[f.Memcmp.Offset:end]) != string(b) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type signaturesConfig struct {
+	Limit  int           `json:"limit"`
+	Before sgo.Signature `json:"before"`
+	Until  sgo.Signature `json:"until"`
+}
+
+// newest first, like a validator
+func (in *internal) rpc_signatures(params []json.RawMessage) (interface{}, error) {
+	id, err := paramKey(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	config := new(signaturesConfig)
+	if 1 < len(params) {
+		err = param(params, 1, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Limit <= 0 || 1000 < config.Limit {
+		config.Limit = 1000
+	}
+	list := in.history[id.String()]
+	ans := make([]*sgorpc.TransactionSignature, 0)
+	started := config.Before.IsZero()
+	for i := len(list) - 1; 0 <= i && len(ans) < config.Limit; i-- {
+		s := list[i]
+		if !started {
+			started = s.sig.Equals(config.Before)
+			continue
+		}
+		if s.sig.Equals(config.Until) {
+			break
+		}
+		blockTime := sgo.UnixTimeSeconds(s.time.Unix())
+		ans = append(ans, &sgorpc.TransactionSignature{
+			Err:                s.errValue(),
+			Signature:          s.sig,
+			Slot:               s.slot,
+			BlockTime:          &blockTime,
+			ConfirmationStatus: sgorpc.ConfirmationStatusFinalized,
+		})
+	}
+	sort.SliceStable(ans, func(i, j int) bool { return ans[j].Slot < ans[i].Slot })
+	return ans, nil
+}
+
+func param(params []json.RawMessage, i int, x interface{}) error {
+	if len(params) <= i {
+		return callError{code: ERROR_PARAMS, err: fmt.Errorf("missing parameter %d", i)}
+	}
+	err := json.Unmarshal(params[i], x)
+	if err != nil {
+		return callError{code: ERROR_PARAMS, err: fmt.Errorf("parameter %d: %s", i, err.Error())}
+	}
+	return nil
+}
+
+func paramKey(params []json.RawMessage, i int) (id sgo.PublicKey, err error) {
+	err = param(params, i, &id)
+	return
+}
+
+func paramSignature(params []json.RawMessage, i int) (sig sgo.Signature, err error) {
+	err = param(params, i, &sig)
+	return
+}
+
+type sendConfig struct {
+	Encoding      string `json:"encoding"`
+	SkipPreflight bool   `json:"skipPreflight"`
+	SigVerify     bool   `json:"sigVerify"`
+}
+
+// transactions are base58 encoded unless the config says otherwise
+func paramTransaction(params []json.RawMessage) (tx *sgo.Transaction, data []byte, config sendConfig, err error) {
+	var encoded string
+	err = param(params, 0, &encoded)
+	if err != nil {
+		return
+	}
+	if 1 < len(params) {
+		err = param(params, 1, &config)
+		if err != nil {
+			return
+		}
+	}
+	switch config.Encoding {
+	case "", "base58":
+		data, err = base58.Decode(encoded)
+	case "base64":
+		data, err = base64.StdEncoding.DecodeString(encoded)
+	default:
+		err = fmt.Errorf("encoding %s is not supported", config.Encoding)
+	}
+	if err != nil {
+		err = callError{code: ERROR_PARAMS, err: err}
+		return
+	}
+	tx, err = sgo.TransactionFromDecoder(bin.NewBinDecoder(data))
+	if err != nil {
+		err = callError{code: ERROR_PARAMS, err: err}
+	}
+	return
+}
+
+type prioritizationFee struct {
+	Slot              uint64 `json:"slot"`
+	PrioritizationFee uint64 `json:"prioritizationFee"`
+}
+
+// The lowest price paid per recent slot by transactions that touched any of
+// accountList, or by any transaction if accountList is empty.  Slots without
+// such transactions are left out.
+func (in *internal) recentPrioritizationFees(accountList []sgo.PublicKey) []prioritizationFee {
+	var start uint64
+	if MAX_BLOCKHASH_AGE < in.slot {
+		start = in.slot - MAX_BLOCKHASH_AGE
+	}
+	slotM := make(map[uint64]uint64)
+	add := func(s *txStatus) {
+		if s.data == nil || s.slot < start {
+			return
+		}
+		fee, present := slotM[s.slot]
+		if !present || s.priorityFee < fee {
+			slotM[s.slot] = s.priorityFee
+		}
+	}
+	if len(accountList) == 0 {
+		for _, s := range in.status {
+			add(s)
+		}
+	} else {
+		for _, id := range accountList {
+			for _, s := range in.history[id.String()] {
+				add(s)
+			}
+		}
+	}
+	ans := make([]prioritizationFee, 0, len(slotM))
+	for slot, fee := range slotM {
+		ans = append(ans, prioritizationFee{Slot: slot, PrioritizationFee: fee})
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Slot < ans[j].Slot })
+	return ans
+}
@@ -0,0 +1,303 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/script"
+	skr "github.com/solpipe/solpipe-tool/state/staker"
+)
+
+// offsets into StakeStateV2
+const (
+	stakeOffsetReserve    = 4
+	stakeOffsetStaker     = 12
+	stakeOffsetWithdrawer = 44
+	stakeOffsetVote       = 124
+	stakeOffsetStake      = 156
+	stakeOffsetActivation = 164
+	stakeOffsetDeactivate = 172
+	stakeOffsetWarmup     = 180
+)
+
+// A native stake account.  Rewards, warm up and lockups are not modelled.
+type stakeAccount struct {
+	id   sgo.PublicKey
+	a    *Account
+	data []byte
+}
+
+func (tc *txContext) run_stake(accountList []*sgo.AccountMeta, data []byte) error {
+	if len(data) < 4 {
+		return errors.New("stake instruction too short")
+	}
+	switch binary.LittleEndian.Uint32(data[0:4]) {
+	case script.STAKE_INSTRUCTION_INITIALIZE:
+		return tc.run_stake_initialize(accountList, data)
+	case script.STAKE_INSTRUCTION_DELEGATE:
+		return tc.run_stake_delegate(accountList)
+	case script.STAKE_INSTRUCTION_SPLIT:
+		return tc.run_stake_split(accountList, data)
+	case script.STAKE_INSTRUCTION_WITHDRAW:
+		return tc.run_stake_withdraw(accountList, data)
+	case script.STAKE_INSTRUCTION_DEACTIVATE:
+		return tc.run_stake_deactivate(accountList)
+	default:
+		return fmt.Errorf("stake instruction %d is not supported", binary.LittleEndian.Uint32(data[0:4]))
+	}
+}
+
+func (tc *txContext) run_stake_initialize(accountList []*sgo.AccountMeta, data []byte) error {
+	if len(data) < 4+32+32 {
+		return errors.New("initialize instruction too short")
+	}
+	sa, err := tc.stake(accountList[0])
+	if err != nil {
+		return err
+	}
+	if sa.state() != skr.STAKE_STATE_UNINITIALIZED {
+		return fmt.Errorf("stake account %s already initialized", sa.id.String())
+	}
+	reserve := rent(script.STAKE_ACCOUNT_SIZE)
+	if sa.a.Lamports < reserve {
+		return fmt.Errorf("stake account %s is not rent exempt", sa.id.String())
+	}
+	binary.LittleEndian.PutUint32(sa.data[0:4], skr.STAKE_STATE_INITIALIZED)
+	binary.LittleEndian.PutUint64(sa.data[stakeOffsetReserve:stakeOffsetReserve+8], reserve)
+	copy(sa.data[stakeOffsetStaker:stakeOffsetWithdrawer+32], data[4:68])
+	tc.save(sa)
+	return nil
+}
+
+func (tc *txContext) run_stake_delegate(accountList []*sgo.AccountMeta) error {
+	sa, err := tc.stake(accountList[0])
+	if err != nil {
+		return err
+	}
+	vote, err := account(accountList[1], "vote")
+	if err != nil {
+		return err
+	}
+	err = sa.authorize(accountList[5], stakeOffsetStaker)
+	if err != nil {
+		return err
+	}
+	switch sa.state() {
+	case skr.STAKE_STATE_INITIALIZED:
+	case skr.STAKE_STATE_STAKE:
+		if sa.active(tc.epoch()) {
+			return fmt.Errorf("stake account %s is already delegated", sa.id.String())
+		}
+	default:
+		return fmt.Errorf("stake account %s is not initialized", sa.id.String())
+	}
+	reserve := sa.u64(stakeOffsetReserve)
+	if sa.a.Lamports <= reserve {
+		return fmt.Errorf("stake account %s has nothing to delegate", sa.id.String())
+	}
+	binary.LittleEndian.PutUint32(sa.data[0:4], skr.STAKE_STATE_STAKE)
+	copy(sa.data[stakeOffsetVote:stakeOffsetVote+32], vote.Bytes())
+	sa.setU64(stakeOffsetStake, sa.a.Lamports-reserve)
+	sa.setU64(stakeOffsetActivation, tc.epoch())
+	sa.setU64(stakeOffsetDeactivate, math.MaxUint64)
+	sa.setU64(stakeOffsetWarmup, math.Float64bits(0.25))
+	tc.save(sa)
+	return nil
+}
+
+func (tc *txContext) run_stake_deactivate(accountList []*sgo.AccountMeta) error {
+	sa, err := tc.stake(accountList[0])
+	if err != nil {
+		return err
+	}
+	err = sa.authorize(accountList[2], stakeOffsetStaker)
+	if err != nil {
+		return err
+	}
+	if sa.state() != skr.STAKE_STATE_STAKE {
+		return fmt.Errorf("stake account %s is not delegated", sa.id.String())
+	}
+	if sa.u64(stakeOffsetDeactivate) != math.MaxUint64 {
+		return fmt.Errorf("stake account %s is already deactivated", sa.id.String())
+	}
+	sa.setU64(stakeOffsetDeactivate, tc.epoch())
+	tc.save(sa)
+	return nil
+}
+
+func (tc *txContext) run_stake_withdraw(accountList []*sgo.AccountMeta, data []byte) error {
+	if len(data) < 12 {
+		return errors.New("withdraw instruction too short")
+	}
+	lamports := binary.LittleEndian.Uint64(data[4:12])
+	sa, err := tc.stake(accountList[0])
+	if err != nil {
+		return err
+	}
+	destination, err := account(accountList[1], "destination")
+	if err != nil {
+		return err
+	}
+	var locked uint64
+	switch sa.state() {
+	case skr.STAKE_STATE_UNINITIALIZED:
+		// the stake account itself must sign
+		err = sa.authorize(accountList[0], -1)
+	case skr.STAKE_STATE_INITIALIZED:
+		err = sa.authorize(accountList[4], stakeOffsetWithdrawer)
+		locked = sa.u64(stakeOffsetReserve)
+	case skr.STAKE_STATE_STAKE:
+		err = sa.authorize(accountList[4], stakeOffsetWithdrawer)
+		locked = sa.u64(stakeOffsetReserve)
+		if sa.active(tc.epoch()) {
+			locked += sa.u64(stakeOffsetStake)
+		}
+	default:
+		err = fmt.Errorf("stake account %s cannot be withdrawn from", sa.id.String())
+	}
+	if err != nil {
+		return err
+	}
+	if sa.a.Lamports < lamports {
+		return fmt.Errorf("insufficient lamports in %s", sa.id.String())
+	}
+	if lamports == sa.a.Lamports {
+		if sa.state() == skr.STAKE_STATE_STAKE && sa.active(tc.epoch()) {
+			return fmt.Errorf("stake account %s is still active", sa.id.String())
+		}
+		// emptying the account resets it
+		for i := range sa.data {
+			sa.data[i] = 0
+		}
+	} else if sa.a.Lamports-lamports < locked {
+		return fmt.Errorf("stake account %s would fall below its locked balance", sa.id.String())
+	}
+	sa.a.Lamports -= lamports
+	tc.save(sa)
+	tc.credit(destination, lamports)
+	return nil
+}
+
+func (tc *txContext) run_stake_split(accountList []*sgo.AccountMeta, data []byte) error {
+	if len(data) < 12 {
+		return errors.New("split instruction too short")
+	}
+	lamports := binary.LittleEndian.Uint64(data[4:12])
+	sa, err := tc.stake(accountList[0])
+	if err != nil {
+		return err
+	}
+	split, err := tc.stake(accountList[1])
+	if err != nil {
+		return err
+	}
+	if sa.id.Equals(split.id) {
+		return errors.New("cannot split a stake account into itself")
+	}
+	err = sa.authorize(accountList[2], stakeOffsetStaker)
+	if err != nil {
+		return err
+	}
+	if split.state() != skr.STAKE_STATE_UNINITIALIZED {
+		return fmt.Errorf("split account %s is already in use", split.id.String())
+	}
+	if split.a.Lamports < rent(script.STAKE_ACCOUNT_SIZE) {
+		return fmt.Errorf("split account %s is not rent exempt", split.id.String())
+	}
+	reserve := sa.u64(stakeOffsetReserve)
+	if lamports == 0 || sa.a.Lamports < lamports+reserve {
+		return fmt.Errorf("cannot split %d lamports from %s", lamports, sa.id.String())
+	}
+	switch sa.state() {
+	case skr.STAKE_STATE_INITIALIZED:
+	case skr.STAKE_STATE_STAKE:
+		stake := sa.u64(stakeOffsetStake)
+		if stake < lamports {
+			return fmt.Errorf("cannot split %d lamports from the stake of %s", lamports, sa.id.String())
+		}
+		sa.setU64(stakeOffsetStake, stake-lamports)
+	default:
+		return fmt.Errorf("stake account %s is not initialized", sa.id.String())
+	}
+	// the split account already holds its reserve, so all of lamports is staked
+	copy(split.data, sa.data)
+	if sa.state() == skr.STAKE_STATE_STAKE {
+		split.setU64(stakeOffsetStake, lamports)
+	}
+	sa.a.Lamports -= lamports
+	split.a.Lamports += lamports
+	tc.save(sa)
+	tc.save(split)
+	return nil
+}
+
+func (tc *txContext) epoch() uint64 {
+	return tc.slot / SLOTS_PER_EPOCH
+}
+
+func (tc *txContext) stake(m *sgo.AccountMeta) (*stakeAccount, error) {
+	id, err := account(m, "stake")
+	if err != nil {
+		return nil, err
+	}
+	if !m.IsWritable {
+		return nil, fmt.Errorf("stake account %s is not writable", id.String())
+	}
+	a := tc.v.get(id)
+	if a == nil || !a.Owner.Equals(sgo.StakeProgramID) {
+		return nil, fmt.Errorf("account %s is not owned by the stake program", id.String())
+	}
+	if uint64(len(a.Data)) != script.STAKE_ACCOUNT_SIZE {
+		return nil, fmt.Errorf("stake account %s has the wrong size", id.String())
+	}
+	return &stakeAccount{id: id, a: a, data: a.Data}, nil
+}
+
+func (tc *txContext) save(sa *stakeAccount) {
+	sa.a.Data = sa.data
+	tc.v.set(sa.id, sa.a)
+}
+
+func (sa *stakeAccount) state() uint32 {
+	return binary.LittleEndian.Uint32(sa.data[0:4])
+}
+
+func (sa *stakeAccount) u64(offset int) uint64 {
+	return binary.LittleEndian.Uint64(sa.data[offset : offset+8])
+}
+
+func (sa *stakeAccount) setU64(offset int, x uint64) {
+	binary.LittleEndian.PutUint64(sa.data[offset:offset+8], x)
+}
+
+// the authority at offset must have signed; a negative offset means the stake account itself
+func (sa *stakeAccount) authorize(m *sgo.AccountMeta, offset int) error {
+	id, err := signer(m, "authority")
+	if err != nil {
+		return err
+	}
+	expected := sa.id
+	if 0 <= offset {
+		expected = sgo.PublicKeyFromBytes(sa.data[offset : offset+32])
+	}
+	if !id.Equals(expected) {
+		return fmt.Errorf("%s is not the authority of stake account %s", id.String(), sa.id.String())
+	}
+	return nil
+}
+
+// Stake deactivated in the epoch it was activated never became effective.
+func (sa *stakeAccount) active(epoch uint64) bool {
+	activation := sa.u64(stakeOffsetActivation)
+	deactivation := sa.u64(stakeOffsetDeactivate)
+	if deactivation == math.MaxUint64 {
+		return true
+	}
+	if deactivation == activation {
+		return false
+	}
+	return epoch <= deactivation
+}
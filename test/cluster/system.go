@@ -0,0 +1,188 @@
+package cluster
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgosys "github.com/SolmateDev/solana-go/programs/system"
+	bin "github.com/gagliardetto/binary"
+	"github.com/solpipe/solpipe-tool/script"
+)
+
+func (tc *txContext) run_system(accountList []*sgo.AccountMeta, data []byte) error {
+	inst, err := sgosys.DecodeInstruction(accountList, data)
+	if err != nil {
+		return err
+	}
+	switch impl := inst.Impl.(type) {
+	case *sgosys.CreateAccount:
+		funding, err := signer(impl.GetFundingAccount(), "funding")
+		if err != nil {
+			return err
+		}
+		id, err := signer(impl.GetNewAccount(), "new")
+		if err != nil {
+			return err
+		}
+		lamports, err := arg(impl.Lamports, "lamports")
+		if err != nil {
+			return err
+		}
+		space, err := arg(impl.Space, "space")
+		if err != nil {
+			return err
+		}
+		owner, err := arg(impl.Owner, "owner")
+		if err != nil {
+			return err
+		}
+		if tc.v.get(id) != nil {
+			return fmt.Errorf("account %s already in use", id.String())
+		}
+		err = tc.debit(funding, lamports)
+		if err != nil {
+			return err
+		}
+		tc.v.set(id, &Account{Lamports: lamports, Owner: owner, Data: make([]byte, space)})
+		return nil
+	case *sgosys.Transfer:
+		funding, err := signer(impl.GetFundingAccount(), "funding")
+		if err != nil {
+			return err
+		}
+		recipient, err := account(impl.GetRecipientAccount(), "recipient")
+		if err != nil {
+			return err
+		}
+		lamports, err := arg(impl.Lamports, "lamports")
+		if err != nil {
+			return err
+		}
+		err = tc.debit(funding, lamports)
+		if err != nil {
+			return err
+		}
+		tc.credit(recipient, lamports)
+		return nil
+	case *sgosys.Assign:
+		id, err := signer(impl.GetAssignedAccount(), "assigned")
+		if err != nil {
+			return err
+		}
+		owner, err := arg(impl.Owner, "owner")
+		if err != nil {
+			return err
+		}
+		a := tc.v.get(id)
+		if a == nil {
+			return fmt.Errorf("account %s does not exist", id.String())
+		}
+		a.Owner = owner
+		tc.v.set(id, a)
+		return nil
+	case *sgosys.Allocate:
+		id, err := signer(impl.GetNewAccount(), "new")
+		if err != nil {
+			return err
+		}
+		space, err := arg(impl.Space, "space")
+		if err != nil {
+			return err
+		}
+		a := tc.v.get(id)
+		if a == nil {
+			return fmt.Errorf("account %s does not exist", id.String())
+		}
+		if 0 < len(a.Data) {
+			return fmt.Errorf("account %s already in use", id.String())
+		}
+		a.Data = make([]byte, space)
+		tc.v.set(id, a)
+		return nil
+	case *sgosys.InitializeNonceAccount:
+		id, err := account(impl.GetNonceAccount(), "nonce")
+		if err != nil {
+			return err
+		}
+		authority, err := arg(impl.Authorized, "authorized")
+		if err != nil {
+			return err
+		}
+		a := tc.v.get(id)
+		if a == nil || !a.Owner.Equals(sgo.SystemProgramID) || len(a.Data) != script.NONCE_ACCOUNT_SIZE {
+			return fmt.Errorf("account %s cannot hold a nonce", id.String())
+		}
+		if a.Lamports < rent(script.NONCE_ACCOUNT_SIZE) {
+			return fmt.Errorf("nonce account %s is not rent exempt", id.String())
+		}
+		_, err = script.ParseNonce(a.Data)
+		if err == nil {
+			return fmt.Errorf("nonce account %s already initialized", id.String())
+		}
+		return tc.set_nonce(id, a, authority)
+	case *sgosys.AdvanceNonceAccount:
+		id, err := account(impl.GetNonceAccount(), "nonce")
+		if err != nil {
+			return err
+		}
+		authority, err := signer(impl.GetNonceAuthorityAccount(), "nonce authority")
+		if err != nil {
+			return err
+		}
+		a := tc.v.get(id)
+		if a == nil {
+			return fmt.Errorf("account %s does not exist", id.String())
+		}
+		na, err := script.ParseNonce(a.Data)
+		if err != nil {
+			return err
+		}
+		if !na.AuthorizedPubkey.Equals(authority) {
+			return fmt.Errorf("%s is not the nonce authority", authority.String())
+		}
+		if sgo.Hash(na.Nonce).Equals(tc.in.blockhash) {
+			return errors.New("nonce can only advance once per slot")
+		}
+		return tc.set_nonce(id, a, authority)
+	default:
+		return fmt.Errorf("system instruction %T is not supported", inst.Impl)
+	}
+}
+
+// store the current blockhash in the nonce account
+func (tc *txContext) set_nonce(id sgo.PublicKey, a *Account, authority sgo.PublicKey) error {
+	na := sgosys.NonceAccount{
+		Version:          1,
+		State:            script.NONCE_STATE_INITIALIZED,
+		AuthorizedPubkey: authority,
+		Nonce:            sgo.PublicKey(tc.in.blockhash),
+		FeeCalculator:    sgosys.FeeCalculator{LamportsPerSignature: LAMPORTS_PER_SIGNATURE},
+	}
+	buf := new(bytes.Buffer)
+	err := bin.NewBinEncoder(buf).Encode(na)
+	if err != nil {
+		return err
+	}
+	a.Data = buf.Bytes()
+	tc.v.set(id, a)
+	return nil
+}
+
+// true if tx uses a durable nonce whose value matches its blockhash
+func (in *internal) durable(tx *sgo.Transaction) bool {
+	id, ok := script.DurableNonce(tx)
+	if !ok {
+		return false
+	}
+	a := in.view().get(id)
+	if a == nil {
+		return false
+	}
+	na, err := script.ParseNonce(a.Data)
+	if err != nil {
+		return false
+	}
+	return sgo.Hash(na.Nonce).Equals(tx.Message.RecentBlockhash)
+}
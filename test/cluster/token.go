@@ -0,0 +1,312 @@
+package cluster
+
+import (
+	"fmt"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgotkn "github.com/SolmateDev/solana-go/programs/token"
+	bin "github.com/gagliardetto/binary"
+)
+
+const (
+	TOKEN_ACCOUNT_SIZE = 165
+	MINT_SIZE          = 82
+)
+
+func (tc *txContext) run_token(accountList []*sgo.AccountMeta, data []byte) error {
+	inst, err := sgotkn.DecodeInstruction(accountList, data)
+	if err != nil {
+		return err
+	}
+	switch impl := inst.Impl.(type) {
+	case *sgotkn.InitializeMint:
+		id, err := account(impl.GetMintAccount(), "mint")
+		if err != nil {
+			return err
+		}
+		decimals, err := arg(impl.Decimals, "decimals")
+		if err != nil {
+			return err
+		}
+		authority, err := arg(impl.MintAuthority, "mint authority")
+		if err != nil {
+			return err
+		}
+		a := tc.v.get(id)
+		if a == nil || !a.Owner.Equals(sgo.TokenProgramID) || len(a.Data) != MINT_SIZE {
+			return fmt.Errorf("mint %s is not an uninitialized token account", id.String())
+		}
+		m := new(sgotkn.Mint)
+		err = bin.NewBinDecoder(a.Data).Decode(m)
+		if err == nil && m.IsInitialized {
+			return fmt.Errorf("mint %s already initialized", id.String())
+		}
+		return tc.set_token(id, &sgotkn.Mint{
+			MintAuthority:   &authority,
+			Decimals:        decimals,
+			IsInitialized:   true,
+			FreezeAuthority: impl.FreezeAuthority,
+		})
+	case *sgotkn.InitializeAccount:
+		id, err := account(impl.GetAccount(), "account")
+		if err != nil {
+			return err
+		}
+		mintId, err := account(impl.GetMintAccount(), "mint")
+		if err != nil {
+			return err
+		}
+		owner, err := account(impl.GetOwnerAccount(), "owner")
+		if err != nil {
+			return err
+		}
+		a := tc.v.get(id)
+		if a == nil || !a.Owner.Equals(sgo.TokenProgramID) || len(a.Data) != TOKEN_ACCOUNT_SIZE {
+			return fmt.Errorf("account %s is not an uninitialized token account", id.String())
+		}
+		_, err = tc.mint(mintId)
+		if err != nil {
+			return err
+		}
+		return tc.set_token(id, &sgotkn.Account{Mint: mintId, Owner: owner, State: sgotkn.Initialized})
+	case *sgotkn.MintTo:
+		mintId, err := account(impl.GetMintAccount(), "mint")
+		if err != nil {
+			return err
+		}
+		destination, err := account(impl.GetDestinationAccount(), "destination")
+		if err != nil {
+			return err
+		}
+		authority, err := signer(impl.GetAuthorityAccount(), "authority")
+		if err != nil {
+			return err
+		}
+		amount, err := arg(impl.Amount, "amount")
+		if err != nil {
+			return err
+		}
+		m, err := tc.mint(mintId)
+		if err != nil {
+			return err
+		}
+		if m.MintAuthority == nil || !m.MintAuthority.Equals(authority) {
+			return fmt.Errorf("%s is not the mint authority", authority.String())
+		}
+		d, err := tc.token_account(destination)
+		if err != nil {
+			return err
+		}
+		if !d.Mint.Equals(mintId) {
+			return fmt.Errorf("account %s is for another mint", destination.String())
+		}
+		m.Supply += amount
+		d.Amount += amount
+		err = tc.set_token(mintId, m)
+		if err != nil {
+			return err
+		}
+		return tc.set_token(destination, d)
+	case *sgotkn.Transfer:
+		return tc.token_transfer_signed(impl.GetSourceAccount(), impl.GetDestinationAccount(), impl.GetOwnerAccount(), impl.Amount)
+	case *sgotkn.TransferChecked:
+		return tc.token_transfer_signed(impl.GetSourceAccount(), impl.GetDestinationAccount(), impl.GetOwnerAccount(), impl.Amount)
+	case *sgotkn.CloseAccount:
+		id, err := account(impl.GetAccount(), "account")
+		if err != nil {
+			return err
+		}
+		destination, err := account(impl.GetDestinationAccount(), "destination")
+		if err != nil {
+			return err
+		}
+		owner, err := signer(impl.GetOwnerAccount(), "owner")
+		if err != nil {
+			return err
+		}
+		ta, err := tc.token_account(id)
+		if err != nil {
+			return err
+		}
+		if !ta.Owner.Equals(owner) {
+			return fmt.Errorf("%s does not own %s", owner.String(), id.String())
+		}
+		if ta.IsNative == nil && 0 < ta.Amount {
+			return fmt.Errorf("account %s still holds tokens", id.String())
+		}
+		return tc.close(id, destination)
+	case *sgotkn.SyncNative:
+		id, err := account(impl.GetTokenAccount(), "token")
+		if err != nil {
+			return err
+		}
+		ta, err := tc.token_account(id)
+		if err != nil {
+			return err
+		}
+		if ta.IsNative == nil {
+			return fmt.Errorf("account %s is not native", id.String())
+		}
+		ta.Amount = tc.v.get(id).Lamports - *ta.IsNative
+		return tc.set_token(id, ta)
+	default:
+		return fmt.Errorf("token instruction %T is not supported", inst.Impl)
+	}
+}
+
+// the payer funds a token account at the address derived from wallet and mint
+func (tc *txContext) run_associated_token(accountList []*sgo.AccountMeta, data []byte) error {
+	if len(accountList) < 4 {
+		return fmt.Errorf("expected 4 accounts, got %d", len(accountList))
+	}
+	payer, err := signer(accountList[0], "payer")
+	if err != nil {
+		return err
+	}
+	id := accountList[1].PublicKey
+	wallet := accountList[2].PublicKey
+	mintId := accountList[3].PublicKey
+	expected, _, err := sgo.FindAssociatedTokenAddress(wallet, mintId)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(id) {
+		return fmt.Errorf("%s is not the associated token account of %s", id.String(), wallet.String())
+	}
+	if tc.v.get(id) != nil {
+		if 0 < len(data) && data[0] == 1 {
+			// CreateIdempotent
+			return nil
+		}
+		return fmt.Errorf("account %s already exists", id.String())
+	}
+	_, err = tc.mint(mintId)
+	if err != nil {
+		return err
+	}
+	err = tc.create(payer, id, sgo.TokenProgramID, make([]byte, TOKEN_ACCOUNT_SIZE))
+	if err != nil {
+		return err
+	}
+	ta := &sgotkn.Account{Mint: mintId, Owner: wallet, State: sgotkn.Initialized}
+	if mintId.Equals(sgo.SolMint) {
+		reserve := rent(TOKEN_ACCOUNT_SIZE)
+		ta.IsNative = &reserve
+	}
+	return tc.set_token(id, ta)
+}
+
+func (tc *txContext) mint(id sgo.PublicKey) (*sgotkn.Mint, error) {
+	a := tc.v.get(id)
+	if a == nil || !a.Owner.Equals(sgo.TokenProgramID) {
+		return nil, fmt.Errorf("mint %s does not exist", id.String())
+	}
+	m := new(sgotkn.Mint)
+	err := bin.NewBinDecoder(a.Data).Decode(m)
+	if err != nil {
+		return nil, err
+	}
+	if !m.IsInitialized {
+		return nil, fmt.Errorf("mint %s is not initialized", id.String())
+	}
+	return m, nil
+}
+
+func (tc *txContext) token_account(id sgo.PublicKey) (*sgotkn.Account, error) {
+	a := tc.v.get(id)
+	if a == nil || !a.Owner.Equals(sgo.TokenProgramID) || len(a.Data) != TOKEN_ACCOUNT_SIZE {
+		return nil, fmt.Errorf("token account %s does not exist", id.String())
+	}
+	ta := new(sgotkn.Account)
+	err := bin.NewBinDecoder(a.Data).Decode(ta)
+	if err != nil {
+		return nil, err
+	}
+	if ta.State != sgotkn.Initialized {
+		return nil, fmt.Errorf("token account %s is not initialized", id.String())
+	}
+	return ta, nil
+}
+
+// write a mint or token account over the existing account data
+func (tc *txContext) set_token(id sgo.PublicKey, x interface{}) error {
+	data, err := bin.MarshalBin(x)
+	if err != nil {
+		return err
+	}
+	a := tc.v.get(id)
+	if a == nil || len(a.Data) != len(data) {
+		return fmt.Errorf("account %s has the wrong size", id.String())
+	}
+	a.Data = data
+	tc.v.set(id, a)
+	return nil
+}
+
+func (tc *txContext) token_transfer_signed(sourceMeta, destinationMeta, ownerMeta *sgo.AccountMeta, amount *uint64) error {
+	source, err := account(sourceMeta, "source")
+	if err != nil {
+		return err
+	}
+	destination, err := account(destinationMeta, "destination")
+	if err != nil {
+		return err
+	}
+	owner, err := signer(ownerMeta, "owner")
+	if err != nil {
+		return err
+	}
+	x, err := arg(amount, "amount")
+	if err != nil {
+		return err
+	}
+	s, err := tc.token_account(source)
+	if err != nil {
+		return err
+	}
+	if !s.Owner.Equals(owner) {
+		return fmt.Errorf("%s does not own %s", owner.String(), source.String())
+	}
+	return tc.token_transfer(source, destination, x)
+}
+
+// move tokens without checking who signed; programs use this for vaults they own
+func (tc *txContext) token_transfer(source sgo.PublicKey, destination sgo.PublicKey, amount uint64) error {
+	s, err := tc.token_account(source)
+	if err != nil {
+		return err
+	}
+	d, err := tc.token_account(destination)
+	if err != nil {
+		return err
+	}
+	if !s.Mint.Equals(d.Mint) {
+		return fmt.Errorf("accounts %s and %s hold different mints", source.String(), destination.String())
+	}
+	if s.Amount < amount {
+		return fmt.Errorf("insufficient funds in %s", source.String())
+	}
+	if source.Equals(destination) {
+		return nil
+	}
+	s.Amount -= amount
+	d.Amount += amount
+	err = tc.set_token(source, s)
+	if err != nil {
+		return err
+	}
+	return tc.set_token(destination, d)
+}
+
+// create an initialized token account, as the CBA program does for its vaults
+func (tc *txContext) init_token_account(payer sgo.PublicKey, id sgo.PublicKey, mintId sgo.PublicKey, owner sgo.PublicKey) error {
+	_, err := tc.mint(mintId)
+	if err != nil {
+		return err
+	}
+	err = tc.create(payer, id, sgo.TokenProgramID, make([]byte, TOKEN_ACCOUNT_SIZE))
+	if err != nil {
+		return err
+	}
+	return tc.set_token(id, &sgotkn.Account{Mint: mintId, Owner: owner, State: sgotkn.Initialized})
+}
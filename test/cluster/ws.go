@@ -0,0 +1,293 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	sgorpc "github.com/SolmateDev/solana-go/rpc"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// notifications queued per connection before the connection is dropped
+const WS_BUFFER_SIZE = 1000
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type subscription struct {
+	id        uint64
+	method    string
+	account   sgo.PublicKey // account or program
+	signature sgo.Signature
+	conn      *wsConn
+}
+
+type wsConn struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	writeC chan []byte
+}
+
+func (e1 external) serve_ws(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	ctx, cancel := context.WithCancel(e1.ctx)
+	wc := &wsConn{ctx: ctx, cancel: cancel, writeC: make(chan []byte, WS_BUFFER_SIZE)}
+	go loopWsWrite(ctx, cancel, conn, wc.writeC)
+	go loopWsClose(ctx, conn)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		req := new(rpcRequest)
+		err = json.Unmarshal(data, req)
+		if err != nil {
+			wc.write(errorResponse(nil, ERROR_PARSE, err))
+			continue
+		}
+		err = e1.send_cb(func(in *internal) {
+			in.ws_call(wc, req)
+		})
+		if err != nil {
+			break
+		}
+	}
+	cancel()
+	e1.send_cb(func(in *internal) {
+		for id, s := range in.subM {
+			if s.conn == wc {
+				delete(in.subM, id)
+			}
+		}
+	})
+}
+
+func loopWsWrite(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, writeC <-chan []byte) {
+	defer cancel()
+	doneC := ctx.Done()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case data := <-writeC:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			err := conn.WriteMessage(websocket.TextMessage, data)
+			if err != nil {
+				break out
+			}
+		}
+	}
+}
+
+func loopWsClose(ctx context.Context, conn *websocket.Conn) {
+	<-ctx.Done()
+	conn.Close()
+}
+
+func (wc *wsConn) write(x interface{}) {
+	data, err := json.Marshal(x)
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	select {
+	case wc.writeC <- data:
+	default:
+		// like a real validator, drop clients that do not keep up
+		wc.cancel()
+	}
+}
+
+func (wc *wsConn) notify(method string, subId uint64, result interface{}) {
+	wc.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params": map[string]interface{}{
+			"result":       result,
+			"subscription": subId,
+		},
+	})
+}
+
+func (in *internal) ws_call(wc *wsConn, req *rpcRequest) {
+	s := &subscription{method: req.Method, conn: wc}
+	var err error
+	switch req.Method {
+	case "accountSubscribe", "programSubscribe":
+		s.account, err = paramKey(req.Params, 0)
+	case "signatureSubscribe":
+		s.signature, err = paramSignature(req.Params, 0)
+	case "slotSubscribe", "blockSubscribe":
+	case "accountUnsubscribe", "programUnsubscribe", "signatureUnsubscribe", "slotUnsubscribe", "blockUnsubscribe":
+		var id uint64
+		err = param(req.Params, 0, &id)
+		if err != nil {
+			wc.write(errorResponse(req.Id, ERROR_PARAMS, err))
+			return
+		}
+		x, present := in.subM[id]
+		if present && x.conn == wc {
+			delete(in.subM, id)
+		}
+		wc.write(resultResponse(req.Id, present))
+		return
+	default:
+		wc.write(errorResponse(req.Id, ERROR_METHOD, fmt.Errorf("method %s is not supported", req.Method)))
+		return
+	}
+	if err != nil {
+		wc.write(errorResponse(req.Id, ERROR_PARAMS, err))
+		return
+	}
+	s.id = in.nextSubId
+	in.nextSubId++
+	in.subM[s.id] = s
+	wc.write(resultResponse(req.Id, s.id))
+
+	if s.method == "signatureSubscribe" {
+		status, present := in.status[s.signature]
+		if present {
+			in.notify_signature(status)
+		}
+	}
+}
+
+func (in *internal) context() sgorpc.RPCContext {
+	return sgorpc.RPCContext{Context: sgorpc.Context{Slot: in.slot}}
+}
+
+func (in *internal) notify_account(id sgo.PublicKey, old *Account, a *Account) {
+	for _, s := range in.subM {
+		switch s.method {
+		case "accountSubscribe":
+			if s.account.Equals(id) {
+				s.conn.notify("accountNotification", s.id, map[string]interface{}{
+					"context": in.context().Context,
+					"value":   rpcAccount(a),
+				})
+			}
+		case "programSubscribe":
+			// closed accounts are sent with no lamports
+			if (old != nil && old.Owner.Equals(s.account)) || (a != nil && a.Owner.Equals(s.account)) {
+				s.conn.notify("programNotification", s.id, map[string]interface{}{
+					"context": in.context().Context,
+					"value":   sgorpc.KeyedAccount{Pubkey: id, Account: rpcAccount(a)},
+				})
+			}
+		}
+	}
+}
+
+func (in *internal) notify_slot(parent uint64) {
+	for _, s := range in.subM {
+		if s.method == "slotSubscribe" {
+			s.conn.notify("slotNotification", s.id, map[string]interface{}{
+				"parent": parent,
+				"root":   parent,
+				"slot":   in.slot,
+			})
+		}
+	}
+}
+
+func (in *internal) notify_block() {
+	var block *sgorpc.GetBlockResult
+	for _, s := range in.subM {
+		if s.method != "blockSubscribe" {
+			continue
+		}
+		if block == nil {
+			block = in.block_result()
+		}
+		s.conn.notify("blockNotification", s.id, map[string]interface{}{
+			"context": in.context().Context,
+			"value": map[string]interface{}{
+				"slot":  in.slot,
+				"err":   nil,
+				"block": block,
+			},
+		})
+	}
+}
+
+func (in *internal) block_result() *sgorpc.GetBlockResult {
+	blockTime := sgo.UnixTimeSeconds(time.Now().Unix())
+	height := in.slot
+	ans := &sgorpc.GetBlockResult{
+		Blockhash:    in.blockhash,
+		ParentSlot:   in.slot - 1,
+		Transactions: make([]sgorpc.TransactionWithMeta, 0, len(in.block)),
+		Signatures:   make([]sgo.Signature, 0, len(in.block)),
+		BlockTime:    &blockTime,
+		BlockHeight:  &height,
+	}
+	for _, s := range in.block {
+		if s.data == nil {
+			continue
+		}
+		ans.Transactions = append(ans.Transactions, sgorpc.TransactionWithMeta{
+			Transaction: sgorpc.DataBytesOrJSONFromBytes(s.data),
+			Meta:        s.meta(),
+		})
+		ans.Signatures = append(ans.Signatures, s.sig)
+	}
+	return ans
+}
+
+// signature subscriptions end after the first notification
+func (in *internal) notify_signature(status *txStatus) {
+	for id, s := range in.subM {
+		if s.method == "signatureSubscribe" && s.signature.Equals(status.sig) {
+			s.conn.notify("signatureNotification", s.id, map[string]interface{}{
+				"context": sgorpc.Context{Slot: status.slot},
+				"value":   map[string]interface{}{"err": status.errValue()},
+			})
+			delete(in.subM, id)
+		}
+	}
+}
+
+func (s *txStatus) errValue() interface{} {
+	if s.err == nil {
+		return nil
+	}
+	return s.err.Error()
+}
+
+func (s *txStatus) meta() *sgorpc.TransactionMeta {
+	return &sgorpc.TransactionMeta{
+		Err:               s.errValue(),
+		Fee:               s.fee,
+		PreBalances:       []uint64{},
+		PostBalances:      []uint64{},
+		InnerInstructions: []sgorpc.InnerInstruction{},
+		LogMessages:       []string{},
+	}
+}
+
+// deleted accounts are reported as empty system accounts
+func rpcAccount(a *Account) *sgorpc.Account {
+	if a == nil {
+		return &sgorpc.Account{
+			Owner: sgo.SystemProgramID,
+			Data:  sgorpc.DataBytesOrJSONFromBytes([]byte{}),
+		}
+	}
+	return &sgorpc.Account{
+		Lamports:   a.Lamports,
+		Owner:      a.Owner,
+		Data:       sgorpc.DataBytesOrJSONFromBytes(a.Data),
+		Executable: a.Executable,
+	}
+}
@@ -422,25 +422,11 @@ func (e1 external) CreateStake(ctx context.Context, req *pbt.CreateStakeRequest)
 		return
 	}
 
-	rpcC := make(chan *sgorpc.Client, 1)
-	wsC := make(chan *sgows.Client, 1)
-	e1.internalC <- func(in *internal) {
-		wsC <- in.ts.Ws
-		rpcC <- in.ts.Rpc
-	}
-	var rpcClient *sgorpc.Client
-	var wsClient *sgows.Client
-	select {
-	case <-ctx.Done():
-		err = errors.New("canceled")
-	case wsClient = <-wsC:
-		rpcClient = <-rpcC
-	}
-
+	rpcClient, wsClient, err := e1.clients(ctx)
 	if err != nil {
 		return
 	}
-	err = CreateStake(ctx, e1.url, rpcClient, wsClient, funds, stake, admin, req.Amount)
+	err = CreateStake(ctx, rpcClient, wsClient, funds, stake, admin, req.Amount)
 	if err != nil {
 		return
 	}
@@ -467,9 +453,29 @@ func (e1 external) DelegateStake(ctx context.Context, req *pbt.DelegateStakeRequ
 	if err != nil {
 		return
 	}
-	err = DelegateStake(ctx, e1.url, payer, stake, stakeAdmin, vote)
+	rpcClient, wsClient, err := e1.clients(ctx)
+	if err != nil {
+		return
+	}
+	err = DelegateStake(ctx, rpcClient, wsClient, payer, stake, stakeAdmin, vote)
 	if err != nil {
 		return
 	}
 	return
 }
+
+func (e1 external) clients(ctx context.Context) (rpcClient *sgorpc.Client, wsClient *sgows.Client, err error) {
+	rpcC := make(chan *sgorpc.Client, 1)
+	wsC := make(chan *sgows.Client, 1)
+	e1.internalC <- func(in *internal) {
+		wsC <- in.ts.Ws
+		rpcC <- in.ts.Rpc
+	}
+	select {
+	case <-ctx.Done():
+		err = errors.New("canceled")
+	case wsClient = <-wsC:
+		rpcClient = <-rpcC
+	}
+	return
+}
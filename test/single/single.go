@@ -218,6 +218,7 @@ func Load(ctx context.Context) (*SingleSandbox, error) {
 	return ans, nil
 }
 
+// Only for the solana CLI fallback; prefer Script.
 func (s *SingleSandbox) ShellSolana() (shell.Solana, error) {
 	return shell.CreateSolana(s.rpcUrl)
 }
@@ -0,0 +1,313 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EXPORT_INTERVAL   = 1 * time.Second
+	EXPORT_BATCH_SIZE = 256
+	EXPORT_QUEUE_SIZE = 4096
+)
+
+// Receives finished spans in batches.
+type Exporter interface {
+	Export(list []SpanData) error
+	Close() error
+}
+
+var exportMutex = &sync.RWMutex{}
+var exportC chan<- SpanData
+
+// drop the span if nothing is exporting or the queue is full; tracing must not slow down transactions
+func export(d SpanData) {
+	exportMutex.RLock()
+	defer exportMutex.RUnlock()
+	if exportC == nil {
+		return
+	}
+	select {
+	case exportC <- d:
+	default:
+	}
+}
+
+// Export spans until ctx is done.  target is either an OTLP/HTTP url
+// (ie http://localhost:4318/v1/traces) or a file path to which spans are
+// appended as json lines.  signalC carries an error if the exporter cannot be set up.
+func Run(ctx context.Context, target string, service string) (signalC <-chan error) {
+	errorC := make(chan error, 1)
+	signalC = errorC
+
+	var exporter Exporter
+	var err error
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		exporter = CreateOtlpExporter(target, service)
+	} else {
+		exporter, err = CreateFileExporter(target, service)
+		if err != nil {
+			errorC <- err
+			return
+		}
+	}
+
+	spanC := make(chan SpanData, EXPORT_QUEUE_SIZE)
+	exportMutex.Lock()
+	if exportC != nil {
+		exportMutex.Unlock()
+		exporter.Close()
+		errorC <- errors.New("tracing is already running")
+		return
+	}
+	exportC = spanC
+	exportMutex.Unlock()
+	log.Debugf("exporting spans to %s", target)
+
+	go loopExport(ctx, spanC, exporter)
+	return
+}
+
+func loopExport(ctx context.Context, spanC <-chan SpanData, exporter Exporter) {
+	doneC := ctx.Done()
+	list := make([]SpanData, 0, EXPORT_BATCH_SIZE)
+	flush := func() {
+		if len(list) == 0 {
+			return
+		}
+		err := exporter.Export(list)
+		if err != nil {
+			log.Debugf("failed to export %d spans: %s", len(list), err.Error())
+		}
+		list = make([]SpanData, 0, EXPORT_BATCH_SIZE)
+	}
+	ticker := time.NewTicker(EXPORT_INTERVAL)
+	defer ticker.Stop()
+out:
+	for {
+		select {
+		case <-doneC:
+			break out
+		case <-ticker.C:
+			flush()
+		case d := <-spanC:
+			list = append(list, d)
+			if EXPORT_BATCH_SIZE <= len(list) {
+				flush()
+			}
+		}
+	}
+
+	exportMutex.Lock()
+	exportC = nil
+	exportMutex.Unlock()
+	// spans already queued still go out
+	for done := false; !done; {
+		select {
+		case d := <-spanC:
+			list = append(list, d)
+		default:
+			done = true
+		}
+	}
+	flush()
+	err := exporter.Close()
+	if err != nil {
+		log.Debug(err)
+	}
+}
+
+type fileExporter struct {
+	service string
+	f       *os.File
+}
+
+type fileSpan struct {
+	Service    string            `json:"service"`
+	TraceId    string            `json:"trace_id"`
+	SpanId     string            `json:"span_id"`
+	ParentId   string            `json:"parent_span_id,omitempty"`
+	Name       string            `json:"name"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	DurationMs float64           `json:"duration_ms"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Append spans to a file, one json object per line.
+func CreateFileExporter(filePath string, service string) (Exporter, error) {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return fileExporter{service: service, f: f}, nil
+}
+
+func (e1 fileExporter) Export(list []SpanData) error {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	for _, d := range list {
+		x := fileSpan{
+			Service:    e1.service,
+			TraceId:    d.TraceId.String(),
+			SpanId:     d.SpanId.String(),
+			Name:       d.Name,
+			Start:      d.Start,
+			End:        d.End,
+			DurationMs: float64(d.End.Sub(d.Start).Microseconds()) / 1000,
+			Attributes: d.Attributes,
+			Error:      d.Error,
+		}
+		if d.ParentId != (SpanId{}) {
+			x.ParentId = d.ParentId.String()
+		}
+		err := encoder.Encode(&x)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := e1.f.Write(buf.Bytes())
+	return err
+}
+
+func (e1 fileExporter) Close() error {
+	return e1.f.Close()
+}
+
+type otlpExporter struct {
+	url     string
+	service string
+	client  *http.Client
+}
+
+// Post spans to an OpenTelemetry collector using OTLP/HTTP with json encoding.
+func CreateOtlpExporter(url string, service string) Exporter {
+	return otlpExporter{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOk         = 1
+	otlpStatusError      = 2
+)
+
+func (e1 otlpExporter) Export(list []SpanData) error {
+	spans := make([]otlpSpan, len(list))
+	for i, d := range list {
+		spans[i] = otlpSpan{
+			TraceId:           d.TraceId.String(),
+			SpanId:            d.SpanId.String(),
+			Name:              d.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(d.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(d.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(d.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOk},
+		}
+		if d.ParentId != (SpanId{}) {
+			spans[i].ParentSpanId = d.ParentId.String()
+		}
+		if d.Error != "" {
+			spans[i].Status = otlpStatus{Code: otlpStatusError, Message: d.Error}
+		}
+	}
+	data, err := json.Marshal(&otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: otlpAttributes(map[string]string{"service.name": e1.service})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "solpipe"},
+			Spans: spans,
+		}},
+	}}})
+	if err != nil {
+		return err
+	}
+	resp, err := e1.client.Post(e1.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e1 otlpExporter) Close() error {
+	return nil
+}
+
+func otlpAttributes(m map[string]string) []otlpAttribute {
+	list := make([]otlpAttribute, 0, len(m))
+	for k, v := range m {
+		list = append(list, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
@@ -0,0 +1,222 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// W3C trace context header, carried in gRPC metadata between proxies
+const HEADER_TRACEPARENT = "traceparent"
+
+const ATTRIBUTE_TX_HASH = "tx.hash"
+
+type TraceId [16]byte
+
+type SpanId [8]byte
+
+func (id TraceId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// A timed step in the life of a transaction.  Spans nest via context.Context.
+type Span struct {
+	mutex      *sync.Mutex
+	traceId    TraceId
+	spanId     SpanId
+	parentId   SpanId
+	name       string
+	start      time.Time
+	attributes map[string]string
+	ended      bool
+}
+
+// What gets exported once a span ends.
+type SpanData struct {
+	TraceId    TraceId
+	SpanId     SpanId
+	ParentId   SpanId // zero for the root span
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+}
+
+type spanKey struct{}
+
+// parent received from another process
+type remoteKey struct{}
+
+type remoteParent struct {
+	traceId TraceId
+	spanId  SpanId
+}
+
+// Start a span as a child of the span in ctx.  Without a parent, the span starts a new trace.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	s := create(ctx, name)
+	if s.traceId == (TraceId{}) {
+		rand.Read(s.traceId[:])
+	}
+	return WithSpan(ctx, s), s
+}
+
+// Start a span for a transaction.  Without a parent, the trace id is taken from
+// the transaction hash so hops that lost the trace context still line up.
+func StartTx(ctx context.Context, name string, txHash sgo.Hash) (context.Context, *Span) {
+	s := create(ctx, name)
+	if s.traceId == (TraceId{}) {
+		copy(s.traceId[:], txHash[:])
+	}
+	s.SetAttribute(ATTRIBUTE_TX_HASH, txHash.String())
+	return WithSpan(ctx, s), s
+}
+
+func create(ctx context.Context, name string) *Span {
+	s := &Span{
+		mutex:      &sync.Mutex{},
+		name:       name,
+		start:      time.Now(),
+		attributes: make(map[string]string),
+	}
+	rand.Read(s.spanId[:])
+	if parent := FromContext(ctx); parent != nil {
+		s.traceId = parent.traceId
+		s.parentId = parent.spanId
+	} else if remote, ok := ctx.Value(remoteKey{}).(remoteParent); ok {
+		s.traceId = remote.traceId
+		s.parentId = remote.spanId
+	}
+	return s
+}
+
+// Carry span over to another context, ie when a goroutine works on behalf of
+// a request but runs under its own context.
+func WithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// return nil if there is no span in ctx
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+func (s *Span) TraceId() TraceId {
+	return s.traceId
+}
+
+func (s *Span) SpanId() SpanId {
+	return s.spanId
+}
+
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.attributes[key] = value
+	s.mutex.Unlock()
+}
+
+// Finish the span and hand it to the exporter.  Calling End more than once does nothing.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	d := SpanData{
+		TraceId:    s.traceId,
+		SpanId:     s.spanId,
+		ParentId:   s.parentId,
+		Name:       s.name,
+		Start:      s.start,
+		End:        time.Now(),
+		Attributes: make(map[string]string, len(s.attributes)),
+	}
+	for k, v := range s.attributes {
+		d.Attributes[k] = v
+	}
+	s.mutex.Unlock()
+	if err != nil {
+		d.Error = err.Error()
+	}
+	export(d)
+}
+
+// Add the span in ctx to the outgoing gRPC metadata.
+func Inject(ctx context.Context) context.Context {
+	s := FromContext(ctx)
+	if s == nil {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, HEADER_TRACEPARENT, s.traceparent())
+}
+
+// Read the parent span from incoming gRPC metadata.  Spans started from the
+// returned context belong to the caller's trace.
+func Extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	list := md.Get(HEADER_TRACEPARENT)
+	if len(list) == 0 {
+		return ctx
+	}
+	r, err := parseTraceparent(list[0])
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, r)
+}
+
+func (s *Span) traceparent() string {
+	return "00-" + s.traceId.String() + "-" + s.spanId.String() + "-01"
+}
+
+func parseTraceparent(x string) (r remoteParent, err error) {
+	parts := strings.Split(x, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		err = errors.New("bad traceparent")
+		return
+	}
+	t, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return
+	}
+	s, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return
+	}
+	if len(t) != len(r.traceId) || len(s) != len(r.spanId) {
+		err = errors.New("bad traceparent")
+		return
+	}
+	copy(r.traceId[:], t)
+	copy(r.spanId[:], s)
+	if r.traceId == (TraceId{}) || r.spanId == (SpanId{}) {
+		err = errors.New("bad traceparent")
+	}
+	return
+}
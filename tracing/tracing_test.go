@@ -0,0 +1,146 @@
+package tracing_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sgo "github.com/SolmateDev/solana-go"
+	"github.com/solpipe/solpipe-tool/tracing"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPropagation(t *testing.T) {
+	var txHash sgo.Hash
+	copy(txHash[:], []byte("some transaction hash, 32 bytes!"))
+	ctx, root := tracing.StartTx(context.Background(), "client.submit", txHash)
+	var expected tracing.TraceId
+	copy(expected[:], txHash[:])
+	if root.TraceId() != expected {
+		t.Fatalf("trace id %s not taken from the tx hash", root.TraceId().String())
+	}
+
+	// simulate the hop over grpc
+	ctx = tracing.Inject(ctx)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		t.Fatal("no outgoing metadata")
+	}
+	serverCtx := tracing.Extract(metadata.NewIncomingContext(context.Background(), md))
+	_, child := tracing.StartTx(serverCtx, "server.submit", txHash)
+	if child.TraceId() != root.TraceId() {
+		t.Fatalf("trace %s != %s", child.TraceId().String(), root.TraceId().String())
+	}
+	if child.SpanId() == root.SpanId() {
+		t.Fatal("child reused the parent span id")
+	}
+
+	_, other := tracing.Start(context.Background(), "other")
+	if other.TraceId() == root.TraceId() {
+		t.Fatal("unrelated span joined the trace")
+	}
+}
+
+func TestExport(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "spans.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	signalC := tracing.Run(ctx, filePath, "test")
+	spanCtx, parent := tracing.Start(ctx, "parent")
+	_, child := tracing.Start(spanCtx, "child")
+	child.SetAttribute("slot", "5")
+	child.End(errors.New("expired"))
+	parent.End(nil)
+	cancel()
+
+	lines := waitForLines(t, filePath, 2)
+	var x struct {
+		Service    string            `json:"service"`
+		TraceId    string            `json:"trace_id"`
+		ParentId   string            `json:"parent_span_id"`
+		Name       string            `json:"name"`
+		Attributes map[string]string `json:"attributes"`
+		Error      string            `json:"error"`
+	}
+	err := json.Unmarshal([]byte(lines[0]), &x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Name != "child" || x.Service != "test" || x.Error != "expired" || x.Attributes["slot"] != "5" {
+		t.Fatalf("unexpected span %s", lines[0])
+	}
+	if x.ParentId != parent.SpanId().String() || x.TraceId != parent.TraceId().String() {
+		t.Fatalf("child not linked to the parent: %s", lines[0])
+	}
+	select {
+	case err = <-signalC:
+		t.Fatal(err)
+	default:
+	}
+
+	// otlp
+	bodyC := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodyC <- data
+	}))
+	defer server.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tracing.Run(ctx, server.URL+"/v1/traces", "test")
+	_, span := tracing.Start(ctx, "otlp")
+	span.End(nil)
+	var data []byte
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	case data = <-bodyC:
+	}
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceId string `json:"traceId"`
+					Name    string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 || spans[0].Name != "otlp" || spans[0].TraceId != span.TraceId().String() {
+		t.Fatalf("unexpected request %s", string(data))
+	}
+}
+
+func waitForLines(t *testing.T, filePath string, n int) []string {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		f, err := os.Open(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := []string{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+		if n <= len(lines) {
+			return lines
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected %d spans", n)
+	return nil
+}
@@ -14,7 +14,6 @@ import (
 	"strings"
 
 	sgo "github.com/SolmateDev/solana-go"
-	bin "github.com/gagliardetto/binary"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -29,18 +28,8 @@ func readSeed() (seed []byte, err error) {
 
 func iterateSeed(seed []byte, i uint64) (reader io.Reader, err error) {
 	bigArray := make([]byte, len(seed)+8)
-	writer := bin.NewBinEncoder(bytes.NewBuffer(bigArray))
-
-	err = writer.WriteUint64(i, binary.BigEndian)
-	if err != nil {
-		return
-	}
-	for i := 0; i < len(seed); i++ {
-		err = writer.WriteByte(seed[i])
-		if err != nil {
-			return
-		}
-	}
+	binary.BigEndian.PutUint64(bigArray[0:8], i)
+	copy(bigArray[8:], seed)
 	x := sha512.Sum512(bigArray)
 	reader = bytes.NewBuffer(x[:])
 
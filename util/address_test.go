@@ -0,0 +1,28 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestIterateSeed(t *testing.T) {
+	seed, err := readSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]uint64)
+	for i := uint64(0); i < 10; i++ {
+		reader, err := iterateSeed(seed, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, _, err := ed25519.GenerateKey(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j, present := seen[string(pub)]; present {
+			t.Fatalf("iterations %d and %d produced the same key", j, i)
+		}
+		seen[string(pub)] = i
+	}
+}
@@ -0,0 +1,152 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type ApiKeyPlacement uint8
+
+const (
+	API_KEY_HEADER ApiKeyPlacement = 0 // sent as an http header
+	API_KEY_QUERY  ApiKeyPlacement = 1 // added to the url query
+	API_KEY_PATH   ApiKeyPlacement = 2 // appended to the url path
+)
+
+// An API key for a commercial RPC provider.  Apply the key to both the rpc and
+// websocket urls.
+type ApiKey struct {
+	Placement ApiKeyPlacement
+	Name      string // header or query parameter name
+	Prefix    string // put in front of the key in a header (ie "Bearer ")
+	Key       string
+}
+
+// how each provider expects to receive the key
+var apiKeyPresets = map[string]ApiKey{
+	"bearer":    {Placement: API_KEY_HEADER, Name: "Authorization", Prefix: "Bearer "},
+	"helius":    {Placement: API_KEY_QUERY, Name: "api-key"},
+	"quicknode": {Placement: API_KEY_PATH},
+	"alchemy":   {Placement: API_KEY_PATH},
+	"triton":    {Placement: API_KEY_PATH},
+	"path":      {Placement: API_KEY_PATH},
+}
+
+// Parse an API key of the form:
+//
+//	KEY                    Authorization: Bearer KEY
+//	bearer:KEY             Authorization: Bearer KEY
+//	header:NAME:KEY        NAME: KEY
+//	query:NAME:KEY         ?NAME=KEY
+//	path:KEY               /KEY appended to the url path
+//	helius:KEY             ?api-key=KEY
+//	quicknode:KEY          /KEY appended to the url path
+//	alchemy:KEY            /KEY appended to the url path
+//	triton:KEY             /KEY appended to the url path
+func ParseApiKey(s string) (ans ApiKey, err error) {
+	if len(s) == 0 {
+		err = errors.New("blank api key")
+		return
+	}
+	x := strings.SplitN(s, ":", 2)
+	if len(x) == 1 {
+		ans = apiKeyPresets["bearer"]
+		ans.Key = s
+		return
+	}
+	switch x[0] {
+	case "header", "query":
+		y := strings.SplitN(x[1], ":", 2)
+		if len(y) != 2 || len(y[0]) == 0 {
+			err = fmt.Errorf("api key must be of the form %s:NAME:KEY", x[0])
+			return
+		}
+		ans.Placement = API_KEY_HEADER
+		if x[0] == "query" {
+			ans.Placement = API_KEY_QUERY
+		}
+		ans.Name = y[0]
+		ans.Key = y[1]
+	default:
+		preset, present := apiKeyPresets[x[0]]
+		if !present {
+			err = fmt.Errorf("unknown api key provider %s", x[0])
+			return
+		}
+		ans = preset
+		ans.Key = x[1]
+	}
+	if len(ans.Key) == 0 {
+		err = errors.New("blank api key")
+	}
+	return
+}
+
+// Add the key to the url or to the headers.  The headers are modified in place
+// and the new url is returned.
+func (k ApiKey) Apply(rawUrl string, headers http.Header) (string, error) {
+	if k.Placement == API_KEY_HEADER {
+		headers.Set(k.Name, k.Prefix+k.Key)
+		return rawUrl, nil
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+	switch k.Placement {
+	case API_KEY_QUERY:
+		q := u.Query()
+		q.Set(k.Name, k.Key)
+		u.RawQuery = q.Encode()
+	case API_KEY_PATH:
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + k.Key
+	default:
+		return "", errors.New("unknown api key placement")
+	}
+	return u.String(), nil
+}
+
+// replace the key in s so that it can be logged
+func (k ApiKey) Redact(s string) string {
+	if len(k.Key) == 0 {
+		return s
+	}
+	s = strings.ReplaceAll(s, k.Key, "[REDACTED]")
+	// the key may have been escaped in a url
+	if escaped := url.QueryEscape(k.Key); escaped != k.Key {
+		s = strings.ReplaceAll(s, escaped, "[REDACTED]")
+	}
+	return s
+}
+
+// A logrus hook that redacts the key from every message and string field.
+// Add it with log.AddHook once the key has been parsed.
+func (k ApiKey) LogHook() log.Hook {
+	return apiKeyHook{k: k}
+}
+
+type apiKeyHook struct {
+	k ApiKey
+}
+
+func (h apiKeyHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h apiKeyHook) Fire(entry *log.Entry) error {
+	entry.Message = h.k.Redact(entry.Message)
+	for name, v := range entry.Data {
+		switch x := v.(type) {
+		case string:
+			entry.Data[name] = h.k.Redact(x)
+		case error:
+			entry.Data[name] = h.k.Redact(x.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestParseApiKey(t *testing.T) {
+	for _, x := range []struct {
+		s   string
+		key ApiKey
+	}{
+		{"abc", ApiKey{Placement: API_KEY_HEADER, Name: "Authorization", Prefix: "Bearer ", Key: "abc"}},
+		{"bearer:abc", ApiKey{Placement: API_KEY_HEADER, Name: "Authorization", Prefix: "Bearer ", Key: "abc"}},
+		{"header:X-Token:a:b", ApiKey{Placement: API_KEY_HEADER, Name: "X-Token", Key: "a:b"}},
+		{"query:token:abc", ApiKey{Placement: API_KEY_QUERY, Name: "token", Key: "abc"}},
+		{"helius:abc", ApiKey{Placement: API_KEY_QUERY, Name: "api-key", Key: "abc"}},
+		{"quicknode:abc", ApiKey{Placement: API_KEY_PATH, Key: "abc"}},
+		{"path:abc", ApiKey{Placement: API_KEY_PATH, Key: "abc"}},
+	} {
+		key, err := ParseApiKey(x.s)
+		if err != nil {
+			t.Fatalf("%s: %s", x.s, err.Error())
+		}
+		if key != x.key {
+			t.Errorf("%s: %+v != %+v", x.s, key, x.key)
+		}
+	}
+	for _, s := range []string{"", "header:abc", "query::abc", "unknown:abc", "helius:"} {
+		_, err := ParseApiKey(s)
+		if err == nil {
+			t.Errorf("accepted %s", s)
+		}
+	}
+}
+
+func TestApiKeyApply(t *testing.T) {
+	for _, x := range []struct {
+		key     string
+		url     string
+		ans     string
+		headers http.Header
+	}{
+		{"abc", "https://rpc.example.com", "https://rpc.example.com", http.Header{"Authorization": {"Bearer abc"}}},
+		{"helius:a b", "https://rpc.example.com/?x=1", "https://rpc.example.com/?api-key=a+b&x=1", http.Header{}},
+		{"path:abc", "https://rpc.example.com", "https://rpc.example.com/abc", http.Header{}},
+		{"path:abc", "https://rpc.example.com/solana/", "https://rpc.example.com/solana/abc", http.Header{}},
+		{"path:abc", "wss://rpc.example.com/ws?x=1", "wss://rpc.example.com/ws/abc?x=1", http.Header{}},
+	} {
+		key, err := ParseApiKey(x.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := http.Header{}
+		ans, err := key.Apply(x.url, headers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ans != x.ans {
+			t.Errorf("%s on %s: %s != %s", x.key, x.url, ans, x.ans)
+		}
+		if len(headers) != len(x.headers) || headers.Get("Authorization") != x.headers.Get("Authorization") {
+			t.Errorf("%s: headers %+v", x.key, headers)
+		}
+	}
+}
+
+func TestApiKeyRedact(t *testing.T) {
+	key, err := ParseApiKey("helius:a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := key.Apply("https://rpc.example.com", http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := key.Redact("key a/b in " + u); strings.Contains(s, "a/b") || strings.Contains(s, "a%2Fb") {
+		t.Fatalf("not redacted: %s", s)
+	}
+
+	buf := new(bytes.Buffer)
+	logger := log.New()
+	logger.SetOutput(buf)
+	logger.AddHook(key.LogHook())
+	logger.WithField("url", u).Info("dialing a/b")
+	if strings.Contains(buf.String(), "a/b") || strings.Contains(buf.String(), "a%2Fb") {
+		t.Fatalf("not redacted: %s", buf.String())
+	}
+}
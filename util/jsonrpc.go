@@ -0,0 +1,75 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Solana JSON RPC methods that can be passed through to an rpc node on behalf
+// of someone else.  Anything that writes, such as requestAirdrop, or that is
+// expensive to serve, such as getProgramAccounts, is left out.
+var READ_ONLY_METHODS = map[string]bool{
+	"getAccountInfo":                    true,
+	"getBalance":                        true,
+	"getBlockHeight":                    true,
+	"getEpochInfo":                      true,
+	"getEpochSchedule":                  true,
+	"getFeeForMessage":                  true,
+	"getFirstAvailableBlock":            true,
+	"getGenesisHash":                    true,
+	"getHealth":                         true,
+	"getLatestBlockhash":                true,
+	"getMinimumBalanceForRentExemption": true,
+	"getMultipleAccounts":               true,
+	"getRecentPrioritizationFees":       true,
+	"getSignatureStatuses":              true,
+	"getSlot":                           true,
+	"getTokenAccountBalance":            true,
+	"getTokenAccountsByOwner":           true,
+	"getTransaction":                    true,
+	"getVersion":                        true,
+	"isBlockhashValid":                  true,
+	"simulateTransaction":               true,
+}
+
+// Websocket methods that can be passed through to an rpc node.
+var SUBSCRIPTION_METHODS = map[string]bool{
+	"accountSubscribe":     true,
+	"accountUnsubscribe":   true,
+	"signatureSubscribe":   true,
+	"signatureUnsubscribe": true,
+	"slotSubscribe":        true,
+	"slotUnsubscribe":      true,
+}
+
+// The methods called in a JSON RPC payload, which is either a single request
+// or a batch.
+func JsonRpcMethods(payload []byte) ([]string, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 {
+		return nil, errors.New("blank payload")
+	}
+	type request struct {
+		Method string `json:"method"`
+	}
+	list := make([]request, 0, 1)
+	if payload[0] == '[' {
+		err := json.Unmarshal(payload, &list)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r := request{}
+		err := json.Unmarshal(payload, &r)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	ans := make([]string, len(list))
+	for i, r := range list {
+		ans[i] = r.Method
+	}
+	return ans, nil
+}
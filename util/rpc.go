@@ -11,6 +11,7 @@ import (
 
 	sgorpc "github.com/SolmateDev/solana-go/rpc"
 	sgows "github.com/SolmateDev/solana-go/rpc/ws"
+	"github.com/solpipe/solpipe-tool/state/sub"
 )
 
 func GetGID() uint64 {
@@ -26,6 +27,7 @@ type RpcConfig struct {
 	Rpc     string
 	Ws      string
 	Headers http.Header
+	ApiKey  *ApiKey // applied to both urls when connecting; optional
 }
 
 func RpcConfigFromEnv() (*RpcConfig, error) {
@@ -39,6 +41,14 @@ func RpcConfigFromEnv() (*RpcConfig, error) {
 	if !present {
 		return nil, errors.New("no ws url")
 	}
+	apiKey, present := os.LookupEnv("API_KEY")
+	if present && 0 < len(apiKey) {
+		k, err := ParseApiKey(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		config.ApiKey = &k
+	}
 	return config, nil
 }
 
@@ -50,11 +60,24 @@ func RpcConnect(ctx context.Context, config *RpcConfig) (*sgorpc.Client, *sgows.
 			return nil, nil, err
 		}
 	}
-	if config.Headers == nil {
-		config.Headers = http.Header{}
+	headers := http.Header{}
+	for k, v := range config.Headers {
+		headers[k] = v
+	}
+	rpcUrl := config.Rpc
+	wsUrl := config.Ws
+	if config.ApiKey != nil {
+		rpcUrl, err = config.ApiKey.Apply(rpcUrl, headers)
+		if err != nil {
+			return nil, nil, err
+		}
+		wsUrl, err = config.ApiKey.Apply(wsUrl, headers)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	rpcClient := sgorpc.NewWithHeaders(config.Rpc, config.Headers)
-	wsClient, err := sgows.ConnectWithHeaders(ctx, config.Ws, config.Headers)
+	rpcClient := sgorpc.NewWithHeaders(rpcUrl, headers)
+	wsClient, err := sub.Dial(ctx, wsUrl, headers)
 	if err != nil {
 		return nil, nil, err
 	}
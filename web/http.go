@@ -1,18 +1,25 @@
 package web
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/solpipe/solpipe-tool/util"
 )
 
 const HEADER_NAME = "Internal-Route"
 const HEADER_JSON_RPC = "jsonrpc"
 const HEADER_GRPC = "grpc"
 
+// largest JSON RPC request passed through to the rpc node
+const JSON_RPC_BODY_MAX = 1 << 20
+
 func (e1 external) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	//log.Debugf("serving url=%s  or uri path=%s", r.URL.String(), r.URL.Path)
@@ -24,23 +31,17 @@ func (e1 external) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			log.Debug("going to grpc by header")
 			// grpc package name is admin, so we filter by that in the uri path
 			if r.Header.Get("Upgrade") == "websocket" {
-				e1.ws_proxy(w, r, e1.grpcWebUrl)
+				e1.ws_proxy(w, r, wsRemote(e1.grpcWebUrl, r), nil, nil)
 			} else {
-				e1.proxy_http(w, r, e1.grpcWebUrl)
+				e1.proxy_http(w, r, e1.grpcWebUrl, nil)
 			}
 		case HEADER_JSON_RPC:
-			if r.Header.Get("Upgrade") == "websocket" {
-				log.Debugf("going to json rpc!!! with remote=%s", e1.wsUrl)
-				e1.ws_proxy(w, r, e1.wsUrl)
-			} else {
-				log.Debugf("going to json rpc!!! with remote=%s", e1.rpcUrl)
-				e1.proxy_http(w, r, e1.rpcUrl)
-			}
+			e1.jsonrpc(w, r)
 		default:
 			log.Debugf("error with header=%s value=%s", HEADER_NAME, routeName[0])
 			w.WriteHeader(http.StatusNotFound)
-			return
 		}
+		return
 	}
 
 	// Implement route forwarding
@@ -67,37 +68,108 @@ func (e1 external) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 		}
 	case "/jsonrpc":
-		if r.Header.Get("Upgrade") == "websocket" {
-			log.Debugf("going to json rpc!!! with remote=%s", e1.wsUrl)
-			e1.ws_proxy(w, r, e1.wsUrl)
-		} else {
-			log.Debugf("going to json rpc!!! with remote=%s", e1.rpcUrl)
-			e1.proxy_http(w, r, e1.rpcUrl)
-		}
+		e1.jsonrpc(w, r)
 	case "/ws":
 		if r.Header.Get("Upgrade") == "websocket" {
-			e1.ws_proxy(w, r, e1.frontendUrl)
+			e1.ws_proxy(w, r, wsRemote(e1.frontendUrl, r), nil, nil)
 		} else {
-			e1.proxy_http(w, r, e1.frontendUrl)
+			e1.proxy_http(w, r, e1.frontendUrl, nil)
 		}
 	default:
 		//log.Debug("going to default")
 		if r.Header.Get("Upgrade") == "websocket" {
-			e1.ws_proxy(w, r, e1.frontendUrl)
+			e1.ws_proxy(w, r, wsRemote(e1.frontendUrl, r), nil, nil)
 		} else {
-			e1.proxy_http(w, r, e1.frontendUrl)
+			e1.proxy_http(w, r, e1.frontendUrl, nil)
 		}
 	}
 }
 
 // pass on http requests to a front end that may be running React or Vue.
-func (e1 external) proxy_http(w http.ResponseWriter, r *http.Request, remote *url.URL) {
+// The headers, if any, are set on the request sent to the remote.
+func (e1 external) proxy_http(w http.ResponseWriter, r *http.Request, remote *url.URL, headers http.Header) {
 
 	if remote == nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 	proxy := httputil.NewSingleHostReverseProxy(remote)
+	if 0 < len(headers) {
+		director := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			director(req)
+			for k, v := range headers {
+				req.Header[k] = v
+			}
+		}
+	}
 
 	proxy.ServeHTTP(w, r)
 }
+
+// Pass Solana JSON RPC requests through to the rpc node with the api key, if
+// any, attached.  Since anyone who can reach this server can use the key, only
+// util.READ_ONLY_METHODS and, over the websocket, util.SUBSCRIPTION_METHODS
+// are passed through.  Requests go to the rpc and websocket urls as
+// configured; the local path is not appended because some providers put the
+// key in the path.
+func (e1 external) jsonrpc(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") == "websocket" {
+		log.Debugf("going to json rpc websocket with remote=%s", e1.wsUrl.Redacted())
+		e1.ws_proxy(w, r, e1.wsUrl.String(), e1.rpcHeaders, checkSubscription)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(r.Body, JSON_RPC_BODY_MAX))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	methodList, err := util.JsonRpcMethods(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, method := range methodList {
+		if !util.READ_ONLY_METHODS[method] {
+			http.Error(w, fmt.Sprintf("method %s is not allowed", method), http.StatusForbidden)
+			return
+		}
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, e1.rpcUrl.String(), bytes.NewReader(payload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for k, v := range e1.rpcHeaders {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Debugf("json rpc proxy: %s", err.Error())
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func checkSubscription(payload []byte) error {
+	methodList, err := util.JsonRpcMethods(payload)
+	if err != nil {
+		return err
+	}
+	for _, method := range methodList {
+		if !util.SUBSCRIPTION_METHODS[method] {
+			return fmt.Errorf("method %s is not allowed over websocket", method)
+		}
+	}
+	return nil
+}
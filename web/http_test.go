@@ -0,0 +1,79 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/solpipe/solpipe-tool/util"
+)
+
+// The key is placed at the end of the rpc url path, so the local /jsonrpc path
+// must not be appended to it.
+func TestJsonRpcProxy(t *testing.T) {
+	pathList := make([]string, 0)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pathList = append(pathList, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":5}`))
+	}))
+	t.Cleanup(node.Close)
+	key, err := util.ParseApiKey("path:secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := http.Header{}
+	rpcUrlStr, err := key.Apply(node.URL, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcUrl, err := url.Parse(rpcUrlStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1 := external{ctx: context.Background(), rpcUrl: rpcUrl, rpcHeaders: headers}
+	post := func(payload string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		e1.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jsonrpc", strings.NewReader(payload)))
+		return w
+	}
+
+	w := post(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)
+	data, _ := io.ReadAll(w.Body)
+	if w.Code != http.StatusOK || string(data) != `{"jsonrpc":"2.0","id":1,"result":5}` {
+		t.Fatalf("%d %s", w.Code, string(data))
+	}
+	if len(pathList) != 1 || pathList[0] != "/secret" {
+		t.Fatalf("forwarded to %+v", pathList)
+	}
+
+	for _, payload := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"requestAirdrop"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"sendTransaction"}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"getSlot"},{"jsonrpc":"2.0","id":2,"method":"getProgramAccounts"}]`,
+	} {
+		w = post(payload)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s returned %d", payload, w.Code)
+		}
+	}
+	if w = post(`not json`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad payload returned %d", w.Code)
+	}
+	if len(pathList) != 1 {
+		t.Fatalf("forwarded %d requests", len(pathList))
+	}
+}
+
+func TestCheckSubscription(t *testing.T) {
+	if err := checkSubscription([]byte(`{"jsonrpc":"2.0","id":1,"method":"slotSubscribe"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if checkSubscription([]byte(`{"jsonrpc":"2.0","id":1,"method":"blockSubscribe"}`)) == nil {
+		t.Fatal("blockSubscribe passed")
+	}
+}
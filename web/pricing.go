@@ -1,17 +1,176 @@
 package web
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
+	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	prc "github.com/solpipe/solpipe-tool/state/pricing"
 )
 
+// What the /pricing handlers read; implemented by prc.Pricing.
+type priceSource interface {
+	Slot() (uint64, error)
+	Current() ([]prc.Quote, error)
+	Forecast() ([]prc.Quote, error)
+	History(pipelineId sgo.PublicKey, q ts.Query) ([]prc.Quote, error)
+	BidBook(payoutId sgo.PublicKey) (pyt.BidStatus, error)
+}
+
+type PriceQuote struct {
+	PipelineId    string  `json:"pipeline_id"`
+	PayoutId      string  `json:"payout_id"`
+	Start         uint64  `json:"start"`
+	Length        uint64  `json:"length"`
+	CapacityTps   float64 `json:"capacity_tps"`
+	TotalDeposits uint64  `json:"total_deposits"`
+	PricePerTps   float64 `json:"price_per_tps"`
+}
+
+type BidBook struct {
+	PayoutId      string      `json:"payout_id"`
+	IsFinal       bool        `json:"is_final"`
+	TotalDeposits uint64      `json:"total_deposits"`
+	Bids          []BookEntry `json:"bids"`
+}
+
+type BookEntry struct {
+	User    string `json:"user"`
+	Deposit uint64 `json:"deposit"`
+}
+
+// Serve pricing as JSON:
+//
+//	/pricing/slot                      latest slot
+//	/pricing/current                   quotes for periods that are running now
+//	/pricing/forecast                  quotes for upcoming periods
+//	/pricing/history?pipeline=ID       quotes for past periods; takes start, finish and step (in slots)
+//	/pricing/bids?payout=ID            bid book of a payout
 func (e1 external) pricing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 	switch r.URL.Path[len("/pricing"):] {
 	case "/slot":
-		log.Debug("slot")
-		w.WriteHeader(http.StatusNotFound)
+		slot, err := e1.pc.Slot()
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeJson(w, SlotTick{Time: time.Now(), Slot: slot})
+	case "/current":
+		list, err := e1.pc.Current()
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeJson(w, priceQuoteList(list))
+	case "/forecast":
+		list, err := e1.pc.Forecast()
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeJson(w, priceQuoteList(list))
+	case "/history":
+		pipelineId, err := sgo.PublicKeyFromBase58(r.URL.Query().Get("pipeline"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		q, err := parseSlotQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		list, err := e1.pc.History(pipelineId, q)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJson(w, priceQuoteList(list))
+	case "/bids":
+		payoutId, err := sgo.PublicKeyFromBase58(r.URL.Query().Get("payout"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		bs, err := e1.pc.BidBook(payoutId)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		book := BidBook{
+			PayoutId:      payoutId.String(),
+			IsFinal:       bs.IsFinal,
+			TotalDeposits: bs.TotalDeposits,
+			Bids:          make([]BookEntry, 0, len(bs.Bid)),
+		}
+		for _, bid := range bs.Bid {
+			if !bid.IsBlank {
+				book.Bids = append(book.Bids, BookEntry{User: bid.User.String(), Deposit: bid.Deposit})
+			}
+		}
+		writeJson(w, book)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
+
+func priceQuoteList(list []prc.Quote) []PriceQuote {
+	ans := make([]PriceQuote, len(list))
+	for i, q := range list {
+		ans[i] = PriceQuote{
+			PipelineId:    q.PipelineId.String(),
+			PayoutId:      q.PayoutId.String(),
+			Start:         q.Period.Start,
+			Length:        q.Period.Length,
+			CapacityTps:   q.Capacity,
+			TotalDeposits: q.TotalDeposits,
+			PricePerTps:   q.Price(),
+		}
+	}
+	return ans
+}
+
+// read start, finish and step from the url query; missing values are 0
+func parseSlotQuery(r *http.Request) (q ts.Query, err error) {
+	v := r.URL.Query()
+	for _, x := range []struct {
+		name string
+		ptr  *uint64
+	}{
+		{"start", &q.Start},
+		{"finish", &q.Finish},
+		{"step", &q.Step},
+	} {
+		s := v.Get(x.name)
+		if len(s) == 0 {
+			continue
+		}
+		*x.ptr, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return
+		}
+	}
+	_, err = q.Normalize()
+	return
+}
+
+func writeJson(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		log.Debug(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sgo "github.com/SolmateDev/solana-go"
+	cba "github.com/solpipe/cba"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	pyt "github.com/solpipe/solpipe-tool/state/payout"
+	prc "github.com/solpipe/solpipe-tool/state/pricing"
+)
+
+type testPriceSource struct {
+	quote   prc.Quote
+	query   *ts.Query
+	payout  sgo.PublicKey
+	bidList []cba.Bid
+}
+
+func (s testPriceSource) Slot() (uint64, error) {
+	return 77, nil
+}
+
+func (s testPriceSource) Current() ([]prc.Quote, error) {
+	return []prc.Quote{s.quote}, nil
+}
+
+func (s testPriceSource) Forecast() ([]prc.Quote, error) {
+	return []prc.Quote{}, nil
+}
+
+func (s testPriceSource) History(pipelineId sgo.PublicKey, q ts.Query) ([]prc.Quote, error) {
+	*s.query = q
+	return []prc.Quote{s.quote}, nil
+}
+
+func (s testPriceSource) BidBook(payoutId sgo.PublicKey) (pyt.BidStatus, error) {
+	if !payoutId.Equals(s.payout) {
+		return pyt.BidStatus{}, errors.New("unknown payout")
+	}
+	return pyt.BidStatus{Bid: s.bidList, IsFinal: true, TotalDeposits: 30}, nil
+}
+
+func TestPricing(t *testing.T) {
+	pipelineId := sgo.NewWallet().PublicKey()
+	payoutId := sgo.NewWallet().PublicKey()
+	user := sgo.NewWallet().PublicKey()
+	src := testPriceSource{
+		quote: prc.Quote{
+			PipelineId:    pipelineId,
+			PayoutId:      payoutId,
+			Period:        cba.Period{Start: 100, Length: 50},
+			Capacity:      10,
+			TotalDeposits: 30,
+		},
+		query:   new(ts.Query),
+		payout:  payoutId,
+		bidList: []cba.Bid{{User: user, Deposit: 30}, {IsBlank: true}},
+	}
+	e1 := external{ctx: context.Background(), pc: src}
+	get := func(target string, out interface{}) int {
+		w := httptest.NewRecorder()
+		e1.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code == http.StatusOK && out != nil {
+			if err := json.NewDecoder(w.Body).Decode(out); err != nil {
+				t.Fatalf("%s: %s", target, err.Error())
+			}
+		}
+		return w.Code
+	}
+
+	var tick SlotTick
+	if code := get("/pricing/slot", &tick); code != http.StatusOK || tick.Slot != 77 {
+		t.Fatalf("slot %d %+v", code, tick)
+	}
+
+	var list []PriceQuote
+	if code := get("/pricing/current", &list); code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("current %d %+v", code, list)
+	}
+	q := list[0]
+	if q.PipelineId != pipelineId.String() || q.PayoutId != payoutId.String() || q.Start != 100 || q.Length != 50 || q.PricePerTps != 3 {
+		t.Fatalf("quote %+v", q)
+	}
+	if code := get("/pricing/forecast", &list); code != http.StatusOK || len(list) != 0 {
+		t.Fatalf("forecast %d %+v", code, list)
+	}
+
+	code := get("/pricing/history?pipeline="+pipelineId.String()+"&start=10&finish=20&step=5", &list)
+	if code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("history %d %+v", code, list)
+	}
+	if src.query.Start != 10 || src.query.Finish != 20 || src.query.Step != 5 {
+		t.Fatalf("history query %+v", *src.query)
+	}
+
+	var book BidBook
+	if code := get("/pricing/bids?payout="+payoutId.String(), &book); code != http.StatusOK {
+		t.Fatalf("bids %d", code)
+	}
+	if !book.IsFinal || book.TotalDeposits != 30 || len(book.Bids) != 1 || book.Bids[0].User != user.String() {
+		t.Fatalf("book %+v", book)
+	}
+
+	for _, x := range []struct {
+		target string
+		code   int
+	}{
+		{"/pricing/history?pipeline=bad", http.StatusBadRequest},
+		{"/pricing/history?pipeline=" + pipelineId.String() + "&start=x", http.StatusBadRequest},
+		{"/pricing/bids?payout=" + pipelineId.String(), http.StatusNotFound},
+		{"/pricing/unknown", http.StatusNotFound},
+	} {
+		if code := get(x.target, nil); code != x.code {
+			t.Errorf("%s returned %d, expected %d", x.target, code, x.code)
+		}
+	}
+	w := httptest.NewRecorder()
+	e1.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pricing/slot", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("post returned %d", w.Code)
+	}
+}
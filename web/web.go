@@ -9,7 +9,8 @@ import (
 
 	sgo "github.com/SolmateDev/solana-go"
 	log "github.com/sirupsen/logrus"
-	"github.com/solpipe/solpipe-tool/ds/ts/lite"
+	ts "github.com/solpipe/solpipe-tool/ds/ts"
+	tsb "github.com/solpipe/solpipe-tool/ds/ts/backend"
 	prc "github.com/solpipe/solpipe-tool/state/pricing"
 	rtr "github.com/solpipe/solpipe-tool/state/router"
 )
@@ -23,7 +24,8 @@ type external struct {
 	rpcUrl      *url.URL
 	wsUrl       *url.URL
 	grpcWebUrl  *url.URL
-	pc          prc.Pricing
+	rpcHeaders  http.Header
+	pc          priceSource
 }
 
 type Configuration struct {
@@ -32,6 +34,8 @@ type Configuration struct {
 	RpcUrl      string
 	WsUrl       string
 	GrpcWebUrl  string
+	Headers     http.Header      // sent with requests proxied to the rpc and websocket urls (ie api keys)
+	Db          ts.Configuration // where to keep historical pricing data
 }
 
 func Run(
@@ -54,7 +58,7 @@ func Run(
 		}
 	}
 
-	handle, err := lite.Create(ctxC)
+	handle, err := tsb.Create(ctxC, config.Db)
 	if err != nil {
 		errorC <- err
 		cancel()
@@ -103,6 +107,7 @@ func Run(
 		rpcUrl:      rpcUrl,
 		wsUrl:       wsUrl,
 		grpcWebUrl:  grpcWebUrl,
+		rpcHeaders:  config.Headers,
 		pc:          pc,
 	}
 
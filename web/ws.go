@@ -59,25 +59,38 @@ func writeConn(conn *websocket.Conn, typeName string, data interface{}) error {
 	})
 }
 
-func (e1 external) ws_proxy(w http.ResponseWriter, r *http.Request, remote *url.URL) {
+// The websocket url of remote with the local path appended.
+func wsRemote(remote *url.URL, r *http.Request) string {
+	if remote == nil {
+		return ""
+	}
+	// work on a copy so that a query string stays at the end
+	u := *remote
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	u.Path += r.URL.Path
+	if strings.Contains(u.Host, "3001") {
+		u.Path += "ws"
+	}
+	return u.String()
+}
+
+// Pass websocket messages between the client and wsRemoteStr.  If check is
+// set, every message from the client must pass it.
+func (e1 external) ws_proxy(w http.ResponseWriter, r *http.Request, wsRemoteStr string, headers http.Header, check func([]byte) error) {
 	clientCtx := r.Context()
 	clientDoneC := clientCtx.Done()
 	doneC := e1.ctx.Done()
 
-	var wsRemoteStr string
-	remoteStr := remote.String()
-	if strings.HasPrefix(remoteStr, "http") {
-		wsRemoteStr = "ws" + remoteStr[len("http"):] + r.URL.Path
-	} else if strings.HasPrefix(remoteStr, "https") {
-		wsRemoteStr = "wss" + remoteStr[len("https"):] + r.URL.Path
-	} else {
-		wsRemoteStr = remoteStr + r.URL.Path
-	}
-
-	if strings.Contains(wsRemoteStr, "3001") && !strings.Contains(wsRemoteStr, "jsonrpc") {
-		wsRemoteStr += "ws"
+	if len(wsRemoteStr) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
 	}
-	log.Debugf("websocket proxy to remote=%s with local=%s", wsRemoteStr, r.URL.String())
+	log.Debugf("websocket proxy with local=%s", r.URL.String())
 	connLocal, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Debug("Error during connection upgradation:", err)
@@ -85,7 +98,7 @@ func (e1 external) ws_proxy(w http.ResponseWriter, r *http.Request, remote *url.
 	}
 	defer connLocal.Close()
 
-	connRemote, _, err := websocket.DefaultDialer.DialContext(e1.ctx, wsRemoteStr, nil)
+	connRemote, _, err := websocket.DefaultDialer.DialContext(e1.ctx, wsRemoteStr, headers)
 	if err != nil {
 		log.Debugf("websocket(%s) Error: %s", wsRemoteStr, err.Error())
 		return
@@ -94,8 +107,8 @@ func (e1 external) ws_proxy(w http.ResponseWriter, r *http.Request, remote *url.
 
 	// local to remote
 	errorC := make(chan error, 2)
-	go loopMessagePass(connLocal, connRemote, errorC)
-	go loopMessagePass(connRemote, connLocal, errorC)
+	go loopMessagePass(connLocal, connRemote, check, errorC)
+	go loopMessagePass(connRemote, connLocal, nil, errorC)
 
 out:
 
@@ -119,7 +132,7 @@ out:
 
 }
 
-func loopMessagePass(a *websocket.Conn, b *websocket.Conn, errorC chan<- error) {
+func loopMessagePass(a *websocket.Conn, b *websocket.Conn, check func([]byte) error, errorC chan<- error) {
 	var err error
 	var m []byte
 	var msgType int
@@ -135,6 +148,12 @@ out:
 			log.Debug("client - 2")
 			break out
 		}
+		if check != nil {
+			err = check(m)
+			if err != nil {
+				break out
+			}
+		}
 		//log.Debugf("sending message=%s", string(m))
 		err = b.WriteMessage(msgType, m)
 		if err == io.EOF {